@@ -0,0 +1,66 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Policy 单个请求的缓存策略
+type Policy string
+
+// 缓存策略常量
+const (
+	// PolicyAuto 由服务根据请求是否确定性决定是否使用缓存
+	PolicyAuto Policy = ""
+	// PolicyForce 强制使用缓存，即使请求不是确定性的
+	PolicyForce Policy = "force"
+	// PolicyBypass 跳过缓存
+	PolicyBypass Policy = "bypass"
+)
+
+// Valid 检查缓存策略是否有效
+func (p Policy) Valid() bool {
+	switch p {
+	case PolicyAuto, PolicyForce, PolicyBypass:
+		return true
+	}
+	return false
+}
+
+// Cache 缓存后端接口
+type Cache interface {
+	// Get 获取缓存值，不存在或已过期时返回false
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set 设置缓存值，ttl不大于0时不过期
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete 删除缓存值
+	Delete(ctx context.Context, key string) error
+}
+
+// Key 使用请求的规范JSON编码计算缓存键。encoding/json对结构体字段按定义顺序、
+// 对map按键排序编码，因此相同的请求总是得到相同的键
+func Key(namespace string, request interface{}) (string, error) {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request for cache key: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return namespace + ":" + hex.EncodeToString(sum[:]), nil
+}
+
+// expired 检查过期时间是否已过，零值表示不过期
+func expired(expiresAt, now time.Time) bool {
+	return !expiresAt.IsZero() && !now.Before(expiresAt)
+}
+
+// expiry 根据ttl计算过期时间
+func expiry(ttl time.Duration, now time.Time) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return now.Add(ttl)
+}
@@ -0,0 +1,97 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestKeyIsStable(t *testing.T) {
+	a, err := Key("chat", map[string]interface{}{"model": "gpt-4o", "seed": 1})
+	if err != nil {
+		t.Fatalf("Key failed: %v", err)
+	}
+	b, _ := Key("chat", map[string]interface{}{"seed": 1, "model": "gpt-4o"})
+	if a != b {
+		t.Errorf("keys differ for equal requests: %s != %s", a, b)
+	}
+
+	c, _ := Key("chat", map[string]interface{}{"model": "gpt-4o", "seed": 2})
+	if a == c {
+		t.Error("keys equal for different requests")
+	}
+	d, _ := Key("embeddings", map[string]interface{}{"model": "gpt-4o", "seed": 1})
+	if a == d {
+		t.Error("keys equal across namespaces")
+	}
+}
+
+func TestMemoryCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(2)
+
+	c.Set(ctx, "a", []byte("1"), 0)
+	c.Set(ctx, "b", []byte("2"), 0)
+	if _, ok, _ := c.Get(ctx, "a"); !ok {
+		t.Fatal("expected a to be cached")
+	}
+	c.Set(ctx, "c", []byte("3"), 0)
+
+	if _, ok, _ := c.Get(ctx, "b"); ok {
+		t.Error("expected b to be evicted")
+	}
+	if value, ok, _ := c.Get(ctx, "a"); !ok || string(value) != "1" {
+		t.Errorf("a = %q, %v; want 1, true", value, ok)
+	}
+	if c.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", c.Len())
+	}
+}
+
+func TestMemoryCacheExpires(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(0)
+
+	c.Set(ctx, "short", []byte("x"), time.Millisecond)
+	c.Set(ctx, "forever", []byte("y"), 0)
+	time.Sleep(5 * time.Millisecond)
+
+	if _, ok, _ := c.Get(ctx, "short"); ok {
+		t.Error("expected expired entry to miss")
+	}
+	if _, ok, _ := c.Get(ctx, "forever"); !ok {
+		t.Error("expected entry without ttl to hit")
+	}
+}
+
+func TestFileCacheRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	c, err := NewFileCache(dir)
+	if err != nil {
+		t.Fatalf("NewFileCache failed: %v", err)
+	}
+	if err := c.Set(ctx, "chat:abc", []byte(`{"id":"1"}`), time.Hour); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	reopened, _ := NewFileCache(dir)
+	value, ok, err := reopened.Get(ctx, "chat:abc")
+	if err != nil || !ok || string(value) != `{"id":"1"}` {
+		t.Fatalf("Get = %q, %v, %v", value, ok, err)
+	}
+
+	if err := reopened.Delete(ctx, "chat:abc"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, ok, _ := reopened.Get(ctx, "chat:abc"); ok {
+		t.Error("expected deleted entry to miss")
+	}
+
+	c.Set(ctx, "old", []byte("x"), time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	if _, ok, _ := c.Get(ctx, "old"); ok {
+		t.Error("expected expired entry to miss")
+	}
+}
@@ -0,0 +1,8 @@
+// Package cache provides response caching backends for the New-API Go SDK.
+//
+// The Cache interface stores opaque byte values under string keys with an
+// optional TTL. MemoryCache is an in-process LRU cache and FileCache stores one
+// file per entry on disk; any other store can be plugged in by implementing the
+// interface. Services compute keys with Key, which hashes the canonical JSON
+// encoding of the full request.
+package cache
@@ -0,0 +1,97 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// fileEntry 文件缓存条目
+type fileEntry struct {
+	Key       string    `json:"key"`
+	Value     []byte    `json:"value"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// FileCache 磁盘缓存，每个条目保存为目录下的一个JSON文件
+type FileCache struct {
+	dir string
+}
+
+// NewFileCache 创建磁盘缓存，目录不存在时自动创建
+func NewFileCache(dir string) (*FileCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	return &FileCache{dir: dir}, nil
+}
+
+// path 获取缓存文件路径，文件名为键的哈希以支持任意键
+func (c *FileCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// Get 获取缓存值
+func (c *FileCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	path := c.path(key)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("failed to read cache entry: %w", err)
+	}
+
+	var entry fileEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Key != key {
+		// 损坏或冲突的条目视为未命中
+		return nil, false, nil
+	}
+	if expired(entry.ExpiresAt, time.Now()) {
+		os.Remove(path)
+		return nil, false, nil
+	}
+	return entry.Value, true, nil
+}
+
+// Set 设置缓存值，先写入临时文件再重命名以保证原子性
+func (c *FileCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	data, err := json.Marshal(fileEntry{Key: key, Value: value, ExpiresAt: expiry(ttl, time.Now())})
+	if err != nil {
+		return fmt.Errorf("failed to marshal cache entry: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(c.dir, "entry-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.path(key)); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to save cache entry: %w", err)
+	}
+	return nil
+}
+
+// Delete 删除缓存值
+func (c *FileCache) Delete(ctx context.Context, key string) error {
+	if err := os.Remove(c.path(key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete cache entry: %w", err)
+	}
+	return nil
+}
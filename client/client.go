@@ -6,13 +6,19 @@ import (
 	"sync"
 	"time"
 
+	"github.com/hewenyu/newapi-go/cache"
 	"github.com/hewenyu/newapi-go/config"
 	"github.com/hewenyu/newapi-go/internal/transport"
 	"github.com/hewenyu/newapi-go/internal/utils"
 	"github.com/hewenyu/newapi-go/services/audio"
 	"github.com/hewenyu/newapi-go/services/chat"
+	"github.com/hewenyu/newapi-go/services/completions"
 	"github.com/hewenyu/newapi-go/services/embeddings"
+	"github.com/hewenyu/newapi-go/services/image"
+	"github.com/hewenyu/newapi-go/services/models"
+	"github.com/hewenyu/newapi-go/services/moderation"
 	"github.com/hewenyu/newapi-go/types"
+	"github.com/hewenyu/newapi-go/usage"
 )
 
 // Client 是SDK的核心客户端结构
@@ -27,10 +33,27 @@ type Client struct {
 	mu sync.RWMutex
 	// chatService 聊天服务
 	chatService *chat.ChatService
+	// completionService 旧版文本补全服务
+	completionService *completions.CompletionService
 	// embeddingService 嵌入服务
 	embeddingService *embeddings.EmbeddingService
 	// audioService 音频服务
 	audioService *audio.AudioService
+	// imageService 图像服务
+	imageService *image.ImageService
+	// moderationService 内容审核服务
+	moderationService *moderation.ModerationService
+	// modelService 模型服务
+	modelService *models.ModelService
+	// cache 响应缓存，重新初始化服务时保留
+	cache    cache.Cache
+	cacheTTL time.Duration
+	// usageTracker 用量跟踪器，重新初始化服务时保留
+	usageTracker *usage.Tracker
+	// streamStatsHook 流统计钩子，重新初始化服务时保留
+	streamStatsHook chat.StreamStatsHook
+	// streamOptions 流式重连选项，重新初始化传输层时保留
+	streamOptions *transport.StreamOptions
 }
 
 // NewClient 创建一个新的客户端实例
@@ -60,12 +83,26 @@ func NewClient(options ...ClientOption) (*Client, error) {
 	// 初始化聊天服务
 	client.chatService = chat.NewChatService(client.transport, client.logger)
 
+	// 初始化文本补全服务
+	client.completionService = completions.NewCompletionService(client.transport, client.logger)
+
 	// 初始化嵌入服务
 	client.embeddingService = embeddings.NewEmbeddingService(client.transport, client.logger)
 
 	// 初始化音频服务
 	client.audioService = audio.NewAudioService(client.transport, client.logger)
 
+	// 初始化图像服务
+	client.imageService = image.NewImageService(client.transport, client.logger)
+
+	// 初始化内容审核服务
+	client.moderationService = moderation.NewModerationService(client.transport, client.logger)
+
+	// 初始化模型服务
+	client.modelService = models.NewModelService(client.transport, client.logger)
+
+	client.applyUsageTracker()
+
 	client.logger.Info("Client initialized successfully")
 
 	return client, nil
@@ -126,12 +163,29 @@ func (c *Client) UpdateConfig(cfg *config.Config) error {
 	// 重新初始化聊天服务
 	c.chatService = chat.NewChatService(c.transport, c.logger)
 
+	// 重新初始化文本补全服务
+	c.completionService = completions.NewCompletionService(c.transport, c.logger)
+
 	// 重新初始化嵌入服务
 	c.embeddingService = embeddings.NewEmbeddingService(c.transport, c.logger)
 
 	// 重新初始化音频服务
 	c.audioService = audio.NewAudioService(c.transport, c.logger)
 
+	// 重新初始化图像服务
+	c.imageService = image.NewImageService(c.transport, c.logger)
+
+	// 重新初始化内容审核服务
+	c.moderationService = moderation.NewModerationService(c.transport, c.logger)
+
+	// 重新初始化模型服务
+	c.modelService = models.NewModelService(c.transport, c.logger)
+
+	c.applyStreamOptions()
+	c.applyCache()
+	c.applyUsageTracker()
+	c.applyStreamStatsHook()
+
 	c.logger.Info("Client configuration updated successfully")
 
 	return nil
@@ -219,10 +273,34 @@ func (c *Client) SetLogger(logger utils.Logger) {
 		c.chatService = chat.NewChatService(c.transport, c.logger)
 	}
 
+	// 更新文本补全服务的日志器
+	if c.completionService != nil {
+		c.completionService = completions.NewCompletionService(c.transport, c.logger)
+	}
+
 	// 更新嵌入服务的日志器
 	if c.embeddingService != nil {
 		c.embeddingService = embeddings.NewEmbeddingService(c.transport, c.logger)
 	}
+
+	// 更新图像服务的日志器
+	if c.imageService != nil {
+		c.imageService = image.NewImageService(c.transport, c.logger)
+	}
+
+	// 更新内容审核服务的日志器
+	if c.moderationService != nil {
+		c.moderationService = moderation.NewModerationService(c.transport, c.logger)
+	}
+
+	// 更新模型服务的日志器
+	if c.modelService != nil {
+		c.modelService = models.NewModelService(c.transport, c.logger)
+	}
+
+	c.applyCache()
+	c.applyUsageTracker()
+	c.applyStreamStatsHook()
 }
 
 // SetTimeout 设置超时时间
@@ -246,6 +324,110 @@ func (c *Client) SetRetryPolicy(policy transport.RetryPolicy) {
 	}
 }
 
+// SetStreamReconnect 开启流式响应断线后携带Last-Event-ID的重连，maxRetries为0时关闭（默认）。
+// 重连会重新发送计费的请求，只应在服务端支持按Last-Event-ID续传时开启
+func (c *Client) SetStreamReconnect(maxRetries int, delay time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	options := transport.DefaultStreamOptions()
+	options.Retry = maxRetries > 0
+	options.MaxRetries = maxRetries
+	options.RetryDelay = delay
+	c.streamOptions = options
+	c.applyStreamOptions()
+}
+
+// applyStreamOptions 将流式重连选项应用到传输层，调用方需持有锁
+func (c *Client) applyStreamOptions() {
+	if c.streamOptions == nil {
+		return
+	}
+	if hc, ok := c.transport.(interface {
+		SetStreamOptions(*transport.StreamOptions)
+	}); ok {
+		hc.SetStreamOptions(c.streamOptions)
+	}
+}
+
+// SetCache 为聊天和嵌入服务设置响应缓存，cache为nil时禁用缓存，ttl不大于0时缓存不过期
+func (c *Client) SetCache(responseCache cache.Cache, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.cache = responseCache
+	c.cacheTTL = ttl
+	c.applyCache()
+}
+
+// applyCache 将响应缓存应用到服务，调用方需持有锁
+func (c *Client) applyCache() {
+	if c.chatService != nil {
+		c.chatService.SetCache(c.cache, c.cacheTTL)
+	}
+	if c.embeddingService != nil {
+		c.embeddingService.SetCache(c.cache, c.cacheTTL)
+	}
+}
+
+// SetUsageTracker 设置用量跟踪器，tracker为nil时停止记录用量
+func (c *Client) SetUsageTracker(tracker *usage.Tracker) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.usageTracker = tracker
+	c.applyUsageTracker()
+}
+
+// GetUsageTracker 获取用量跟踪器，未设置时返回nil
+func (c *Client) GetUsageTracker() *usage.Tracker {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.usageTracker
+}
+
+// applyUsageTracker 将用量跟踪器应用到服务，调用方需持有锁
+func (c *Client) applyUsageTracker() {
+	// 避免将nil指针包装为非nil的接口值
+	var recorder usage.Recorder
+	if c.usageTracker != nil {
+		recorder = c.usageTracker
+	}
+
+	if c.chatService != nil {
+		c.chatService.SetUsageRecorder(recorder)
+	}
+	if c.completionService != nil {
+		c.completionService.SetUsageRecorder(recorder)
+	}
+	if c.embeddingService != nil {
+		c.embeddingService.SetUsageRecorder(recorder)
+	}
+	if c.audioService != nil {
+		c.audioService.SetUsageRecorder(recorder)
+	}
+	if c.imageService != nil {
+		c.imageService.SetUsageRecorder(recorder)
+	}
+}
+
+// SetStreamStatsHook 设置流统计钩子，每个流式聊天请求结束时接收时延和吞吐统计，hook为nil时取消
+func (c *Client) SetStreamStatsHook(hook chat.StreamStatsHook) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.streamStatsHook = hook
+	c.applyStreamStatsHook()
+}
+
+// applyStreamStatsHook 将流统计钩子应用到聊天服务，调用方需持有锁
+func (c *Client) applyStreamStatsHook() {
+	if c.chatService != nil {
+		c.chatService.SetStreamStatsHook(c.streamStatsHook)
+	}
+}
+
 // IsHealthy 检查客户端健康状态
 func (c *Client) IsHealthy() bool {
 	c.mu.RLock()
@@ -307,6 +489,18 @@ func (c *Client) CreateChatCompletionStream(ctx context.Context, messages []type
 	return c.chatService.CreateChatCompletionStream(ctx, messages, options...)
 }
 
+// StreamChatCompletion 创建类型化的流式聊天完成，可通过range迭代块或增量文本
+func (c *Client) StreamChatCompletion(ctx context.Context, messages []types.ChatMessage, options ...chat.ChatOption) (*chat.Stream, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.chatService == nil {
+		return nil, fmt.Errorf("chat service not initialized")
+	}
+
+	return c.chatService.StreamChatCompletion(ctx, messages, options...)
+}
+
 // SimpleChat 简单聊天
 func (c *Client) SimpleChat(ctx context.Context, message string, options ...chat.ChatOption) (*types.ChatCompletionResponse, error) {
 	c.mu.RLock()
@@ -427,6 +621,18 @@ func (c *Client) CountTokens(messages []types.ChatMessage) int {
 	return c.chatService.CountTokens(messages)
 }
 
+// CountToolTokens 计算工具定义的Token数量
+func (c *Client) CountToolTokens(tools []types.Tool) int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.chatService == nil {
+		return 0
+	}
+
+	return c.chatService.CountToolTokens(tools)
+}
+
 // TruncateMessages 截断消息
 func (c *Client) TruncateMessages(messages []types.ChatMessage, maxTokens int) []types.ChatMessage {
 	c.mu.RLock()
@@ -439,6 +645,100 @@ func (c *Client) TruncateMessages(messages []types.ChatMessage, maxTokens int) [
 	return c.chatService.TruncateMessages(messages, maxTokens)
 }
 
+// FitContext 裁剪消息以适应模型的上下文窗口
+func (c *Client) FitContext(ctx context.Context, messages []types.ChatMessage, options ...chat.ContextOption) ([]types.ChatMessage, *chat.ContextReport, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.chatService == nil {
+		return nil, nil, fmt.Errorf("chat service not initialized")
+	}
+
+	return c.chatService.FitContext(ctx, messages, options...)
+}
+
+// NewConversation 创建新的会话
+func (c *Client) NewConversation(options ...chat.ConversationOption) (*chat.Conversation, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.chatService == nil {
+		return nil, fmt.Errorf("chat service not initialized")
+	}
+
+	return c.chatService.NewConversation(options...), nil
+}
+
+// LoadConversation 从存储加载会话
+func (c *Client) LoadConversation(ctx context.Context, store chat.ConversationStore, id string, options ...chat.ConversationOption) (*chat.Conversation, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.chatService == nil {
+		return nil, fmt.Errorf("chat service not initialized")
+	}
+
+	return chat.LoadConversation(ctx, c.chatService, store, id, options...)
+}
+
+// GetCompletionService 获取旧版文本补全服务
+func (c *Client) GetCompletionService() *completions.CompletionService {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.completionService
+}
+
+// ========== 文本补全服务代理方法 ==========
+
+// CreateCompletion 创建单个提示词的文本补全
+func (c *Client) CreateCompletion(ctx context.Context, prompt string, options ...completions.CompletionOption) (*types.CompletionResponse, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.completionService == nil {
+		return nil, fmt.Errorf("completion service not initialized")
+	}
+
+	return c.completionService.CreateCompletion(ctx, prompt, options...)
+}
+
+// CreateCompletions 创建多个提示词的文本补全
+func (c *Client) CreateCompletions(ctx context.Context, prompts []string, options ...completions.CompletionOption) (*types.CompletionResponse, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.completionService == nil {
+		return nil, fmt.Errorf("completion service not initialized")
+	}
+
+	return c.completionService.CreateCompletions(ctx, prompts, options...)
+}
+
+// CreateCompletionFromTokens 使用Token作为提示词创建文本补全
+func (c *Client) CreateCompletionFromTokens(ctx context.Context, tokens []int, options ...completions.CompletionOption) (*types.CompletionResponse, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.completionService == nil {
+		return nil, fmt.Errorf("completion service not initialized")
+	}
+
+	return c.completionService.CreateCompletionFromTokens(ctx, tokens, options...)
+}
+
+// CreateCompletionStream 创建流式文本补全
+func (c *Client) CreateCompletionStream(ctx context.Context, prompt string, options ...completions.CompletionOption) (types.StreamResponse, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.completionService == nil {
+		return nil, fmt.Errorf("completion service not initialized")
+	}
+
+	return c.completionService.CreateCompletionStream(ctx, prompt, options...)
+}
+
 // GetEmbeddingService 获取嵌入服务
 func (c *Client) GetEmbeddingService() *embeddings.EmbeddingService {
 	c.mu.RLock()
@@ -473,6 +773,18 @@ func (c *Client) CreateEmbeddings(ctx context.Context, texts []string, options .
 	return c.embeddingService.CreateEmbeddings(ctx, texts, options...)
 }
 
+// EmbedAll 拆分为多个请求并发创建大量文本的嵌入向量，结果按输入顺序合并
+func (c *Client) EmbedAll(ctx context.Context, texts []string, options ...embeddings.BatchOption) (*embeddings.BatchResult, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.embeddingService == nil {
+		return nil, fmt.Errorf("embedding service not initialized")
+	}
+
+	return c.embeddingService.EmbedAll(ctx, texts, options...)
+}
+
 // CreateEmbeddingFromTokens 从token创建嵌入向量
 func (c *Client) CreateEmbeddingFromTokens(ctx context.Context, tokens []int, options ...embeddings.EmbeddingOption) (*types.EmbeddingResponse, error) {
 	c.mu.RLock()
@@ -614,3 +926,129 @@ func (c *Client) GetMaxAudioFileSize() int64 {
 
 	return c.audioService.GetMaxFileSize()
 }
+
+// ========== 图像服务代理方法 ==========
+
+// GetImageService 获取图像服务
+func (c *Client) GetImageService() *image.ImageService {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.imageService
+}
+
+// CreateImage 根据提示词生成图像
+func (c *Client) CreateImage(ctx context.Context, prompt string, options ...image.ImageOption) (*types.ImageResponse, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.imageService == nil {
+		return nil, fmt.Errorf("image service is not initialized")
+	}
+
+	return c.imageService.CreateImage(ctx, prompt, options...)
+}
+
+// ========== 内容审核服务代理方法 ==========
+
+// GetModerationService 获取内容审核服务，可作为chat.WithModeration的审核器
+func (c *Client) GetModerationService() *moderation.ModerationService {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.moderationService
+}
+
+// Moderate 审核单个文本
+func (c *Client) Moderate(ctx context.Context, text string, options ...moderation.ModerationOption) (*types.ModerationResponse, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.moderationService == nil {
+		return nil, fmt.Errorf("moderation service not initialized")
+	}
+
+	return c.moderationService.Moderate(ctx, text, options...)
+}
+
+// ModerateBatch 批量审核文本
+func (c *Client) ModerateBatch(ctx context.Context, texts []string, options ...moderation.ModerationOption) (*types.ModerationResponse, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.moderationService == nil {
+		return nil, fmt.Errorf("moderation service not initialized")
+	}
+
+	return c.moderationService.ModerateBatch(ctx, texts, options...)
+}
+
+// ModerateMultimodal 将文本和图片作为同一内容整体审核
+func (c *Client) ModerateMultimodal(ctx context.Context, inputs []types.ModerationInput, options ...moderation.ModerationOption) (*types.ModerationResponse, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.moderationService == nil {
+		return nil, fmt.Errorf("moderation service not initialized")
+	}
+
+	return c.moderationService.ModerateMultimodal(ctx, inputs, options...)
+}
+
+// ========== 模型服务代理方法 ==========
+
+// GetModelService 获取模型服务
+func (c *Client) GetModelService() *models.ModelService {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.modelService
+}
+
+// ListModels 获取网关可用的模型列表
+func (c *Client) ListModels(ctx context.Context) ([]types.Model, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.modelService == nil {
+		return nil, fmt.Errorf("model service not initialized")
+	}
+
+	return c.modelService.ListModels(ctx)
+}
+
+// GetModel 获取指定模型
+func (c *Client) GetModel(ctx context.Context, id string) (*types.Model, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.modelService == nil {
+		return nil, fmt.Errorf("model service not initialized")
+	}
+
+	return c.modelService.GetModel(ctx, id)
+}
+
+// ListModelInfo 获取模型列表并合并模型能力
+func (c *Client) ListModelInfo(ctx context.Context) ([]models.ModelInfo, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.modelService == nil {
+		return nil, fmt.Errorf("model service not initialized")
+	}
+
+	return c.modelService.ListModelInfo(ctx)
+}
+
+// GetModelInfo 获取指定模型并合并模型能力
+func (c *Client) GetModelInfo(ctx context.Context, id string) (*models.ModelInfo, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.modelService == nil {
+		return nil, fmt.Errorf("model service not initialized")
+	}
+
+	return c.modelService.GetModelInfo(ctx, id)
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/hewenyu/newapi-go/config"
+	"github.com/hewenyu/newapi-go/usage"
 )
 
 // ClientOption 定义客户端配置选项的函数类型
@@ -73,6 +74,13 @@ func WithConfigBuilder(builder *config.ConfigBuilder) ClientOption {
 	}
 }
 
+// WithUsageTracker 设置用量跟踪器，所有服务的请求用量都记录到该跟踪器
+func WithUsageTracker(tracker *usage.Tracker) ClientOption {
+	return func(c *Client) {
+		c.usageTracker = tracker
+	}
+}
+
 // applyOptions 应用所有选项到客户端
 func applyOptions(client *Client, options []ClientOption) {
 	for _, option := range options {
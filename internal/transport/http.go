@@ -49,6 +49,7 @@ type HTTPClient struct {
 	requestBuilder  *RequestBuilder
 	responseHandler *ResponseHandler
 	retryPolicy     RetryPolicy
+	streamOptions   *StreamOptions
 	middleware      []Middleware
 	mu              sync.RWMutex
 }
@@ -81,6 +82,7 @@ func NewHTTPClient(baseURL, apiKey string, options ...HTTPOption) *HTTPClient {
 		requestBuilder:  NewRequestBuilder(baseURL, apiKey, 30*time.Second),
 		responseHandler: NewResponseHandler(32 * 1024 * 1024), // 32MB
 		retryPolicy:     NewDefaultRetryPolicy(),
+		streamOptions:   DefaultStreamOptions(),
 		middleware:      make([]Middleware, 0),
 	}
 
@@ -176,7 +178,25 @@ func (hc *HTTPClient) PostStream(ctx context.Context, path string, body interfac
 		return nil, err
 	}
 
-	return NewJSONStreamReader(ctx, reader), nil
+	hc.mu.RLock()
+	options := hc.streamOptions
+	hc.mu.RUnlock()
+
+	if options == nil || !options.Retry {
+		return NewJSONStreamReader(ctx, reader), nil
+	}
+
+	// 断线后携带Last-Event-ID重新发送同一请求，由服务端从断点继续推送
+	reconnect := func(lastEventID string) (io.ReadCloser, error) {
+		req, err := hc.requestBuilder.BuildStreamRequest(ctx, http.MethodPost, path, body)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Last-Event-ID", lastEventID)
+		return hc.DoStream(ctx, req)
+	}
+
+	return newResumableStreamReader(ctx, reader, options, reconnect), nil
 }
 
 // PostMultipart 发送multipart POST请求
@@ -206,6 +226,14 @@ func (hc *HTTPClient) SetRetryPolicy(policy RetryPolicy) {
 	hc.retryPolicy = policy
 }
 
+// SetStreamOptions 设置流式选项，Retry、MaxRetries和RetryDelay控制断线后基于Last-Event-ID的重连，重连需要显式开启
+func (hc *HTTPClient) SetStreamOptions(options *StreamOptions) {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+
+	hc.streamOptions = options
+}
+
 // SetMiddleware 设置中间件
 func (hc *HTTPClient) SetMiddleware(middleware ...Middleware) {
 	hc.mu.Lock()
@@ -314,6 +342,13 @@ func WithRetryPolicy(policy RetryPolicy) HTTPOption {
 	}
 }
 
+// WithStreamOptions 设置流式选项
+func WithStreamOptions(options *StreamOptions) HTTPOption {
+	return func(hc *HTTPClient) {
+		hc.SetStreamOptions(options)
+	}
+}
+
 // WithMiddleware 添加中间件
 func WithMiddleware(middleware ...Middleware) HTTPOption {
 	return func(hc *HTTPClient) {
@@ -0,0 +1,173 @@
+package transport
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strconv"
+)
+
+// sseReadBufferSize SSE解码器的读取缓冲区大小，只影响单次读取量，不限制行长度
+const sseReadBufferSize = 4096
+
+// utf8BOM UTF-8字节序标记
+var utf8BOM = []byte("\xEF\xBB\xBF")
+
+// SSEDecoder 按WHATWG规范解析text/event-stream的同步拉取式解码器。
+// 支持CRLF、LF和单独CR换行，行长度不受限制；"data:"后的单个空格可选，
+// 注释行和":"心跳被忽略，多行data以"\n"连接。每次调用Next只读取解析下一个事件所需的数据
+type SSEDecoder struct {
+	r           *bufio.Reader
+	line        []byte
+	data        bytes.Buffer
+	eventType   string
+	lastEventID string
+	retry       int
+	hasRetry    bool
+	skipLF      bool
+	started     bool
+}
+
+// NewSSEDecoder 创建SSE解码器
+func NewSSEDecoder(r io.Reader) *SSEDecoder {
+	return &SSEDecoder{r: bufio.NewReaderSize(r, sseReadBufferSize)}
+}
+
+// Next 读取下一个事件。流结束时返回io.EOF，未以空行结束的最后一个事件按规范丢弃
+func (d *SSEDecoder) Next() (*StreamEvent, error) {
+	for {
+		line, err := d.readLine()
+		if err != nil {
+			return nil, err
+		}
+
+		// 空行表示事件结束
+		if len(line) == 0 {
+			if event := d.dispatch(); event != nil {
+				return event, nil
+			}
+			continue
+		}
+
+		d.processLine(line)
+	}
+}
+
+// LastEventID 获取最近一次收到的事件ID，可用于断线重连时的Last-Event-ID请求头
+func (d *SSEDecoder) LastEventID() string {
+	return d.lastEventID
+}
+
+// Retry 获取服务端通过retry字段建议的重连间隔（毫秒），未设置时返回false
+func (d *SSEDecoder) Retry() (int, bool) {
+	return d.retry, d.hasRetry
+}
+
+// readLine 读取一行，不包含行结束符。返回的切片在下一次调用前有效
+func (d *SSEDecoder) readLine() ([]byte, error) {
+	d.line = d.line[:0]
+	for {
+		if d.r.Buffered() == 0 {
+			if _, err := d.r.Peek(1); err != nil {
+				return nil, err
+			}
+		}
+		buf, _ := d.r.Peek(d.r.Buffered())
+
+		// 流开头的BOM需要忽略，只有首字节可能属于BOM时才等待后续字节
+		if !d.started {
+			d.started = true
+			if buf[0] == utf8BOM[0] {
+				if prefix, _ := d.r.Peek(len(utf8BOM)); bytes.Equal(prefix, utf8BOM) {
+					d.r.Discard(len(utf8BOM))
+				}
+				continue
+			}
+		}
+
+		// CR之后紧跟的LF属于同一个行结束符
+		if d.skipLF {
+			d.skipLF = false
+			if buf[0] == '\n' {
+				d.r.Discard(1)
+				continue
+			}
+		}
+
+		i := bytes.IndexAny(buf, "\r\n")
+		if i < 0 {
+			d.line = append(d.line, buf...)
+			d.r.Discard(len(buf))
+			continue
+		}
+
+		d.line = append(d.line, buf[:i]...)
+		d.skipLF = buf[i] == '\r'
+		d.r.Discard(i + 1)
+		return d.line, nil
+	}
+}
+
+// processLine 处理一个非空行
+func (d *SSEDecoder) processLine(line []byte) {
+	// 注释行
+	if line[0] == ':' {
+		return
+	}
+
+	field, value := line, []byte(nil)
+	if i := bytes.IndexByte(line, ':'); i >= 0 {
+		field, value = line[:i], line[i+1:]
+		if len(value) > 0 && value[0] == ' ' {
+			value = value[1:]
+		}
+	}
+
+	switch string(field) {
+	case "event":
+		d.eventType = string(value)
+	case "data":
+		d.data.Write(value)
+		d.data.WriteByte('\n')
+	case "id":
+		if bytes.IndexByte(value, 0) < 0 {
+			d.lastEventID = string(value)
+		}
+	case "retry":
+		if len(value) > 0 && isASCIIDigits(value) {
+			if retry, err := strconv.Atoi(string(value)); err == nil {
+				d.retry, d.hasRetry = retry, true
+			}
+		}
+	}
+}
+
+// dispatch 结束当前事件，data为空时不产生事件
+func (d *SSEDecoder) dispatch() *StreamEvent {
+	defer func() {
+		d.data.Reset()
+		d.eventType = ""
+	}()
+
+	if d.data.Len() == 0 {
+		return nil
+	}
+
+	data := d.data.Bytes()
+	return &StreamEvent{
+		Event: d.eventType,
+		Data:  string(data[:len(data)-1]),
+		ID:    d.lastEventID,
+		Retry: d.retry,
+	}
+}
+
+// isASCIIDigits 检查是否只包含ASCII数字
+func isASCIIDigits(value []byte) bool {
+	for _, b := range value {
+		if b < '0' || b > '9' {
+			return false
+		}
+	}
+	return true
+}
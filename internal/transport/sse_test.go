@@ -0,0 +1,221 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// decodeAll 解码所有事件
+func decodeAll(t testing.TB, r io.Reader) []StreamEvent {
+	decoder := NewSSEDecoder(r)
+	var events []StreamEvent
+	for {
+		event, err := decoder.Next()
+		if err == io.EOF {
+			return events
+		}
+		if err != nil {
+			t.Fatalf("Next failed: %v", err)
+		}
+		events = append(events, *event)
+	}
+}
+
+func TestSSEDecoder(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []StreamEvent
+	}{
+		{
+			name:  "data without space",
+			input: "data:{\"a\":1}\n\n",
+			want:  []StreamEvent{{Data: `{"a":1}`}},
+		},
+		{
+			name:  "only one leading space is removed",
+			input: "data:   indented\n\n",
+			want:  []StreamEvent{{Data: "  indented"}},
+		},
+		{
+			name:  "multi-line data",
+			input: "data: first\ndata:\ndata: third\n\n",
+			want:  []StreamEvent{{Data: "first\n\nthird"}},
+		},
+		{
+			name:  "comments and keep-alives",
+			input: ": ping\n:\n\ndata: x\n: inside\n\n",
+			want:  []StreamEvent{{Data: "x"}},
+		},
+		{
+			name:  "crlf and lone cr",
+			input: "event: a\r\ndata: 1\r\n\r\ndata: 2\r\rdata: 3\n\n",
+			want:  []StreamEvent{{Event: "a", Data: "1"}, {Data: "2"}, {Data: "3"}},
+		},
+		{
+			name:  "id persists and retry",
+			input: "id: 7\nretry: 1500\ndata: a\n\ndata: b\n\nid\ndata: c\n\n",
+			want:  []StreamEvent{{Data: "a", ID: "7", Retry: 1500}, {Data: "b", ID: "7", Retry: 1500}, {Data: "c", Retry: 1500}},
+		},
+		{
+			name:  "invalid retry and id with null are ignored",
+			input: "retry: 1s\nid: a\x00b\ndata: x\n\n",
+			want:  []StreamEvent{{Data: "x"}},
+		},
+		{
+			name:  "event without data is not dispatched",
+			input: "event: ping\n\ndata: x\n\n",
+			want:  []StreamEvent{{Data: "x"}},
+		},
+		{
+			name:  "field without colon",
+			input: "data\n\n",
+			want:  []StreamEvent{{Data: ""}},
+		},
+		{
+			name:  "bom is stripped",
+			input: "\xEF\xBB\xBFdata: x\n\n",
+			want:  []StreamEvent{{Data: "x"}},
+		},
+		{
+			name:  "incomplete final event is discarded",
+			input: "data: x\n\ndata: partial\n",
+			want:  []StreamEvent{{Data: "x"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := decodeAll(t, strings.NewReader(tt.input))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("events = %#v, want %#v", got, tt.want)
+			}
+			oneByte := decodeAll(t, iotest.OneByteReader(strings.NewReader(tt.input)))
+			if !reflect.DeepEqual(oneByte, tt.want) {
+				t.Errorf("one-byte events = %#v, want %#v", oneByte, tt.want)
+			}
+		})
+	}
+}
+
+func TestSSEDecoderLargeLine(t *testing.T) {
+	payload := strings.Repeat("x", 1<<20)
+	events := decodeAll(t, strings.NewReader("data: "+payload+"\n\n"))
+	if len(events) != 1 || events[0].Data != payload {
+		t.Fatalf("large payload not decoded, got %d events", len(events))
+	}
+}
+
+// stepReader 每次Read只返回一个预设的片段，用于验证解码器不会读取超过所需的数据
+type stepReader struct {
+	chunks []string
+	reads  int
+}
+
+func (r *stepReader) Read(p []byte) (int, error) {
+	if len(r.chunks) == 0 {
+		return 0, io.EOF
+	}
+	r.reads++
+	n := copy(p, r.chunks[0])
+	r.chunks[0] = r.chunks[0][n:]
+	if r.chunks[0] == "" {
+		r.chunks = r.chunks[1:]
+	}
+	return n, nil
+}
+
+func TestSSEDecoderReadsOnDemand(t *testing.T) {
+	r := &stepReader{chunks: []string{"data: 1\n\n", "data: 2\r", "\n\n"}}
+	decoder := NewSSEDecoder(r)
+
+	if event, err := decoder.Next(); err != nil || event.Data != "1" {
+		t.Fatalf("first event = %v, %v", event, err)
+	}
+	if r.reads != 1 {
+		t.Errorf("first event consumed %d reads, want 1", r.reads)
+	}
+	if event, err := decoder.Next(); err != nil || event.Data != "2" {
+		t.Fatalf("second event = %v, %v", event, err)
+	}
+	if _, err := decoder.Next(); err != io.EOF {
+		t.Errorf("expected EOF, got %v", err)
+	}
+}
+
+func TestJSONStreamReaderErrors(t *testing.T) {
+	reader := NewJSONStreamReader(context.Background(),
+		io.NopCloser(io.MultiReader(strings.NewReader("data: {\"a\":1}\n\n"), iotest.ErrReader(errors.New("boom")))))
+
+	if _, err := reader.Read(); err != nil {
+		t.Fatalf("first Read failed: %v", err)
+	}
+	if _, err := reader.Read(); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("expected read error, got %v", err)
+	}
+	if reader.Err() == nil {
+		t.Error("Err() should report the read error")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	cancelled := NewJSONStreamReader(ctx, io.NopCloser(strings.NewReader("data: {}\n\n")))
+	if _, err := cancelled.Read(); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+// FuzzSSEDecoder 任意输入都不应panic，且与读取分块方式无关
+func FuzzSSEDecoder(f *testing.F) {
+	f.Add([]byte("data: hello\n\n"))
+	f.Add([]byte("event: a\r\ndata:1\r\n\r\n"))
+	f.Add([]byte(": ping\rdata: x\rdata: y\r\r"))
+	f.Add([]byte("\xEF\xBB\xBFid: 1\nretry: 10\ndata\n\n"))
+	f.Add([]byte("data: partial"))
+
+	f.Fuzz(func(t *testing.T, input []byte) {
+		whole := decodeAll(t, bytes.NewReader(input))
+		oneByte := decodeAll(t, iotest.OneByteReader(bytes.NewReader(input)))
+		if !reflect.DeepEqual(whole, oneByte) {
+			t.Fatalf("chunking changed result: %#v vs %#v", whole, oneByte)
+		}
+		for _, event := range whole {
+			if strings.ContainsAny(event.Event, "\r\n") || strings.ContainsRune(event.Data, '\r') {
+				t.Fatalf("line terminator leaked into event: %#v", event)
+			}
+		}
+	})
+}
+
+// FuzzSSERoundTrip 编码后的任意data都应被原样解码
+func FuzzSSERoundTrip(f *testing.F) {
+	f.Add("hello", "\n")
+	f.Add("multi\nline\n\ndata", "\r\n")
+	f.Add(" leading space", "\r")
+
+	f.Fuzz(func(t *testing.T, data, newline string) {
+		if newline != "\n" && newline != "\r" && newline != "\r\n" {
+			newline = "\n"
+		}
+		if strings.ContainsRune(data, '\r') {
+			data = strings.ReplaceAll(data, "\r", "")
+		}
+
+		var encoded strings.Builder
+		for _, line := range strings.Split(data, "\n") {
+			encoded.WriteString("data: " + line + newline)
+		}
+		encoded.WriteString(newline)
+
+		events := decodeAll(t, iotest.HalfReader(strings.NewReader(encoded.String())))
+		if len(events) != 1 || events[0].Data != data {
+			t.Fatalf("round trip of %q = %#v", data, events)
+		}
+	})
+}
@@ -1,16 +1,17 @@
 package transport
 
 import (
-	"bufio"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/hewenyu/newapi-go/internal/utils"
 	"github.com/hewenyu/newapi-go/types"
+	"go.uber.org/zap"
 )
 
 // StreamEvent 流式事件结构体
@@ -21,207 +22,273 @@ type StreamEvent struct {
 	Retry int    `json:"retry,omitempty"`
 }
 
-// StreamProcessor 流式处理器
-type StreamProcessor struct {
-	reader    io.ReadCloser
-	scanner   *bufio.Scanner
-	ctx       context.Context
-	cancel    context.CancelFunc
-	eventChan chan StreamEvent
-	errorChan chan error
-	done      chan bool
-}
-
-// NewStreamProcessor 创建新的流式处理器
-func NewStreamProcessor(ctx context.Context, reader io.ReadCloser) *StreamProcessor {
-	ctx, cancel := context.WithCancel(ctx)
-
-	return &StreamProcessor{
-		reader:    reader,
-		scanner:   bufio.NewScanner(reader),
-		ctx:       ctx,
-		cancel:    cancel,
-		eventChan: make(chan StreamEvent, 100),
-		errorChan: make(chan error, 10),
-		done:      make(chan bool, 1),
-	}
-}
-
-// Start 启动流式处理
-func (sp *StreamProcessor) Start() {
-	go sp.processStream()
-}
-
-// Events 获取事件通道
-func (sp *StreamProcessor) Events() <-chan StreamEvent {
-	return sp.eventChan
+// StreamReader 流式读取器接口
+type StreamReader interface {
+	Read() (interface{}, error)
+	Close() error
+	Err() error
 }
 
-// Errors 获取错误通道
-func (sp *StreamProcessor) Errors() <-chan error {
-	return sp.errorChan
+// JSONStreamReader JSON流式读取器，在调用方的goroutine中同步解析SSE事件，不启动后台goroutine。
+// 通过HTTPClient.PostStream创建时，连接中断后若服务端曾下发事件ID，
+// 会按StreamOptions携带Last-Event-ID重新连接，并跳过重连前已收到的事件
+type JSONStreamReader struct {
+	reader  io.ReadCloser
+	decoder *SSEDecoder
+	ctx     context.Context
+	mu      sync.Mutex
+	err     error
+	closed  bool
+	// stop 注销上下文取消时关闭响应体的回调，流结束后不再挂在调用方的上下文上
+	stop func() bool
+
+	options     *StreamOptions
+	reconnect   func(lastEventID string) (io.ReadCloser, error)
+	lastEventID string
+	retry       time.Duration
+	hasRetry    bool
+	retries     int
+	connection  int
+	seen        map[string]int
+
+	bytesRead    int64
+	firstByte    time.Time
+	doneReceived bool
 }
 
-// Done 获取完成通道
-func (sp *StreamProcessor) Done() <-chan bool {
-	return sp.done
+// NewJSONStreamReader 创建JSON流式读取器
+func NewJSONStreamReader(ctx context.Context, reader io.ReadCloser) *JSONStreamReader {
+	jr := &JSONStreamReader{
+		reader: reader,
+		ctx:    ctx,
+	}
+	jr.decoder = NewSSEDecoder(&countingReader{reader: reader, owner: jr})
+	// 上下文取消时关闭响应体，使阻塞中的Read返回
+	jr.stop = context.AfterFunc(ctx, func() { jr.Close() })
+	return jr
 }
 
-// Close 关闭流式处理器
-func (sp *StreamProcessor) Close() error {
-	sp.cancel()
-	return sp.reader.Close()
+// newResumableStreamReader 创建支持断线重连的JSON流式读取器，reconnect携带Last-Event-ID重新发起请求
+func newResumableStreamReader(ctx context.Context, reader io.ReadCloser, options *StreamOptions, reconnect func(string) (io.ReadCloser, error)) *JSONStreamReader {
+	jr := NewJSONStreamReader(ctx, reader)
+	jr.options = options
+	jr.reconnect = reconnect
+	jr.seen = make(map[string]int)
+	return jr
 }
 
-// processStream 处理流式数据
-func (sp *StreamProcessor) processStream() {
-	defer func() {
-		close(sp.eventChan)
-		close(sp.errorChan)
-		close(sp.done)
-		sp.reader.Close()
-	}()
-
-	var event StreamEvent
-	var lines []string
-
-	for sp.scanner.Scan() {
-		select {
-		case <-sp.ctx.Done():
-			return
-		default:
+// Read 读取下一个JSON对象，以json.RawMessage返回以免调用方重复编解码。
+// 流正常结束或收到[DONE]时返回io.EOF
+func (jr *JSONStreamReader) Read() (interface{}, error) {
+	for {
+		if err := jr.ctx.Err(); err != nil {
+			return nil, err
 		}
 
-		line := strings.TrimSpace(sp.scanner.Text())
-
-		// 空行表示事件结束
-		if line == "" {
-			if len(lines) > 0 {
-				event = sp.parseEvent(lines)
-				if event.Data != "" || event.Event != "" {
-					sp.eventChan <- event
-					utils.LogStreamEvent(sp.ctx, event.Event, event.Data)
-				}
-				lines = nil
+		event, err := jr.decoder.Next()
+		if err != nil {
+			if err == io.EOF {
+				jr.stop()
+				return nil, io.EOF
 			}
+			// 上下文取消导致的读取错误以上下文错误返回
+			if ctxErr := jr.ctx.Err(); ctxErr != nil {
+				return nil, ctxErr
+			}
+			if jr.resume(err) {
+				continue
+			}
+			return nil, jr.setErr(types.NewStreamError(types.ErrTypeAPIError, types.ErrCodeStreamError,
+				fmt.Sprintf("stream read error: %v", err)))
+		}
+		utils.LogStreamEvent(jr.ctx, event.Event, event.Data)
+
+		if jr.duplicate(event) {
 			continue
 		}
 
-		lines = append(lines, line)
-	}
+		// 跳过特殊事件
+		if event.Data == "[DONE]" {
+			jr.mu.Lock()
+			jr.doneReceived = true
+			jr.mu.Unlock()
+			jr.stop()
+			return nil, io.EOF
+		}
 
-	// 处理最后一个事件
-	if len(lines) > 0 {
-		event = sp.parseEvent(lines)
-		if event.Data != "" || event.Event != "" {
-			sp.eventChan <- event
-			utils.LogStreamEvent(sp.ctx, event.Event, event.Data)
+		// 只校验JSON数据，不解码为通用结构
+		var data json.RawMessage
+		if err := json.Unmarshal([]byte(event.Data), &data); err != nil {
+			return nil, types.NewStreamError(types.ErrTypeAPIError, types.ErrCodeParseError,
+				fmt.Sprintf("failed to parse JSON: %v", err))
 		}
+
+		return data, nil
 	}
+}
 
-	// 检查扫描错误
-	if err := sp.scanner.Err(); err != nil {
-		sp.errorChan <- types.NewStreamError(types.ErrTypeAPIError, types.ErrCodeStreamError,
-			fmt.Sprintf("stream scan error: %v", err))
+// duplicate 记录事件ID和重连间隔，并检查事件是否在重连前已经收到。
+// 事件ID在后续事件间延续，因此只跳过ID出现在之前连接中的事件
+func (jr *JSONStreamReader) duplicate(event *StreamEvent) bool {
+	if retry, ok := jr.decoder.Retry(); ok {
+		jr.retry, jr.hasRetry = time.Duration(retry)*time.Millisecond, true
 	}
+	if event.ID == "" {
+		return false
+	}
+	jr.lastEventID = event.ID
 
-	sp.done <- true
+	if jr.seen == nil {
+		return false
+	}
+	if connection, ok := jr.seen[event.ID]; ok {
+		return connection < jr.connection
+	}
+	jr.seen[event.ID] = jr.connection
+	return false
 }
 
-// parseEvent 解析事件
-func (sp *StreamProcessor) parseEvent(lines []string) StreamEvent {
-	var event StreamEvent
+// resume 携带Last-Event-ID重新连接，成功时返回true。
+// 服务端从未下发事件ID时无法确定断点，不进行重连
+func (jr *JSONStreamReader) resume(cause error) bool {
+	if jr.reconnect == nil || jr.options == nil || !jr.options.Retry || jr.lastEventID == "" {
+		return false
+	}
 
-	for _, line := range lines {
-		if strings.HasPrefix(line, "data: ") {
-			data := strings.TrimPrefix(line, "data: ")
-			if event.Data == "" {
-				event.Data = data
-			} else {
-				event.Data += "\n" + data
-			}
-		} else if strings.HasPrefix(line, "event: ") {
-			event.Event = strings.TrimPrefix(line, "event: ")
-		} else if strings.HasPrefix(line, "id: ") {
-			event.ID = strings.TrimPrefix(line, "id: ")
-		} else if strings.HasPrefix(line, "retry: ") {
-			// 忽略解析错误
-			fmt.Sscanf(strings.TrimPrefix(line, "retry: "), "%d", &event.Retry)
+	// 主动关闭导致的读取错误不重连
+	jr.mu.Lock()
+	closed := jr.closed
+	jr.mu.Unlock()
+	if closed {
+		return false
+	}
+
+	for jr.retries < jr.options.MaxRetries {
+		jr.retries++
+		utils.LogError(jr.ctx, cause, "Stream interrupted, reconnecting",
+			zap.String("last_event_id", jr.lastEventID), zap.Int("attempt", jr.retries))
+
+		delay := jr.options.RetryDelay
+		if jr.hasRetry {
+			delay = jr.retry
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-jr.ctx.Done():
+			timer.Stop()
+			return false
+		case <-timer.C:
+		}
+
+		reader, err := jr.reconnect(jr.lastEventID)
+		if err != nil {
+			cause = err
+			continue
+		}
+
+		jr.mu.Lock()
+		if jr.closed {
+			jr.mu.Unlock()
+			reader.Close()
+			return false
 		}
+		previous := jr.reader
+		jr.reader = reader
+		jr.mu.Unlock()
+
+		previous.Close()
+		jr.decoder = NewSSEDecoder(&countingReader{reader: reader, owner: jr})
+		jr.connection++
+		return true
 	}
+	return false
+}
 
-	return event
+// LastEventID 获取最近一次收到的事件ID
+func (jr *JSONStreamReader) LastEventID() string {
+	return jr.lastEventID
 }
 
-// StreamReader 流式读取器接口
-type StreamReader interface {
-	Read() (interface{}, error)
-	Close() error
-	Err() error
+// Retry 获取服务端通过retry字段建议的重连间隔，未设置时返回false
+func (jr *JSONStreamReader) Retry() (time.Duration, bool) {
+	return jr.retry, jr.hasRetry
 }
 
-// JSONStreamReader JSON流式读取器
-type JSONStreamReader struct {
-	processor *StreamProcessor
-	ctx       context.Context
+// BytesRead 获取从响应体读取的字节数，包括重连后的连接
+func (jr *JSONStreamReader) BytesRead() int64 {
+	jr.mu.Lock()
+	defer jr.mu.Unlock()
+
+	return jr.bytesRead
 }
 
-// NewJSONStreamReader 创建JSON流式读取器
-func NewJSONStreamReader(ctx context.Context, reader io.ReadCloser) *JSONStreamReader {
-	processor := NewStreamProcessor(ctx, reader)
-	processor.Start()
+// FirstByteTime 获取收到第一个响应体字节的时间，尚未收到时为零值
+func (jr *JSONStreamReader) FirstByteTime() time.Time {
+	jr.mu.Lock()
+	defer jr.mu.Unlock()
 
-	return &JSONStreamReader{
-		processor: processor,
-		ctx:       ctx,
-	}
+	return jr.firstByte
 }
 
-// Read 读取下一个JSON对象
-func (jr *JSONStreamReader) Read() (interface{}, error) {
-	for {
-		select {
-		case <-jr.ctx.Done():
-			return nil, jr.ctx.Err()
-		case event := <-jr.processor.Events():
-			if event.Data == "" {
-				continue
-			}
+// DoneReceived 检查流是否以[DONE]标记结束
+func (jr *JSONStreamReader) DoneReceived() bool {
+	jr.mu.Lock()
+	defer jr.mu.Unlock()
 
-			// 跳过特殊事件
-			if event.Data == "[DONE]" {
-				return nil, io.EOF
-			}
+	return jr.doneReceived
+}
 
-			// 解析JSON数据
-			var data interface{}
-			if err := json.Unmarshal([]byte(event.Data), &data); err != nil {
-				return nil, types.NewStreamError(types.ErrTypeAPIError, types.ErrCodeParseError,
-					fmt.Sprintf("failed to parse JSON: %v", err))
-			}
+// received 记录读取的字节数
+func (jr *JSONStreamReader) received(n int) {
+	jr.mu.Lock()
+	defer jr.mu.Unlock()
 
-			return data, nil
-		case err := <-jr.processor.Errors():
-			return nil, err
-		case <-jr.processor.Done():
-			return nil, io.EOF
-		}
+	if jr.firstByte.IsZero() {
+		jr.firstByte = time.Now()
 	}
+	jr.bytesRead += int64(n)
 }
 
-// Close 关闭读取器
+// Close 关闭读取器，阻塞中的Read会因响应体关闭而返回
 func (jr *JSONStreamReader) Close() error {
-	return jr.processor.Close()
+	jr.mu.Lock()
+	defer jr.mu.Unlock()
+
+	jr.closed = true
+	jr.stop()
+	return jr.reader.Close()
 }
 
-// Err 获取错误
+// Err 获取读取过程中发生的错误
 func (jr *JSONStreamReader) Err() error {
-	select {
-	case err := <-jr.processor.Errors():
-		return err
-	default:
-		return nil
+	jr.mu.Lock()
+	defer jr.mu.Unlock()
+
+	return jr.err
+}
+
+// setErr 记录读取错误
+func (jr *JSONStreamReader) setErr(err error) error {
+	jr.mu.Lock()
+	defer jr.mu.Unlock()
+
+	jr.err = err
+	return err
+}
+
+// countingReader 统计从响应体读取的字节数，读取时不持有读取器的锁以免阻塞Close
+type countingReader struct {
+	reader io.Reader
+	owner  *JSONStreamReader
+}
+
+// Read 读取数据并计数
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	if n > 0 {
+		c.owner.received(n)
 	}
+	return n, err
 }
 
 // ChatStreamReader 聊天流式读取器
@@ -342,19 +409,21 @@ type StreamOptions struct {
 	Timeout    time.Duration `json:"timeout,omitempty"`
 	MaxEvents  int           `json:"max_events,omitempty"`
 	KeepAlive  bool          `json:"keep_alive,omitempty"`
+	// Retry 断线后携带Last-Event-ID重新发送请求。重连会重新发起计费的生成请求，
+	// 且只有服务端支持按Last-Event-ID续传时结果才正确，因此默认关闭
 	Retry      bool          `json:"retry,omitempty"`
 	MaxRetries int           `json:"max_retries,omitempty"`
 	RetryDelay time.Duration `json:"retry_delay,omitempty"`
 }
 
-// DefaultStreamOptions 默认流式选项
+// DefaultStreamOptions 默认流式选项，断线重连默认关闭
 func DefaultStreamOptions() *StreamOptions {
 	return &StreamOptions{
 		BufferSize: 4096,
 		Timeout:    60 * time.Second,
 		MaxEvents:  1000,
 		KeepAlive:  true,
-		Retry:      true,
+		Retry:      false,
 		MaxRetries: 3,
 		RetryDelay: 1 * time.Second,
 	}
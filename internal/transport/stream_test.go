@@ -0,0 +1,144 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestPostStreamResumesWithLastEventID(t *testing.T) {
+	var mu sync.Mutex
+	var lastEventIDs []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		lastEventIDs = append(lastEventIDs, r.Header.Get("Last-Event-ID"))
+		attempt := len(lastEventIDs)
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		if attempt == 1 {
+			// 声明的长度大于实际写入的内容，客户端读取时连接被截断
+			body := "retry: 1\nid: 1\ndata: {\"n\":1}\n\n"
+			w.Header().Set("Content-Length", fmt.Sprint(len(body)+100))
+			fmt.Fprint(w, body)
+			return
+		}
+		// 服务端重放了断点之前的事件，客户端应跳过
+		fmt.Fprint(w, "id: 1\ndata: {\"n\":1}\n\nid: 2\ndata: {\"n\":2}\n\ndata: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, "test-key",
+		WithStreamOptions(&StreamOptions{Retry: true, MaxRetries: 2}))
+	reader, err := client.PostStream(context.Background(), "/v1/chat/completions", map[string]bool{"stream": true})
+	if err != nil {
+		t.Fatalf("PostStream failed: %v", err)
+	}
+	defer reader.Close()
+
+	var got []int
+	for {
+		data, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Read failed: %v", err)
+		}
+		var event struct {
+			N int `json:"n"`
+		}
+		if err := json.Unmarshal(data.(json.RawMessage), &event); err != nil {
+			t.Fatalf("invalid event data: %v", err)
+		}
+		got = append(got, event.N)
+	}
+
+	if len(got) != 2 || got[0] != 1 || got[1] != 2 {
+		t.Errorf("events = %v, want [1 2]", got)
+	}
+	if len(lastEventIDs) != 2 || lastEventIDs[0] != "" || lastEventIDs[1] != "1" {
+		t.Errorf("Last-Event-ID headers = %q, want [\"\" \"1\"]", lastEventIDs)
+	}
+}
+
+func TestPostStreamWithoutEventIDDoesNotReconnect(t *testing.T) {
+	var mu sync.Mutex
+	requests := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+
+		body := "data: {\"n\":1}\n\n"
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Content-Length", fmt.Sprint(len(body)+100))
+		fmt.Fprint(w, body)
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, "test-key")
+	reader, err := client.PostStream(context.Background(), "/v1/chat/completions", map[string]bool{"stream": true})
+	if err != nil {
+		t.Fatalf("PostStream failed: %v", err)
+	}
+	defer reader.Close()
+
+	if _, err := reader.Read(); err != nil {
+		t.Fatalf("first Read failed: %v", err)
+	}
+	if _, err := reader.Read(); err == nil || err == io.EOF {
+		t.Fatalf("expected stream error, got %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("server received %d requests, want 1", requests)
+	}
+}
+
+func TestPostStreamDoesNotReconnectByDefault(t *testing.T) {
+	var mu sync.Mutex
+	requests := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+
+		// 带有事件ID的流被截断，未开启Retry时不应重新发送请求
+		body := "id: 1\ndata: {\"n\":1}\n\n"
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Content-Length", fmt.Sprint(len(body)+100))
+		fmt.Fprint(w, body)
+	}))
+	defer server.Close()
+
+	if DefaultStreamOptions().Retry {
+		t.Error("reconnect should be disabled by default")
+	}
+
+	client := NewHTTPClient(server.URL, "test-key")
+	reader, err := client.PostStream(context.Background(), "/v1/chat/completions", map[string]bool{"stream": true})
+	if err != nil {
+		t.Fatalf("PostStream failed: %v", err)
+	}
+	defer reader.Close()
+
+	if _, err := reader.Read(); err != nil {
+		t.Fatalf("first Read failed: %v", err)
+	}
+	if _, err := reader.Read(); err == nil || err == io.EOF {
+		t.Fatalf("expected stream error, got %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if requests != 1 {
+		t.Errorf("server received %d requests, want 1", requests)
+	}
+}
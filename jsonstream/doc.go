@@ -0,0 +1,10 @@
+// Package jsonstream provides an incremental JSON parser for streamed model
+// output in the New-API Go SDK.
+//
+// A Parser accepts the text deltas of a structured output or of tool call
+// arguments as they arrive. Every Write returns the JSON Pointer paths that
+// changed, for example a string at /items/3/name growing, and Value returns a
+// best-effort Go value of everything received so far, so a UI can render
+// fields before the object is complete. Once the stream has ended, Final and
+// Decode strictly validate the full text.
+package jsonstream
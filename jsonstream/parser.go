@@ -0,0 +1,559 @@
+package jsonstream
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Op 路径更新类型
+type Op string
+
+// 路径更新类型常量
+const (
+	// OpOpen 对象或数组开始
+	OpOpen Op = "open"
+	// OpAppend 字符串增长，Delta为本次新增的文本，同一路径的Delta拼接后等于完整字符串
+	OpAppend Op = "append"
+	// OpSet 标量值（字符串、数字、布尔或null）完成
+	OpSet Op = "set"
+	// OpClose 对象或数组结束
+	OpClose Op = "close"
+)
+
+// Update 一个JSON Pointer路径上的变化，根值的路径为空字符串
+type Update struct {
+	Path  string      `json:"path"`
+	Op    Op          `json:"op"`
+	Value interface{} `json:"value,omitempty"`
+	Delta string      `json:"delta,omitempty"`
+}
+
+// SyntaxError JSON语法错误
+type SyntaxError struct {
+	Offset int
+	Msg    string
+}
+
+// Error 实现error接口
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("invalid JSON at offset %d: %s", e.Offset, e.Msg)
+}
+
+// nodeKind 节点类型
+type nodeKind int
+
+const (
+	kindObject nodeKind = iota
+	kindArray
+	kindString
+	kindLiteral
+)
+
+// node 已解析的值
+type node struct {
+	kind    nodeKind
+	path    string
+	keys    []string
+	fields  map[string]*node
+	items   []*node
+	text    []byte
+	emitted int
+	done    bool
+}
+
+// frameState 容器内的解析状态
+type frameState int
+
+const (
+	expectKeyOrEnd frameState = iota
+	expectKey
+	expectColon
+	expectValueOrEnd
+	expectValue
+	expectCommaOrEnd
+)
+
+// frame 打开的容器
+type frame struct {
+	node  *node
+	state frameState
+	key   string
+}
+
+// Parser 增量JSON解析器，不是并发安全的
+type Parser struct {
+	text   strings.Builder
+	root   *node
+	stack  []*frame
+	cur    *node
+	key    []byte
+	isKey  bool
+	escape bool
+	hex    []byte
+	high   rune
+	done   bool
+	err    error
+	offset int
+
+	updates []Update
+}
+
+// NewParser 创建增量JSON解析器
+func NewParser() *Parser {
+	return &Parser{}
+}
+
+// Write 追加文本增量，返回本次增量产生的路径更新。
+// 出现语法错误后解析器停止，之后的调用返回同一错误
+func (p *Parser) Write(delta string) ([]Update, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	p.text.WriteString(delta)
+	p.updates = nil
+
+	for i := 0; i < len(delta); i++ {
+		if err := p.step(delta[i]); err != nil {
+			p.err = err
+			return p.updates, err
+		}
+		p.offset++
+	}
+	p.flushAppend()
+	return p.updates, nil
+}
+
+// Text 获取已接收的全部文本
+func (p *Parser) Text() string {
+	return p.text.String()
+}
+
+// Done 检查根值是否已经完整
+func (p *Parser) Done() bool {
+	return p.done
+}
+
+// Err 获取语法错误
+func (p *Parser) Err() error {
+	return p.err
+}
+
+// Value 获取目前为止尽力而为的部分值。对象为map[string]interface{}，数组为[]interface{}，
+// 数字为float64；未完成的字符串按已接收的部分返回，尚无法确定的数字和字面量被省略
+func (p *Parser) Value() interface{} {
+	value, _ := partialValue(p.root)
+	return value
+}
+
+// PartialDecode 将部分值解码到v，可用于在对象完整之前填充结构体
+func (p *Parser) PartialDecode(v interface{}) error {
+	data, err := json.Marshal(p.Value())
+	if err != nil {
+		return fmt.Errorf("failed to encode partial value: %w", err)
+	}
+	return json.Unmarshal(data, v)
+}
+
+// Final 严格校验完整文本并返回解析后的值，文本不完整或有语法错误时返回错误
+func (p *Parser) Final() (interface{}, error) {
+	var value interface{}
+	if err := p.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// Decode 严格校验完整文本并解码到v，v为结构体时不允许出现未定义的字段
+func (p *Parser) Decode(v interface{}) error {
+	if err := p.complete(); err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(p.text.String()))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("failed to decode JSON: %w", err)
+	}
+	return nil
+}
+
+// complete 结束输入，顶层数字等以分隔符结束的值在此完成
+func (p *Parser) complete() error {
+	if p.err != nil {
+		return p.err
+	}
+	if p.cur != nil && p.cur.kind == kindLiteral && len(p.stack) == 0 {
+		if err := p.finishLiteral(); err != nil {
+			p.err = err
+			return err
+		}
+	}
+	if !p.done {
+		return &SyntaxError{Offset: p.offset, Msg: "unexpected end of JSON input"}
+	}
+	return nil
+}
+
+// step 处理一个字节
+func (p *Parser) step(c byte) error {
+	if p.cur != nil {
+		if p.cur.kind == kindString || p.isKey {
+			return p.stringByte(c)
+		}
+		if isLiteralByte(c) {
+			p.cur.text = append(p.cur.text, c)
+			return nil
+		}
+		if err := p.finishLiteral(); err != nil {
+			return err
+		}
+	}
+
+	if isSpace(c) {
+		return nil
+	}
+
+	if len(p.stack) == 0 {
+		if p.done {
+			return p.syntaxError("unexpected %q after top-level value", c)
+		}
+		return p.beginValue(c, "")
+	}
+
+	top := p.stack[len(p.stack)-1]
+	switch top.state {
+	case expectKeyOrEnd, expectKey:
+		if c == '"' {
+			p.isKey, p.key = true, p.key[:0]
+			p.cur = &node{kind: kindString}
+			return nil
+		}
+		if c == '}' && top.state == expectKeyOrEnd {
+			return p.closeContainer()
+		}
+		return p.syntaxError("expected object key, got %q", c)
+	case expectColon:
+		if c != ':' {
+			return p.syntaxError("expected ':', got %q", c)
+		}
+		top.state = expectValue
+		return nil
+	case expectValueOrEnd:
+		if c == ']' {
+			return p.closeContainer()
+		}
+		return p.beginValue(c, childPath(top))
+	case expectValue:
+		return p.beginValue(c, childPath(top))
+	case expectCommaOrEnd:
+		switch {
+		case c == ',' && top.node.kind == kindObject:
+			top.state = expectKey
+		case c == ',':
+			top.state = expectValue
+		case c == '}' && top.node.kind == kindObject, c == ']' && top.node.kind == kindArray:
+			return p.closeContainer()
+		default:
+			return p.syntaxError("expected ',' or end of container, got %q", c)
+		}
+	}
+	return nil
+}
+
+// beginValue 开始一个新值
+func (p *Parser) beginValue(c byte, path string) error {
+	var n *node
+	switch {
+	case c == '{':
+		n = &node{kind: kindObject, fields: make(map[string]*node)}
+	case c == '[':
+		n = &node{kind: kindArray}
+	case c == '"':
+		n = &node{kind: kindString}
+	case c == '-' || (c >= '0' && c <= '9') || c == 't' || c == 'f' || c == 'n':
+		n = &node{kind: kindLiteral, text: []byte{c}}
+	default:
+		return p.syntaxError("unexpected %q, expected a value", c)
+	}
+	n.path = path
+	p.attach(n)
+
+	switch n.kind {
+	case kindObject:
+		p.stack = append(p.stack, &frame{node: n, state: expectKeyOrEnd})
+		p.emit(Update{Path: path, Op: OpOpen})
+	case kindArray:
+		p.stack = append(p.stack, &frame{node: n, state: expectValueOrEnd})
+		p.emit(Update{Path: path, Op: OpOpen})
+	default:
+		p.cur = n
+	}
+	return nil
+}
+
+// attach 将新值挂到父容器
+func (p *Parser) attach(n *node) {
+	if len(p.stack) == 0 {
+		p.root = n
+		return
+	}
+
+	top := p.stack[len(p.stack)-1]
+	if top.node.kind == kindObject {
+		if _, exists := top.node.fields[top.key]; !exists {
+			top.node.keys = append(top.node.keys, top.key)
+		}
+		top.node.fields[top.key] = n
+	} else {
+		top.node.items = append(top.node.items, n)
+	}
+	top.state = expectCommaOrEnd
+}
+
+// stringByte 处理字符串中的一个字节
+func (p *Parser) stringByte(c byte) error {
+	switch {
+	case p.hex != nil:
+		p.hex = append(p.hex, c)
+		if len(p.hex) < 4 {
+			return nil
+		}
+		code, err := strconv.ParseUint(string(p.hex), 16, 32)
+		p.hex = nil
+		if err != nil {
+			return p.syntaxError("invalid unicode escape")
+		}
+		p.appendRune(rune(code))
+		return nil
+	case p.escape:
+		p.escape = false
+		if c == 'u' {
+			p.hex = make([]byte, 0, 4)
+			return nil
+		}
+		decoded, ok := escapes[c]
+		if !ok {
+			return p.syntaxError("invalid escape character %q", c)
+		}
+		p.appendBytes(decoded)
+		return nil
+	case c == '\\':
+		p.escape = true
+		return nil
+	case c == '"':
+		p.flushHigh()
+		return p.finishString()
+	case c < 0x20:
+		return p.syntaxError("invalid control character in string")
+	default:
+		p.appendBytes(c)
+		return nil
+	}
+}
+
+// escapes 单字符转义
+var escapes = map[byte]byte{'"': '"', '\\': '\\', '/': '/', 'b': '\b', 'f': '\f', 'n': '\n', 'r': '\r', 't': '\t'}
+
+// appendRune 追加\u转义的字符，合并UTF-16代理对
+func (p *Parser) appendRune(r rune) {
+	if p.high != 0 {
+		high := p.high
+		p.high = 0
+		if utf16.IsSurrogate(r) {
+			if combined := utf16.DecodeRune(high, r); combined != utf8.RuneError {
+				p.appendString(string(combined))
+				return
+			}
+		}
+		p.appendString(string(utf8.RuneError))
+	}
+	if r >= 0xD800 && r < 0xDC00 {
+		p.high = r
+		return
+	}
+	p.appendString(string(r))
+}
+
+// flushHigh 未配对的高位代理替换为U+FFFD
+func (p *Parser) flushHigh() {
+	if p.high != 0 {
+		p.high = 0
+		p.appendString(string(utf8.RuneError))
+	}
+}
+
+// appendBytes 向当前字符串追加字节
+func (p *Parser) appendBytes(c byte) {
+	p.flushHigh()
+	if p.isKey {
+		p.key = append(p.key, c)
+	} else {
+		p.cur.text = append(p.cur.text, c)
+	}
+}
+
+// appendString 向当前字符串追加文本
+func (p *Parser) appendString(s string) {
+	if p.isKey {
+		p.key = append(p.key, s...)
+	} else {
+		p.cur.text = append(p.cur.text, s...)
+	}
+}
+
+// finishString 结束当前字符串
+func (p *Parser) finishString() error {
+	if p.isKey {
+		top := p.stack[len(p.stack)-1]
+		top.key = string(p.key)
+		top.state = expectColon
+		p.isKey, p.cur = false, nil
+		return nil
+	}
+
+	// 先输出尚未输出的增长部分，使OpAppend的Delta拼接后等于完整字符串
+	p.flushAppend()
+	n := p.cur
+	n.done = true
+	p.cur = nil
+	p.emit(Update{Path: n.path, Op: OpSet, Value: string(n.text)})
+	p.finishRoot()
+	return nil
+}
+
+// finishLiteral 结束当前数字或字面量
+func (p *Parser) finishLiteral() error {
+	n := p.cur
+	p.cur = nil
+
+	var value interface{}
+	if err := json.Unmarshal(n.text, &value); err != nil {
+		return p.syntaxError("invalid literal %q", n.text)
+	}
+	n.done = true
+	p.emit(Update{Path: n.path, Op: OpSet, Value: value})
+	p.finishRoot()
+	return nil
+}
+
+// closeContainer 结束当前对象或数组
+func (p *Parser) closeContainer() error {
+	top := p.stack[len(p.stack)-1]
+	p.stack = p.stack[:len(p.stack)-1]
+	top.node.done = true
+
+	value, _ := partialValue(top.node)
+	p.emit(Update{Path: top.node.path, Op: OpClose, Value: value})
+	p.finishRoot()
+	return nil
+}
+
+// finishRoot 顶层值完成
+func (p *Parser) finishRoot() {
+	if len(p.stack) == 0 {
+		p.done = true
+	}
+}
+
+// flushAppend 输出当前字符串本次增长的部分，不完整的UTF-8字符留到下次输出
+func (p *Parser) flushAppend() {
+	n := p.cur
+	if n == nil || n.kind != kindString || p.isKey {
+		return
+	}
+
+	end := len(n.text)
+	for start := end - 1; start >= n.emitted && start >= end-utf8.UTFMax; start-- {
+		if utf8.RuneStart(n.text[start]) {
+			if !utf8.FullRune(n.text[start:end]) {
+				end = start
+			}
+			break
+		}
+	}
+	if end <= n.emitted {
+		return
+	}
+
+	delta := string(n.text[n.emitted:end])
+	n.emitted = end
+	p.emit(Update{Path: n.path, Op: OpAppend, Value: string(n.text[:end]), Delta: delta})
+}
+
+// emit 记录路径更新
+func (p *Parser) emit(update Update) {
+	p.updates = append(p.updates, update)
+}
+
+// syntaxError 创建语法错误
+func (p *Parser) syntaxError(format string, args ...interface{}) error {
+	return &SyntaxError{Offset: p.offset, Msg: fmt.Sprintf(format, args...)}
+}
+
+// partialValue 将节点转换为Go值，无法确定的值返回false
+func partialValue(n *node) (interface{}, bool) {
+	if n == nil {
+		return nil, false
+	}
+
+	switch n.kind {
+	case kindObject:
+		object := make(map[string]interface{}, len(n.keys))
+		for _, key := range n.keys {
+			if value, ok := partialValue(n.fields[key]); ok {
+				object[key] = value
+			}
+		}
+		return object, true
+	case kindArray:
+		array := make([]interface{}, 0, len(n.items))
+		for _, item := range n.items {
+			if value, ok := partialValue(item); ok {
+				array = append(array, value)
+			}
+		}
+		return array, true
+	case kindString:
+		text := n.text
+		if !n.done {
+			text = text[:n.emitted]
+		}
+		return string(text), true
+	default:
+		var value interface{}
+		if err := json.Unmarshal(n.text, &value); err != nil {
+			return nil, false
+		}
+		return value, true
+	}
+}
+
+// childPath 获取容器中下一个值的JSON Pointer路径
+func childPath(f *frame) string {
+	if f.node.kind == kindObject {
+		return f.node.path + "/" + EscapePointer(f.key)
+	}
+	return f.node.path + "/" + strconv.Itoa(len(f.node.items))
+}
+
+// EscapePointer 按RFC 6901转义JSON Pointer中的路径片段
+func EscapePointer(token string) string {
+	if !strings.ContainsAny(token, "~/") {
+		return token
+	}
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
+
+// isSpace 检查是否为JSON空白
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// isLiteralByte 检查是否可能属于数字或true/false/null
+func isLiteralByte(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || c == '-' || c == '+' || c == '.' || c == 'E'
+}
@@ -0,0 +1,201 @@
+package jsonstream
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// feed 按固定大小切分文本写入解析器，返回所有更新
+func feed(t *testing.T, p *Parser, text string, size int) []Update {
+	t.Helper()
+	var updates []Update
+	for len(text) > 0 {
+		n := size
+		if n > len(text) {
+			n = len(text)
+		}
+		batch, err := p.Write(text[:n])
+		if err != nil {
+			t.Fatalf("Write(%q) failed: %v", text[:n], err)
+		}
+		updates = append(updates, batch...)
+		text = text[n:]
+	}
+	return updates
+}
+
+func TestParserPathUpdates(t *testing.T) {
+	p := NewParser()
+	updates := feed(t, p, `{"items":[{"name":"Go"},{"name":"Rust","tags":["a/b"]}],"n":12}`, 3)
+
+	var sets []string
+	appends := map[string]string{}
+	for _, u := range updates {
+		switch u.Op {
+		case OpSet:
+			sets = append(sets, u.Path)
+		case OpAppend:
+			appends[u.Path] += u.Delta
+		}
+	}
+
+	wantSets := []string{"/items/0/name", "/items/1/name", "/items/1/tags/0", "/n"}
+	if !reflect.DeepEqual(sets, wantSets) {
+		t.Errorf("set paths = %v, want %v", sets, wantSets)
+	}
+	if appends["/items/1/name"] != "Rust" {
+		t.Errorf("appended deltas for /items/1/name = %q", appends["/items/1/name"])
+	}
+	if last := updates[len(updates)-1]; last.Path != "" || last.Op != OpClose {
+		t.Errorf("last update = %+v, want root close", last)
+	}
+
+	var value struct {
+		Items []struct {
+			Name string   `json:"name"`
+			Tags []string `json:"tags"`
+		} `json:"items"`
+		N int `json:"n"`
+	}
+	if err := p.Decode(&value); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if len(value.Items) != 2 || value.Items[1].Tags[0] != "a/b" || value.N != 12 {
+		t.Errorf("decoded value = %+v", value)
+	}
+}
+
+func TestParserPartialValue(t *testing.T) {
+	p := NewParser()
+	feed(t, p, `{"title":"Hel`, 4)
+
+	if got := p.Value(); !reflect.DeepEqual(got, map[string]interface{}{"title": "Hel"}) {
+		t.Errorf("partial value = %#v", got)
+	}
+
+	feed(t, p, `lo","count":1`, 100)
+	var partial struct {
+		Title string `json:"title"`
+		Count int    `json:"count"`
+	}
+	if err := p.PartialDecode(&partial); err != nil {
+		t.Fatalf("PartialDecode failed: %v", err)
+	}
+	if partial.Title != "Hello" || partial.Count != 1 {
+		t.Errorf("partial struct = %+v", partial)
+	}
+
+	if _, err := p.Final(); err == nil {
+		t.Error("Final should fail on incomplete input")
+	}
+	feed(t, p, `}`, 1)
+	if final, err := p.Final(); err != nil || !reflect.DeepEqual(final, map[string]interface{}{"title": "Hello", "count": float64(1)}) {
+		t.Errorf("Final = %#v, %v", final, err)
+	}
+}
+
+func TestParserEscapesAndUnicode(t *testing.T) {
+	text := `{"a~b/c":"line\n\"q\" é 😀 世界"}`
+	for size := 1; size <= len(text); size++ {
+		p := NewParser()
+		updates := feed(t, p, text, size)
+
+		var appended string
+		for _, u := range updates {
+			if u.Op == OpAppend {
+				if u.Path != "/a~0b~1c" {
+					t.Fatalf("append path = %q", u.Path)
+				}
+				appended += u.Delta
+			}
+		}
+		want := "line\n\"q\" é 😀 世界"
+		if appended != "" && !strings.HasPrefix(want, appended) {
+			t.Errorf("size %d: appended %q is not a prefix of %q", size, appended, want)
+		}
+		final, err := p.Final()
+		if err != nil {
+			t.Fatalf("size %d: Final failed: %v", size, err)
+		}
+		if got := final.(map[string]interface{})["a~b/c"]; got != want {
+			t.Errorf("size %d: value = %q, want %q", size, got, want)
+		}
+	}
+}
+
+func TestParserTopLevelScalarsAndErrors(t *testing.T) {
+	p := NewParser()
+	feed(t, p, " 42", 1)
+	if got := p.Value(); got != float64(42) {
+		t.Errorf("partial number = %v", got)
+	}
+	if final, err := p.Final(); err != nil || final != float64(42) {
+		t.Errorf("Final = %v, %v", final, err)
+	}
+
+	for _, text := range []string{`{"a" 1}`, `[1,]`, `{"a":tru}`, `{} x`, `{"a":"\x"}`} {
+		p := NewParser()
+		var err error
+		for i := 0; i < len(text) && err == nil; i++ {
+			_, err = p.Write(text[i : i+1])
+		}
+		if err == nil {
+			_, err = p.Final()
+		}
+		if err == nil {
+			t.Errorf("%s: expected syntax error", text)
+		}
+	}
+
+	p = NewParser()
+	feed(t, p, `{"a":1,"extra":2}`, 5)
+	var strict struct {
+		A int `json:"a"`
+	}
+	if err := p.Decode(&strict); err == nil {
+		t.Error("Decode should reject unknown fields")
+	}
+}
+
+// FuzzParser 任意切分方式下，解析器对合法JSON的结果与encoding/json一致
+func FuzzParser(f *testing.F) {
+	f.Add(`{"a":[1,2,{"b":"c"}],"d":null,"e":true}`, 3)
+	f.Add(`"é😀"`, 1)
+	f.Add(`[-1.5e3, 0, false]`, 2)
+
+	f.Fuzz(func(t *testing.T, text string, size int) {
+		if size <= 0 || size > 64 {
+			size = 1
+		}
+		var want interface{}
+		validJSON := json.Unmarshal([]byte(text), &want) == nil
+
+		p := NewParser()
+		var err error
+		for rest := text; len(rest) > 0 && err == nil; {
+			n := size
+			if n > len(rest) {
+				n = len(rest)
+			}
+			_, err = p.Write(rest[:n])
+			rest = rest[n:]
+			p.Value()
+		}
+		if err != nil {
+			if validJSON {
+				t.Fatalf("valid JSON %q rejected: %v", text, err)
+			}
+			return
+		}
+
+		got, err := p.Final()
+		if validJSON != (err == nil) {
+			t.Fatalf("Final(%q) error = %v, encoding/json valid = %v", text, err, validJSON)
+		}
+		if validJSON && !reflect.DeepEqual(got, want) {
+			t.Fatalf("Final(%q) = %#v, want %#v", text, got, want)
+		}
+	})
+}
@@ -0,0 +1,28 @@
+// Package prompt provides a prompt template engine built on text/template.
+//
+// A template renders into a list of chat messages (system, user and few-shot
+// assistant turns) that can be passed straight to CreateChatCompletion. Templates
+// can be written in a plain-text format with role markers, built from message
+// definitions in code, share partials, and be loaded from a directory or an
+// embedded file system. Every template carries a name and version tag that are
+// recorded in the request metadata when the completion is stored with
+// chat.WithStore(true).
+//
+// The text format looks like this:
+//
+//	---
+//	name: summarize
+//	version: v3
+//	---
+//	[system]
+//	You write {{.Style}} summaries.
+//
+//	[user]
+//	{{template "example_input"}}
+//
+//	[assistant]
+//	{{template "example_output"}}
+//
+//	[user]
+//	Summarize: {{.Text}}
+package prompt
@@ -0,0 +1,139 @@
+package prompt
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hewenyu/newapi-go/types"
+)
+
+// TemplateExt 模板文件扩展名
+const TemplateExt = ".tmpl"
+
+// ErrTemplateNotFound 模板不存在
+var ErrTemplateNotFound = errors.New("prompt template not found")
+
+// Library 按名称管理的模板集合
+type Library struct {
+	templates map[string]*Template
+	mu        sync.RWMutex
+}
+
+// NewLibrary 创建模板集合
+func NewLibrary(templates ...*Template) *Library {
+	library := &Library{templates: make(map[string]*Template)}
+	for _, t := range templates {
+		library.Add(t)
+	}
+	return library
+}
+
+// Add 添加模板，同名模板会被覆盖
+func (l *Library) Add(t *Template) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.templates[t.Name()] = t
+}
+
+// Get 获取模板
+func (l *Library) Get(name string) (*Template, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	t, ok := l.templates[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+	return t, nil
+}
+
+// Names 获取所有模板名称
+func (l *Library) Names() []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	names := make([]string, 0, len(l.templates))
+	for name := range l.templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Render 渲染指定名称的模板
+func (l *Library) Render(name string, data interface{}) ([]types.ChatMessage, error) {
+	t, err := l.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	return t.Render(data)
+}
+
+// LoadFS 从文件系统的root目录递归加载所有.tmpl模板，可用于embed.FS。
+// 文件名以_开头的文件作为局部模板，可在所有模板中通过去掉_和扩展名后的名称引用；
+// 其余文件的模板名称默认为相对root的路径去掉扩展名，可由头部的name覆盖
+func LoadFS(fsys fs.FS, root string, options ...TemplateOption) (*Library, error) {
+	var partials, sources []string
+	err := fs.WalkDir(fsys, root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || path.Ext(p) != TemplateExt {
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), "_") {
+			partials = append(partials, p)
+		} else {
+			sources = append(sources, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk templates: %w", err)
+	}
+
+	loadOptions := append([]TemplateOption(nil), options...)
+	for _, p := range partials {
+		data, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read partial %s: %w", p, err)
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(path.Base(p), "_"), TemplateExt)
+		loadOptions = append(loadOptions, WithPartial(name, string(data)))
+	}
+
+	library := NewLibrary()
+	for _, p := range sources {
+		data, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read template %s: %w", p, err)
+		}
+
+		name := strings.TrimSuffix(p, TemplateExt)
+		if root != "." && root != "" {
+			name = strings.TrimPrefix(name, path.Clean(root)+"/")
+		}
+
+		t, err := Parse(name, string(data), loadOptions...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load template %s: %w", p, err)
+		}
+		if _, err := library.Get(t.Name()); err == nil {
+			return nil, fmt.Errorf("duplicate template name %q in %s", t.Name(), p)
+		}
+		library.Add(t)
+	}
+	return library, nil
+}
+
+// LoadDir 从目录递归加载所有.tmpl模板
+func LoadDir(dir string, options ...TemplateOption) (*Library, error) {
+	return LoadFS(os.DirFS(dir), ".", options...)
+}
@@ -0,0 +1,385 @@
+package prompt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/hewenyu/newapi-go/services/chat"
+	"github.com/hewenyu/newapi-go/types"
+)
+
+// 请求元数据中记录模板信息的键
+const (
+	MetadataKeyTemplate = "prompt_template"
+	MetadataKeyVersion  = "prompt_version"
+)
+
+// MessageTemplate 单条消息模板
+type MessageTemplate struct {
+	Role    string
+	Content string
+}
+
+// TemplateConfig 模板配置
+type TemplateConfig struct {
+	Version     string
+	Description string
+	Partials    map[string]string
+	Funcs       template.FuncMap
+}
+
+// TemplateOption 模板选项函数类型
+type TemplateOption func(*TemplateConfig)
+
+// DefaultTemplateConfig 默认模板配置
+func DefaultTemplateConfig() *TemplateConfig {
+	return &TemplateConfig{
+		Partials: make(map[string]string),
+		Funcs:    make(template.FuncMap),
+	}
+}
+
+// WithVersion 设置模板版本，文本格式的头部信息优先
+func WithVersion(version string) TemplateOption {
+	return func(c *TemplateConfig) {
+		c.Version = version
+	}
+}
+
+// WithDescription 设置模板描述
+func WithDescription(description string) TemplateOption {
+	return func(c *TemplateConfig) {
+		c.Description = description
+	}
+}
+
+// WithPartial 添加可通过{{template "name" .}}引用的局部模板
+func WithPartial(name, source string) TemplateOption {
+	return func(c *TemplateConfig) {
+		c.Partials[name] = source
+	}
+}
+
+// WithFuncs 添加模板函数
+func WithFuncs(funcs template.FuncMap) TemplateOption {
+	return func(c *TemplateConfig) {
+		for k, v := range funcs {
+			c.Funcs[k] = v
+		}
+	}
+}
+
+// defaultFuncs 默认的模板函数
+var defaultFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"join": func(sep string, items []string) string {
+		return strings.Join(items, sep)
+	},
+}
+
+// Template 多消息提示词模板
+type Template struct {
+	name        string
+	version     string
+	description string
+	roles       []string
+	set         *template.Template
+	variables   []string
+}
+
+// messageTemplateName 获取第i条消息在模板集合中的名称
+func messageTemplateName(i int) string {
+	return fmt.Sprintf("message_%d", i)
+}
+
+// ParseMessages 使用消息模板列表创建模板
+func ParseMessages(name string, messages []MessageTemplate, options ...TemplateOption) (*Template, error) {
+	config := DefaultTemplateConfig()
+	for _, option := range options {
+		option(config)
+	}
+	return newTemplate(name, "", messages, config)
+}
+
+// newTemplate 解析局部模板和消息模板
+func newTemplate(name, preamble string, messages []MessageTemplate, config *TemplateConfig) (*Template, error) {
+	if name == "" {
+		return nil, types.NewValidationError("name", name, "template name cannot be empty", types.ErrCodeMissingParameter)
+	}
+	if len(messages) == 0 {
+		return nil, types.NewValidationError("messages", messages, "template must contain at least one message", types.ErrCodeMissingParameter)
+	}
+
+	set := template.New(name).Option("missingkey=error").Funcs(defaultFuncs).Funcs(config.Funcs)
+	for partialName, source := range config.Partials {
+		if _, err := set.New(partialName).Parse(source); err != nil {
+			return nil, fmt.Errorf("failed to parse partial %q: %w", partialName, err)
+		}
+	}
+
+	if strings.TrimSpace(preamble) != "" {
+		tmpl, err := set.New(name + "/preamble").Parse(preamble)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse template %q: %w", name, err)
+		}
+		if !definitionsOnly(tmpl.Tree) {
+			return nil, fmt.Errorf("template %q: content before the first role marker must only contain {{define}} blocks", name)
+		}
+	}
+
+	roles := make([]string, len(messages))
+	for i, message := range messages {
+		if !isTemplateRole(message.Role) {
+			return nil, types.NewValidationError(fmt.Sprintf("messages[%d].role", i), message.Role,
+				"unsupported message role", types.ErrCodeInvalidParameter)
+		}
+		if _, err := set.New(messageTemplateName(i)).Parse(message.Content); err != nil {
+			return nil, fmt.Errorf("failed to parse message %d of template %q: %w", i, name, err)
+		}
+		roles[i] = message.Role
+	}
+
+	t := &Template{
+		name:        name,
+		version:     config.Version,
+		description: config.Description,
+		roles:       roles,
+		set:         set,
+	}
+	t.variables = t.collectVariables()
+	return t, nil
+}
+
+// isTemplateRole 检查是否为模板支持的消息角色
+func isTemplateRole(role string) bool {
+	switch role {
+	case types.ChatRoleSystem, types.ChatRoleUser, types.ChatRoleAssistant:
+		return true
+	}
+	return false
+}
+
+// definitionsOnly 检查模板除空白外不包含任何输出
+func definitionsOnly(tree *parse.Tree) bool {
+	if tree == nil || tree.Root == nil {
+		return true
+	}
+	for _, node := range tree.Root.Nodes {
+		text, ok := node.(*parse.TextNode)
+		if !ok || len(bytes.TrimSpace(text.Text)) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Must 在err不为nil时panic，用于包级变量初始化
+func Must(t *Template, err error) *Template {
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// Name 获取模板名称
+func (t *Template) Name() string {
+	return t.name
+}
+
+// Version 获取模板版本
+func (t *Template) Version() string {
+	return t.version
+}
+
+// Description 获取模板描述
+func (t *Template) Description() string {
+	return t.description
+}
+
+// Variables 获取模板引用的顶层变量名，包括通过{{template "x" .}}引用的局部模板中的变量
+func (t *Template) Variables() []string {
+	return append([]string(nil), t.variables...)
+}
+
+// Render 校验变量并渲染为聊天消息，渲染结果为空白的消息会被跳过，便于按条件包含示例
+func (t *Template) Render(data interface{}) ([]types.ChatMessage, error) {
+	if err := t.Validate(data); err != nil {
+		return nil, err
+	}
+
+	messages := make([]types.ChatMessage, 0, len(t.roles))
+	for i, role := range t.roles {
+		var buf bytes.Buffer
+		if err := t.set.ExecuteTemplate(&buf, messageTemplateName(i), data); err != nil {
+			return nil, fmt.Errorf("failed to render message %d of template %q: %w", i, t.name, err)
+		}
+
+		content := strings.TrimSpace(buf.String())
+		if content == "" {
+			continue
+		}
+		messages = append(messages, types.ChatMessage{Role: role, Content: content})
+	}
+
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("template %q rendered no messages", t.name)
+	}
+	return messages, nil
+}
+
+// Options 获取在请求元数据中记录模板名称和版本的聊天选项。
+// OpenAI只接受存储补全（store为true）的请求携带元数据，单独使用时需要同时传入chat.WithStore(true)
+func (t *Template) Options() []chat.ChatOption {
+	options := []chat.ChatOption{chat.WithMetadataValue(MetadataKeyTemplate, t.name)}
+	if t.version != "" {
+		options = append(options, chat.WithMetadataValue(MetadataKeyVersion, t.version))
+	}
+	return options
+}
+
+// Execute 渲染模板并发送聊天完成请求。调用方通过chat.WithStore(true)存储补全时，
+// 模板名称和版本合并到请求元数据中；未开启存储时不附加，以免请求被接口拒绝
+func (t *Template) Execute(ctx context.Context, service *chat.ChatService, data interface{}, options ...chat.ChatOption) (*types.ChatCompletionResponse, error) {
+	messages, err := t.Render(data)
+	if err != nil {
+		return nil, err
+	}
+	options = append(options[:len(options):len(options)], t.metadataOption())
+	return service.CreateChatCompletion(ctx, messages, options...)
+}
+
+// metadataOption 在调用方选项之后应用，开启存储时将模板名称和版本合并到调用方的元数据中
+func (t *Template) metadataOption() chat.ChatOption {
+	return func(config *chat.ChatConfig) {
+		if config.Store == nil || !*config.Store {
+			return
+		}
+		for _, option := range t.Options() {
+			option(config)
+		}
+	}
+}
+
+// TypedTemplate 绑定输入类型的模板，创建时静态检查类型是否提供了所有变量
+type TypedTemplate[T any] struct {
+	*Template
+}
+
+// Typed 将模板绑定到输入类型T，T为结构体时其字段或方法必须覆盖模板的所有变量
+func Typed[T any](t *Template) (*TypedTemplate[T], error) {
+	if missing := missingFields(typeOf[T](), t.variables); len(missing) > 0 {
+		return nil, types.NewValidationError("data", missing,
+			fmt.Sprintf("type %s does not provide template variables: %s", typeOf[T](), strings.Join(missing, ", ")),
+			types.ErrCodeMissingParameter)
+	}
+	return &TypedTemplate[T]{Template: t}, nil
+}
+
+// MustTyped 与Typed相同，失败时panic
+func MustTyped[T any](t *Template) *TypedTemplate[T] {
+	typed, err := Typed[T](t)
+	if err != nil {
+		panic(err)
+	}
+	return typed
+}
+
+// Render 渲染为聊天消息
+func (t *TypedTemplate[T]) Render(data T) ([]types.ChatMessage, error) {
+	return t.Template.Render(data)
+}
+
+// Execute 渲染模板并发送聊天完成请求
+func (t *TypedTemplate[T]) Execute(ctx context.Context, service *chat.ChatService, data T, options ...chat.ChatOption) (*types.ChatCompletionResponse, error) {
+	return t.Template.Execute(ctx, service, data, options...)
+}
+
+var roleMarkerPattern = regexp.MustCompile(`^\[(system|user|assistant)\]\s*$`)
+
+// Parse 解析文本格式的模板。可选的头部以---包围，支持name、version和description，
+// 消息以单独一行的[system]、[user]或[assistant]开始
+func Parse(name, source string, options ...TemplateOption) (*Template, error) {
+	config := DefaultTemplateConfig()
+	for _, option := range options {
+		option(config)
+	}
+
+	body, err := parseFrontMatter(source, &name, config)
+	if err != nil {
+		return nil, fmt.Errorf("template %q: %w", name, err)
+	}
+
+	var preamble strings.Builder
+	var messages []MessageTemplate
+	var content strings.Builder
+	flush := func() {
+		if len(messages) > 0 {
+			messages[len(messages)-1].Content = content.String()
+		}
+		content.Reset()
+	}
+
+	for _, line := range strings.SplitAfter(body, "\n") {
+		if match := roleMarkerPattern.FindStringSubmatch(strings.TrimRight(line, "\r\n")); match != nil {
+			flush()
+			messages = append(messages, MessageTemplate{Role: match[1]})
+			continue
+		}
+		if len(messages) == 0 {
+			preamble.WriteString(line)
+		} else {
+			content.WriteString(line)
+		}
+	}
+	flush()
+
+	return newTemplate(name, preamble.String(), messages, config)
+}
+
+// parseFrontMatter 解析模板头部，返回头部之后的内容
+func parseFrontMatter(source string, name *string, config *TemplateConfig) (string, error) {
+	normalized := strings.ReplaceAll(source, "\r\n", "\n")
+	if !strings.HasPrefix(normalized, "---\n") {
+		return normalized, nil
+	}
+
+	rest := normalized[len("---\n"):]
+	end := strings.Index(rest, "\n---")
+	if end < 0 {
+		return "", fmt.Errorf("unterminated front matter")
+	}
+	header, body := rest[:end], rest[end+len("\n---"):]
+	body = strings.TrimPrefix(body, "\n")
+
+	for i, line := range strings.Split(header, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return "", fmt.Errorf("invalid front matter line %d: %q", i+1, line)
+		}
+		value = strings.Trim(strings.TrimSpace(value), `"'`)
+		switch strings.TrimSpace(key) {
+		case "name":
+			*name = value
+		case "version":
+			config.Version = value
+		case "description":
+			config.Description = value
+		default:
+			return "", fmt.Errorf("unknown front matter key %q", strings.TrimSpace(key))
+		}
+	}
+	return body, nil
+}
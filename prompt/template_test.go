@@ -0,0 +1,179 @@
+package prompt
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/hewenyu/newapi-go/internal/transport"
+	"github.com/hewenyu/newapi-go/internal/utils"
+	"github.com/hewenyu/newapi-go/services/chat"
+	"github.com/hewenyu/newapi-go/types"
+)
+
+const summarizeSource = `---
+name: summarize
+version: v3
+---
+{{define "rules"}}Keep it under {{.Words}} words.{{end}}
+[system]
+You write {{.Style}} summaries. {{template "rules" .}}
+
+[user]
+{{range .Examples}}{{.}}{{end}}
+
+[assistant]
+{{if .Examples}}Understood.{{end}}
+
+[user]
+Summarize: {{.Text}}
+`
+
+type summarizeInput struct {
+	Style    string
+	Words    int
+	Examples []string
+	Text     string
+}
+
+func TestParseRendersMessages(t *testing.T) {
+	tmpl, err := Parse("ignored", summarizeSource)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if tmpl.Name() != "summarize" || tmpl.Version() != "v3" {
+		t.Errorf("unexpected header: name=%q version=%q", tmpl.Name(), tmpl.Version())
+	}
+
+	want := []string{"Examples", "Style", "Text", "Words"}
+	if got := tmpl.Variables(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Variables() = %v, want %v", got, want)
+	}
+
+	messages, err := tmpl.Render(summarizeInput{Style: "short", Words: 50, Text: "Go is fun."})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	// 没有示例时，示例消息渲染为空被跳过
+	if len(messages) != 2 {
+		t.Fatalf("expected 2 messages, got %d: %+v", len(messages), messages)
+	}
+	if messages[0].Role != types.ChatRoleSystem || messages[0].Content != "You write short summaries. Keep it under 50 words." {
+		t.Errorf("unexpected system message: %+v", messages[0])
+	}
+	if messages[1].Role != types.ChatRoleUser || messages[1].Content != "Summarize: Go is fun." {
+		t.Errorf("unexpected user message: %+v", messages[1])
+	}
+}
+
+func TestRenderRejectsUnboundVariables(t *testing.T) {
+	tmpl := Must(ParseMessages("greet", []MessageTemplate{
+		{Role: types.ChatRoleUser, Content: "Hello {{.Name}}, you are {{$.Age}}"},
+	}))
+
+	_, err := tmpl.Render(map[string]interface{}{"Name": "Ann"})
+	if err == nil || !strings.Contains(err.Error(), "Age") {
+		t.Fatalf("expected unbound variable error for Age, got %v", err)
+	}
+
+	if _, err := Typed[struct{ Name string }](tmpl); err == nil {
+		t.Errorf("expected Typed to reject a struct without Age")
+	}
+
+	typed := MustTyped[struct {
+		Name string
+		Age  int
+	}](tmpl)
+	messages, err := typed.Render(struct {
+		Name string
+		Age  int
+	}{"Ann", 30})
+	if err != nil || messages[0].Content != "Hello Ann, you are 30" {
+		t.Errorf("unexpected typed render: %v %+v", err, messages)
+	}
+}
+
+func TestLoadFSWithPartials(t *testing.T) {
+	fsys := fstest.MapFS{
+		"prompts/_tone.tmpl":         {Data: []byte("Be {{.Tone}}.")},
+		"prompts/support/reply.tmpl": {Data: []byte("[system]\n{{template \"tone\" .}}\n[user]\n{{.Question}}\n")},
+	}
+
+	library, err := LoadFS(fsys, "prompts", WithVersion("2024-06"))
+	if err != nil {
+		t.Fatalf("LoadFS failed: %v", err)
+	}
+	if names := library.Names(); !reflect.DeepEqual(names, []string{"support/reply"}) {
+		t.Fatalf("Names() = %v", names)
+	}
+
+	messages, err := library.Render("support/reply", map[string]string{"Tone": "kind", "Question": "Why?"})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if messages[0].Content != "Be kind." || messages[1].Content != "Why?" {
+		t.Errorf("unexpected messages: %+v", messages)
+	}
+
+	tmpl, _ := library.Get("support/reply")
+	config := chat.DefaultChatConfig()
+	for _, option := range tmpl.Options() {
+		option(config)
+	}
+	if config.Metadata[MetadataKeyTemplate] != "support/reply" || config.Metadata[MetadataKeyVersion] != "2024-06" {
+		t.Errorf("unexpected metadata: %v", config.Metadata)
+	}
+}
+
+func TestParseRejectsContentBeforeFirstRole(t *testing.T) {
+	if _, err := Parse("bad", "stray text\n[user]\nhi\n"); err == nil {
+		t.Errorf("expected error for content before the first role marker")
+	}
+}
+
+func TestExecuteMergesMetadata(t *testing.T) {
+	var requests []types.ChatCompletionRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req types.ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		requests = append(requests, req)
+		json.NewEncoder(w).Encode(types.ChatCompletionResponse{
+			ID:      "chatcmpl-1",
+			Choices: []types.ChatCompletionChoice{{Message: types.NewAssistantMessage("ok")}},
+		})
+	}))
+	defer server.Close()
+	service := chat.NewChatService(transport.NewHTTPClient(server.URL, "test-key"), utils.GetLogger())
+
+	tmpl, err := Parse("summarize", summarizeSource)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	data := map[string]interface{}{"Style": "short", "Words": 10, "Examples": []string{}, "Text": "long text"}
+	userMetadata := map[string]string{"user": "42"}
+
+	// 未开启存储时不附加模板元数据，调用方的元数据原样发送
+	if _, err := tmpl.Execute(context.Background(), service, data, chat.WithMetadata(userMetadata)); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if got := requests[0].Metadata; !reflect.DeepEqual(got, userMetadata) {
+		t.Errorf("metadata without store = %v", got)
+	}
+
+	// 开启存储时与调用方的元数据合并
+	if _, err := tmpl.Execute(context.Background(), service, data, chat.WithMetadata(userMetadata), chat.WithStore(true)); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	want := map[string]string{"user": "42", MetadataKeyTemplate: "summarize", MetadataKeyVersion: "v3"}
+	if got := requests[1]; !reflect.DeepEqual(got.Metadata, want) || got.Store == nil || !*got.Store {
+		t.Errorf("metadata with store = %v, store = %v", got.Metadata, got.Store)
+	}
+	if len(userMetadata) != 1 {
+		t.Errorf("caller metadata was modified: %v", userMetadata)
+	}
+}
@@ -0,0 +1,185 @@
+package prompt
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template/parse"
+
+	"github.com/hewenyu/newapi-go/types"
+)
+
+// collectVariables 遍历所有消息模板的语法树，收集以根数据为上下文引用的变量名
+func (t *Template) collectVariables() []string {
+	vars := make(map[string]bool)
+	visited := make(map[string]bool)
+	for i := range t.roles {
+		if tmpl := t.set.Lookup(messageTemplateName(i)); tmpl != nil && tmpl.Tree != nil {
+			t.walk(tmpl.Tree.Root, true, vars, visited)
+		}
+	}
+
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// walk 遍历语法树节点。root表示当前的.是否为根数据，range和with内部的.不是根数据
+func (t *Template) walk(node parse.Node, root bool, vars, visited map[string]bool) {
+	switch n := node.(type) {
+	case nil:
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			t.walk(child, root, vars, visited)
+		}
+	case *parse.ActionNode:
+		t.walk(n.Pipe, root, vars, visited)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			t.walk(cmd, root, vars, visited)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			t.walk(arg, root, vars, visited)
+		}
+	case *parse.ChainNode:
+		t.walk(n.Node, root, vars, visited)
+	case *parse.FieldNode:
+		if root && len(n.Ident) > 0 {
+			vars[n.Ident[0]] = true
+		}
+	case *parse.VariableNode:
+		if len(n.Ident) > 1 && n.Ident[0] == "$" {
+			vars[n.Ident[1]] = true
+		}
+	case *parse.IfNode:
+		t.walk(n.Pipe, root, vars, visited)
+		t.walk(n.List, root, vars, visited)
+		t.walk(n.ElseList, root, vars, visited)
+	case *parse.RangeNode:
+		t.walk(n.Pipe, root, vars, visited)
+		t.walk(n.List, false, vars, visited)
+		t.walk(n.ElseList, root, vars, visited)
+	case *parse.WithNode:
+		t.walk(n.Pipe, root, vars, visited)
+		t.walk(n.List, false, vars, visited)
+		t.walk(n.ElseList, root, vars, visited)
+	case *parse.TemplateNode:
+		t.walk(n.Pipe, root, vars, visited)
+		if root && passesDot(n.Pipe) && !visited[n.Name] {
+			visited[n.Name] = true
+			if tmpl := t.set.Lookup(n.Name); tmpl != nil && tmpl.Tree != nil {
+				t.walk(tmpl.Tree.Root, true, vars, visited)
+			}
+		}
+	}
+}
+
+// passesDot 检查{{template}}是否将根数据原样传入
+func passesDot(pipe *parse.PipeNode) bool {
+	if pipe == nil || len(pipe.Cmds) != 1 || len(pipe.Cmds[0].Args) != 1 {
+		return false
+	}
+	_, ok := pipe.Cmds[0].Args[0].(*parse.DotNode)
+	return ok
+}
+
+// Validate 检查数据是否绑定了模板引用的所有变量。map需要包含所有键，结构体需要包含对应的字段或方法
+func (t *Template) Validate(data interface{}) error {
+	if len(t.variables) == 0 {
+		return nil
+	}
+
+	missing, err := missingValues(data, t.variables)
+	if err != nil {
+		return fmt.Errorf("template %q: %w", t.name, err)
+	}
+	if len(missing) > 0 {
+		return types.NewValidationError("data", missing,
+			fmt.Sprintf("template %q has unbound variables: %s", t.name, strings.Join(missing, ", ")),
+			types.ErrCodeMissingParameter)
+	}
+	return nil
+}
+
+// missingValues 获取数据中缺失的变量
+func missingValues(data interface{}, names []string) ([]string, error) {
+	value := reflect.ValueOf(data)
+	for value.IsValid() && (value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface) {
+		if value.IsNil() {
+			return nil, fmt.Errorf("data cannot be nil")
+		}
+		if value.Kind() == reflect.Pointer && hasAllMethods(value.Type(), names) {
+			return nil, nil
+		}
+		value = value.Elem()
+	}
+	if !value.IsValid() {
+		return nil, fmt.Errorf("data cannot be nil")
+	}
+
+	switch value.Kind() {
+	case reflect.Map:
+		if value.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("data map must have string keys")
+		}
+		var missing []string
+		for _, name := range names {
+			if !value.MapIndex(reflect.ValueOf(name).Convert(value.Type().Key())).IsValid() {
+				missing = append(missing, name)
+			}
+		}
+		return missing, nil
+	case reflect.Struct:
+		return missingFields(value.Type(), names), nil
+	default:
+		return nil, fmt.Errorf("data must be a map or struct, got %s", value.Type())
+	}
+}
+
+// missingFields 获取结构体类型中缺失的字段或方法，非结构体类型无法静态检查，返回nil
+func missingFields(typ reflect.Type, names []string) []string {
+	for typ != nil && typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var missing []string
+	for _, name := range names {
+		if field, ok := typ.FieldByName(name); ok && field.IsExported() {
+			continue
+		}
+		if _, ok := reflect.PointerTo(typ).MethodByName(name); ok {
+			continue
+		}
+		missing = append(missing, name)
+	}
+	return missing
+}
+
+// hasAllMethods 检查类型是否以方法提供所有变量
+func hasAllMethods(typ reflect.Type, names []string) bool {
+	for _, name := range names {
+		if _, ok := typ.MethodByName(name); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// typeOf 获取类型参数对应的反射类型
+func typeOf[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
@@ -152,12 +152,12 @@ func (c *ClaudeToNewAPIConverter) convertContent(claudeContent []claudeTypes.Con
 				Text: item.Text,
 			})
 		case claudeTypes.ContentTypeImage:
-			// 支持图像URL
+			// 支持图像URL和base64图像源
 			if item.ImageURL != "" {
-				messageContents = append(messageContents, types.MessageContent{
-					Type:     types.ChatMessageTypeImageURL,
-					ImageURL: item.ImageURL,
-				})
+				messageContents = append(messageContents, types.NewImageURLPart(item.ImageURL, ""))
+			} else if item.Source != nil && item.Source.Data != "" {
+				dataURI := fmt.Sprintf("data:%s;base64,%s", item.Source.MediaType, item.Source.Data)
+				messageContents = append(messageContents, types.NewImageURLPart(dataURI, ""))
 			}
 		default:
 			return nil, fmt.Errorf("unsupported content type: %s", item.Type)
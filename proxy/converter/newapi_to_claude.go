@@ -3,6 +3,7 @@ package converter
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	claudeTypes "github.com/hewenyu/newapi-go/proxy/types"
@@ -72,6 +73,11 @@ func (c *NewAPIToClaudeConverter) ConvertResponse(newAPIResp *types.ChatCompleti
 func (c *NewAPIToClaudeConverter) convertContent(message types.ChatMessage) []claudeTypes.ContentItem {
 	var content []claudeTypes.ContentItem
 
+	// 推理内容映射为thinking内容块，位于回答之前
+	if message.HasReasoningContent() {
+		content = append(content, claudeTypes.NewThinkingContent(message.ReasoningContent))
+	}
+
 	switch msgContent := message.Content.(type) {
 	case string:
 		// 简单文本内容
@@ -93,11 +99,8 @@ func (c *NewAPIToClaudeConverter) convertContent(message types.ChatMessage) []cl
 					})
 				}
 			case types.ChatMessageTypeImageURL:
-				if item.ImageURL != "" {
-					content = append(content, claudeTypes.ContentItem{
-						Type:     claudeTypes.ContentTypeImage,
-						ImageURL: item.ImageURL,
-					})
+				if item.ImageURL != nil && item.ImageURL.URL != "" {
+					content = append(content, c.convertImage(item.ImageURL.URL))
 				}
 			}
 		}
@@ -111,8 +114,8 @@ func (c *NewAPIToClaudeConverter) convertContent(message types.ChatMessage) []cl
 		}
 	}
 
-	// 如果没有内容，添加空文本
-	if len(content) == 0 {
+	// 如果没有回答内容，添加空文本
+	if len(content) == 0 || (len(content) == 1 && message.HasReasoningContent()) {
 		content = append(content, claudeTypes.ContentItem{
 			Type: claudeTypes.ContentTypeText,
 			Text: "",
@@ -122,6 +125,27 @@ func (c *NewAPIToClaudeConverter) convertContent(message types.ChatMessage) []cl
 	return content
 }
 
+// convertImage 转换图像URL，base64数据URI转换为Claude图像源
+func (c *NewAPIToClaudeConverter) convertImage(url string) claudeTypes.ContentItem {
+	if strings.HasPrefix(url, "data:") {
+		if header, data, ok := strings.Cut(strings.TrimPrefix(url, "data:"), ","); ok && strings.HasSuffix(header, ";base64") {
+			return claudeTypes.ContentItem{
+				Type: claudeTypes.ContentTypeImage,
+				Source: &claudeTypes.Image{
+					Type:      "base64",
+					MediaType: strings.TrimSuffix(header, ";base64"),
+					Data:      data,
+				},
+			}
+		}
+	}
+
+	return claudeTypes.ContentItem{
+		Type:     claudeTypes.ContentTypeImage,
+		ImageURL: url,
+	}
+}
+
 // mapStopReason 映射停止原因
 func (c *NewAPIToClaudeConverter) mapStopReason(finishReason string) string {
 	if mapped, exists := c.stopReasonMapping[finishReason]; exists {
@@ -149,29 +173,6 @@ func (c *NewAPIToClaudeConverter) convertError(err *types.ErrorResponse) error {
 	}
 }
 
-// ConvertStreamChunk 转换流式响应块
-func (c *NewAPIToClaudeConverter) ConvertStreamChunk(chunk *types.ChatCompletionChunk, originalModel string) (*claudeTypes.StreamEvent, error) {
-	if chunk == nil {
-		return nil, fmt.Errorf("chunk is nil")
-	}
-
-	// 获取第一个选择
-	if len(chunk.Choices) == 0 {
-		return nil, fmt.Errorf("no choices in chunk")
-	}
-
-	choice := chunk.Choices[0]
-
-	// 根据完成原因确定事件类型
-	if choice.FinishReason != "" {
-		// 发送完成事件
-		return c.createMessageStopEvent(), nil
-	}
-
-	// 发送内容增量事件
-	return c.createContentDeltaEvent(choice.Delta, 0), nil
-}
-
 // createMessageStartEvent 创建消息开始事件
 func (c *NewAPIToClaudeConverter) createMessageStartEvent(id, model string) *claudeTypes.StreamEvent {
 	messageStart := claudeTypes.MessageStartEvent{
@@ -196,24 +197,6 @@ func (c *NewAPIToClaudeConverter) createMessageStartEvent(id, model string) *cla
 	}
 }
 
-// createContentBlockStartEvent 创建内容块开始事件
-func (c *NewAPIToClaudeConverter) createContentBlockStartEvent(index int) *claudeTypes.StreamEvent {
-	contentStart := claudeTypes.ContentBlockStartEvent{
-		Type:  claudeTypes.EventContentBlockStart,
-		Index: index,
-		ContentBlock: claudeTypes.ContentItem{
-			Type: claudeTypes.ContentTypeText,
-			Text: "",
-		},
-	}
-
-	return &claudeTypes.StreamEvent{
-		Type:  claudeTypes.EventContentBlockStart,
-		Event: "content_block_start",
-		Data:  c.marshalToRawMessage(contentStart),
-	}
-}
-
 // createContentDeltaEvent 创建内容增量事件
 func (c *NewAPIToClaudeConverter) createContentDeltaEvent(delta types.ChatMessage, index int) *claudeTypes.StreamEvent {
 	text := delta.GetTextContent()
@@ -327,38 +310,6 @@ func (c *NewAPIToClaudeConverter) marshalToRawMessage(data interface{}) []byte {
 	return []byte("{}")
 }
 
-// GenerateStreamEvents 生成完整的流式事件序列
-func (c *NewAPIToClaudeConverter) GenerateStreamEvents(messageID, model string) []*claudeTypes.StreamEvent {
-	var events []*claudeTypes.StreamEvent
-
-	// 1. 消息开始事件
-	events = append(events, c.createMessageStartEvent(messageID, model))
-
-	// 2. 内容块开始事件
-	events = append(events, c.createContentBlockStartEvent(0))
-
-	// 3. Ping事件
-	events = append(events, c.createPingEvent())
-
-	return events
-}
-
-// GenerateStreamEndEvents 生成流式结束事件序列
-func (c *NewAPIToClaudeConverter) GenerateStreamEndEvents(stopReason string, usage *types.Usage) []*claudeTypes.StreamEvent {
-	var events []*claudeTypes.StreamEvent
-
-	// 1. 内容块停止事件
-	events = append(events, c.createContentBlockStopEvent(0))
-
-	// 2. 消息增量事件（包含使用量）
-	events = append(events, c.createMessageDeltaEvent(stopReason, usage))
-
-	// 3. 消息停止事件
-	events = append(events, c.createMessageStopEvent())
-
-	return events
-}
-
 // GenerateID 生成消息ID
 func (c *NewAPIToClaudeConverter) GenerateID() string {
 	return fmt.Sprintf("msg_%d", time.Now().UnixNano())
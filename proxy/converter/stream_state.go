@@ -0,0 +1,140 @@
+package converter
+
+import (
+	claudeTypes "github.com/hewenyu/newapi-go/proxy/types"
+	"github.com/hewenyu/newapi-go/types"
+)
+
+// StreamState 流式转换状态
+// 记录当前打开的内容块，使推理增量和回答增量分别落在thinking块和text块中
+type StreamState struct {
+	blockIndex int
+	blockType  string
+	stopReason string
+	usage      *types.Usage
+}
+
+// NewStreamState 创建流式转换状态
+func NewStreamState() *StreamState {
+	return &StreamState{blockIndex: -1}
+}
+
+// GetStopReason 获取已记录的结束原因
+func (s *StreamState) GetStopReason() string {
+	return s.stopReason
+}
+
+// GetUsage 获取已记录的使用量
+func (s *StreamState) GetUsage() *types.Usage {
+	return s.usage
+}
+
+// GenerateStreamStartEvents 生成流式开始事件序列（不含内容块，内容块按需打开）
+func (c *NewAPIToClaudeConverter) GenerateStreamStartEvents(messageID, model string) []*claudeTypes.StreamEvent {
+	return []*claudeTypes.StreamEvent{
+		c.createMessageStartEvent(messageID, model),
+		c.createPingEvent(),
+	}
+}
+
+// ConvertStreamChunkEvents 根据流式状态转换响应块
+// 推理增量转换为thinking_delta，回答增量转换为文本增量，块类型切换时自动关闭旧块并打开新块
+func (c *NewAPIToClaudeConverter) ConvertStreamChunkEvents(chunk *types.ChatCompletionChunk, state *StreamState) []*claudeTypes.StreamEvent {
+	var events []*claudeTypes.StreamEvent
+	if chunk == nil || state == nil {
+		return events
+	}
+
+	if chunk.Usage != nil {
+		state.usage = chunk.Usage
+	}
+
+	if len(chunk.Choices) == 0 {
+		return events
+	}
+
+	choice := chunk.Choices[0]
+
+	if reasoning := choice.GetReasoningContent(); reasoning != "" {
+		events = append(events, c.switchBlock(state, claudeTypes.ContentTypeThinking)...)
+		events = append(events, c.createThinkingDeltaEvent(reasoning, state.blockIndex))
+	}
+
+	if text := choice.GetContent(); text != "" {
+		events = append(events, c.switchBlock(state, claudeTypes.ContentTypeText)...)
+		events = append(events, c.createContentDeltaEvent(choice.Delta, state.blockIndex))
+	}
+
+	if choice.FinishReason != "" {
+		state.stopReason = choice.FinishReason
+	}
+
+	return events
+}
+
+// GenerateStreamStopEvents 根据流式状态生成结束事件序列
+func (c *NewAPIToClaudeConverter) GenerateStreamStopEvents(state *StreamState) []*claudeTypes.StreamEvent {
+	if state == nil {
+		state = NewStreamState()
+	}
+
+	var events []*claudeTypes.StreamEvent
+
+	// 没有任何内容时仍然输出一个空文本块
+	if state.blockType == "" {
+		events = append(events, c.switchBlock(state, claudeTypes.ContentTypeText)...)
+	}
+
+	events = append(events, c.createContentBlockStopEvent(state.blockIndex))
+	events = append(events, c.createMessageDeltaEvent(state.stopReason, state.usage))
+	events = append(events, c.createMessageStopEvent())
+
+	return events
+}
+
+// switchBlock 切换到指定类型的内容块
+func (c *NewAPIToClaudeConverter) switchBlock(state *StreamState, blockType string) []*claudeTypes.StreamEvent {
+	if state.blockType == blockType {
+		return nil
+	}
+
+	var events []*claudeTypes.StreamEvent
+	if state.blockType != "" {
+		events = append(events, c.createContentBlockStopEvent(state.blockIndex))
+	}
+
+	state.blockIndex++
+	state.blockType = blockType
+
+	contentStart := claudeTypes.ContentBlockStartEvent{
+		Type:         claudeTypes.EventContentBlockStart,
+		Index:        state.blockIndex,
+		ContentBlock: claudeTypes.NewStreamContentBlock(blockType),
+	}
+
+	events = append(events, &claudeTypes.StreamEvent{
+		Type:  claudeTypes.EventContentBlockStart,
+		Event: "content_block_start",
+		Data:  c.marshalToRawMessage(contentStart),
+	})
+
+	return events
+}
+
+// createThinkingDeltaEvent 创建思考增量事件
+func (c *NewAPIToClaudeConverter) createThinkingDeltaEvent(thinking string, index int) *claudeTypes.StreamEvent {
+	thinkingDelta := claudeTypes.ContentBlockDeltaEvent{
+		Type:  claudeTypes.EventContentBlockDelta,
+		Index: index,
+		Delta: claudeTypes.ContentBlockDelta{
+			Type:     claudeTypes.DeltaTypeThinking,
+			Thinking: thinking,
+		},
+	}
+
+	return &claudeTypes.StreamEvent{
+		Type:  claudeTypes.EventContentBlockDelta,
+		Event: "content_block_delta",
+		Data:  c.marshalToRawMessage(thinkingDelta),
+	}
+}
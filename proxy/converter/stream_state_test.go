@@ -0,0 +1,118 @@
+package converter
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	claudeTypes "github.com/hewenyu/newapi-go/proxy/types"
+	"github.com/hewenyu/newapi-go/types"
+)
+
+// deltaChunk 创建包含推理增量和回答增量的响应块
+func deltaChunk(reasoning, content string) *types.ChatCompletionChunk {
+	return &types.ChatCompletionChunk{
+		ID:      "chatcmpl-stream",
+		Choices: []types.ChatCompletionChunkChoice{{Delta: types.ChatMessage{ReasoningContent: reasoning, Content: content}}},
+	}
+}
+
+// describeEvents 将事件序列描述为"类型[索引]"的形式，便于比较顺序
+func describeEvents(t *testing.T, events []*claudeTypes.StreamEvent) string {
+	t.Helper()
+
+	parts := make([]string, 0, len(events))
+	for _, event := range events {
+		var data struct {
+			Index *int `json:"index"`
+			Delta struct {
+				Type string `json:"type"`
+			} `json:"delta"`
+		}
+		if err := json.Unmarshal(event.Data, &data); err != nil {
+			t.Fatalf("invalid event data %s: %v", event.Data, err)
+		}
+
+		part := event.Type
+		if data.Index != nil {
+			part += fmt.Sprintf("[%d]", *data.Index)
+		}
+		if event.Type == claudeTypes.EventContentBlockDelta {
+			part += ":" + data.Delta.Type
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestStreamReasoningMapsToThinkingBlock(t *testing.T) {
+	c := NewNewAPIToClaudeConverter()
+	state := NewStreamState()
+
+	events := c.GenerateStreamStartEvents("msg_1", "claude-3")
+	for _, chunk := range []*types.ChatCompletionChunk{
+		deltaChunk("Let me", ""),
+		deltaChunk(" think", ""),
+		deltaChunk("", "Hi"),
+		deltaChunk("", " there"),
+	} {
+		events = append(events, c.ConvertStreamChunkEvents(chunk, state)...)
+	}
+
+	finish := deltaChunk("", "")
+	finish.Choices[0].FinishReason = types.FinishReasonStop
+	finish.Usage = &types.Usage{PromptTokens: 5, CompletionTokens: 7}
+	events = append(events, c.ConvertStreamChunkEvents(finish, state)...)
+	events = append(events, c.GenerateStreamStopEvents(state)...)
+
+	want := "message_start ping " +
+		"content_block_start[0] content_block_delta[0]:thinking_delta content_block_delta[0]:thinking_delta " +
+		"content_block_stop[0] content_block_start[1] content_block_delta[1]:text content_block_delta[1]:text " +
+		"content_block_stop[1] message_delta message_stop"
+	if got := describeEvents(t, events); got != want {
+		t.Errorf("events =\n%s\nwant\n%s", got, want)
+	}
+
+	// 空的内容块必须带有thinking和text字段
+	if got := string(events[2].Data); !strings.Contains(got, `"content_block":{"type":"thinking","thinking":""}`) {
+		t.Errorf("thinking block start = %s", got)
+	}
+	if got := string(events[6].Data); !strings.Contains(got, `"content_block":{"type":"text","text":""}`) {
+		t.Errorf("text block start = %s", got)
+	}
+	if got := string(events[3].Data); !strings.Contains(got, `"thinking":"Let me"`) {
+		t.Errorf("thinking delta = %s", got)
+	}
+
+	var delta claudeTypes.MessageDeltaEvent
+	if err := json.Unmarshal(events[len(events)-2].Data, &delta); err != nil {
+		t.Fatalf("invalid message_delta: %v", err)
+	}
+	if delta.Delta.StopReason != claudeTypes.StopReasonEndTurn || delta.Usage.InputTokens != 5 || delta.Usage.OutputTokens != 7 {
+		t.Errorf("message_delta = %+v", delta)
+	}
+}
+
+func TestStreamReasoningAndContentInOneChunk(t *testing.T) {
+	c := NewNewAPIToClaudeConverter()
+	state := NewStreamState()
+
+	events := c.ConvertStreamChunkEvents(deltaChunk("thought", "answer"), state)
+	want := "content_block_start[0] content_block_delta[0]:thinking_delta " +
+		"content_block_stop[0] content_block_start[1] content_block_delta[1]:text"
+	if got := describeEvents(t, events); got != want {
+		t.Errorf("events = %s, want %s", got, want)
+	}
+}
+
+func TestStreamStopWithoutContent(t *testing.T) {
+	c := NewNewAPIToClaudeConverter()
+
+	// 没有任何内容时仍然输出一个空文本块
+	events := c.GenerateStreamStopEvents(NewStreamState())
+	want := "content_block_start[0] content_block_stop[0] message_delta message_stop"
+	if got := describeEvents(t, events); got != want {
+		t.Errorf("events = %s, want %s", got, want)
+	}
+}
@@ -138,11 +138,14 @@ func (h *MessageHandler) handleStreamMessage(w http.ResponseWriter, r *http.Requ
 	messageID := h.newAPIToClaudeConverter.GenerateID()
 
 	// 发送初始事件
-	startEvents := h.newAPIToClaudeConverter.GenerateStreamEvents(messageID, claudeReq.Model)
+	startEvents := h.newAPIToClaudeConverter.GenerateStreamStartEvents(messageID, claudeReq.Model)
 	for _, event := range startEvents {
 		h.sendStreamEvent(w, flusher, event)
 	}
 
+	// 内容块状态（推理内容映射为thinking块）
+	state := converter.NewStreamState()
+
 	// 处理流式数据
 	for {
 		select {
@@ -163,21 +166,21 @@ func (h *MessageHandler) handleStreamMessage(w http.ResponseWriter, r *http.Requ
 		}
 
 		// 处理事件
-		if err := h.processStreamEvent(w, flusher, event); err != nil {
+		if err := h.processStreamEvent(w, flusher, event, state); err != nil {
 			h.sendStreamError(w, flusher, err)
 			return
 		}
 	}
 
 	// 发送结束事件
-	endEvents := h.newAPIToClaudeConverter.GenerateStreamEndEvents("", nil)
+	endEvents := h.newAPIToClaudeConverter.GenerateStreamStopEvents(state)
 	for _, event := range endEvents {
 		h.sendStreamEvent(w, flusher, event)
 	}
 }
 
 // processStreamEvent 处理流式事件
-func (h *MessageHandler) processStreamEvent(w http.ResponseWriter, flusher http.Flusher, event *types.StreamEvent) error {
+func (h *MessageHandler) processStreamEvent(w http.ResponseWriter, flusher http.Flusher, event *types.StreamEvent, state *converter.StreamState) error {
 	// 解析事件数据
 	if event.Type == types.StreamEventTypeData {
 		// 处理流式数据
@@ -186,14 +189,10 @@ func (h *MessageHandler) processStreamEvent(w http.ResponseWriter, flusher http.
 			return err
 		}
 
-		// 转换为Claude格式
-		claudeEvent, err := h.newAPIToClaudeConverter.ConvertStreamChunk(&chunk, "")
-		if err != nil {
-			return err
+		// 转换为Claude格式并发送
+		for _, claudeEvent := range h.newAPIToClaudeConverter.ConvertStreamChunkEvents(&chunk, state) {
+			h.sendStreamEvent(w, flusher, claudeEvent)
 		}
-
-		// 发送转换后的事件
-		h.sendStreamEvent(w, flusher, claudeEvent)
 	}
 
 	return nil
@@ -21,8 +21,14 @@ const (
 
 // 内容类型常量
 const (
-	ContentTypeText  = "text"
-	ContentTypeImage = "image"
+	ContentTypeText     = "text"
+	ContentTypeImage    = "image"
+	ContentTypeThinking = "thinking"
+)
+
+// 内容块增量类型常量
+const (
+	DeltaTypeThinking = "thinking_delta"
 )
 
 // 停止原因常量
@@ -55,6 +61,7 @@ type ClaudeMessage struct {
 type ContentItem struct {
 	Type     string `json:"type"`
 	Text     string `json:"text,omitempty"`
+	Thinking string `json:"thinking,omitempty"`
 	Source   *Image `json:"source,omitempty"`
 	ImageURL string `json:"image_url,omitempty"`
 }
@@ -162,9 +169,29 @@ type MessageStopEvent struct {
 
 // ContentBlockStartEvent 内容块开始事件
 type ContentBlockStartEvent struct {
-	Type         string      `json:"type"`
-	Index        int         `json:"index"`
-	ContentBlock ContentItem `json:"content_block"`
+	Type         string             `json:"type"`
+	Index        int                `json:"index"`
+	ContentBlock StreamContentBlock `json:"content_block"`
+}
+
+// StreamContentBlock 内容块开始事件中的空内容块，Claude客户端要求text或thinking字段即使为空也存在
+type StreamContentBlock struct {
+	Type     string  `json:"type"`
+	Text     *string `json:"text,omitempty"`
+	Thinking *string `json:"thinking,omitempty"`
+}
+
+// NewStreamContentBlock 创建指定类型的空内容块
+func NewStreamContentBlock(blockType string) StreamContentBlock {
+	empty := ""
+	block := StreamContentBlock{Type: blockType}
+	switch blockType {
+	case ContentTypeThinking:
+		block.Thinking = &empty
+	case ContentTypeText:
+		block.Text = &empty
+	}
+	return block
 }
 
 // ContentBlockDeltaEvent 内容块增量事件
@@ -176,8 +203,9 @@ type ContentBlockDeltaEvent struct {
 
 // ContentBlockDelta 内容块增量
 type ContentBlockDelta struct {
-	Type string `json:"type"`
-	Text string `json:"text,omitempty"`
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	Thinking string `json:"thinking,omitempty"`
 }
 
 // ContentBlockStopEvent 内容块停止事件
@@ -207,6 +235,14 @@ func NewTextContent(text string) ContentItem {
 	}
 }
 
+// NewThinkingContent 创建思考内容
+func NewThinkingContent(thinking string) ContentItem {
+	return ContentItem{
+		Type:     ContentTypeThinking,
+		Thinking: thinking,
+	}
+}
+
 // NewImageContent 创建图像内容
 func NewImageContent(imageURL string) ContentItem {
 	return ContentItem{
@@ -362,6 +398,16 @@ func (r *ClaudeResponse) GetFirstTextContent() string {
 	return ""
 }
 
+// GetThinkingContent 获取思考内容
+func (r *ClaudeResponse) GetThinkingContent() string {
+	for _, content := range r.Content {
+		if content.Type == ContentTypeThinking {
+			return content.Thinking
+		}
+	}
+	return ""
+}
+
 // GenerateID 生成消息ID
 func GenerateID() string {
 	return fmt.Sprintf("msg_%d", time.Now().UnixNano())
@@ -15,6 +15,7 @@ import (
 	"github.com/hewenyu/newapi-go/internal/transport"
 	"github.com/hewenyu/newapi-go/internal/utils"
 	"github.com/hewenyu/newapi-go/types"
+	"github.com/hewenyu/newapi-go/usage"
 	"go.uber.org/zap"
 )
 
@@ -23,6 +24,7 @@ type AudioService struct {
 	transport transport.HTTPTransport
 	logger    utils.Logger
 	config    *AudioConfig
+	recorder  usage.Recorder
 	mu        sync.RWMutex
 }
 
@@ -89,6 +91,10 @@ func (s *AudioService) CreateTranscription(ctx context.Context, audioFile string
 		return nil, fmt.Errorf("invalid transcription request: %w", err)
 	}
 
+	if err := s.checkBudget(ctx, req.Model); err != nil {
+		return nil, err
+	}
+
 	// 发送multipart请求
 	resp, err := s.postMultipartFile(ctx, "/v1/audio/transcriptions", audioFile, req)
 	if err != nil {
@@ -110,6 +116,9 @@ func (s *AudioService) CreateTranscription(ctx context.Context, audioFile string
 		return nil, fmt.Errorf("API error: %s", apiErr.Message)
 	}
 
+	// 只有verbose_json格式返回音频时长
+	s.recordUsage(ctx, usage.Record{Service: usage.ServiceTranscription, Model: req.Model, AudioSeconds: transcriptionResp.Duration})
+
 	s.logger.Debug("Audio transcription created successfully", zap.String("text", transcriptionResp.Text[:min(50, len(transcriptionResp.Text))]))
 	return &transcriptionResp, nil
 }
@@ -0,0 +1,295 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hewenyu/newapi-go/types"
+)
+
+// DefaultBatchConcurrency 默认的批量请求并发数
+const DefaultBatchConcurrency = 4
+
+// BatchConfig 批量请求配置
+type BatchConfig struct {
+	Concurrency int
+	StopOnError bool
+}
+
+// BatchOption 批量请求选项函数类型
+type BatchOption func(*BatchConfig)
+
+// DefaultBatchConfig 默认批量请求配置
+func DefaultBatchConfig() *BatchConfig {
+	return &BatchConfig{
+		Concurrency: DefaultBatchConcurrency,
+	}
+}
+
+// WithConcurrency 设置最大并发数
+func WithConcurrency(concurrency int) BatchOption {
+	return func(c *BatchConfig) {
+		c.Concurrency = concurrency
+	}
+}
+
+// WithStopOnError 设置出现第一个错误时是否取消其余请求
+func WithStopOnError(stop bool) BatchOption {
+	return func(c *BatchConfig) {
+		c.StopOnError = stop
+	}
+}
+
+// MapResult Map的单项结果
+type MapResult[R any] struct {
+	Index int
+	Value R
+	Err   error
+}
+
+// Map 以有界并发处理items，结果顺序与输入一致，每项独立返回错误。
+// 上下文取消后尚未开始的项返回上下文错误
+func Map[T, R any](ctx context.Context, items []T, fn func(ctx context.Context, index int, item T) (R, error), options ...BatchOption) []MapResult[R] {
+	config := DefaultBatchConfig()
+	for _, option := range options {
+		option(config)
+	}
+	if config.Concurrency <= 0 {
+		config.Concurrency = DefaultBatchConcurrency
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]MapResult[R], len(items))
+	sem := make(chan struct{}, config.Concurrency)
+	var wg sync.WaitGroup
+
+	for i, item := range items {
+		results[i].Index = i
+
+		select {
+		case <-ctx.Done():
+			results[i].Err = ctx.Err()
+			continue
+		case sem <- struct{}{}:
+		}
+
+		wg.Add(1)
+		go func(i int, item T) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if err := ctx.Err(); err != nil {
+				results[i].Err = err
+				return
+			}
+			value, err := fn(ctx, i, item)
+			results[i].Value = value
+			results[i].Err = err
+			if err != nil && config.StopOnError {
+				cancel()
+			}
+		}(i, item)
+	}
+
+	wg.Wait()
+	return results
+}
+
+// BatchRequest 批量请求中的单个聊天请求
+type BatchRequest struct {
+	Messages []types.ChatMessage
+	Options  []ChatOption
+}
+
+// BatchResult 批量请求的单个结果
+type BatchResult struct {
+	Index    int
+	Response *types.ChatCompletionResponse
+	Err      error
+}
+
+// ModelRequests 构造向多个模型发送同一消息的请求列表
+func ModelRequests(messages []types.ChatMessage, models []string, options ...ChatOption) []BatchRequest {
+	requests := make([]BatchRequest, 0, len(models))
+	for _, model := range models {
+		requestOptions := append(append([]ChatOption(nil), options...), WithModel(model))
+		requests = append(requests, BatchRequest{Messages: messages, Options: requestOptions})
+	}
+	return requests
+}
+
+// Batch 以有界并发发送多个聊天请求，结果顺序与请求一致。
+// 请求通过CreateChatCompletion发送，遵循服务配置的速率限制器和模型降级
+func Batch(ctx context.Context, service *ChatService, requests []BatchRequest, options ...BatchOption) []BatchResult {
+	mapped := Map(ctx, requests, func(ctx context.Context, _ int, req BatchRequest) (*types.ChatCompletionResponse, error) {
+		return service.CreateChatCompletion(ctx, req.Messages, req.Options...)
+	}, options...)
+
+	results := make([]BatchResult, len(mapped))
+	for i, result := range mapped {
+		results[i] = BatchResult{Index: result.Index, Response: result.Value, Err: result.Err}
+	}
+	return results
+}
+
+// Race 并发发送所有请求，返回第一个成功的结果并取消其余请求。全部失败时返回合并的错误
+func Race(ctx context.Context, service *ChatService, requests []BatchRequest, options ...BatchOption) (*BatchResult, error) {
+	if len(requests) == 0 {
+		return nil, fmt.Errorf("requests cannot be empty")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mu sync.Mutex
+	var winner *BatchResult
+	results := Map(ctx, requests, func(ctx context.Context, index int, req BatchRequest) (*types.ChatCompletionResponse, error) {
+		resp, err := service.CreateChatCompletion(ctx, req.Messages, req.Options...)
+		if err != nil {
+			return nil, err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if winner == nil {
+			winner = &BatchResult{Index: index, Response: resp}
+			cancel()
+		}
+		return resp, nil
+	}, append(append([]BatchOption(nil), options...), WithStopOnError(false))...)
+
+	if winner != nil {
+		return winner, nil
+	}
+	return nil, joinBatchErrors(len(requests), results)
+}
+
+// joinBatchErrors 合并所有失败请求的错误
+func joinBatchErrors[R any](total int, results []MapResult[R]) error {
+	errs := make([]error, 0, len(results))
+	for _, result := range results {
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("request %d: %w", result.Index, result.Err))
+		}
+	}
+	return fmt.Errorf("all %d requests failed: %w", total, errors.Join(errs...))
+}
+
+// Selector 从成功的候选结果中选出最佳者，返回其在candidates中的下标
+type Selector func(ctx context.Context, candidates []BatchResult) (int, error)
+
+// Scorer 为候选回复打分，分数越高越好
+type Scorer func(ctx context.Context, resp *types.ChatCompletionResponse) (float64, error)
+
+// BestOfResult BestOf的结果
+type BestOfResult struct {
+	Best    BatchResult
+	Results []BatchResult
+}
+
+// BestOf 并发发送所有请求，并用selector从成功的结果中选出最佳回复。
+// 至少需要一个请求成功，失败的请求保留在Results中
+func BestOf(ctx context.Context, service *ChatService, requests []BatchRequest, selector Selector, options ...BatchOption) (*BestOfResult, error) {
+	if len(requests) == 0 {
+		return nil, fmt.Errorf("requests cannot be empty")
+	}
+	if selector == nil {
+		return nil, fmt.Errorf("selector cannot be nil")
+	}
+
+	results := Batch(ctx, service, requests, options...)
+
+	candidates := make([]BatchResult, 0, len(results))
+	for _, result := range results {
+		if result.Err == nil {
+			candidates = append(candidates, result)
+		}
+	}
+	if len(candidates) == 0 {
+		errs := make([]MapResult[struct{}], len(results))
+		for i, result := range results {
+			errs[i] = MapResult[struct{}]{Index: result.Index, Err: result.Err}
+		}
+		return nil, joinBatchErrors(len(requests), errs)
+	}
+
+	best := 0
+	if len(candidates) > 1 {
+		selected, err := selector(ctx, candidates)
+		if err != nil {
+			return nil, fmt.Errorf("failed to select best candidate: %w", err)
+		}
+		if selected < 0 || selected >= len(candidates) {
+			return nil, fmt.Errorf("selected candidate %d out of range [0, %d)", selected, len(candidates))
+		}
+		best = selected
+	}
+
+	return &BestOfResult{Best: candidates[best], Results: results}, nil
+}
+
+// ScoreSelector 使用打分函数选择得分最高的候选，得分相同时选择靠前的
+func ScoreSelector(scorer Scorer) Selector {
+	return func(ctx context.Context, candidates []BatchResult) (int, error) {
+		best := -1
+		var bestScore float64
+		for i, candidate := range candidates {
+			score, err := scorer(ctx, candidate.Response)
+			if err != nil {
+				return -1, fmt.Errorf("failed to score candidate %d: %w", candidate.Index, err)
+			}
+			if best < 0 || score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+		return best, nil
+	}
+}
+
+// DefaultJudgePrompt 默认的评审提示词
+const DefaultJudgePrompt = "You are an impartial judge. Compare the candidate answers below " +
+	"and pick the single best one. Reply with the number of the best candidate only."
+
+var judgeNumberPattern = regexp.MustCompile(`\d+`)
+
+// JudgeSelector 使用评审模型选择最佳候选。criteria为附加的评判标准，可为空
+func JudgeSelector(service *ChatService, model, criteria string) Selector {
+	return func(ctx context.Context, candidates []BatchResult) (int, error) {
+		var prompt strings.Builder
+		prompt.WriteString(DefaultJudgePrompt)
+		if criteria != "" {
+			prompt.WriteString("\n\nCriteria: ")
+			prompt.WriteString(criteria)
+		}
+
+		var body strings.Builder
+		for i, candidate := range candidates {
+			fmt.Fprintf(&body, "Candidate %d:\n%s\n\n", i+1, candidate.Response.GetFirstContent())
+		}
+
+		resp, err := service.CreateChatCompletion(ctx, []types.ChatMessage{
+			types.NewSystemMessage(prompt.String()),
+			types.NewUserMessage(strings.TrimSpace(body.String())),
+		}, WithModel(model))
+		if err != nil {
+			return -1, fmt.Errorf("judge request failed: %w", err)
+		}
+
+		match := judgeNumberPattern.FindString(resp.GetFirstContent())
+		if match == "" {
+			return -1, fmt.Errorf("judge reply contains no candidate number: %q", resp.GetFirstContent())
+		}
+		number, err := strconv.Atoi(match)
+		if err != nil || number < 1 || number > len(candidates) {
+			return -1, fmt.Errorf("judge selected invalid candidate: %q", match)
+		}
+		return number - 1, nil
+	}
+}
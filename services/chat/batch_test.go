@@ -0,0 +1,161 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hewenyu/newapi-go/types"
+)
+
+func TestMapBoundedAndOrdered(t *testing.T) {
+	var running, peak int32
+	results := Map(context.Background(), []int{1, 2, 3, 4, 5, 6}, func(ctx context.Context, _ int, n int) (int, error) {
+		current := atomic.AddInt32(&running, 1)
+		for {
+			old := atomic.LoadInt32(&peak)
+			if current <= old || atomic.CompareAndSwapInt32(&peak, old, current) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+
+		if n == 3 {
+			return 0, errors.New("boom")
+		}
+		return n * n, nil
+	}, WithConcurrency(2))
+
+	if peak > 2 {
+		t.Errorf("peak concurrency = %d, want <= 2", peak)
+	}
+	for i, result := range results {
+		if result.Index != i {
+			t.Errorf("results[%d].Index = %d", i, result.Index)
+		}
+		if i == 2 {
+			if result.Err == nil {
+				t.Errorf("expected error for item 3")
+			}
+			continue
+		}
+		if result.Err != nil || result.Value != (i+1)*(i+1) {
+			t.Errorf("results[%d] = %+v", i, result)
+		}
+	}
+}
+
+func TestMapCancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results := Map(ctx, []string{"a", "b"}, func(ctx context.Context, _ int, s string) (string, error) {
+		return s, nil
+	})
+	for _, result := range results {
+		if !errors.Is(result.Err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got %v", result.Err)
+		}
+	}
+}
+
+// modelHandler 按请求的模型返回回复，delay中的模型会延迟响应
+func modelHandler(delay map[string]time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req types.ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+
+		select {
+		case <-time.After(delay[req.Model]):
+		case <-r.Context().Done():
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(textResponse("answer from " + req.Model))
+	})
+}
+
+func TestBatchPreservesOrder(t *testing.T) {
+	service := newHandlerService(t, modelHandler(map[string]time.Duration{"slow": 20 * time.Millisecond}))
+	messages := []types.ChatMessage{types.NewUserMessage("hi")}
+
+	results := Batch(context.Background(), service, ModelRequests(messages, []string{"slow", "fast"}))
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	if results[0].Response.GetFirstContent() != "answer from slow" || results[1].Response.GetFirstContent() != "answer from fast" {
+		t.Errorf("results out of order: %q, %q", results[0].Response.GetFirstContent(), results[1].Response.GetFirstContent())
+	}
+}
+
+func TestRaceReturnsFirstSuccess(t *testing.T) {
+	service := newHandlerService(t, modelHandler(map[string]time.Duration{"slow": 2 * time.Second}))
+	messages := []types.ChatMessage{types.NewUserMessage("hi")}
+
+	start := time.Now()
+	winner, err := Race(context.Background(), service, ModelRequests(messages, []string{"slow", "fast"}))
+	if err != nil {
+		t.Fatalf("Race failed: %v", err)
+	}
+	if winner.Index != 1 || winner.Response.GetFirstContent() != "answer from fast" {
+		t.Errorf("unexpected winner: %+v", winner)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("Race did not cancel the slow request")
+	}
+}
+
+func TestBestOfWithScorerAndJudge(t *testing.T) {
+	service := newHandlerService(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req types.ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+
+		content := "answer from " + req.Model
+		if req.Model == "judge" {
+			content = "Candidate 2 is best."
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(textResponse(content))
+	}))
+	requests := ModelRequests([]types.ChatMessage{types.NewUserMessage("hi")}, []string{"a", "bb", "c"})
+
+	scored, err := BestOf(context.Background(), service, requests, ScoreSelector(func(ctx context.Context, resp *types.ChatCompletionResponse) (float64, error) {
+		return float64(len(resp.GetFirstContent())), nil
+	}))
+	if err != nil {
+		t.Fatalf("BestOf with scorer failed: %v", err)
+	}
+	if scored.Best.Index != 1 {
+		t.Errorf("scorer picked %d, want 1", scored.Best.Index)
+	}
+
+	judged, err := BestOf(context.Background(), service, requests, JudgeSelector(service, "judge", ""))
+	if err != nil {
+		t.Fatalf("BestOf with judge failed: %v", err)
+	}
+	if judged.Best.Index != 1 || len(judged.Results) != 3 {
+		t.Errorf("judge picked %d, want 1", judged.Best.Index)
+	}
+}
+
+func TestRateLimiterAppliesToRequests(t *testing.T) {
+	service := newHandlerService(t, modelHandler(nil))
+	service.SetRateLimiter(NewTokenBucketLimiter(20, 1))
+	messages := []types.ChatMessage{types.NewUserMessage("hi")}
+
+	start := time.Now()
+	results := Batch(context.Background(), service, ModelRequests(messages, []string{"a", "b", "c"}))
+	for _, result := range results {
+		if result.Err != nil {
+			t.Fatalf("request %d failed: %v", result.Index, result.Err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("3 requests at 20 rps with burst 1 took %v, expected >= 100ms", elapsed)
+	}
+}
@@ -0,0 +1,327 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/hewenyu/newapi-go/cache"
+	"github.com/hewenyu/newapi-go/internal/utils"
+	"github.com/hewenyu/newapi-go/types"
+	"go.uber.org/zap"
+)
+
+// cacheNamespace 聊天响应的缓存键前缀
+const cacheNamespace = "chat"
+
+// SetCache 设置响应缓存，c为nil时禁用缓存，ttl不大于0时缓存不过期。
+// 默认只缓存temperature显式设置为0的请求，可通过WithCachePolicy逐个请求调整
+func (s *ChatService) SetCache(c cache.Cache, ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cache = c
+	s.cacheTTL = ttl
+}
+
+// cacheKeyRequest 参与缓存键计算的请求内容
+type cacheKeyRequest struct {
+	Request        *types.ChatCompletionRequest `json:"request"`
+	FallbackModels []string                     `json:"fallback_models,omitempty"`
+}
+
+// cacheEntry 缓存中保存的响应
+type cacheEntry struct {
+	Response    *types.ChatCompletionResponse `json:"response"`
+	ServedModel string                        `json:"served_model,omitempty"`
+}
+
+// responseCache 单个请求的缓存上下文
+type responseCache struct {
+	cache  cache.Cache
+	ttl    time.Duration
+	key    string
+	logger utils.Logger
+}
+
+// requestCache 获取请求的缓存上下文，未配置缓存时返回nil，请求不可缓存时key为空
+func (s *ChatService) requestCache(messages []types.ChatMessage, config *ChatConfig) *responseCache {
+	s.mu.RLock()
+	c, ttl := s.cache, s.cacheTTL
+	s.mu.RUnlock()
+
+	if c == nil {
+		return nil
+	}
+	rc := &responseCache{cache: c, ttl: ttl, logger: s.logger}
+	if !config.cacheable() {
+		return rc
+	}
+
+	// 流式参数不影响响应内容，流式与非流式请求共享缓存
+	req := config.ToRequest(messages)
+	req.Stream = false
+	req.StreamOptions = nil
+
+	key, err := cache.Key(cacheNamespace, cacheKeyRequest{Request: req, FallbackModels: config.FallbackModels})
+	if err != nil {
+		s.logger.Warn("Failed to compute cache key", zap.Error(err))
+		return rc
+	}
+	rc.key = key
+	return rc
+}
+
+// cacheable 检查请求是否可缓存，未设置temperature时服务端使用非零默认值，视为不可缓存
+func (c *ChatConfig) cacheable() bool {
+	switch c.CachePolicy {
+	case cache.PolicyForce:
+		return true
+	case cache.PolicyBypass:
+		return false
+	}
+	return c.Temperature != nil && *c.Temperature <= 0
+}
+
+// status 获取未命中时的缓存状态
+func (r *responseCache) status() string {
+	if r.key == "" {
+		return types.CacheStatusBypass
+	}
+	return types.CacheStatusMiss
+}
+
+// get 读取缓存的响应，缓存错误只记录日志
+func (r *responseCache) get(ctx context.Context) *types.ChatCompletionResponse {
+	if r.key == "" {
+		return nil
+	}
+
+	data, ok, err := r.cache.Get(ctx, r.key)
+	if err != nil {
+		r.logger.Warn("Failed to read response cache", zap.String("key", r.key), zap.Error(err))
+		return nil
+	}
+	if !ok {
+		return nil
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Response == nil {
+		r.logger.Warn("Discarding invalid response cache entry", zap.String("key", r.key), zap.Error(err))
+		return nil
+	}
+
+	resp := entry.Response
+	resp.ServedModel = entry.ServedModel
+	resp.CacheStatus = types.CacheStatusHit
+	return resp
+}
+
+// set 写入响应，缓存错误只记录日志
+func (r *responseCache) set(ctx context.Context, resp *types.ChatCompletionResponse) {
+	if r.key == "" || resp == nil {
+		return
+	}
+
+	data, err := json.Marshal(cacheEntry{Response: resp, ServedModel: resp.ServedModel})
+	if err != nil {
+		r.logger.Warn("Failed to encode response cache entry", zap.Error(err))
+		return
+	}
+	if err := r.cache.Set(ctx, r.key, data, r.ttl); err != nil {
+		r.logger.Warn("Failed to write response cache", zap.String("key", r.key), zap.Error(err))
+	}
+}
+
+// replayChunks 将缓存的响应转换为流式块：每个选择一个包含完整内容的块，最后是用量块
+func replayChunks(resp *types.ChatCompletionResponse) []types.ChatCompletionChunk {
+	base := types.ChatCompletionChunk{
+		ID:                resp.ID,
+		Object:            "chat.completion.chunk",
+		Created:           resp.Created,
+		Model:             resp.Model,
+		SystemFingerprint: resp.SystemFingerprint,
+		ServiceTier:       resp.ServiceTier,
+	}
+
+	chunks := make([]types.ChatCompletionChunk, 0, len(resp.Choices)+1)
+	for _, choice := range resp.Choices {
+		delta := choice.Message
+		if len(delta.ToolCalls) > 0 {
+			delta.ToolCalls = make([]types.ToolCall, len(choice.Message.ToolCalls))
+			for i, call := range choice.Message.ToolCalls {
+				call.Index = types.IntPtr(i)
+				delta.ToolCalls[i] = call
+			}
+		}
+
+		chunk := base
+		chunk.Choices = []types.ChatCompletionChunkChoice{{
+			Index:        choice.Index,
+			Delta:        delta,
+			FinishReason: choice.FinishReason,
+			LogProbs:     choice.LogProbs,
+		}}
+		chunks = append(chunks, chunk)
+	}
+
+	usage := base
+	usage.Choices = []types.ChatCompletionChunkChoice{}
+	usage.Usage = &types.Usage{}
+	*usage.Usage = resp.Usage
+	return append(chunks, usage)
+}
+
+// replayStream 以合成的流重放缓存的响应
+type replayStream struct {
+	ctx         context.Context
+	chunks      []types.ChatCompletionChunk
+	servedModel string
+	mu          sync.Mutex
+	done        bool
+}
+
+// newReplayStream 创建重放缓存响应的流
+func newReplayStream(ctx context.Context, resp *types.ChatCompletionResponse) *replayStream {
+	return &replayStream{
+		ctx:         ctx,
+		chunks:      replayChunks(resp),
+		servedModel: resp.ServedModel,
+	}
+}
+
+// Next 获取下一个事件
+func (r *replayStream) Next() (*types.StreamEvent, error) {
+	if err := r.ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.done || len(r.chunks) == 0 {
+		r.done = true
+		return nil, io.EOF
+	}
+	chunk := r.chunks[0]
+	r.chunks = r.chunks[1:]
+
+	data, err := json.Marshal(chunk)
+	if err != nil {
+		return nil, err
+	}
+	return &types.StreamEvent{Type: types.StreamEventTypeData, Data: data}, nil
+}
+
+// Close 关闭流
+func (r *replayStream) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.done = true
+	return nil
+}
+
+// Err 获取错误
+func (r *replayStream) Err() error {
+	return nil
+}
+
+// Done 检查是否完成
+func (r *replayStream) Done() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.done
+}
+
+// Context 获取上下文
+func (r *replayStream) Context() context.Context {
+	return r.ctx
+}
+
+// ServedModel 获取缓存响应的模型
+func (r *replayStream) ServedModel() string {
+	return r.servedModel
+}
+
+// CacheStatus 获取缓存状态
+func (r *replayStream) CacheStatus() string {
+	return types.CacheStatusHit
+}
+
+// cachingStream 在流正常结束时将合并后的响应写入缓存
+type cachingStream struct {
+	stream types.StreamResponse
+	cache  *responseCache
+	chunks []types.ChatCompletionChunk
+	stored bool
+}
+
+// Next 获取下一个事件
+func (c *cachingStream) Next() (*types.StreamEvent, error) {
+	event, err := c.stream.Next()
+	if err == io.EOF {
+		c.store()
+	}
+	if err != nil || c.cache.key == "" || event.Type != types.StreamEventTypeData {
+		return event, err
+	}
+
+	var chunk types.ChatCompletionChunk
+	if json.Unmarshal(event.Data, &chunk) == nil {
+		c.chunks = append(c.chunks, chunk)
+	}
+	return event, nil
+}
+
+// store 合并已接收的块并写入缓存，只执行一次
+func (c *cachingStream) store() {
+	if c.stored || len(c.chunks) == 0 {
+		return
+	}
+	c.stored = true
+
+	resp := mergeChunks(c.chunks)
+	resp.ServedModel = c.ServedModel()
+	if resp.ServedModel == "" {
+		resp.ServedModel = resp.Model
+	}
+	c.cache.set(c.stream.Context(), resp)
+}
+
+// Close 关闭流，未读取完的响应不会被缓存
+func (c *cachingStream) Close() error {
+	return c.stream.Close()
+}
+
+// Err 获取错误
+func (c *cachingStream) Err() error {
+	return c.stream.Err()
+}
+
+// Done 检查是否完成
+func (c *cachingStream) Done() bool {
+	return c.stream.Done()
+}
+
+// Context 获取上下文
+func (c *cachingStream) Context() context.Context {
+	return c.stream.Context()
+}
+
+// ServedModel 获取实际处理请求的模型
+func (c *cachingStream) ServedModel() string {
+	if reporter, ok := c.stream.(interface{ ServedModel() string }); ok {
+		return reporter.ServedModel()
+	}
+	return ""
+}
+
+// CacheStatus 获取缓存状态
+func (c *cachingStream) CacheStatus() string {
+	return c.cache.status()
+}
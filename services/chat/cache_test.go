@@ -0,0 +1,142 @@
+package chat
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/hewenyu/newapi-go/cache"
+	"github.com/hewenyu/newapi-go/types"
+)
+
+func TestCacheServesDeterministicRequests(t *testing.T) {
+	service, fake := newFakeService(t, textResponse("cached answer"))
+	service.SetCache(cache.NewMemoryCache(10), 0)
+	ctx := context.Background()
+	messages := []types.ChatMessage{types.NewUserMessage("hi")}
+
+	first, err := service.CreateChatCompletion(ctx, messages, WithTemperature(0))
+	if err != nil {
+		t.Fatalf("first request failed: %v", err)
+	}
+	if first.CacheStatus != types.CacheStatusMiss {
+		t.Errorf("first CacheStatus = %q, want miss", first.CacheStatus)
+	}
+
+	second, err := service.CreateChatCompletion(ctx, messages, WithTemperature(0))
+	if err != nil {
+		t.Fatalf("second request failed: %v", err)
+	}
+	if second.CacheStatus != types.CacheStatusHit {
+		t.Errorf("second CacheStatus = %q, want hit", second.CacheStatus)
+	}
+	if second.GetFirstContent() != "cached answer" {
+		t.Errorf("cached content = %q", second.GetFirstContent())
+	}
+	if len(fake.requests) != 1 {
+		t.Errorf("server received %d requests, want 1", len(fake.requests))
+	}
+
+	// 请求内容不同时不命中
+	if _, err := service.CreateChatCompletion(ctx, messages, WithTemperature(0), WithSeed(7)); err == nil {
+		t.Error("expected request with different seed to reach the server")
+	}
+}
+
+func TestCacheBypassesSampledRequests(t *testing.T) {
+	service, fake := newFakeService(t, textResponse("one"), textResponse("two"), textResponse("three"))
+	service.SetCache(cache.NewMemoryCache(10), 0)
+	ctx := context.Background()
+	messages := []types.ChatMessage{types.NewUserMessage("hi")}
+
+	for i := 0; i < 2; i++ {
+		resp, err := service.CreateChatCompletion(ctx, messages, WithTemperature(0.7))
+		if err != nil {
+			t.Fatalf("request %d failed: %v", i, err)
+		}
+		if resp.CacheStatus != types.CacheStatusBypass {
+			t.Errorf("CacheStatus = %q, want bypass", resp.CacheStatus)
+		}
+	}
+
+	forced, err := service.CreateChatCompletion(ctx, messages, WithTemperature(0.7), WithCachePolicy(cache.PolicyForce))
+	if err != nil {
+		t.Fatalf("forced request failed: %v", err)
+	}
+	if forced.CacheStatus != types.CacheStatusMiss {
+		t.Errorf("forced CacheStatus = %q, want miss", forced.CacheStatus)
+	}
+	if len(fake.requests) != 3 {
+		t.Errorf("server received %d requests, want 3", len(fake.requests))
+	}
+}
+
+func TestCacheReplaysStream(t *testing.T) {
+	service, fake := newFakeService(t, textChunks("Hel", "lo"))
+	service.SetCache(cache.NewMemoryCache(10), 0)
+	ctx := context.Background()
+	messages := []types.ChatMessage{types.NewUserMessage("hi")}
+
+	collect := func() *types.ChatCompletionResponse {
+		stream, err := service.CreateChatCompletionStream(ctx, messages, WithTemperature(0))
+		if err != nil {
+			t.Fatalf("stream failed: %v", err)
+		}
+		defer stream.Close()
+		processor := stream.(*ChatStreamProcessor)
+		for {
+			if _, err := processor.Next(); err != nil {
+				if err != io.EOF {
+					t.Fatalf("stream error: %v", err)
+				}
+				break
+			}
+		}
+		return processor.CollectResponse()
+	}
+
+	first := collect()
+	if first.CacheStatus != types.CacheStatusMiss || first.GetFirstContent() != "Hello" {
+		t.Fatalf("first stream = %q (%s)", first.GetFirstContent(), first.CacheStatus)
+	}
+
+	replayed := collect()
+	if replayed.CacheStatus != types.CacheStatusHit {
+		t.Errorf("replayed CacheStatus = %q, want hit", replayed.CacheStatus)
+	}
+	if replayed.GetFirstContent() != "Hello" {
+		t.Errorf("replayed content = %q, want Hello", replayed.GetFirstContent())
+	}
+	if len(fake.requests) != 1 {
+		t.Errorf("server received %d requests, want 1", len(fake.requests))
+	}
+
+	// 流式响应也可被非流式请求命中
+	resp, err := service.CreateChatCompletion(ctx, messages, WithTemperature(0))
+	if err != nil || resp.CacheStatus != types.CacheStatusHit {
+		t.Errorf("non-stream request = %v, %v; want cache hit", resp, err)
+	}
+}
+
+func TestReplayChunksRoundTrip(t *testing.T) {
+	resp := toolCallResponse("call_1", "get_weather", `{"city":"Paris"}`)
+	resp.Usage = types.Usage{PromptTokens: 3, CompletionTokens: 5, TotalTokens: 8}
+
+	merged := mergeChunks(replayChunks(resp))
+	if len(merged.Choices) != 1 {
+		t.Fatalf("got %d choices, want 1", len(merged.Choices))
+	}
+	calls := merged.Choices[0].Message.ToolCalls
+	if len(calls) != 1 || calls[0].ID != "call_1" || calls[0].Function.Arguments != `{"city":"Paris"}` {
+		t.Errorf("tool calls = %+v", calls)
+	}
+	if calls[0].Index != nil {
+		t.Error("expected merged tool call index to be cleared")
+	}
+	if merged.Choices[0].FinishReason != types.FinishReasonToolCalls {
+		t.Errorf("finish reason = %q", merged.Choices[0].FinishReason)
+	}
+	if merged.Usage.TotalTokens != 8 {
+		t.Errorf("usage = %+v", merged.Usage)
+	}
+}
@@ -7,10 +7,14 @@ import (
 	"io"
 	"net/http"
 	"sync"
+	"time"
 
+	"github.com/hewenyu/newapi-go/cache"
 	"github.com/hewenyu/newapi-go/internal/transport"
 	"github.com/hewenyu/newapi-go/internal/utils"
+	"github.com/hewenyu/newapi-go/tokenizer"
 	"github.com/hewenyu/newapi-go/types"
+	"github.com/hewenyu/newapi-go/usage"
 	"go.uber.org/zap"
 )
 
@@ -19,6 +23,11 @@ type ChatService struct {
 	transport transport.HTTPTransport
 	logger    utils.Logger
 	config    *ChatConfig
+	limiter   RateLimiter
+	cache     cache.Cache
+	cacheTTL  time.Duration
+	recorder  usage.Recorder
+	statsHook StreamStatsHook
 	mu        sync.RWMutex
 }
 
@@ -54,7 +63,7 @@ func parseJSONResponse(resp *http.Response, v interface{}) error {
 	return nil
 }
 
-// CreateChatCompletion 创建聊天完成
+// CreateChatCompletion 创建聊天完成，配置了降级模型时按顺序尝试
 func (s *ChatService) CreateChatCompletion(ctx context.Context, messages []types.ChatMessage, options ...ChatOption) (*types.ChatCompletionResponse, error) {
 	// 验证输入
 	if len(messages) == 0 {
@@ -72,11 +81,65 @@ func (s *ChatService) CreateChatCompletion(ctx context.Context, messages []types
 		return nil, fmt.Errorf("invalid chat config: %w", err)
 	}
 
+	annotation, err := s.moderateInput(ctx, messages, config)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.cachedChatCompletion(ctx, messages, config)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.moderateOutput(ctx, resp, config, annotation); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// cachedChatCompletion 配置了缓存时先查询缓存，未命中时发送请求并写入缓存
+func (s *ChatService) cachedChatCompletion(ctx context.Context, messages []types.ChatMessage, config *ChatConfig) (*types.ChatCompletionResponse, error) {
+	rc := s.requestCache(messages, config)
+	if rc != nil {
+		if resp := rc.get(ctx); resp != nil {
+			s.logger.Debug("Chat completion served from cache", zap.String("id", resp.ID))
+			return resp, nil
+		}
+	}
+
+	var resp *types.ChatCompletionResponse
+	var err error
+	if len(config.FallbackModels) > 0 {
+		resp, err = s.createWithFallback(ctx, messages, config)
+	} else {
+		resp, err = s.createChatCompletion(ctx, messages, config)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if rc != nil {
+		rc.set(ctx, resp)
+		resp.CacheStatus = rc.status()
+	}
+	return resp, nil
+}
+
+// createChatCompletion 使用给定配置发送一次聊天完成请求
+func (s *ChatService) createChatCompletion(ctx context.Context, messages []types.ChatMessage, config *ChatConfig) (*types.ChatCompletionResponse, error) {
+	if err := s.checkBudget(ctx, config.Model); err != nil {
+		return nil, err
+	}
+	if err := s.waitRateLimit(ctx); err != nil {
+		return nil, err
+	}
+
 	// 构建请求
 	req := config.ToRequest(messages)
 
-	// 确保不是流式请求
+	// 确保不是流式请求，stream_options 仅适用于流式请求
 	req.Stream = false
+	req.StreamOptions = nil
 
 	// 发送请求
 	resp, err := s.transport.Post(ctx, "/v1/chat/completions", req)
@@ -84,26 +147,41 @@ func (s *ChatService) CreateChatCompletion(ctx context.Context, messages []types
 		s.logger.Error("Failed to create chat completion", zap.Error(err))
 		return nil, fmt.Errorf("failed to create chat completion: %w", err)
 	}
+	statusCode := resp.StatusCode
 
 	// 解析响应
 	var chatResp types.ChatCompletionResponse
 	if err := parseJSONResponse(resp, &chatResp); err != nil {
+		if statusCode >= http.StatusBadRequest {
+			apiErr := types.FromHTTPStatusCode(statusCode, http.StatusText(statusCode))
+			s.logger.Error("API returned error", zap.Int("status_code", statusCode))
+			return nil, fmt.Errorf("API error: %w", apiErr)
+		}
 		s.logger.Error("Failed to parse chat completion response", zap.Error(err))
 		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
 
 	// 检查API错误
 	if chatResp.IsError() {
-		apiErr := chatResp.GetError()
-		s.logger.Error("API returned error", zap.String("error", apiErr.Message))
-		return nil, fmt.Errorf("API error: %s", apiErr.Message)
+		errResp := chatResp.GetError()
+		apiErr := types.NewAPIError(errResp.Type, errResp.Code, errResp.Message, statusCode).WithParam(errResp.Param)
+		s.logger.Error("API returned error", zap.String("error", errResp.Message))
+		return nil, fmt.Errorf("API error: %w", apiErr)
+	}
+	if statusCode >= http.StatusBadRequest {
+		apiErr := types.FromHTTPStatusCode(statusCode, http.StatusText(statusCode))
+		s.logger.Error("API returned error", zap.Int("status_code", statusCode))
+		return nil, fmt.Errorf("API error: %w", apiErr)
 	}
 
+	chatResp.ServedModel = config.Model
+	s.recordUsage(ctx, usage.FromUsage(usage.ServiceChat, config.Model, &chatResp.Usage))
+
 	s.logger.Debug("Chat completion created successfully", zap.String("id", chatResp.ID))
 	return &chatResp, nil
 }
 
-// CreateChatCompletionStream 创建流式聊天完成
+// CreateChatCompletionStream 创建流式聊天完成，配置了降级模型时仅在尚未输出任何Token前切换模型
 func (s *ChatService) CreateChatCompletionStream(ctx context.Context, messages []types.ChatMessage, options ...ChatOption) (types.StreamResponse, error) {
 	// 验证输入
 	if len(messages) == 0 {
@@ -121,6 +199,63 @@ func (s *ChatService) CreateChatCompletionStream(ctx context.Context, messages [
 		return nil, fmt.Errorf("invalid chat config: %w", err)
 	}
 
+	annotation, err := s.moderateInput(ctx, messages, config)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, collector := withStatsCollector(ctx)
+	stream, err := s.cachedStream(ctx, messages, config)
+	if err != nil {
+		return nil, err
+	}
+	if annotation != nil {
+		stream = &moderatingStream{stream: stream, service: s, config: config, annotation: annotation}
+	}
+	stream = &statsStream{stream: stream, collector: collector, hook: s.streamStatsHook()}
+
+	// 创建流式处理器
+	streamProcessor := NewChatStreamProcessor(stream, s.logger)
+
+	s.logger.Debug("Chat completion stream created successfully")
+	return streamProcessor, nil
+}
+
+// cachedStream 配置了缓存时重放缓存的响应，未命中时打开流并在结束时写入缓存
+func (s *ChatService) cachedStream(ctx context.Context, messages []types.ChatMessage, config *ChatConfig) (types.StreamResponse, error) {
+	rc := s.requestCache(messages, config)
+	if rc != nil {
+		if resp := rc.get(ctx); resp != nil {
+			s.logger.Debug("Chat completion stream replayed from cache", zap.String("id", resp.ID))
+			return newReplayStream(ctx, resp), nil
+		}
+	}
+
+	var stream types.StreamResponse
+	var err error
+	if len(config.FallbackModels) > 0 {
+		stream, err = s.openFallbackStream(ctx, messages, config)
+	} else {
+		stream, err = s.openResumableStream(ctx, messages, config)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if rc != nil {
+		stream = &cachingStream{stream: stream, cache: rc}
+	}
+	return stream, nil
+}
+
+// openStream 使用给定配置打开一个流式请求
+func (s *ChatService) openStream(ctx context.Context, messages []types.ChatMessage, config *ChatConfig) (*streamReaderAdapter, error) {
+	if err := s.checkBudget(ctx, config.Model); err != nil {
+		return nil, err
+	}
+	if err := s.waitRateLimit(ctx); err != nil {
+		return nil, err
+	}
+
 	// 构建请求
 	req := config.ToRequest(messages)
 
@@ -135,45 +270,59 @@ func (s *ChatService) CreateChatCompletionStream(ctx context.Context, messages [
 	}
 
 	// 创建适配器来桥接transport.StreamReader和types.StreamResponse
-	adapter := &streamReaderAdapter{
-		reader: streamReader,
-		ctx:    ctx,
-	}
-
-	// 创建流式处理器
-	streamProcessor := NewChatStreamProcessor(adapter, s.logger)
-
-	s.logger.Debug("Chat completion stream created successfully")
-	return streamProcessor, nil
+	return &streamReaderAdapter{
+		reader:    streamReader,
+		ctx:       ctx,
+		model:     config.Model,
+		meter:     usage.NewStreamMeter(ctx, s.usageRecorder(), usage.ServiceChat, config.Model),
+		collector: statsCollectorFrom(ctx),
+	}, nil
 }
 
 // streamReaderAdapter 适配器，将transport.StreamReader适配为types.StreamResponse
 type streamReaderAdapter struct {
 	reader transport.StreamReader
 	ctx    context.Context
+	model  string
+	meter  *usage.StreamMeter
+
+	collector *streamStatsCollector
+	reported  int64
 }
 
 // Next 获取下一个事件
 func (a *streamReaderAdapter) Next() (*types.StreamEvent, error) {
 	data, err := a.reader.Read()
+	a.report(err)
 	if err != nil {
+		// 流结束或出错都记录一次，出错前已产生的用量同样计费
+		a.meter.Finish()
 		return nil, err
 	}
 
-	// 将data转换为JSON
-	jsonData, marshalErr := json.Marshal(data)
-	if marshalErr != nil {
-		return nil, fmt.Errorf("failed to marshal stream data: %w", marshalErr)
+	// transport已返回原始JSON，其他实现的数据再编码为JSON
+	jsonData, ok := data.(json.RawMessage)
+	if !ok {
+		var marshalErr error
+		if jsonData, marshalErr = json.Marshal(data); marshalErr != nil {
+			return nil, fmt.Errorf("failed to marshal stream data: %w", marshalErr)
+		}
 	}
+	a.meter.Observe(jsonData)
 
-	return &types.StreamEvent{
+	event := &types.StreamEvent{
 		Type: types.StreamEventTypeData,
-		Data: json.RawMessage(jsonData),
-	}, nil
+		Data: jsonData,
+	}
+	if reporter, ok := a.reader.(interface{ LastEventID() string }); ok {
+		event.ID = reporter.LastEventID()
+	}
+	return event, nil
 }
 
-// Close 关闭流
+// Close 关闭流，提前关闭的流同样记录用量
 func (a *streamReaderAdapter) Close() error {
+	a.meter.Finish()
 	return a.reader.Close()
 }
 
@@ -192,6 +341,11 @@ func (a *streamReaderAdapter) Context() context.Context {
 	return a.ctx
 }
 
+// ServedModel 获取请求的模型
+func (a *streamReaderAdapter) ServedModel() string {
+	return a.model
+}
+
 // ChatWithHistory 带历史记录的聊天
 func (s *ChatService) ChatWithHistory(ctx context.Context, userMessage string, history []types.ChatMessage, options ...ChatOption) (*types.ChatCompletionResponse, error) {
 	// 构建消息列表
@@ -279,7 +433,7 @@ func (s *ChatService) ValidateMessage(message types.ChatMessage) error {
 		return fmt.Errorf("invalid message role: %s", message.Role)
 	}
 
-	if message.GetTextContent() == "" && len(message.ToolCalls) == 0 && message.FunctionCall == nil {
+	if len(message.GetContentParts()) == 0 && len(message.ToolCalls) == 0 && message.FunctionCall == nil {
 		return fmt.Errorf("message must have content, tool calls, or function call")
 	}
 
@@ -340,62 +494,47 @@ func (s *ChatService) GetLastAssistantMessage(messages []types.ChatMessage) *typ
 	return nil
 }
 
-// CountTokens 计算Token数量（简单估算）
+// CountTokens 计算消息列表在当前模型下的Token数量，包含聊天格式开销
 func (s *ChatService) CountTokens(messages []types.ChatMessage) int {
-	totalTokens := 0
-
-	for _, message := range messages {
-		// 简单估算：每个字符约0.25个token
-		content := message.GetTextContent()
-		totalTokens += len(content) / 4
-
-		// 角色和结构的开销
-		totalTokens += 10
-	}
+	return tokenizer.CountMessages(s.getConfig().Model, messages)
+}
 
-	return totalTokens
+// CountToolTokens 计算工具定义在当前模型下的Token数量
+func (s *ChatService) CountToolTokens(tools []types.Tool) int {
+	return tokenizer.CountTools(s.getConfig().Model, tools)
 }
 
-// TruncateMessages 截断消息以适应Token限制
+// TruncateMessages 截断消息以适应Token限制。
+// 使用滑动窗口策略：保留系统消息和最新的用户轮次，工具调用与其结果作为整体保留或丢弃
 func (s *ChatService) TruncateMessages(messages []types.ChatMessage, maxTokens int) []types.ChatMessage {
 	if len(messages) == 0 {
 		return messages
 	}
 
-	// 保留系统消息
-	var systemMessages []types.ChatMessage
-	var otherMessages []types.ChatMessage
-
-	for _, message := range messages {
-		if message.Role == types.ChatRoleSystem {
-			systemMessages = append(systemMessages, message)
-		} else {
-			otherMessages = append(otherMessages, message)
-		}
+	manager := NewContextManager(
+		WithContextModel(s.getConfig().Model),
+		WithContextWindow(maxTokens),
+	)
+	result, _, err := manager.Fit(context.Background(), messages)
+	if err != nil {
+		return messages
 	}
+	return result
+}
 
-	// 计算系统消息的Token数量
-	systemTokens := s.CountTokens(systemMessages)
-	availableTokens := maxTokens - systemTokens
-
-	if availableTokens <= 0 {
-		return systemMessages
+// FitContext 使用上下文管理器裁剪消息以适应当前模型的上下文窗口。
+// 默认使用服务配置的模型、工具定义，并为回复预留MaxCompletionTokens或MaxTokens
+func (s *ChatService) FitContext(ctx context.Context, messages []types.ChatMessage, options ...ContextOption) ([]types.ChatMessage, *ContextReport, error) {
+	config := s.getConfig()
+	reserved := config.MaxCompletionTokens
+	if reserved == 0 {
+		reserved = config.MaxTokens
 	}
 
-	// 从最新的消息开始保留
-	result := make([]types.ChatMessage, 0, len(messages))
-	result = append(result, systemMessages...)
-
-	currentTokens := 0
-	for i := len(otherMessages) - 1; i >= 0; i-- {
-		messageTokens := s.CountTokens([]types.ChatMessage{otherMessages[i]})
-		if currentTokens+messageTokens > availableTokens {
-			break
-		}
-
-		result = append([]types.ChatMessage{otherMessages[i]}, result...)
-		currentTokens += messageTokens
+	defaults := []ContextOption{
+		WithContextModel(config.Model),
+		WithReservedTokens(reserved),
+		WithContextTools(config.Tools),
 	}
-
-	return result
+	return NewContextManager(append(defaults, options...)...).Fit(ctx, messages)
 }
@@ -0,0 +1,275 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hewenyu/newapi-go/tokenizer"
+	"github.com/hewenyu/newapi-go/types"
+)
+
+// replyPrimingTokens 回复引导所占用的Token数量
+const replyPrimingTokens = 3
+
+// MessageGroup 消息组，组内消息在裁剪时作为整体保留或丢弃。
+// 带有tool_calls的助手消息与其对应的tool消息属于同一组
+type MessageGroup struct {
+	Index    int                 // 组内第一条消息在原始列表中的位置
+	Messages []types.ChatMessage // 组内消息
+	Tokens   int                 // 组内消息的Token数量
+	Required bool                // 是否必须保留（系统消息、固定消息、最新用户轮次）
+}
+
+// ContextReport 上下文裁剪报告
+type ContextReport struct {
+	Strategy       string         `json:"strategy"`
+	Budget         int            `json:"budget"`
+	OriginalTokens int            `json:"original_tokens"`
+	FinalTokens    int            `json:"final_tokens"`
+	Dropped        []MessageGroup `json:"dropped,omitempty"`
+	DroppedCount   int            `json:"dropped_count"`
+	Summary        string         `json:"summary,omitempty"`
+	OverBudget     bool           `json:"over_budget"`
+}
+
+// ContextResult 裁剪策略的执行结果
+type ContextResult struct {
+	Messages []types.ChatMessage
+	Dropped  []MessageGroup
+	Summary  string
+}
+
+// ContextStrategy 上下文裁剪策略接口
+type ContextStrategy interface {
+	// Name 策略名称
+	Name() string
+	// Fit 在预算内选择要保留的消息组，必须保留Required组并保持原始顺序
+	Fit(ctx context.Context, groups []MessageGroup, budget int) (*ContextResult, error)
+}
+
+// ContextConfig 上下文管理配置
+type ContextConfig struct {
+	Model          string
+	ContextWindow  int
+	ReservedTokens int
+	Tools          []types.Tool
+	Strategy       ContextStrategy
+	KeepSystem     bool
+	PinFunc        func(index int, message types.ChatMessage) bool
+}
+
+// ContextOption 上下文管理选项函数类型
+type ContextOption func(*ContextConfig)
+
+// DefaultContextConfig 默认上下文管理配置
+func DefaultContextConfig() *ContextConfig {
+	return &ContextConfig{
+		Model:      DefaultChatConfig().Model,
+		Strategy:   NewSlidingWindowStrategy(),
+		KeepSystem: true,
+	}
+}
+
+// WithContextModel 设置模型，用于Token计数和查询上下文窗口大小
+func WithContextModel(model string) ContextOption {
+	return func(c *ContextConfig) {
+		c.Model = model
+	}
+}
+
+// WithContextWindow 设置上下文窗口大小，覆盖模型注册表中的值
+func WithContextWindow(tokens int) ContextOption {
+	return func(c *ContextConfig) {
+		c.ContextWindow = tokens
+	}
+}
+
+// WithReservedTokens 设置为模型回复预留的Token数量
+func WithReservedTokens(tokens int) ContextOption {
+	return func(c *ContextConfig) {
+		c.ReservedTokens = tokens
+	}
+}
+
+// WithContextTools 设置请求携带的工具定义，其Token数量计入预算
+func WithContextTools(tools []types.Tool) ContextOption {
+	return func(c *ContextConfig) {
+		c.Tools = tools
+	}
+}
+
+// WithContextStrategy 设置裁剪策略
+func WithContextStrategy(strategy ContextStrategy) ContextOption {
+	return func(c *ContextConfig) {
+		c.Strategy = strategy
+	}
+}
+
+// WithKeepSystem 设置是否总是保留系统消息
+func WithKeepSystem(keep bool) ContextOption {
+	return func(c *ContextConfig) {
+		c.KeepSystem = keep
+	}
+}
+
+// WithPinFunc 设置固定消息的判断函数，固定的消息总是被保留
+func WithPinFunc(fn func(index int, message types.ChatMessage) bool) ContextOption {
+	return func(c *ContextConfig) {
+		c.PinFunc = fn
+	}
+}
+
+// WithPinnedIndices 固定指定位置的消息
+func WithPinnedIndices(indices ...int) ContextOption {
+	pinned := make(map[int]bool, len(indices))
+	for _, index := range indices {
+		pinned[index] = true
+	}
+	return WithPinFunc(func(index int, _ types.ChatMessage) bool {
+		return pinned[index]
+	})
+}
+
+// ContextManager 上下文窗口管理器
+type ContextManager struct {
+	config *ContextConfig
+}
+
+// NewContextManager 创建上下文窗口管理器
+func NewContextManager(options ...ContextOption) *ContextManager {
+	config := DefaultContextConfig()
+	for _, option := range options {
+		option(config)
+	}
+	return &ContextManager{config: config}
+}
+
+// Budget 获取消息可用的Token预算
+func (m *ContextManager) Budget() int {
+	window := m.config.ContextWindow
+	if window <= 0 {
+		window = GetContextWindow(m.config.Model)
+	}
+	return window - m.config.ReservedTokens - tokenizer.CountTools(m.config.Model, m.config.Tools) - replyPrimingTokens
+}
+
+// Fit 裁剪消息以适应上下文窗口，返回保留的消息和裁剪报告
+func (m *ContextManager) Fit(ctx context.Context, messages []types.ChatMessage) ([]types.ChatMessage, *ContextReport, error) {
+	if m.config.Strategy == nil {
+		return nil, nil, fmt.Errorf("context strategy cannot be nil")
+	}
+
+	budget := m.Budget()
+	groups := m.groupMessages(messages)
+	report := &ContextReport{
+		Strategy:       m.config.Strategy.Name(),
+		Budget:         budget,
+		OriginalTokens: tokenizer.CountMessages(m.config.Model, messages),
+	}
+
+	result, err := m.config.Strategy.Fit(ctx, groups, budget)
+	if err != nil {
+		return nil, nil, fmt.Errorf("context strategy %s failed: %w", report.Strategy, err)
+	}
+
+	report.Dropped = result.Dropped
+	for _, group := range result.Dropped {
+		report.DroppedCount += len(group.Messages)
+	}
+	report.Summary = result.Summary
+	report.FinalTokens = tokenizer.CountMessages(m.config.Model, result.Messages)
+	report.OverBudget = report.FinalTokens-replyPrimingTokens > budget
+
+	return result.Messages, report, nil
+}
+
+// groupMessages 将消息划分为消息组，并标记必须保留的组
+func (m *ContextManager) groupMessages(messages []types.ChatMessage) []MessageGroup {
+	lastUser := -1
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == types.ChatRoleUser {
+			lastUser = i
+			break
+		}
+	}
+
+	var groups []MessageGroup
+	for i := 0; i < len(messages); {
+		end := groupEnd(messages, i)
+		group := MessageGroup{Index: i, Messages: messages[i:end]}
+		for j := i; j < end; j++ {
+			group.Tokens += tokenizer.CountMessage(m.config.Model, messages[j])
+			if m.isRequired(j, messages[j], lastUser) {
+				group.Required = true
+			}
+		}
+		groups = append(groups, group)
+		i = end
+	}
+	return groups
+}
+
+// isRequired 判断消息是否必须保留
+func (m *ContextManager) isRequired(index int, message types.ChatMessage, lastUser int) bool {
+	if m.config.KeepSystem && message.Role == types.ChatRoleSystem {
+		return true
+	}
+	if m.config.PinFunc != nil && m.config.PinFunc(index, message) {
+		return true
+	}
+	// 最新的用户消息及其之后的消息构成当前轮次
+	return lastUser >= 0 && index >= lastUser
+}
+
+// groupEnd 返回从start开始的消息组的结束位置。
+// 工具调用消息与紧随其后的工具/函数结果消息组成一组
+func groupEnd(messages []types.ChatMessage, start int) int {
+	end := start + 1
+	message := messages[start]
+	if message.Role != types.ChatRoleAssistant || (!message.HasToolCalls() && !message.HasFunctionCall()) {
+		return end
+	}
+
+	for end < len(messages) {
+		role := messages[end].Role
+		if role != types.ChatRoleTool && role != types.ChatRoleFunction {
+			break
+		}
+		end++
+	}
+	return end
+}
+
+// flattenGroups 按顺序展开消息组
+func flattenGroups(groups []MessageGroup) []types.ChatMessage {
+	var messages []types.ChatMessage
+	for _, group := range groups {
+		messages = append(messages, group.Messages...)
+	}
+	return messages
+}
+
+// splitGroups 根据保留标记拆分消息组
+func splitGroups(groups []MessageGroup, keep []bool) ([]types.ChatMessage, []MessageGroup) {
+	var kept, dropped []MessageGroup
+	for i, group := range groups {
+		if keep[i] {
+			kept = append(kept, group)
+		} else {
+			dropped = append(dropped, group)
+		}
+	}
+	return flattenGroups(kept), dropped
+}
+
+// requiredTokens 标记必须保留的组并返回其Token总数
+func requiredTokens(groups []MessageGroup, keep []bool) int {
+	used := 0
+	for i, group := range groups {
+		if group.Required {
+			keep[i] = true
+			used += group.Tokens
+		}
+	}
+	return used
+}
@@ -0,0 +1,234 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hewenyu/newapi-go/types"
+)
+
+// 裁剪策略名称常量
+const (
+	StrategySlidingWindow = "sliding_window"
+	StrategyMiddleOut     = "middle_out"
+	StrategySummarize     = "summarize"
+)
+
+// DefaultSummaryPrompt 默认的历史摘要提示词
+const DefaultSummaryPrompt = "Summarize the following conversation history concisely. " +
+	"Preserve facts, decisions, names and open questions that later turns may rely on."
+
+// SummaryMessagePrefix 摘要系统消息的前缀
+const SummaryMessagePrefix = "Summary of earlier conversation:\n"
+
+// SlidingWindowStrategy 滑动窗口策略，从最新的消息组开始保留，直到预算用尽
+type SlidingWindowStrategy struct{}
+
+// NewSlidingWindowStrategy 创建滑动窗口策略
+func NewSlidingWindowStrategy() *SlidingWindowStrategy {
+	return &SlidingWindowStrategy{}
+}
+
+// Name 策略名称
+func (s *SlidingWindowStrategy) Name() string {
+	return StrategySlidingWindow
+}
+
+// Fit 保留必须的消息组，然后从新到旧保留连续的消息组
+func (s *SlidingWindowStrategy) Fit(ctx context.Context, groups []MessageGroup, budget int) (*ContextResult, error) {
+	keep := make([]bool, len(groups))
+	used := requiredTokens(groups, keep)
+
+	for i := len(groups) - 1; i >= 0; i-- {
+		if groups[i].Required {
+			continue
+		}
+		if used+groups[i].Tokens > budget {
+			break
+		}
+		keep[i] = true
+		used += groups[i].Tokens
+	}
+
+	messages, dropped := splitGroups(groups, keep)
+	return &ContextResult{Messages: messages, Dropped: dropped}, nil
+}
+
+// MiddleOutStrategy 中间截断策略，交替保留最新和最早的消息组，丢弃中间部分
+type MiddleOutStrategy struct{}
+
+// NewMiddleOutStrategy 创建中间截断策略
+func NewMiddleOutStrategy() *MiddleOutStrategy {
+	return &MiddleOutStrategy{}
+}
+
+// Name 策略名称
+func (s *MiddleOutStrategy) Name() string {
+	return StrategyMiddleOut
+}
+
+// Fit 保留必须的消息组，然后从两端向中间交替保留消息组
+func (s *MiddleOutStrategy) Fit(ctx context.Context, groups []MessageGroup, budget int) (*ContextResult, error) {
+	keep := make([]bool, len(groups))
+	used := requiredTokens(groups, keep)
+
+	var optional []int
+	for i, group := range groups {
+		if !group.Required {
+			optional = append(optional, i)
+		}
+	}
+
+	head, tail := 0, len(optional)-1
+	for head <= tail {
+		progressed := false
+		if idx := optional[tail]; used+groups[idx].Tokens <= budget {
+			keep[idx] = true
+			used += groups[idx].Tokens
+			tail--
+			progressed = true
+		}
+		if head <= tail {
+			if idx := optional[head]; used+groups[idx].Tokens <= budget {
+				keep[idx] = true
+				used += groups[idx].Tokens
+				head++
+				progressed = true
+			}
+		}
+		if !progressed {
+			break
+		}
+	}
+
+	messages, dropped := splitGroups(groups, keep)
+	return &ContextResult{Messages: messages, Dropped: dropped}, nil
+}
+
+// Summarizer 历史摘要接口
+type Summarizer interface {
+	Summarize(ctx context.Context, messages []types.ChatMessage) (string, error)
+}
+
+// SummarizeStrategy 摘要策略，使用基础策略裁剪后将被丢弃的历史生成摘要，
+// 并作为系统消息插入到被丢弃部分原来的位置
+type SummarizeStrategy struct {
+	summarizer    Summarizer
+	base          ContextStrategy
+	summaryTokens int
+}
+
+// NewSummarizeStrategy 创建摘要策略，base为空时使用滑动窗口策略，
+// summaryTokens为摘要预留的Token数量
+func NewSummarizeStrategy(summarizer Summarizer, base ContextStrategy, summaryTokens int) *SummarizeStrategy {
+	if base == nil {
+		base = NewSlidingWindowStrategy()
+	}
+	return &SummarizeStrategy{
+		summarizer:    summarizer,
+		base:          base,
+		summaryTokens: summaryTokens,
+	}
+}
+
+// Name 策略名称
+func (s *SummarizeStrategy) Name() string {
+	return StrategySummarize
+}
+
+// Fit 无需裁剪时直接返回，否则为摘要预留预算后裁剪并生成摘要
+func (s *SummarizeStrategy) Fit(ctx context.Context, groups []MessageGroup, budget int) (*ContextResult, error) {
+	if s.summarizer == nil {
+		return nil, fmt.Errorf("summarizer cannot be nil")
+	}
+
+	result, err := s.base.Fit(ctx, groups, budget)
+	if err != nil || len(result.Dropped) == 0 {
+		return result, err
+	}
+
+	result, err = s.base.Fit(ctx, groups, budget-s.summaryTokens)
+	if err != nil || len(result.Dropped) == 0 {
+		return result, err
+	}
+
+	summary, err := s.summarizer.Summarize(ctx, flattenGroups(result.Dropped))
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize dropped messages: %w", err)
+	}
+
+	result.Summary = summary
+	result.Messages = insertSummary(result.Messages, groups, result.Dropped, types.NewSystemMessage(SummaryMessagePrefix+summary))
+	return result, nil
+}
+
+// insertSummary 将摘要消息插入到被丢弃部分原来的位置，即第一个被丢弃的组之前保留的消息之后。
+// 滑动窗口时位于开头的系统消息之后，中间截断时位于保留的开头消息之后
+func insertSummary(messages []types.ChatMessage, groups, dropped []MessageGroup, summary types.ChatMessage) []types.ChatMessage {
+	pos := 0
+	for _, group := range groups {
+		if group.Index >= dropped[0].Index {
+			break
+		}
+		pos += len(group.Messages)
+	}
+	if pos > len(messages) {
+		pos = len(messages)
+	}
+
+	result := make([]types.ChatMessage, 0, len(messages)+1)
+	result = append(result, messages[:pos]...)
+	result = append(result, summary)
+	return append(result, messages[pos:]...)
+}
+
+// ModelSummarizer 使用聊天模型生成摘要，通常配置为低成本模型
+type ModelSummarizer struct {
+	service   *ChatService
+	model     string
+	maxTokens int
+	prompt    string
+}
+
+// NewModelSummarizer 创建模型摘要器
+func NewModelSummarizer(service *ChatService, model string, maxTokens int) *ModelSummarizer {
+	return &ModelSummarizer{
+		service:   service,
+		model:     model,
+		maxTokens: maxTokens,
+		prompt:    DefaultSummaryPrompt,
+	}
+}
+
+// WithPrompt 设置摘要提示词
+func (s *ModelSummarizer) WithPrompt(prompt string) *ModelSummarizer {
+	s.prompt = prompt
+	return s
+}
+
+// Summarize 生成消息摘要
+func (s *ModelSummarizer) Summarize(ctx context.Context, messages []types.ChatMessage) (string, error) {
+	var transcript strings.Builder
+	for _, message := range messages {
+		text := message.GetTextContent()
+		for _, call := range message.ToolCalls {
+			text += fmt.Sprintf("\n[tool call %s(%s)]", call.Function.Name, call.Function.Arguments)
+		}
+		fmt.Fprintf(&transcript, "%s: %s\n", message.Role, text)
+	}
+
+	options := []ChatOption{WithModel(s.model)}
+	if s.maxTokens > 0 {
+		options = append(options, WithMaxTokens(s.maxTokens))
+	}
+
+	resp, err := s.service.CreateChatCompletion(ctx, []types.ChatMessage{
+		types.NewSystemMessage(s.prompt),
+		types.NewUserMessage(transcript.String()),
+	}, options...)
+	if err != nil {
+		return "", err
+	}
+	return resp.GetFirstContent(), nil
+}
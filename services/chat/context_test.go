@@ -0,0 +1,153 @@
+package chat
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hewenyu/newapi-go/types"
+)
+
+// longText 生成约n个Token的文本
+func longText(n int) string {
+	return strings.Repeat(" word", n)
+}
+
+func toolCallHistory() []types.ChatMessage {
+	return []types.ChatMessage{
+		types.NewSystemMessage("You are helpful."),
+		types.NewUserMessage("old question" + longText(50)),
+		{
+			Role:      types.ChatRoleAssistant,
+			ToolCalls: []types.ToolCall{{ID: "call_1", Type: types.ToolCallTypeFunction, Function: types.FunctionCall{Name: "lookup", Arguments: "{}"}}},
+		},
+		types.NewToolMessage("call_1", "tool result"+longText(50)),
+		types.NewAssistantMessage("old answer"),
+		types.NewUserMessage("latest question"),
+	}
+}
+
+func TestSlidingWindowKeepsToolPairsAndLatestTurn(t *testing.T) {
+	messages := toolCallHistory()
+	manager := NewContextManager(WithContextModel("gpt-4o"), WithContextWindow(90))
+
+	result, report, err := manager.Fit(context.Background(), messages)
+	if err != nil {
+		t.Fatalf("Fit failed: %v", err)
+	}
+
+	if result[0].Role != types.ChatRoleSystem || result[len(result)-1].GetTextContent() != "latest question" {
+		t.Fatalf("system message or latest turn missing: %+v", result)
+	}
+	for i, msg := range result {
+		if msg.Role == types.ChatRoleTool && (i == 0 || !result[i-1].HasToolCalls()) {
+			t.Errorf("tool message at %d orphaned from its tool call", i)
+		}
+		if msg.HasToolCalls() && (i+1 >= len(result) || result[i+1].Role != types.ChatRoleTool) {
+			t.Errorf("tool call at %d kept without its result", i)
+		}
+	}
+	if report.DroppedCount == 0 || report.FinalTokens >= report.OriginalTokens {
+		t.Errorf("expected messages to be dropped, report: %+v", report)
+	}
+}
+
+func TestMiddleOutKeepsBothEnds(t *testing.T) {
+	messages := []types.ChatMessage{
+		types.NewUserMessage("first"),
+		types.NewAssistantMessage("middle" + longText(200)),
+		types.NewAssistantMessage("near end"),
+		types.NewUserMessage("last"),
+	}
+	manager := NewContextManager(
+		WithContextModel("gpt-4o"),
+		WithContextWindow(60),
+		WithContextStrategy(NewMiddleOutStrategy()),
+	)
+
+	result, report, err := manager.Fit(context.Background(), messages)
+	if err != nil {
+		t.Fatalf("Fit failed: %v", err)
+	}
+	if len(result) != 3 || result[0].GetTextContent() != "first" || report.DroppedCount != 1 {
+		t.Errorf("expected only the middle message to be dropped, got %d messages, report %+v", len(result), report)
+	}
+}
+
+type staticSummarizer struct {
+	received int
+}
+
+func (s *staticSummarizer) Summarize(ctx context.Context, messages []types.ChatMessage) (string, error) {
+	s.received = len(messages)
+	return "earlier tool lookup", nil
+}
+
+func TestSummarizeStrategyInsertsSummary(t *testing.T) {
+	summarizer := &staticSummarizer{}
+	manager := NewContextManager(
+		WithContextModel("gpt-4o"),
+		WithContextWindow(120),
+		WithContextStrategy(NewSummarizeStrategy(summarizer, nil, 30)),
+	)
+
+	result, report, err := manager.Fit(context.Background(), toolCallHistory())
+	if err != nil {
+		t.Fatalf("Fit failed: %v", err)
+	}
+	if summarizer.received == 0 || report.Summary == "" {
+		t.Fatalf("expected dropped history to be summarized, report %+v", report)
+	}
+	if result[1].Role != types.ChatRoleSystem || !strings.HasPrefix(result[1].GetTextContent(), SummaryMessagePrefix) {
+		t.Errorf("summary should follow the system prompt, got %+v", result[1])
+	}
+}
+
+func TestSummarizeStrategyMiddleOutPlacesSummaryAtDroppedSpan(t *testing.T) {
+	summarizer := &staticSummarizer{}
+	messages := []types.ChatMessage{
+		types.NewSystemMessage("You are helpful."),
+		types.NewUserMessage("first"),
+		types.NewAssistantMessage("middle" + longText(200)),
+		types.NewAssistantMessage("near end"),
+		types.NewUserMessage("last"),
+	}
+	manager := NewContextManager(
+		WithContextModel("gpt-4o"),
+		WithContextWindow(100),
+		WithContextStrategy(NewSummarizeStrategy(summarizer, NewMiddleOutStrategy(), 30)),
+	)
+
+	result, report, err := manager.Fit(context.Background(), messages)
+	if err != nil {
+		t.Fatalf("Fit failed: %v", err)
+	}
+	if summarizer.received != 1 || report.DroppedCount != 1 {
+		t.Fatalf("expected only the middle message to be summarized, received %d, report %+v", summarizer.received, report)
+	}
+
+	var got []string
+	for _, msg := range result {
+		text := msg.GetTextContent()
+		if strings.HasPrefix(text, SummaryMessagePrefix) {
+			text = "<summary>"
+		}
+		got = append(got, text)
+	}
+	want := "You are helpful.|first|<summary>|near end|last"
+	if strings.Join(got, "|") != want {
+		t.Errorf("messages = %s, want %s", strings.Join(got, "|"), want)
+	}
+}
+
+func TestGetContextWindowLongestPrefix(t *testing.T) {
+	if got := GetContextWindow("gpt-4o-mini"); got != 128000 {
+		t.Errorf("GetContextWindow(gpt-4o-mini) = %d", got)
+	}
+	if got := GetContextWindow("gpt-4-0613"); got != 8192 {
+		t.Errorf("GetContextWindow(gpt-4-0613) = %d", got)
+	}
+	if got := GetContextWindow("unknown-model"); got != DefaultContextWindow {
+		t.Errorf("GetContextWindow(unknown) = %d", got)
+	}
+}
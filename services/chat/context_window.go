@@ -0,0 +1,24 @@
+package chat
+
+import (
+	"github.com/hewenyu/newapi-go/types"
+)
+
+// DefaultContextWindow 未知模型使用的默认上下文窗口大小
+const DefaultContextWindow = 8192
+
+// RegisterContextWindow 注册模型前缀的上下文窗口大小，覆盖能力注册表中该前缀的上下文窗口
+func RegisterContextWindow(modelPrefix string, tokens int) {
+	types.DefaultCapabilityRegistry().Update(modelPrefix, func(capabilities *types.ModelCapabilities) {
+		capabilities.ContextWindow = tokens
+	})
+}
+
+// GetContextWindow 获取模型的上下文窗口大小，按能力注册表的最长前缀匹配，
+// 未知模型返回DefaultContextWindow
+func GetContextWindow(model string) int {
+	if capabilities, ok := types.LookupModelCapabilities(model); ok && capabilities.ContextWindow > 0 {
+		return capabilities.ContextWindow
+	}
+	return DefaultContextWindow
+}
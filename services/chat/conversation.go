@@ -0,0 +1,354 @@
+package chat
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hewenyu/newapi-go/types"
+	"go.uber.org/zap"
+)
+
+// DefaultMaxToolRounds 默认的自动工具调用最大轮数
+const DefaultMaxToolRounds = 8
+
+// ErrMaxToolRounds 自动工具调用达到最大轮数时模型仍在请求工具调用
+var ErrMaxToolRounds = errors.New("maximum tool call rounds reached")
+
+// ToolHandler 工具调用处理函数，返回的内容作为tool消息追加到会话中
+type ToolHandler func(ctx context.Context, call types.ToolCall) (string, error)
+
+// ConversationConfig 会话配置
+type ConversationConfig struct {
+	ID            string
+	SystemPrompt  string
+	ChatOptions   []ChatOption
+	TokenBudget   int
+	Strategy      ContextStrategy
+	Store         ConversationStore
+	ToolHandler   ToolHandler
+	MaxToolRounds int
+	Metadata      map[string]string
+}
+
+// ConversationOption 会话选项函数类型
+type ConversationOption func(*ConversationConfig)
+
+// DefaultConversationConfig 默认会话配置
+func DefaultConversationConfig() *ConversationConfig {
+	return &ConversationConfig{
+		MaxToolRounds: DefaultMaxToolRounds,
+		Metadata:      make(map[string]string),
+	}
+}
+
+// WithConversationID 设置会话ID，默认随机生成
+func WithConversationID(id string) ConversationOption {
+	return func(c *ConversationConfig) {
+		c.ID = id
+	}
+}
+
+// WithSystemPrompt 设置系统提示词
+func WithSystemPrompt(prompt string) ConversationOption {
+	return func(c *ConversationConfig) {
+		c.SystemPrompt = prompt
+	}
+}
+
+// WithDefaultOptions 设置会话每次请求使用的默认聊天选项
+func WithDefaultOptions(options ...ChatOption) ConversationOption {
+	return func(c *ConversationConfig) {
+		c.ChatOptions = append(c.ChatOptions, options...)
+	}
+}
+
+// WithTokenBudget 设置发送历史消息的Token预算，超出时按裁剪策略裁剪请求中的历史，
+// 会话本身保存的历史不受影响
+func WithTokenBudget(tokens int) ConversationOption {
+	return func(c *ConversationConfig) {
+		c.TokenBudget = tokens
+	}
+}
+
+// WithConversationStrategy 设置Token预算使用的裁剪策略，默认为滑动窗口
+func WithConversationStrategy(strategy ContextStrategy) ConversationOption {
+	return func(c *ConversationConfig) {
+		c.Strategy = strategy
+	}
+}
+
+// WithConversationStore 设置会话存储，每次成功发送后自动保存
+func WithConversationStore(store ConversationStore) ConversationOption {
+	return func(c *ConversationConfig) {
+		c.Store = store
+	}
+}
+
+// WithToolHandler 设置工具调用处理函数，Send会自动执行工具调用并继续对话
+func WithToolHandler(handler ToolHandler) ConversationOption {
+	return func(c *ConversationConfig) {
+		c.ToolHandler = handler
+	}
+}
+
+// WithMaxToolRounds 设置单次Send中自动工具调用的最大轮数。达到上限时模型仍请求工具调用，
+// Send返回ErrMaxToolRounds且不修改会话历史，避免历史中留下没有结果的工具调用
+func WithMaxToolRounds(rounds int) ConversationOption {
+	return func(c *ConversationConfig) {
+		c.MaxToolRounds = rounds
+	}
+}
+
+// WithConversationMetadata 设置会话元数据
+func WithConversationMetadata(metadata map[string]string) ConversationOption {
+	return func(c *ConversationConfig) {
+		for k, v := range metadata {
+			c.Metadata[k] = v
+		}
+	}
+}
+
+// Conversation 会话，维护历史消息、系统提示词、默认选项和Token预算。
+// 同一会话的消息应顺序发送
+type Conversation struct {
+	service   *ChatService
+	config    *ConversationConfig
+	id        string
+	parentID  string
+	messages  []types.ChatMessage
+	createdAt time.Time
+	updatedAt time.Time
+	mu        sync.RWMutex
+}
+
+// NewConversation 创建新的会话
+func NewConversation(service *ChatService, options ...ConversationOption) *Conversation {
+	config := DefaultConversationConfig()
+	for _, option := range options {
+		option(config)
+	}
+
+	id := config.ID
+	if id == "" {
+		id = generateConversationID()
+	}
+
+	now := time.Now()
+	return &Conversation{
+		service:   service,
+		config:    config,
+		id:        id,
+		createdAt: now,
+		updatedAt: now,
+	}
+}
+
+// NewConversation 使用当前服务创建新的会话
+func (s *ChatService) NewConversation(options ...ConversationOption) *Conversation {
+	return NewConversation(s, options...)
+}
+
+// generateConversationID 生成会话ID
+func generateConversationID() string {
+	bytes := make([]byte, 12)
+	if _, err := rand.Read(bytes); err != nil {
+		return fmt.Sprintf("conv_%d", time.Now().UnixNano())
+	}
+	return fmt.Sprintf("conv_%x", bytes)
+}
+
+// ID 获取会话ID
+func (c *Conversation) ID() string {
+	return c.id
+}
+
+// ParentID 获取分支来源的会话ID
+func (c *Conversation) ParentID() string {
+	return c.parentID
+}
+
+// SystemPrompt 获取系统提示词
+func (c *Conversation) SystemPrompt() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.config.SystemPrompt
+}
+
+// SetSystemPrompt 设置系统提示词
+func (c *Conversation) SetSystemPrompt(prompt string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.config.SystemPrompt = prompt
+	c.updatedAt = time.Now()
+}
+
+// History 获取历史消息副本，不包含系统提示词
+func (c *Conversation) History() []types.ChatMessage {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	history := make([]types.ChatMessage, len(c.messages))
+	copy(history, c.messages)
+	return history
+}
+
+// Messages 获取包含系统提示词的完整消息列表
+func (c *Conversation) Messages() []types.ChatMessage {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.buildMessagesLocked(nil)
+}
+
+// Len 获取历史消息数量
+func (c *Conversation) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return len(c.messages)
+}
+
+// LastReply 获取最后一条助手消息
+func (c *Conversation) LastReply() *types.ChatMessage {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for i := len(c.messages) - 1; i >= 0; i-- {
+		if c.messages[i].Role == types.ChatRoleAssistant {
+			message := c.messages[i]
+			return &message
+		}
+	}
+	return nil
+}
+
+// AddMessage 手动追加消息到历史
+func (c *Conversation) AddMessage(messages ...types.ChatMessage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.messages = append(c.messages, messages...)
+	c.updatedAt = time.Now()
+}
+
+// AddToolResult 追加工具调用结果
+func (c *Conversation) AddToolResult(toolCallID, content string) {
+	c.AddMessage(types.NewToolMessage(toolCallID, content))
+}
+
+// Reset 清空历史消息，保留系统提示词和配置
+func (c *Conversation) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.messages = nil
+	c.updatedAt = time.Now()
+}
+
+// Fork 复制当前会话为新的分支，新会话拥有独立的ID和历史
+func (c *Conversation) Fork() *Conversation {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.forkLocked(len(c.messages))
+}
+
+// BranchAt 从历史中第n条消息之前创建分支，新会话只包含前n条历史消息
+func (c *Conversation) BranchAt(n int) (*Conversation, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if n < 0 || n > len(c.messages) {
+		return nil, fmt.Errorf("branch point %d out of range [0, %d]", n, len(c.messages))
+	}
+	return c.forkLocked(n), nil
+}
+
+// forkLocked 复制会话的前n条历史消息，调用方需持有读锁
+func (c *Conversation) forkLocked(n int) *Conversation {
+	config := *c.config
+	config.ChatOptions = append([]ChatOption(nil), c.config.ChatOptions...)
+	config.Metadata = make(map[string]string, len(c.config.Metadata))
+	for k, v := range c.config.Metadata {
+		config.Metadata[k] = v
+	}
+
+	now := time.Now()
+	fork := &Conversation{
+		service:   c.service,
+		config:    &config,
+		id:        generateConversationID(),
+		parentID:  c.id,
+		messages:  make([]types.ChatMessage, n),
+		createdAt: now,
+		updatedAt: now,
+	}
+	copy(fork.messages, c.messages[:n])
+	return fork
+}
+
+// buildMessagesLocked 构建系统提示词、历史消息和待发送消息组成的请求消息，调用方需持有读锁
+func (c *Conversation) buildMessagesLocked(pending []types.ChatMessage) []types.ChatMessage {
+	messages := make([]types.ChatMessage, 0, len(c.messages)+len(pending)+1)
+	if c.config.SystemPrompt != "" {
+		messages = append(messages, types.NewSystemMessage(c.config.SystemPrompt))
+	}
+	messages = append(messages, c.messages...)
+	return append(messages, pending...)
+}
+
+// requestMessages 构建请求消息，并在设置了Token预算时进行裁剪
+func (c *Conversation) requestMessages(ctx context.Context, pending []types.ChatMessage, options []ChatOption) ([]types.ChatMessage, error) {
+	c.mu.RLock()
+	messages := c.buildMessagesLocked(pending)
+	budget := c.config.TokenBudget
+	strategy := c.config.Strategy
+	c.mu.RUnlock()
+
+	if budget <= 0 {
+		return messages, nil
+	}
+
+	config := c.service.getConfig()
+	for _, option := range options {
+		option(config)
+	}
+
+	contextOptions := []ContextOption{WithContextModel(config.Model), WithContextWindow(budget)}
+	if strategy != nil {
+		contextOptions = append(contextOptions, WithContextStrategy(strategy))
+	}
+	fitted, _, err := NewContextManager(contextOptions...).Fit(ctx, messages)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fit conversation history: %w", err)
+	}
+	return fitted, nil
+}
+
+// chatOptions 合并会话默认选项和本次请求选项
+func (c *Conversation) chatOptions(options []ChatOption) []ChatOption {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	merged := make([]ChatOption, 0, len(c.config.ChatOptions)+len(options))
+	merged = append(merged, c.config.ChatOptions...)
+	return append(merged, options...)
+}
+
+// commit 将本轮消息追加到历史并自动保存
+func (c *Conversation) commit(ctx context.Context, messages []types.ChatMessage) {
+	c.AddMessage(messages...)
+
+	if c.config.Store == nil {
+		return
+	}
+	if err := c.Save(ctx); err != nil {
+		c.service.logger.Warn("Failed to save conversation", zap.String("id", c.id), zap.Error(err))
+	}
+}
@@ -0,0 +1,177 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/hewenyu/newapi-go/types"
+)
+
+// Send 发送用户消息，成功后将用户消息、助手回复以及自动执行的工具调用结果追加到历史
+func (c *Conversation) Send(ctx context.Context, content string, options ...ChatOption) (*types.ChatCompletionResponse, error) {
+	return c.SendMessage(ctx, types.NewUserMessage(content), options...)
+}
+
+// SendMessage 发送任意消息（如多模态消息），行为与Send相同
+func (c *Conversation) SendMessage(ctx context.Context, message types.ChatMessage, options ...ChatOption) (*types.ChatCompletionResponse, error) {
+	return c.send(ctx, []types.ChatMessage{message}, options)
+}
+
+// Continue 不追加新消息，直接使用当前历史请求下一条回复，
+// 通常用于手动追加工具调用结果之后
+func (c *Conversation) Continue(ctx context.Context, options ...ChatOption) (*types.ChatCompletionResponse, error) {
+	if c.Len() == 0 {
+		return nil, fmt.Errorf("conversation has no messages to continue")
+	}
+	return c.send(ctx, nil, options)
+}
+
+// send 发送请求并处理自动工具调用，全部成功后才提交到历史。工具调用超过最大轮数时返回ErrMaxToolRounds
+func (c *Conversation) send(ctx context.Context, pending []types.ChatMessage, options []ChatOption) (*types.ChatCompletionResponse, error) {
+	options = c.chatOptions(options)
+
+	for round := 0; ; round++ {
+		messages, err := c.requestMessages(ctx, pending, options)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := c.service.CreateChatCompletion(ctx, messages, options...)
+		if err != nil {
+			return nil, err
+		}
+
+		reply := resp.GetFirstMessage()
+		if reply == nil {
+			return nil, fmt.Errorf("response contains no message")
+		}
+		pending = append(pending, normalizeReply(*reply))
+
+		if !reply.HasToolCalls() || c.config.ToolHandler == nil {
+			c.commit(ctx, pending)
+			return resp, nil
+		}
+		// 未执行的工具调用没有对应的tool消息，提交后下一次请求会被接口拒绝，因此整轮不提交
+		if round >= c.config.MaxToolRounds {
+			return nil, fmt.Errorf("%w (%d)", ErrMaxToolRounds, c.config.MaxToolRounds)
+		}
+		pending = append(pending, c.runTools(ctx, reply.ToolCalls)...)
+	}
+}
+
+// runTools 执行工具调用，处理函数返回错误时将错误信息作为工具结果返回给模型
+func (c *Conversation) runTools(ctx context.Context, calls []types.ToolCall) []types.ChatMessage {
+	results := make([]types.ChatMessage, 0, len(calls))
+	for _, call := range calls {
+		content, err := c.config.ToolHandler(ctx, call)
+		if err != nil {
+			content = fmt.Sprintf("error: %v", err)
+		}
+		results = append(results, types.NewToolMessage(call.ID, content))
+	}
+	return results
+}
+
+// normalizeReply 规范化助手回复以便作为历史消息再次发送
+func normalizeReply(reply types.ChatMessage) types.ChatMessage {
+	if reply.Role == "" {
+		reply.Role = types.ChatRoleAssistant
+	}
+	reply.ToolCalls = types.ClearToolCallIndexes(reply.ToolCalls)
+	return reply
+}
+
+// SendStream 以流式方式发送用户消息，流读取完毕后自动将消息追加到历史
+func (c *Conversation) SendStream(ctx context.Context, content string, options ...ChatOption) (*ConversationStream, error) {
+	return c.SendMessageStream(ctx, types.NewUserMessage(content), options...)
+}
+
+// SendMessageStream 以流式方式发送任意消息
+func (c *Conversation) SendMessageStream(ctx context.Context, message types.ChatMessage, options ...ChatOption) (*ConversationStream, error) {
+	return c.sendStream(ctx, []types.ChatMessage{message}, options)
+}
+
+// ContinueStream 以流式方式基于当前历史请求下一条回复
+func (c *Conversation) ContinueStream(ctx context.Context, options ...ChatOption) (*ConversationStream, error) {
+	if c.Len() == 0 {
+		return nil, fmt.Errorf("conversation has no messages to continue")
+	}
+	return c.sendStream(ctx, nil, options)
+}
+
+// sendStream 创建会话流
+func (c *Conversation) sendStream(ctx context.Context, pending []types.ChatMessage, options []ChatOption) (*ConversationStream, error) {
+	options = c.chatOptions(options)
+	messages, err := c.requestMessages(ctx, pending, options)
+	if err != nil {
+		return nil, err
+	}
+
+	stream, err := c.service.CreateChatCompletionStream(ctx, messages, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	processor, ok := stream.(*ChatStreamProcessor)
+	if !ok {
+		processor = NewChatStreamProcessor(stream, c.service.logger)
+	}
+
+	return &ConversationStream{
+		ChatStreamProcessor: processor,
+		conversation:        c,
+		pending:             pending,
+		ctx:                 ctx,
+	}, nil
+}
+
+// ConversationStream 会话流，读取到流结束时将本轮消息提交到会话历史。
+// 如果配置了工具处理函数，工具调用会被执行并追加结果，之后可调用ContinueStream继续
+type ConversationStream struct {
+	*ChatStreamProcessor
+	conversation *Conversation
+	pending      []types.ChatMessage
+	ctx          context.Context
+	once         sync.Once
+	reply        *types.ChatMessage
+	toolsRan     bool
+}
+
+// Next 获取下一个流式事件，流结束时提交会话历史
+func (s *ConversationStream) Next() (*types.StreamEvent, error) {
+	event, err := s.ChatStreamProcessor.Next()
+	if err == io.EOF {
+		s.once.Do(s.commit)
+	}
+	return event, err
+}
+
+// Reply 获取流结束后合并的助手回复，流未结束时返回nil
+func (s *ConversationStream) Reply() *types.ChatMessage {
+	return s.reply
+}
+
+// ToolsPending 是否执行了工具调用并需要继续对话
+func (s *ConversationStream) ToolsPending() bool {
+	return s.toolsRan
+}
+
+// commit 合并流式回复并提交到会话历史
+func (s *ConversationStream) commit() {
+	resp := s.CollectResponse()
+	if resp == nil || resp.GetFirstMessage() == nil {
+		return
+	}
+
+	reply := normalizeReply(*resp.GetFirstMessage())
+	s.reply = &reply
+	messages := append(s.pending, reply)
+
+	if reply.HasToolCalls() && s.conversation.config.ToolHandler != nil {
+		messages = append(messages, s.conversation.runTools(s.ctx, reply.ToolCalls)...)
+		s.toolsRan = true
+	}
+	s.conversation.commit(s.ctx, messages)
+}
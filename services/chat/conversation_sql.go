@@ -0,0 +1,179 @@
+package chat
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// SQL占位符风格常量
+const (
+	PlaceholderQuestion = "question" // ?，适用于MySQL、SQLite
+	PlaceholderDollar   = "dollar"   // $1，适用于PostgreSQL
+)
+
+// DefaultConversationTable 默认的会话表名
+const DefaultConversationTable = "conversations"
+
+var sqlIdentifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// SQLStoreConfig SQL会话存储配置
+type SQLStoreConfig struct {
+	Table       string
+	Placeholder string
+}
+
+// SQLStoreOption SQL会话存储选项函数类型
+type SQLStoreOption func(*SQLStoreConfig)
+
+// WithSQLTable 设置会话表名
+func WithSQLTable(table string) SQLStoreOption {
+	return func(c *SQLStoreConfig) {
+		c.Table = table
+	}
+}
+
+// WithSQLPlaceholder 设置SQL占位符风格
+func WithSQLPlaceholder(placeholder string) SQLStoreOption {
+	return func(c *SQLStoreConfig) {
+		c.Placeholder = placeholder
+	}
+}
+
+// SQLConversationStore 基于database/sql的会话存储，调用方负责注册数据库驱动。
+// 会话以JSON形式保存在表的data列中
+type SQLConversationStore struct {
+	db     *sql.DB
+	config *SQLStoreConfig
+}
+
+// NewSQLConversationStore 创建SQL会话存储
+func NewSQLConversationStore(db *sql.DB, options ...SQLStoreOption) (*SQLConversationStore, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database cannot be nil")
+	}
+
+	config := &SQLStoreConfig{
+		Table:       DefaultConversationTable,
+		Placeholder: PlaceholderQuestion,
+	}
+	for _, option := range options {
+		option(config)
+	}
+
+	if !sqlIdentifierPattern.MatchString(config.Table) {
+		return nil, fmt.Errorf("invalid table name: %q", config.Table)
+	}
+	if config.Placeholder != PlaceholderQuestion && config.Placeholder != PlaceholderDollar {
+		return nil, fmt.Errorf("unsupported placeholder style: %q", config.Placeholder)
+	}
+
+	return &SQLConversationStore{db: db, config: config}, nil
+}
+
+// bind 获取第n个参数的占位符
+func (s *SQLConversationStore) bind(n int) string {
+	if s.config.Placeholder == PlaceholderDollar {
+		return fmt.Sprintf("$%d", n)
+	}
+	return "?"
+}
+
+// CreateTable 创建会话表（如不存在）
+func (s *SQLConversationStore) CreateTable(ctx context.Context) error {
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	id VARCHAR(255) PRIMARY KEY,
+	parent_id VARCHAR(255),
+	data TEXT NOT NULL,
+	updated_at BIGINT NOT NULL
+)`, s.config.Table)
+
+	if _, err := s.db.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to create conversation table: %w", err)
+	}
+	return nil
+}
+
+// Save 保存会话快照，在事务中先删除再插入以兼容不同数据库
+func (s *SQLConversationStore) Save(ctx context.Context, snapshot *ConversationSnapshot) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to marshal conversation: %w", err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	deleteQuery := fmt.Sprintf("DELETE FROM %s WHERE id = %s", s.config.Table, s.bind(1))
+	if _, err := tx.ExecContext(ctx, deleteQuery, snapshot.ID); err != nil {
+		return fmt.Errorf("failed to replace conversation: %w", err)
+	}
+
+	insertQuery := fmt.Sprintf("INSERT INTO %s (id, parent_id, data, updated_at) VALUES (%s, %s, %s, %s)",
+		s.config.Table, s.bind(1), s.bind(2), s.bind(3), s.bind(4))
+	if _, err := tx.ExecContext(ctx, insertQuery, snapshot.ID, snapshot.ParentID, string(data), snapshot.UpdatedAt.UnixMilli()); err != nil {
+		return fmt.Errorf("failed to insert conversation: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit conversation: %w", err)
+	}
+	return nil
+}
+
+// Load 加载会话快照
+func (s *SQLConversationStore) Load(ctx context.Context, id string) (*ConversationSnapshot, error) {
+	query := fmt.Sprintf("SELECT data FROM %s WHERE id = %s", s.config.Table, s.bind(1))
+
+	var data string
+	if err := s.db.QueryRowContext(ctx, query, id).Scan(&data); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrConversationNotFound
+		}
+		return nil, fmt.Errorf("failed to load conversation: %w", err)
+	}
+
+	var snapshot ConversationSnapshot
+	if err := json.Unmarshal([]byte(data), &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal conversation: %w", err)
+	}
+	return &snapshot, nil
+}
+
+// Delete 删除会话快照
+func (s *SQLConversationStore) Delete(ctx context.Context, id string) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE id = %s", s.config.Table, s.bind(1))
+	if _, err := s.db.ExecContext(ctx, query, id); err != nil {
+		return fmt.Errorf("failed to delete conversation: %w", err)
+	}
+	return nil
+}
+
+// List 列出所有会话ID，按更新时间倒序
+func (s *SQLConversationStore) List(ctx context.Context) ([]string, error) {
+	query := fmt.Sprintf("SELECT id FROM %s ORDER BY updated_at DESC", s.config.Table)
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list conversations: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan conversation id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list conversations: %w", err)
+	}
+	return ids, nil
+}
@@ -0,0 +1,285 @@
+package chat
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hewenyu/newapi-go/types"
+)
+
+var (
+	stubDeletePattern = regexp.MustCompile(`^DELETE FROM (\w+) WHERE id = (\S+)$`)
+	stubInsertPattern = regexp.MustCompile(`^INSERT INTO (\w+) \(id, parent_id, data, updated_at\) VALUES \((\S+), (\S+), (\S+), (\S+)\)$`)
+	stubLoadPattern   = regexp.MustCompile(`^SELECT data FROM (\w+) WHERE id = (\S+)$`)
+	stubListPattern   = regexp.MustCompile(`^SELECT id FROM (\w+) ORDER BY updated_at DESC$`)
+	stubCreatePattern = regexp.MustCompile(`^CREATE TABLE IF NOT EXISTS (\w+) \(`)
+)
+
+// stubSQLRow 模拟表中的一行
+type stubSQLRow struct {
+	data      string
+	updatedAt int64
+}
+
+// stubSQLDB 内存中的database/sql驱动，只支持SQLConversationStore生成的语句，
+// 并校验表名和占位符风格
+type stubSQLDB struct {
+	table       string
+	placeholder string
+
+	mu      sync.Mutex
+	rows    map[string]stubSQLRow
+	queries []string
+}
+
+// newStubSQLDB 创建模拟数据库
+func newStubSQLDB(t *testing.T, table, placeholder string) (*sql.DB, *stubSQLDB) {
+	stub := &stubSQLDB{table: table, placeholder: placeholder, rows: make(map[string]stubSQLRow)}
+	db := sql.OpenDB(stub)
+	t.Cleanup(func() { db.Close() })
+	return db, stub
+}
+
+// Connect 实现driver.Connector
+func (d *stubSQLDB) Connect(context.Context) (driver.Conn, error) {
+	return &stubSQLConn{db: d}, nil
+}
+
+// Driver 实现driver.Connector
+func (d *stubSQLDB) Driver() driver.Driver {
+	return stubSQLDriver{db: d}
+}
+
+// checkPlaceholders 校验语句中的占位符符合配置的风格
+func (d *stubSQLDB) checkPlaceholders(placeholders ...string) error {
+	for i, placeholder := range placeholders {
+		want := "?"
+		if d.placeholder == PlaceholderDollar {
+			want = fmt.Sprintf("$%d", i+1)
+		}
+		if placeholder != want {
+			return fmt.Errorf("placeholder %d is %q, want %q", i+1, placeholder, want)
+		}
+	}
+	return nil
+}
+
+// exec 执行语句，返回查询结果列
+func (d *stubSQLDB) exec(query string, args []driver.Value) ([][]driver.Value, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.queries = append(d.queries, query)
+
+	var match []string
+	for _, pattern := range []*regexp.Regexp{stubDeletePattern, stubInsertPattern, stubLoadPattern, stubListPattern, stubCreatePattern} {
+		if match = pattern.FindStringSubmatch(query); match != nil {
+			break
+		}
+	}
+	if match == nil {
+		return nil, fmt.Errorf("unexpected query: %s", query)
+	}
+	if match[1] != d.table {
+		return nil, fmt.Errorf("unexpected table %q", match[1])
+	}
+	if err := d.checkPlaceholders(match[2:]...); err != nil {
+		return nil, err
+	}
+	if len(args) != len(match)-2 {
+		return nil, fmt.Errorf("got %d arguments for %d placeholders", len(args), len(match)-2)
+	}
+
+	switch {
+	case strings.HasPrefix(query, "DELETE"):
+		delete(d.rows, args[0].(string))
+	case strings.HasPrefix(query, "INSERT"):
+		id := args[0].(string)
+		if _, exists := d.rows[id]; exists {
+			return nil, fmt.Errorf("duplicate primary key %q", id)
+		}
+		d.rows[id] = stubSQLRow{data: args[2].(string), updatedAt: args[3].(int64)}
+	case strings.HasPrefix(query, "SELECT data"):
+		if row, ok := d.rows[args[0].(string)]; ok {
+			return [][]driver.Value{{row.data}}, nil
+		}
+	case strings.HasPrefix(query, "SELECT id"):
+		ids := make([]string, 0, len(d.rows))
+		for id := range d.rows {
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool { return d.rows[ids[i]].updatedAt > d.rows[ids[j]].updatedAt })
+
+		result := make([][]driver.Value, len(ids))
+		for i, id := range ids {
+			result[i] = []driver.Value{id}
+		}
+		return result, nil
+	}
+	return nil, nil
+}
+
+// stubSQLDriver 实现driver.Driver
+type stubSQLDriver struct {
+	db *stubSQLDB
+}
+
+// Open 实现driver.Driver
+func (d stubSQLDriver) Open(string) (driver.Conn, error) {
+	return &stubSQLConn{db: d.db}, nil
+}
+
+// stubSQLConn 模拟连接，事务直接作用于共享数据
+type stubSQLConn struct {
+	db *stubSQLDB
+}
+
+func (c *stubSQLConn) Prepare(query string) (driver.Stmt, error) {
+	return &stubSQLStmt{db: c.db, query: query}, nil
+}
+
+func (c *stubSQLConn) Close() error { return nil }
+
+func (c *stubSQLConn) Begin() (driver.Tx, error) { return stubSQLTx{}, nil }
+
+// stubSQLTx 模拟事务
+type stubSQLTx struct{}
+
+func (stubSQLTx) Commit() error   { return nil }
+func (stubSQLTx) Rollback() error { return nil }
+
+// stubSQLStmt 模拟预编译语句
+type stubSQLStmt struct {
+	db    *stubSQLDB
+	query string
+}
+
+func (s *stubSQLStmt) Close() error  { return nil }
+func (s *stubSQLStmt) NumInput() int { return -1 }
+
+func (s *stubSQLStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if _, err := s.db.exec(s.query, args); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s *stubSQLStmt) Query(args []driver.Value) (driver.Rows, error) {
+	values, err := s.db.exec(s.query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &stubSQLRows{values: values}, nil
+}
+
+// stubSQLRows 模拟单列查询结果
+type stubSQLRows struct {
+	values [][]driver.Value
+}
+
+func (r *stubSQLRows) Columns() []string { return []string{"value"} }
+func (r *stubSQLRows) Close() error      { return nil }
+
+func (r *stubSQLRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+func TestSQLConversationStore(t *testing.T) {
+	for _, placeholder := range []string{PlaceholderQuestion, PlaceholderDollar} {
+		t.Run(placeholder, func(t *testing.T) {
+			ctx := context.Background()
+			db, stub := newStubSQLDB(t, "chat_history", placeholder)
+
+			store, err := NewSQLConversationStore(db, WithSQLTable("chat_history"), WithSQLPlaceholder(placeholder))
+			if err != nil {
+				t.Fatalf("NewSQLConversationStore failed: %v", err)
+			}
+			if err := store.CreateTable(ctx); err != nil {
+				t.Fatalf("CreateTable failed: %v", err)
+			}
+
+			now := time.UnixMilli(1700000000000)
+			first := &ConversationSnapshot{ID: "first", Messages: []types.ChatMessage{types.NewUserMessage("hi")}, UpdatedAt: now}
+			second := &ConversationSnapshot{ID: "second", ParentID: "first", UpdatedAt: now.Add(time.Second)}
+			for _, snapshot := range []*ConversationSnapshot{first, second} {
+				if err := store.Save(ctx, snapshot); err != nil {
+					t.Fatalf("Save(%s) failed: %v", snapshot.ID, err)
+				}
+			}
+			if ids, _ := store.List(ctx); strings.Join(ids, ",") != "second,first" {
+				t.Errorf("List() = %v, want most recently updated first", ids)
+			}
+
+			// 再次保存覆盖已有记录
+			first.Messages = append(first.Messages, types.NewAssistantMessage("hello"))
+			first.UpdatedAt = now.Add(2 * time.Second)
+			if err := store.Save(ctx, first); err != nil {
+				t.Fatalf("Save(first) overwrite failed: %v", err)
+			}
+
+			loaded, err := store.Load(ctx, "first")
+			if err != nil {
+				t.Fatalf("Load failed: %v", err)
+			}
+			if len(loaded.Messages) != 2 || loaded.Messages[1].Content != "hello" || !loaded.UpdatedAt.Equal(first.UpdatedAt) {
+				t.Errorf("loaded snapshot = %+v", loaded)
+			}
+			if ids, _ := store.List(ctx); strings.Join(ids, ",") != "first,second" {
+				t.Errorf("List() after overwrite = %v", ids)
+			}
+
+			if err := store.Delete(ctx, "second"); err != nil {
+				t.Fatalf("Delete failed: %v", err)
+			}
+			if _, err := store.Load(ctx, "second"); !errors.Is(err, ErrConversationNotFound) {
+				t.Errorf("Load after Delete = %v, want ErrConversationNotFound", err)
+			}
+			if ids, _ := store.List(ctx); len(ids) != 1 || ids[0] != "first" {
+				t.Errorf("List() after Delete = %v", ids)
+			}
+
+			want := "INSERT INTO chat_history (id, parent_id, data, updated_at) VALUES (?, ?, ?, ?)"
+			if placeholder == PlaceholderDollar {
+				want = "INSERT INTO chat_history (id, parent_id, data, updated_at) VALUES ($1, $2, $3, $4)"
+			}
+			found := false
+			for _, query := range stub.queries {
+				found = found || query == want
+			}
+			if !found {
+				t.Errorf("insert query %q not issued, got %q", want, stub.queries)
+			}
+		})
+	}
+}
+
+func TestNewSQLConversationStoreValidation(t *testing.T) {
+	if _, err := NewSQLConversationStore(nil); err == nil {
+		t.Error("expected error for nil database")
+	}
+
+	db, _ := newStubSQLDB(t, DefaultConversationTable, PlaceholderQuestion)
+	for _, option := range []SQLStoreOption{
+		WithSQLTable("conversations; DROP TABLE users"),
+		WithSQLTable(""),
+		WithSQLPlaceholder("named"),
+	} {
+		if _, err := NewSQLConversationStore(db, option); err == nil {
+			t.Error("expected validation error")
+		}
+	}
+}
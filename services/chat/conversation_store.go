@@ -0,0 +1,278 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hewenyu/newapi-go/types"
+)
+
+// ErrConversationNotFound 会话不存在
+var ErrConversationNotFound = errors.New("conversation not found")
+
+// ConversationSnapshot 会话快照，用于持久化会话状态。
+// 聊天选项和工具处理函数无法序列化，加载时需要重新传入
+type ConversationSnapshot struct {
+	ID           string              `json:"id"`
+	ParentID     string              `json:"parent_id,omitempty"`
+	SystemPrompt string              `json:"system_prompt,omitempty"`
+	Messages     []types.ChatMessage `json:"messages"`
+	TokenBudget  int                 `json:"token_budget,omitempty"`
+	Metadata     map[string]string   `json:"metadata,omitempty"`
+	CreatedAt    time.Time           `json:"created_at"`
+	UpdatedAt    time.Time           `json:"updated_at"`
+}
+
+// ConversationStore 会话存储接口
+type ConversationStore interface {
+	// Save 保存会话快照，已存在时覆盖
+	Save(ctx context.Context, snapshot *ConversationSnapshot) error
+	// Load 加载会话快照，不存在时返回ErrConversationNotFound
+	Load(ctx context.Context, id string) (*ConversationSnapshot, error)
+	// Delete 删除会话快照
+	Delete(ctx context.Context, id string) error
+	// List 列出所有会话ID
+	List(ctx context.Context) ([]string, error)
+}
+
+// Snapshot 获取会话快照
+func (c *Conversation) Snapshot() *ConversationSnapshot {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	snapshot := &ConversationSnapshot{
+		ID:           c.id,
+		ParentID:     c.parentID,
+		SystemPrompt: c.config.SystemPrompt,
+		Messages:     make([]types.ChatMessage, len(c.messages)),
+		TokenBudget:  c.config.TokenBudget,
+		Metadata:     make(map[string]string, len(c.config.Metadata)),
+		CreatedAt:    c.createdAt,
+		UpdatedAt:    c.updatedAt,
+	}
+	copy(snapshot.Messages, c.messages)
+	for k, v := range c.config.Metadata {
+		snapshot.Metadata[k] = v
+	}
+	return snapshot
+}
+
+// Save 将会话保存到配置的存储
+func (c *Conversation) Save(ctx context.Context) error {
+	if c.config.Store == nil {
+		return fmt.Errorf("conversation store not configured")
+	}
+	return c.config.Store.Save(ctx, c.Snapshot())
+}
+
+// RestoreConversation 从快照恢复会话，options用于重新设置聊天选项、存储和工具处理函数
+func RestoreConversation(service *ChatService, snapshot *ConversationSnapshot, options ...ConversationOption) *Conversation {
+	restoreOptions := []ConversationOption{
+		WithConversationID(snapshot.ID),
+		WithSystemPrompt(snapshot.SystemPrompt),
+		WithTokenBudget(snapshot.TokenBudget),
+		WithConversationMetadata(snapshot.Metadata),
+	}
+	conversation := NewConversation(service, append(restoreOptions, options...)...)
+	conversation.parentID = snapshot.ParentID
+	conversation.messages = append([]types.ChatMessage(nil), snapshot.Messages...)
+	conversation.createdAt = snapshot.CreatedAt
+	conversation.updatedAt = snapshot.UpdatedAt
+	return conversation
+}
+
+// LoadConversation 从存储加载会话，加载后的会话使用同一存储自动保存
+func LoadConversation(ctx context.Context, service *ChatService, store ConversationStore, id string, options ...ConversationOption) (*Conversation, error) {
+	snapshot, err := store.Load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return RestoreConversation(service, snapshot, append([]ConversationOption{WithConversationStore(store)}, options...)...), nil
+}
+
+// cloneSnapshot 通过JSON深拷贝快照，避免调用方修改存储中的数据
+func cloneSnapshot(snapshot *ConversationSnapshot) (*ConversationSnapshot, error) {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal conversation: %w", err)
+	}
+	var clone ConversationSnapshot
+	if err := json.Unmarshal(data, &clone); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal conversation: %w", err)
+	}
+	return &clone, nil
+}
+
+// MemoryConversationStore 内存会话存储
+type MemoryConversationStore struct {
+	snapshots map[string]*ConversationSnapshot
+	mu        sync.RWMutex
+}
+
+// NewMemoryConversationStore 创建内存会话存储
+func NewMemoryConversationStore() *MemoryConversationStore {
+	return &MemoryConversationStore{
+		snapshots: make(map[string]*ConversationSnapshot),
+	}
+}
+
+// Save 保存会话快照
+func (s *MemoryConversationStore) Save(ctx context.Context, snapshot *ConversationSnapshot) error {
+	clone, err := cloneSnapshot(snapshot)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.snapshots[snapshot.ID] = clone
+	return nil
+}
+
+// Load 加载会话快照
+func (s *MemoryConversationStore) Load(ctx context.Context, id string) (*ConversationSnapshot, error) {
+	s.mu.RLock()
+	snapshot, ok := s.snapshots[id]
+	s.mu.RUnlock()
+
+	if !ok {
+		return nil, ErrConversationNotFound
+	}
+	return cloneSnapshot(snapshot)
+}
+
+// Delete 删除会话快照
+func (s *MemoryConversationStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.snapshots, id)
+	return nil
+}
+
+// List 列出所有会话ID
+func (s *MemoryConversationStore) List(ctx context.Context) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ids := make([]string, 0, len(s.snapshots))
+	for id := range s.snapshots {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// FileConversationStore JSON文件会话存储，每个会话保存为目录下的一个JSON文件
+type FileConversationStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewFileConversationStore 创建JSON文件会话存储，目录不存在时自动创建
+func NewFileConversationStore(dir string) (*FileConversationStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create conversation directory: %w", err)
+	}
+	return &FileConversationStore{dir: dir}, nil
+}
+
+// path 获取会话文件路径
+func (s *FileConversationStore) path(id string) (string, error) {
+	if id == "" || strings.ContainsAny(id, `/\`) || id == "." || id == ".." {
+		return "", fmt.Errorf("invalid conversation id: %q", id)
+	}
+	return filepath.Join(s.dir, id+".json"), nil
+}
+
+// Save 保存会话快照，先写入临时文件再重命名以保证原子性
+func (s *FileConversationStore) Save(ctx context.Context, snapshot *ConversationSnapshot) error {
+	path, err := s.path(snapshot.ID)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal conversation: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write conversation: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to save conversation: %w", err)
+	}
+	return nil
+}
+
+// Load 加载会话快照
+func (s *FileConversationStore) Load(ctx context.Context, id string) (*ConversationSnapshot, error) {
+	path, err := s.path(id)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrConversationNotFound
+		}
+		return nil, fmt.Errorf("failed to read conversation: %w", err)
+	}
+
+	var snapshot ConversationSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal conversation: %w", err)
+	}
+	return &snapshot, nil
+}
+
+// Delete 删除会话快照
+func (s *FileConversationStore) Delete(ctx context.Context, id string) error {
+	path, err := s.path(id)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete conversation: %w", err)
+	}
+	return nil
+}
+
+// List 列出所有会话ID
+func (s *FileConversationStore) List(ctx context.Context) ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list conversations: %w", err)
+	}
+
+	var ids []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		ids = append(ids, strings.TrimSuffix(name, ".json"))
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
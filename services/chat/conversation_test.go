@@ -0,0 +1,132 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/hewenyu/newapi-go/types"
+)
+
+func TestConversationSendRunsTools(t *testing.T) {
+	service, fake := newFakeService(t,
+		toolCallResponse("call_1", "get_time", "{}"),
+		textResponse("It is noon."),
+	)
+
+	conv := service.NewConversation(
+		WithSystemPrompt("You are a clock."),
+		WithToolHandler(func(ctx context.Context, call types.ToolCall) (string, error) {
+			return "12:00", nil
+		}),
+	)
+
+	resp, err := conv.Send(context.Background(), "What time is it?")
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if resp.GetFirstContent() != "It is noon." {
+		t.Errorf("unexpected reply: %q", resp.GetFirstContent())
+	}
+
+	history := conv.History()
+	wantRoles := []string{types.ChatRoleUser, types.ChatRoleAssistant, types.ChatRoleTool, types.ChatRoleAssistant}
+	if len(history) != len(wantRoles) {
+		t.Fatalf("expected %d history messages, got %d", len(wantRoles), len(history))
+	}
+	for i, role := range wantRoles {
+		if history[i].Role != role {
+			t.Errorf("history[%d].Role = %s, want %s", i, history[i].Role, role)
+		}
+	}
+
+	second := fake.requests[1].Messages
+	if second[0].Role != types.ChatRoleSystem || second[len(second)-1].ToolCallID != "call_1" {
+		t.Errorf("second request should carry system prompt and tool result, got %+v", second)
+	}
+}
+
+func TestConversationSendMaxToolRounds(t *testing.T) {
+	service, fake := newFakeService(t,
+		toolCallResponse("call_1", "get_time", "{}"),
+		toolCallResponse("call_2", "get_time", "{}"),
+	)
+
+	calls := 0
+	conv := service.NewConversation(
+		WithMaxToolRounds(1),
+		WithToolHandler(func(ctx context.Context, call types.ToolCall) (string, error) {
+			calls++
+			return "12:00", nil
+		}),
+	)
+
+	resp, err := conv.Send(context.Background(), "What time is it?")
+	if !errors.Is(err, ErrMaxToolRounds) {
+		t.Fatalf("expected ErrMaxToolRounds, got resp=%v err=%v", resp, err)
+	}
+	if calls != 1 || len(fake.requests) != 2 {
+		t.Errorf("tool calls = %d, requests = %d, want 1 and 2", calls, len(fake.requests))
+	}
+	// 没有结果的工具调用不能进入历史，否则下一次请求会被接口拒绝
+	if conv.Len() != 0 {
+		t.Errorf("history should be unchanged, got %+v", conv.History())
+	}
+}
+
+func TestConversationSendStreamCommitsReply(t *testing.T) {
+	service, _ := newFakeService(t, textChunks("Hel", "lo"))
+	conv := service.NewConversation()
+
+	stream, err := conv.SendStream(context.Background(), "hi")
+	if err != nil {
+		t.Fatalf("SendStream failed: %v", err)
+	}
+	for {
+		if _, err := stream.Next(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("stream error: %v", err)
+		}
+	}
+
+	if conv.Len() != 2 || conv.LastReply().GetTextContent() != "Hello" {
+		t.Errorf("stream reply not committed, history: %+v", conv.History())
+	}
+}
+
+func TestConversationForkAndStore(t *testing.T) {
+	service, _ := newFakeService(t)
+	store, err := NewFileConversationStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conv := service.NewConversation(WithSystemPrompt("sys"), WithConversationStore(store))
+	conv.AddMessage(types.NewUserMessage("one"), types.NewAssistantMessage("two"))
+
+	branch, err := conv.BranchAt(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if branch.Len() != 1 || branch.ParentID() != conv.ID() || branch.ID() == conv.ID() {
+		t.Errorf("unexpected branch: len=%d parent=%s", branch.Len(), branch.ParentID())
+	}
+
+	ctx := context.Background()
+	if err := conv.Save(ctx); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	loaded, err := LoadConversation(ctx, service, store, conv.ID())
+	if err != nil {
+		t.Fatalf("LoadConversation failed: %v", err)
+	}
+	if loaded.SystemPrompt() != "sys" || loaded.Len() != 2 || loaded.History()[1].GetTextContent() != "two" {
+		t.Errorf("loaded conversation mismatch: %+v", loaded.Snapshot())
+	}
+
+	if _, err := store.Load(ctx, "missing"); err != ErrConversationNotFound {
+		t.Errorf("expected ErrConversationNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,329 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/hewenyu/newapi-go/types"
+	"go.uber.org/zap"
+)
+
+// DefaultFallbackClasses 默认触发模型降级的错误分类
+var DefaultFallbackClasses = []types.ErrorClass{
+	types.ErrorClassRateLimit,
+	types.ErrorClassServer,
+	types.ErrorClassContextLength,
+	types.ErrorClassContentFilter,
+}
+
+// FallbackAttempt 单个模型的尝试结果
+type FallbackAttempt struct {
+	Model string
+	Err   error
+}
+
+// FallbackError 降级链中所有模型均失败时返回的错误，Unwrap返回最后一个模型的错误
+type FallbackError struct {
+	Attempts []FallbackAttempt
+}
+
+// Error 实现error接口
+func (e *FallbackError) Error() string {
+	parts := make([]string, 0, len(e.Attempts))
+	for _, attempt := range e.Attempts {
+		parts = append(parts, fmt.Sprintf("%s: %v", attempt.Model, attempt.Err))
+	}
+	return fmt.Sprintf("all fallback models failed (%s)", strings.Join(parts, "; "))
+}
+
+// Unwrap 返回最后一个模型的错误
+func (e *FallbackError) Unwrap() error {
+	if len(e.Attempts) == 0 {
+		return nil
+	}
+	return e.Attempts[len(e.Attempts)-1].Err
+}
+
+// fallbackChain 获取去重后的模型降级链，主模型在前
+func (c *ChatConfig) fallbackChain() []string {
+	chain := make([]string, 0, len(c.FallbackModels)+1)
+	seen := make(map[string]bool, len(c.FallbackModels)+1)
+	for _, model := range append([]string{c.Model}, c.FallbackModels...) {
+		if !seen[model] {
+			seen[model] = true
+			chain = append(chain, model)
+		}
+	}
+	return chain
+}
+
+// shouldFallback 判断错误是否应切换到下一个模型
+func (c *ChatConfig) shouldFallback(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	classes := c.FallbackOn
+	if classes == nil {
+		classes = DefaultFallbackClasses
+	}
+
+	class := types.ClassifyError(err)
+	for _, c := range classes {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// createWithFallback 按降级链依次尝试非流式请求
+func (s *ChatService) createWithFallback(ctx context.Context, messages []types.ChatMessage, config *ChatConfig) (*types.ChatCompletionResponse, error) {
+	chain := config.fallbackChain()
+	attempts := make([]FallbackAttempt, 0, len(chain))
+
+	for i, model := range chain {
+		attemptConfig := config.Clone()
+		attemptConfig.Model = model
+
+		resp, err := s.createChatCompletion(ctx, messages, attemptConfig)
+		if err == nil {
+			return resp, nil
+		}
+		attempts = append(attempts, FallbackAttempt{Model: model, Err: err})
+
+		if i == len(chain)-1 || !config.shouldFallback(ctx, err) {
+			break
+		}
+		s.logger.Warn("Chat completion failed, falling back to next model",
+			zap.String("model", model),
+			zap.String("next_model", chain[i+1]),
+			zap.String("error_class", string(types.ClassifyError(err))),
+			zap.Error(err))
+	}
+
+	if len(attempts) == 1 {
+		return nil, attempts[0].Err
+	}
+	return nil, &FallbackError{Attempts: attempts}
+}
+
+// openFallbackStream 打开支持模型降级的流
+func (s *ChatService) openFallbackStream(ctx context.Context, messages []types.ChatMessage, config *ChatConfig) (*fallbackStream, error) {
+	stream := &fallbackStream{
+		service:  s,
+		ctx:      ctx,
+		messages: messages,
+		config:   config,
+		chain:    config.fallbackChain(),
+	}
+	if err := stream.open(nil); err != nil {
+		return nil, err
+	}
+	return stream, nil
+}
+
+// fallbackStream 支持模型降级的流。在第一个Token到达之前缓存事件，
+// 此时出现可降级的错误会切换到下一个模型，调用方不会看到失败模型的任何输出
+type fallbackStream struct {
+	service  *ChatService
+	ctx      context.Context
+	messages []types.ChatMessage
+	config   *ChatConfig
+	chain    []string
+
+	mu       sync.Mutex
+	current  modelStream
+	next     int
+	attempts []FallbackAttempt
+	pending  []*types.StreamEvent
+	emitted  bool
+	done     bool
+	closed   bool
+	err      error
+}
+
+// open 打开降级链中的下一个可用模型，cause为导致切换的错误
+func (f *fallbackStream) open(cause error) error {
+	if cause != nil {
+		f.attempts = append(f.attempts, FallbackAttempt{Model: f.current.ServedModel(), Err: cause})
+	}
+
+	for f.next < len(f.chain) {
+		model := f.chain[f.next]
+		f.next++
+
+		if cause != nil {
+			f.service.logger.Warn("Chat completion stream failed before first token, falling back to next model",
+				zap.String("next_model", model),
+				zap.String("error_class", string(types.ClassifyError(cause))),
+				zap.Error(cause))
+		}
+
+		attemptConfig := f.config.Clone()
+		attemptConfig.Model = model
+		stream, err := f.service.openResumableStream(f.ctx, f.messages, attemptConfig)
+		if err == nil {
+			f.mu.Lock()
+			f.current = stream
+			f.pending = nil
+			closed := f.closed
+			f.mu.Unlock()
+			if closed {
+				stream.Close()
+			}
+			return nil
+		}
+
+		f.attempts = append(f.attempts, FallbackAttempt{Model: model, Err: err})
+		if !f.config.shouldFallback(f.ctx, err) {
+			break
+		}
+		cause = err
+	}
+
+	if len(f.attempts) == 1 {
+		return f.attempts[0].Err
+	}
+	return &FallbackError{Attempts: f.attempts}
+}
+
+// Next 获取下一个事件
+func (f *fallbackStream) Next() (*types.StreamEvent, error) {
+	for {
+		if f.emitted && len(f.pending) > 0 {
+			event := f.pending[0]
+			f.pending = f.pending[1:]
+			return event, nil
+		}
+
+		event, err := f.current.Next()
+		if err == nil && !f.emitted {
+			err = streamEventError(event)
+		}
+
+		if err != nil {
+			if err == io.EOF && len(f.pending) > 0 {
+				// 流正常结束但没有Token，原样交付缓存的事件
+				f.emitted = true
+				continue
+			}
+			if !f.emitted && err != io.EOF && f.next < len(f.chain) && f.config.shouldFallback(f.ctx, err) {
+				f.current.Close()
+				if openErr := f.open(err); openErr != nil {
+					return nil, f.finish(openErr)
+				}
+				continue
+			}
+			return nil, f.finish(err)
+		}
+
+		if f.emitted {
+			return event, nil
+		}
+		f.pending = append(f.pending, event)
+		f.emitted = streamEventHasToken(event)
+	}
+}
+
+// finish 记录流结束状态
+func (f *fallbackStream) finish(err error) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.done = true
+	if err != io.EOF {
+		f.err = err
+	}
+	return err
+}
+
+// Close 关闭流
+func (f *fallbackStream) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.closed = true
+	f.done = true
+	if f.current != nil {
+		return f.current.Close()
+	}
+	return nil
+}
+
+// Err 获取错误
+func (f *fallbackStream) Err() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.err
+}
+
+// Done 检查是否完成
+func (f *fallbackStream) Done() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.done
+}
+
+// Context 获取上下文
+func (f *fallbackStream) Context() context.Context {
+	return f.ctx
+}
+
+// ServedModel 获取当前处理请求的模型
+func (f *fallbackStream) ServedModel() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.current == nil {
+		return ""
+	}
+	return f.current.ServedModel()
+}
+
+// streamEventProbe 用于检查流式事件内容的结构
+type streamEventProbe struct {
+	Choices []types.ChatCompletionChunkChoice `json:"choices"`
+	Error   *types.ErrorResponse              `json:"error"`
+}
+
+// streamEventError 检查流式事件中是否携带错误体
+func streamEventError(event *types.StreamEvent) error {
+	if event == nil || event.Type != types.StreamEventTypeData {
+		return nil
+	}
+
+	var probe streamEventProbe
+	if err := json.Unmarshal(event.Data, &probe); err != nil || probe.Error == nil {
+		return nil
+	}
+	return types.NewAPIError(probe.Error.Type, probe.Error.Code, probe.Error.Message, 0).WithParam(probe.Error.Param)
+}
+
+// streamEventHasToken 检查流式事件是否包含模型输出
+func streamEventHasToken(event *types.StreamEvent) bool {
+	if event == nil || event.Type != types.StreamEventTypeData {
+		return false
+	}
+
+	var probe streamEventProbe
+	if err := json.Unmarshal(event.Data, &probe); err != nil {
+		// 无法解析的数据无法判断，保守地视为已输出
+		return true
+	}
+	for i := range probe.Choices {
+		choice := &probe.Choices[i]
+		if choice.GetContent() != "" || choice.GetReasoningContent() != "" ||
+			choice.Delta.Refusal != "" || choice.Delta.Audio != nil || len(choice.Delta.ToolCalls) > 0 {
+			return true
+		}
+	}
+	return false
+}
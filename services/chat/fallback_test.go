@@ -0,0 +1,149 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/hewenyu/newapi-go/types"
+)
+
+func TestCreateChatCompletionFallsBack(t *testing.T) {
+	service, fake := newFakeService(t,
+		errorReply(http.StatusServiceUnavailable, types.ErrTypeAPIError, "", "model overloaded"),
+		errorReply(http.StatusTooManyRequests, types.ErrTypeRateLimit, "", "slow down"),
+		textResponse("ok"),
+	)
+
+	resp, err := service.CreateChatCompletion(context.Background(),
+		[]types.ChatMessage{types.NewUserMessage("hi")},
+		WithModel("gemini-2.5-pro"),
+		WithFallbackModels("gpt-4.1-mini", "glm-4-flash"),
+	)
+	if err != nil {
+		t.Fatalf("CreateChatCompletion failed: %v", err)
+	}
+	if resp.ServedModel != "glm-4-flash" {
+		t.Errorf("ServedModel = %q, want glm-4-flash", resp.ServedModel)
+	}
+
+	want := []string{"gemini-2.5-pro", "gpt-4.1-mini", "glm-4-flash"}
+	for i, req := range fake.requests {
+		if req.Model != want[i] {
+			t.Errorf("request %d model = %q, want %q", i, req.Model, want[i])
+		}
+	}
+}
+
+func TestCreateChatCompletionDoesNotFallBackOnClientError(t *testing.T) {
+	service, fake := newFakeService(t,
+		errorReply(http.StatusUnauthorized, types.ErrTypeAuthentication, types.ErrCodeInvalidAPIKey, "bad key"),
+	)
+
+	_, err := service.CreateChatCompletion(context.Background(),
+		[]types.ChatMessage{types.NewUserMessage("hi")},
+		WithFallbackModels("gpt-4.1-mini"),
+	)
+
+	var apiErr *types.APIError
+	if !errors.As(err, &apiErr) || apiErr.Class() != types.ErrorClassAuthentication {
+		t.Fatalf("expected authentication APIError, got %v", err)
+	}
+	if len(fake.requests) != 1 {
+		t.Errorf("expected 1 request, got %d", len(fake.requests))
+	}
+}
+
+func TestCreateChatCompletionFallbackExhausted(t *testing.T) {
+	service, _ := newFakeService(t,
+		errorReply(http.StatusBadRequest, types.ErrTypeInvalidRequest, types.ErrCodeContextLengthExceeded, "too long"),
+		errorReply(http.StatusBadRequest, types.ErrTypeInvalidRequest, types.ErrCodeContentFilter, "filtered"),
+	)
+
+	_, err := service.CreateChatCompletion(context.Background(),
+		[]types.ChatMessage{types.NewUserMessage("hi")},
+		WithFallbackModels("gpt-4.1-mini"),
+	)
+
+	var fallbackErr *FallbackError
+	if !errors.As(err, &fallbackErr) || len(fallbackErr.Attempts) != 2 {
+		t.Fatalf("expected FallbackError with 2 attempts, got %v", err)
+	}
+	if types.ClassifyError(err) != types.ErrorClassContentFilter {
+		t.Errorf("expected last error to be content filter, got %s", types.ClassifyError(err))
+	}
+}
+
+func TestStreamFallsBackBeforeFirstToken(t *testing.T) {
+	service, fake := newFakeService(t,
+		errorReply(http.StatusServiceUnavailable, types.ErrTypeAPIError, "", "overloaded"),
+		rawReply{
+			status:      http.StatusOK,
+			contentType: "text/event-stream",
+			body: "data: {\"choices\":[{\"index\":0,\"delta\":{\"role\":\"assistant\"}}]}\n\n" +
+				"data: {\"error\":{\"type\":\"rate_limit_error\",\"message\":\"busy\"}}\n\n",
+		},
+		textChunks("Hel", "lo"),
+	)
+
+	stream, err := service.CreateChatCompletionStream(context.Background(),
+		[]types.ChatMessage{types.NewUserMessage("hi")},
+		WithModel("gemini-2.5-pro"),
+		WithFallbackModels("gpt-4.1-mini", "glm-4-flash"),
+	)
+	if err != nil {
+		t.Fatalf("CreateChatCompletionStream failed: %v", err)
+	}
+	processor := stream.(*ChatStreamProcessor)
+	for {
+		if _, err := processor.Next(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("stream error: %v", err)
+		}
+	}
+
+	if len(fake.requests) != 3 {
+		t.Fatalf("expected 3 requests, got %d", len(fake.requests))
+	}
+	resp := processor.CollectResponse()
+	if resp.GetFirstContent() != "Hello" || resp.ServedModel != "glm-4-flash" {
+		t.Errorf("unexpected response: content=%q served=%q", resp.GetFirstContent(), resp.ServedModel)
+	}
+}
+
+func TestStreamDoesNotFallBackAfterFirstToken(t *testing.T) {
+	service, fake := newFakeService(t,
+		rawReply{
+			status:      http.StatusOK,
+			contentType: "text/event-stream",
+			body: "data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"partial\"}}]}\n\n" +
+				"data: {\"error\":{\"type\":\"rate_limit_error\",\"message\":\"busy\"}}\n\n" +
+				"data: [DONE]\n\n",
+		},
+		textChunks("unused"),
+	)
+
+	stream, err := service.CreateChatCompletionStream(context.Background(),
+		[]types.ChatMessage{types.NewUserMessage("hi")},
+		WithFallbackModels("gpt-4.1-mini"),
+	)
+	if err != nil {
+		t.Fatalf("CreateChatCompletionStream failed: %v", err)
+	}
+	processor := stream.(*ChatStreamProcessor)
+	for {
+		if _, err := processor.Next(); err != nil {
+			break
+		}
+	}
+
+	if len(fake.requests) != 1 {
+		t.Errorf("expected no fallback after first token, got %d requests", len(fake.requests))
+	}
+	if processor.CollectContent() != "partial" {
+		t.Errorf("unexpected content: %q", processor.CollectContent())
+	}
+}
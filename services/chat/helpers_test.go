@@ -0,0 +1,139 @@
+package chat
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/hewenyu/newapi-go/internal/transport"
+	"github.com/hewenyu/newapi-go/internal/utils"
+	"github.com/hewenyu/newapi-go/types"
+)
+
+// fakeServer 模拟聊天完成接口，按顺序返回预设的回复并记录收到的请求
+type fakeServer struct {
+	t        *testing.T
+	mu       sync.Mutex
+	replies  []interface{}
+	requests []types.ChatCompletionRequest
+}
+
+// ServeHTTP 返回队列中的下一个回复，[]types.ChatCompletionChunk 以SSE流返回
+func (f *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req types.ChatCompletionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		f.t.Errorf("failed to decode request: %v", err)
+	}
+
+	f.mu.Lock()
+	f.requests = append(f.requests, req)
+	if len(f.replies) == 0 {
+		f.mu.Unlock()
+		http.Error(w, `{"error":{"message":"no reply queued"}}`, http.StatusNotImplemented)
+		return
+	}
+	reply := f.replies[0]
+	f.replies = f.replies[1:]
+	f.mu.Unlock()
+
+	if raw, ok := reply.(rawReply); ok {
+		w.Header().Set("Content-Type", raw.contentType)
+		w.WriteHeader(raw.status)
+		fmt.Fprint(w, raw.body)
+		return
+	}
+
+	if chunks, ok := reply.([]types.ChatCompletionChunk); ok {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range chunks {
+			data, _ := json.Marshal(chunk)
+			fmt.Fprintf(w, "data: %s\n\n", data)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reply)
+}
+
+// rawReply 原样返回的回复
+type rawReply struct {
+	status      int
+	contentType string
+	body        string
+}
+
+// errorReply 构造OpenAI格式的错误回复
+func errorReply(status int, errType, code, message string) rawReply {
+	body, _ := json.Marshal(map[string]interface{}{
+		"error": types.ErrorResponse{Type: errType, Code: code, Message: message},
+	})
+	return rawReply{status: status, contentType: "application/json", body: string(body)}
+}
+
+// newFakeService 创建连接到模拟服务器的聊天服务
+func newFakeService(t *testing.T, replies ...interface{}) (*ChatService, *fakeServer) {
+	fake := &fakeServer{t: t, replies: replies}
+	return newHandlerService(t, fake), fake
+}
+
+// newHandlerService 创建连接到自定义处理函数的聊天服务
+func newHandlerService(t *testing.T, handler http.Handler) *ChatService {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	httpTransport := transport.NewHTTPClient(server.URL, "test-key")
+	return NewChatService(httpTransport, utils.GetLogger())
+}
+
+// textResponse 构造文本回复
+func textResponse(content string) *types.ChatCompletionResponse {
+	return &types.ChatCompletionResponse{
+		ID:    "chatcmpl-test",
+		Model: "gpt-4o",
+		Choices: []types.ChatCompletionChoice{{
+			Message:      types.NewAssistantMessage(content),
+			FinishReason: types.FinishReasonStop,
+		}},
+	}
+}
+
+// toolCallResponse 构造工具调用回复
+func toolCallResponse(id, name, arguments string) *types.ChatCompletionResponse {
+	return &types.ChatCompletionResponse{
+		ID:    "chatcmpl-tool",
+		Model: "gpt-4o",
+		Choices: []types.ChatCompletionChoice{{
+			Message: types.ChatMessage{
+				Role: types.ChatRoleAssistant,
+				ToolCalls: []types.ToolCall{{
+					ID:       id,
+					Type:     types.ToolCallTypeFunction,
+					Function: types.FunctionCall{Name: name, Arguments: arguments},
+				}},
+			},
+			FinishReason: types.FinishReasonToolCalls,
+		}},
+	}
+}
+
+// textChunks 构造流式文本块
+func textChunks(deltas ...string) []types.ChatCompletionChunk {
+	chunks := make([]types.ChatCompletionChunk, 0, len(deltas))
+	for i, delta := range deltas {
+		message := types.ChatMessage{Content: delta}
+		if i == 0 {
+			message.Role = types.ChatRoleAssistant
+		}
+		chunks = append(chunks, types.ChatCompletionChunk{
+			ID:      "chatcmpl-stream",
+			Model:   "gpt-4o",
+			Choices: []types.ChatCompletionChunkChoice{{Delta: message}},
+		})
+	}
+	return chunks
+}
@@ -0,0 +1,235 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/hewenyu/newapi-go/types"
+	"go.uber.org/zap"
+)
+
+// Moderator 内容审核接口，moderation.ModerationService实现了该接口
+type Moderator interface {
+	// ModerateContent 将一组输入项作为同一内容整体审核
+	ModerateContent(ctx context.Context, inputs []types.ModerationInput) (*types.ModerationResult, error)
+}
+
+// ModerationScope 内容审核范围
+type ModerationScope string
+
+// 内容审核范围常量
+const (
+	ModerateInput  ModerationScope = "input"
+	ModerateOutput ModerationScope = "output"
+	ModerateBoth   ModerationScope = "both"
+)
+
+// ModerationAction 内容超出阈值时的处理方式
+type ModerationAction string
+
+// 内容审核处理方式常量
+const (
+	// ModerationBlock 返回ModerationError，审核服务出错时请求同样失败
+	ModerationBlock ModerationAction = "block"
+	// ModerationAnnotate 正常返回并在响应的Moderation字段中标注，审核服务出错时只记录日志
+	ModerationAnnotate ModerationAction = "annotate"
+)
+
+// 内容审核阶段常量
+const (
+	ModerationStageInput  = "input"
+	ModerationStageOutput = "output"
+)
+
+// ModerationError 内容超出审核阈值时返回的错误
+type ModerationError struct {
+	Stage   string
+	Verdict *types.ModerationVerdict
+}
+
+// Error 实现error接口
+func (e *ModerationError) Error() string {
+	categories := make([]string, len(e.Verdict.Categories))
+	for i, category := range e.Verdict.Categories {
+		categories[i] = string(category)
+	}
+	return fmt.Sprintf("%s blocked by moderation: %s", e.Stage, strings.Join(categories, ", "))
+}
+
+// includes 检查审核范围是否包含指定阶段
+func (s ModerationScope) includes(stage string) bool {
+	return s == ModerateBoth || string(s) == stage
+}
+
+// moderationEnabled 检查是否启用了内容审核
+func (c *ChatConfig) moderationEnabled() bool {
+	return c.Moderator != nil
+}
+
+// moderationInputs 提取新一轮用户输入（最后一条助手消息之后的用户消息）的文本和图片
+func moderationInputs(messages []types.ChatMessage) []types.ModerationInput {
+	start := 0
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == types.ChatRoleAssistant {
+			start = i + 1
+			break
+		}
+	}
+
+	var inputs []types.ModerationInput
+	for _, message := range messages[start:] {
+		if message.Role != types.ChatRoleUser {
+			continue
+		}
+		for _, part := range message.GetContentParts() {
+			switch {
+			case part.Type == types.ChatMessageTypeText && strings.TrimSpace(part.Text) != "":
+				inputs = append(inputs, types.NewModerationTextInput(part.Text))
+			case part.Type == types.ChatMessageTypeImageURL && part.ImageURL != nil && part.ImageURL.URL != "":
+				inputs = append(inputs, types.NewModerationImageInput(part.ImageURL.URL))
+			}
+		}
+	}
+	return inputs
+}
+
+// moderate 审核内容并按配置的处理方式返回结论，阻断时返回ModerationError
+func (s *ChatService) moderate(ctx context.Context, config *ChatConfig, stage string, inputs []types.ModerationInput) (*types.ModerationVerdict, error) {
+	if len(inputs) == 0 {
+		return nil, nil
+	}
+
+	result, err := config.Moderator.ModerateContent(ctx, inputs)
+	if err != nil {
+		if config.ModerationAction == ModerationAnnotate {
+			s.logger.Warn("Content moderation failed", zap.String("stage", stage), zap.Error(err))
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to moderate %s: %w", stage, err)
+	}
+
+	verdict := result.Evaluate(config.ModerationThresholds)
+	if verdict.Flagged && config.ModerationAction != ModerationAnnotate {
+		return nil, &ModerationError{Stage: stage, Verdict: verdict}
+	}
+	return verdict, nil
+}
+
+// moderateInput 按配置审核用户输入，未启用时返回nil
+func (s *ChatService) moderateInput(ctx context.Context, messages []types.ChatMessage, config *ChatConfig) (*types.ModerationAnnotation, error) {
+	if !config.moderationEnabled() {
+		return nil, nil
+	}
+
+	annotation := &types.ModerationAnnotation{}
+	if config.ModerationScope.includes(ModerationStageInput) {
+		verdict, err := s.moderate(ctx, config, ModerationStageInput, moderationInputs(messages))
+		if err != nil {
+			return nil, err
+		}
+		annotation.Input = verdict
+	}
+	return annotation, nil
+}
+
+// moderateOutput 按配置审核模型输出并写入标注
+func (s *ChatService) moderateOutput(ctx context.Context, resp *types.ChatCompletionResponse, config *ChatConfig, annotation *types.ModerationAnnotation) error {
+	if annotation == nil {
+		return nil
+	}
+
+	if config.ModerationScope.includes(ModerationStageOutput) {
+		var inputs []types.ModerationInput
+		for _, choice := range resp.Choices {
+			if text := choice.Message.GetTextContent(); strings.TrimSpace(text) != "" {
+				inputs = append(inputs, types.NewModerationTextInput(text))
+			}
+		}
+		verdict, err := s.moderate(ctx, config, ModerationStageOutput, inputs)
+		if err != nil {
+			return err
+		}
+		annotation.Output = verdict
+	}
+
+	resp.Moderation = annotation
+	return nil
+}
+
+// moderatingStream 在流结束时审核完整输出。阻断时Next返回ModerationError而不是io.EOF，
+// 此时已交付的内容应由调用方撤回
+type moderatingStream struct {
+	stream     types.StreamResponse
+	service    *ChatService
+	config     *ChatConfig
+	annotation *types.ModerationAnnotation
+	chunks     []types.ChatCompletionChunk
+	checked    bool
+}
+
+// Next 获取下一个事件
+func (m *moderatingStream) Next() (*types.StreamEvent, error) {
+	event, err := m.stream.Next()
+	if err == io.EOF && !m.checked {
+		m.checked = true
+		if len(m.chunks) > 0 {
+			if modErr := m.service.moderateOutput(m.stream.Context(), mergeChunks(m.chunks), m.config, m.annotation); modErr != nil {
+				return nil, modErr
+			}
+		}
+		return nil, err
+	}
+	if err != nil || !m.config.ModerationScope.includes(ModerationStageOutput) || event.Type != types.StreamEventTypeData {
+		return event, err
+	}
+
+	var chunk types.ChatCompletionChunk
+	if json.Unmarshal(event.Data, &chunk) == nil {
+		m.chunks = append(m.chunks, chunk)
+	}
+	return event, nil
+}
+
+// Close 关闭流
+func (m *moderatingStream) Close() error {
+	return m.stream.Close()
+}
+
+// Err 获取错误
+func (m *moderatingStream) Err() error {
+	return m.stream.Err()
+}
+
+// Done 检查是否完成
+func (m *moderatingStream) Done() bool {
+	return m.stream.Done()
+}
+
+// Context 获取上下文
+func (m *moderatingStream) Context() context.Context {
+	return m.stream.Context()
+}
+
+// ServedModel 获取实际处理请求的模型
+func (m *moderatingStream) ServedModel() string {
+	if reporter, ok := m.stream.(interface{ ServedModel() string }); ok {
+		return reporter.ServedModel()
+	}
+	return ""
+}
+
+// CacheStatus 获取缓存状态
+func (m *moderatingStream) CacheStatus() string {
+	if reporter, ok := m.stream.(interface{ CacheStatus() string }); ok {
+		return reporter.CacheStatus()
+	}
+	return ""
+}
+
+// Moderation 获取审核标注，输出审核结果在流结束后可用
+func (m *moderatingStream) Moderation() *types.ModerationAnnotation {
+	return m.annotation
+}
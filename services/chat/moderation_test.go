@@ -0,0 +1,141 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/hewenyu/newapi-go/types"
+)
+
+// fakeModerator 按文本内容返回审核结果
+type fakeModerator struct {
+	mu     sync.Mutex
+	scores map[string]float64
+	calls  [][]types.ModerationInput
+}
+
+// ModerateContent 文本在scores中时返回对应的暴力类别得分
+func (f *fakeModerator) ModerateContent(ctx context.Context, inputs []types.ModerationInput) (*types.ModerationResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, inputs)
+
+	result := &types.ModerationResult{
+		Categories:     map[types.ModerationCategory]bool{},
+		CategoryScores: map[types.ModerationCategory]float64{},
+	}
+	for _, input := range inputs {
+		score := f.scores[input.Text]
+		if score > result.CategoryScores[types.ModerationViolence] {
+			result.CategoryScores[types.ModerationViolence] = score
+		}
+		if score >= 0.5 {
+			result.Flagged = true
+			result.Categories[types.ModerationViolence] = true
+		}
+	}
+	return result, nil
+}
+
+func TestModerationBlocksInput(t *testing.T) {
+	service, fake := newFakeService(t, textResponse("never sent"))
+	moderator := &fakeModerator{scores: map[string]float64{"bad": 0.9}}
+
+	history := []types.ChatMessage{
+		types.NewUserMessage("bad"),
+		types.NewAssistantMessage("earlier answer"),
+		types.NewUserMessage("fine question"),
+	}
+	_, err := service.CreateChatCompletion(context.Background(), history,
+		WithModeration(moderator, ModerateInput, ModerationBlock))
+	if err != nil {
+		t.Fatalf("history before the last assistant message must not be moderated: %v", err)
+	}
+	if len(moderator.calls) != 1 || len(moderator.calls[0]) != 1 || moderator.calls[0][0].Text != "fine question" {
+		t.Errorf("moderated inputs = %+v", moderator.calls)
+	}
+
+	_, err = service.CreateChatCompletion(context.Background(), []types.ChatMessage{types.NewUserMessage("bad")},
+		WithModeration(moderator, ModerateInput, ModerationBlock))
+	var modErr *ModerationError
+	if !errors.As(err, &modErr) || modErr.Stage != ModerationStageInput {
+		t.Fatalf("err = %v, want input ModerationError", err)
+	}
+	if len(fake.requests) != 1 {
+		t.Errorf("server received %d requests, want 1", len(fake.requests))
+	}
+}
+
+func TestModerationAnnotatesOutput(t *testing.T) {
+	service, _ := newFakeService(t, textResponse("violent reply"), textResponse("violent reply"))
+	moderator := &fakeModerator{scores: map[string]float64{"violent reply": 0.3}}
+	ctx := context.Background()
+	messages := []types.ChatMessage{types.NewUserMessage("hi")}
+
+	resp, err := service.CreateChatCompletion(ctx, messages,
+		WithModeration(moderator, ModerateBoth, ModerationAnnotate),
+		WithModerationThresholds(map[types.ModerationCategory]float64{types.ModerationViolence: 0.2}))
+	if err != nil {
+		t.Fatalf("annotate must not fail the request: %v", err)
+	}
+	if resp.Moderation == nil || resp.Moderation.Input == nil || resp.Moderation.Input.Flagged {
+		t.Fatalf("input annotation = %+v", resp.Moderation)
+	}
+	output := resp.Moderation.Output
+	if output == nil || !output.Flagged || len(output.Categories) != 1 || output.Categories[0] != types.ModerationViolence {
+		t.Errorf("output annotation = %+v", output)
+	}
+
+	_, err = service.CreateChatCompletion(ctx, messages,
+		WithModeration(moderator, ModerateOutput, ModerationBlock),
+		WithModerationThresholds(map[types.ModerationCategory]float64{types.ModerationViolence: 0.2}))
+	var modErr *ModerationError
+	if !errors.As(err, &modErr) || modErr.Stage != ModerationStageOutput {
+		t.Errorf("err = %v, want output ModerationError", err)
+	}
+}
+
+func TestModerationBlocksStreamOutputAtEnd(t *testing.T) {
+	service, _ := newFakeService(t, textChunks("bad ", "words"))
+	moderator := &fakeModerator{scores: map[string]float64{"bad words": 0.8}}
+
+	stream, err := service.CreateChatCompletionStream(context.Background(), []types.ChatMessage{types.NewUserMessage("hi")},
+		WithModeration(moderator, ModerateOutput, ModerationBlock))
+	if err != nil {
+		t.Fatalf("stream failed: %v", err)
+	}
+	defer stream.Close()
+
+	var events int
+	for {
+		_, err = stream.Next()
+		if err != nil {
+			break
+		}
+		events++
+	}
+	var modErr *ModerationError
+	if err == io.EOF || !errors.As(err, &modErr) {
+		t.Fatalf("stream ended with %v, want ModerationError", err)
+	}
+	if events != 2 {
+		t.Errorf("received %d events before moderation, want 2", events)
+	}
+}
+
+func TestModerationConfigValidation(t *testing.T) {
+	config := DefaultChatConfig()
+	WithModeration(&fakeModerator{}, "everything", ModerationBlock)(config)
+	if err := config.Validate(); err == nil {
+		t.Error("expected error for invalid moderation scope")
+	}
+
+	config = DefaultChatConfig()
+	WithModerationThresholds(map[types.ModerationCategory]float64{types.ModerationHate: 1.5})(config)
+	if err := config.Validate(); err == nil {
+		t.Error("expected error for threshold above 1")
+	}
+}
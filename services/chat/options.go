@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/hewenyu/newapi-go/cache"
 	"github.com/hewenyu/newapi-go/types"
 )
 
@@ -11,46 +12,63 @@ import (
 type ChatOption func(*ChatConfig)
 
 // ChatConfig 聊天配置结构
+// 采样参数使用指针类型，nil 表示不发送该参数
 type ChatConfig struct {
-	Model            string                    `json:"model"`
-	MaxTokens        int                       `json:"max_tokens"`
-	Temperature      float64                   `json:"temperature"`
-	TopP             float64                   `json:"top_p"`
-	N                int                       `json:"n"`
-	Stream           bool                      `json:"stream"`
-	Stop             interface{}               `json:"stop"`
-	PresencePenalty  float64                   `json:"presence_penalty"`
-	FrequencyPenalty float64                   `json:"frequency_penalty"`
-	LogitBias        map[string]float64        `json:"logit_bias"`
-	User             string                    `json:"user"`
-	Functions        []types.ChatFunction      `json:"functions"`
-	FunctionCall     interface{}               `json:"function_call"`
-	Tools            []types.Tool              `json:"tools"`
-	ToolChoice       interface{}               `json:"tool_choice"`
-	ResponseFormat   *types.ChatResponseFormat `json:"response_format"`
-	Seed             int                       `json:"seed"`
-	LogProbs         bool                      `json:"logprobs"`
-	TopLogProbs      int                       `json:"top_logprobs"`
-	Timeout          time.Duration             `json:"timeout"`
-	ExtraBody        map[string]interface{}    `json:"extra_body"`
+	Model               string                    `json:"model"`
+	MaxTokens           int                       `json:"max_tokens"`
+	MaxCompletionTokens int                       `json:"max_completion_tokens"`
+	Temperature         *float64                  `json:"temperature"`
+	TopP                *float64                  `json:"top_p"`
+	N                   int                       `json:"n"`
+	Stream              bool                      `json:"stream"`
+	StreamOptions       *types.ChatStreamOptions  `json:"stream_options"`
+	Stop                interface{}               `json:"stop"`
+	PresencePenalty     *float64                  `json:"presence_penalty"`
+	FrequencyPenalty    *float64                  `json:"frequency_penalty"`
+	LogitBias           map[string]float64        `json:"logit_bias"`
+	User                string                    `json:"user"`
+	Functions           []types.ChatFunction      `json:"functions"`
+	FunctionCall        interface{}               `json:"function_call"`
+	Tools               []types.Tool              `json:"tools"`
+	ToolChoice          interface{}               `json:"tool_choice"`
+	ParallelToolCalls   *bool                     `json:"parallel_tool_calls"`
+	ResponseFormat      *types.ChatResponseFormat `json:"response_format"`
+	Seed                *int                      `json:"seed"`
+	LogProbs            bool                      `json:"logprobs"`
+	TopLogProbs         int                       `json:"top_logprobs"`
+	ReasoningEffort     string                    `json:"reasoning_effort"`
+	Store               *bool                     `json:"store"`
+	Metadata            map[string]string         `json:"metadata"`
+	Modalities          []string                  `json:"modalities"`
+	Audio               *types.ChatAudioParams    `json:"audio"`
+	Prediction          *types.ChatPrediction     `json:"prediction"`
+	ServiceTier         string                    `json:"service_tier"`
+	Timeout             time.Duration             `json:"timeout"`
+	ExtraBody           map[string]interface{}    `json:"extra_body"`
+	FallbackModels      []string                  `json:"fallback_models"`
+	FallbackOn          []types.ErrorClass        `json:"fallback_on"`
+	CachePolicy         cache.Policy              `json:"cache_policy"`
+	StreamResumeRetries int                       `json:"stream_resume_retries"`
+	StreamResumeDelay   time.Duration             `json:"stream_resume_delay"`
+
+	// 内容审核配置
+	Moderator            Moderator                            `json:"-"`
+	ModerationScope      ModerationScope                      `json:"moderation_scope"`
+	ModerationAction     ModerationAction                     `json:"moderation_action"`
+	ModerationThresholds map[types.ModerationCategory]float64 `json:"moderation_thresholds"`
 }
 
 // DefaultChatConfig 返回默认的聊天配置
 func DefaultChatConfig() *ChatConfig {
 	return &ChatConfig{
-		Model:            "gpt-3.5-turbo",
-		MaxTokens:        1000,
-		Temperature:      1.0,
-		TopP:             1.0,
-		N:                1,
-		Stream:           false,
-		PresencePenalty:  0.0,
-		FrequencyPenalty: 0.0,
-		LogitBias:        make(map[string]float64),
-		LogProbs:         false,
-		TopLogProbs:      0,
-		Timeout:          30 * time.Second,
-		ExtraBody:        make(map[string]interface{}),
+		Model:     "gpt-3.5-turbo",
+		MaxTokens: 1000,
+		N:         1,
+		Stream:    false,
+		LogitBias: make(map[string]float64),
+		LogProbs:  false,
+		Timeout:   30 * time.Second,
+		ExtraBody: make(map[string]interface{}),
 	}
 }
 
@@ -68,17 +86,25 @@ func WithMaxTokens(maxTokens int) ChatOption {
 	}
 }
 
+// WithMaxCompletionTokens 设置补全最大Token数量（包含推理Token）
+// 设置后请求中不再发送 max_tokens，o系列模型不支持 max_tokens
+func WithMaxCompletionTokens(maxCompletionTokens int) ChatOption {
+	return func(config *ChatConfig) {
+		config.MaxCompletionTokens = maxCompletionTokens
+	}
+}
+
 // WithTemperature 设置温度参数
 func WithTemperature(temperature float64) ChatOption {
 	return func(config *ChatConfig) {
-		config.Temperature = temperature
+		config.Temperature = &temperature
 	}
 }
 
 // WithTopP 设置TopP参数
 func WithTopP(topP float64) ChatOption {
 	return func(config *ChatConfig) {
-		config.TopP = topP
+		config.TopP = &topP
 	}
 }
 
@@ -96,6 +122,18 @@ func WithStream(stream bool) ChatOption {
 	}
 }
 
+// WithStreamOptions 设置流式响应选项，仅在流式请求中发送
+func WithStreamOptions(options *types.ChatStreamOptions) ChatOption {
+	return func(config *ChatConfig) {
+		config.StreamOptions = options
+	}
+}
+
+// WithStreamIncludeUsage 设置流式响应在结束前返回使用量统计
+func WithStreamIncludeUsage() ChatOption {
+	return WithStreamOptions(&types.ChatStreamOptions{IncludeUsage: true})
+}
+
 // WithStop 设置停止序列
 func WithStop(stop interface{}) ChatOption {
 	return func(config *ChatConfig) {
@@ -106,14 +144,14 @@ func WithStop(stop interface{}) ChatOption {
 // WithPresencePenalty 设置存在惩罚
 func WithPresencePenalty(penalty float64) ChatOption {
 	return func(config *ChatConfig) {
-		config.PresencePenalty = penalty
+		config.PresencePenalty = &penalty
 	}
 }
 
 // WithFrequencyPenalty 设置频率惩罚
 func WithFrequencyPenalty(penalty float64) ChatOption {
 	return func(config *ChatConfig) {
-		config.FrequencyPenalty = penalty
+		config.FrequencyPenalty = &penalty
 	}
 }
 
@@ -159,6 +197,13 @@ func WithToolChoice(toolChoice interface{}) ChatOption {
 	}
 }
 
+// WithParallelToolCalls 设置是否允许并行工具调用
+func WithParallelToolCalls(parallel bool) ChatOption {
+	return func(config *ChatConfig) {
+		config.ParallelToolCalls = &parallel
+	}
+}
+
 // WithResponseFormat 设置响应格式
 func WithResponseFormat(format *types.ChatResponseFormat) ChatOption {
 	return func(config *ChatConfig) {
@@ -169,7 +214,7 @@ func WithResponseFormat(format *types.ChatResponseFormat) ChatOption {
 // WithSeed 设置随机种子
 func WithSeed(seed int) ChatOption {
 	return func(config *ChatConfig) {
-		config.Seed = seed
+		config.Seed = &seed
 	}
 }
 
@@ -187,6 +232,74 @@ func WithTopLogProbs(topLogProbs int) ChatOption {
 	}
 }
 
+// WithReasoningEffort 设置推理强度（适用于o系列等推理模型）
+func WithReasoningEffort(effort string) ChatOption {
+	return func(config *ChatConfig) {
+		config.ReasoningEffort = effort
+	}
+}
+
+// WithStore 设置是否存储补全输出
+func WithStore(store bool) ChatOption {
+	return func(config *ChatConfig) {
+		config.Store = &store
+	}
+}
+
+// WithMetadata 设置请求元数据（最多16个键值对）
+func WithMetadata(metadata map[string]string) ChatOption {
+	return func(config *ChatConfig) {
+		config.Metadata = metadata
+	}
+}
+
+// WithMetadataValue 设置单个元数据键值，与已有元数据合并
+func WithMetadataValue(key, value string) ChatOption {
+	return func(config *ChatConfig) {
+		// 复制后再修改，避免改动调用方通过WithMetadata传入的map
+		metadata := make(map[string]string, len(config.Metadata)+1)
+		for k, v := range config.Metadata {
+			metadata[k] = v
+		}
+		metadata[key] = value
+		config.Metadata = metadata
+	}
+}
+
+// WithModalities 设置输出模态，例如 text、audio
+func WithModalities(modalities ...string) ChatOption {
+	return func(config *ChatConfig) {
+		config.Modalities = modalities
+	}
+}
+
+// WithAudioOutput 设置音频输出参数，并在输出模态中加入音频
+func WithAudioOutput(voice, format string) ChatOption {
+	return func(config *ChatConfig) {
+		config.Audio = &types.ChatAudioParams{
+			Voice:  voice,
+			Format: format,
+		}
+		if len(config.Modalities) == 0 {
+			config.Modalities = []string{types.ModalityText, types.ModalityAudio}
+		}
+	}
+}
+
+// WithPrediction 设置预测输出
+func WithPrediction(prediction *types.ChatPrediction) ChatOption {
+	return func(config *ChatConfig) {
+		config.Prediction = prediction
+	}
+}
+
+// WithServiceTier 设置服务层级
+func WithServiceTier(tier string) ChatOption {
+	return func(config *ChatConfig) {
+		config.ServiceTier = tier
+	}
+}
+
 // WithTimeout 设置超时时间
 func WithTimeout(timeout time.Duration) ChatOption {
 	return func(config *ChatConfig) {
@@ -194,37 +307,103 @@ func WithTimeout(timeout time.Duration) ChatOption {
 	}
 }
 
-// WithExtraBody 设置额外的请求体参数
+// WithExtraBody 设置额外的请求体参数，参数合并到请求体顶层并覆盖同名字段
 func WithExtraBody(extraBody map[string]interface{}) ChatOption {
 	return func(config *ChatConfig) {
 		config.ExtraBody = extraBody
 	}
 }
 
+// WithFallbackModels 设置降级模型列表，主模型请求失败时按顺序尝试
+func WithFallbackModels(models ...string) ChatOption {
+	return func(config *ChatConfig) {
+		config.FallbackModels = append([]string(nil), models...)
+	}
+}
+
+// WithFallbackOn 设置触发模型降级的错误分类，默认为DefaultFallbackClasses
+func WithFallbackOn(classes ...types.ErrorClass) ChatOption {
+	return func(config *ChatConfig) {
+		config.FallbackOn = append([]types.ErrorClass(nil), classes...)
+	}
+}
+
+// WithCachePolicy 设置响应缓存策略，仅在服务配置了缓存时生效。
+// 默认只缓存temperature为0的确定性请求，cache.PolicyForce强制缓存，cache.PolicyBypass跳过缓存
+func WithCachePolicy(policy cache.Policy) ChatOption {
+	return func(config *ChatConfig) {
+		config.CachePolicy = policy
+	}
+}
+
+// WithStreamResume 设置流式响应中断后的续写次数和间隔，maxRetries为0时关闭续写。
+// 服务端下发事件ID时传输层先通过Last-Event-ID重连，仍失败时以已输出内容为前缀发起续写请求
+func WithStreamResume(maxRetries int, delay time.Duration) ChatOption {
+	return func(config *ChatConfig) {
+		config.StreamResumeRetries = maxRetries
+		config.StreamResumeDelay = delay
+	}
+}
+
+// WithModeration 使用moderator审核用户输入、模型输出或两者，moderator为nil时关闭审核。
+// 用户输入只审核最后一条助手消息之后的用户消息，流式请求的输出在流结束时审核
+func WithModeration(moderator Moderator, scope ModerationScope, action ModerationAction) ChatOption {
+	return func(config *ChatConfig) {
+		config.Moderator = moderator
+		config.ModerationScope = scope
+		config.ModerationAction = action
+	}
+}
+
+// WithModerationThresholds 设置各审核类别的得分阈值，未设置的类别沿用服务端的标记
+func WithModerationThresholds(thresholds map[types.ModerationCategory]float64) ChatOption {
+	return func(config *ChatConfig) {
+		config.ModerationThresholds = make(map[types.ModerationCategory]float64, len(thresholds))
+		for category, threshold := range thresholds {
+			config.ModerationThresholds[category] = threshold
+		}
+	}
+}
+
 // ToRequest 将配置转换为请求结构
 func (c *ChatConfig) ToRequest(messages []types.ChatMessage) *types.ChatCompletionRequest {
 	req := &types.ChatCompletionRequest{
-		Model:            c.Model,
-		Messages:         messages,
-		MaxTokens:        c.MaxTokens,
-		Temperature:      c.Temperature,
-		TopP:             c.TopP,
-		N:                c.N,
-		Stream:           c.Stream,
-		Stop:             c.Stop,
-		PresencePenalty:  c.PresencePenalty,
-		FrequencyPenalty: c.FrequencyPenalty,
-		LogitBias:        c.LogitBias,
-		User:             c.User,
-		Functions:        c.Functions,
-		FunctionCall:     c.FunctionCall,
-		Tools:            c.Tools,
-		ToolChoice:       c.ToolChoice,
-		ResponseFormat:   c.ResponseFormat,
-		Seed:             c.Seed,
-		LogProbs:         c.LogProbs,
-		TopLogProbs:      c.TopLogProbs,
-		ExtraBody:        c.ExtraBody,
+		Model:               c.Model,
+		Messages:            types.StripReasoningContent(messages),
+		MaxTokens:           c.MaxTokens,
+		MaxCompletionTokens: c.MaxCompletionTokens,
+		Temperature:         c.Temperature,
+		TopP:                c.TopP,
+		N:                   c.N,
+		Stream:              c.Stream,
+		StreamOptions:       c.StreamOptions,
+		Stop:                c.Stop,
+		PresencePenalty:     c.PresencePenalty,
+		FrequencyPenalty:    c.FrequencyPenalty,
+		LogitBias:           c.LogitBias,
+		User:                c.User,
+		Functions:           c.Functions,
+		FunctionCall:        c.FunctionCall,
+		Tools:               c.Tools,
+		ToolChoice:          c.ToolChoice,
+		ParallelToolCalls:   c.ParallelToolCalls,
+		ResponseFormat:      c.ResponseFormat,
+		Seed:                c.Seed,
+		LogProbs:            c.LogProbs,
+		TopLogProbs:         c.TopLogProbs,
+		ReasoningEffort:     c.ReasoningEffort,
+		Store:               c.Store,
+		Metadata:            c.Metadata,
+		Modalities:          c.Modalities,
+		Audio:               c.Audio,
+		Prediction:          c.Prediction,
+		ServiceTier:         c.ServiceTier,
+		ExtraBody:           c.ExtraBody,
+	}
+
+	// max_completion_tokens 取代已弃用的 max_tokens
+	if req.MaxCompletionTokens > 0 {
+		req.MaxTokens = 0
 	}
 
 	// 设置默认值
@@ -263,6 +442,35 @@ func (c *ChatConfig) Clone() *ChatConfig {
 		copy(clone.Tools, c.Tools)
 	}
 
+	if c.Metadata != nil {
+		clone.Metadata = make(map[string]string, len(c.Metadata))
+		for k, v := range c.Metadata {
+			clone.Metadata[k] = v
+		}
+	}
+
+	if c.Modalities != nil {
+		clone.Modalities = make([]string, len(c.Modalities))
+		copy(clone.Modalities, c.Modalities)
+	}
+
+	if c.FallbackModels != nil {
+		clone.FallbackModels = make([]string, len(c.FallbackModels))
+		copy(clone.FallbackModels, c.FallbackModels)
+	}
+
+	if c.FallbackOn != nil {
+		clone.FallbackOn = make([]types.ErrorClass, len(c.FallbackOn))
+		copy(clone.FallbackOn, c.FallbackOn)
+	}
+
+	if c.ModerationThresholds != nil {
+		clone.ModerationThresholds = make(map[types.ModerationCategory]float64, len(c.ModerationThresholds))
+		for k, v := range c.ModerationThresholds {
+			clone.ModerationThresholds[k] = v
+		}
+	}
+
 	return &clone
 }
 
@@ -276,11 +484,15 @@ func (c *ChatConfig) Validate() error {
 		return fmt.Errorf("max_tokens must be non-negative")
 	}
 
-	if c.Temperature < 0.0 || c.Temperature > 2.0 {
+	if c.MaxCompletionTokens < 0 {
+		return fmt.Errorf("max_completion_tokens must be non-negative")
+	}
+
+	if c.Temperature != nil && (*c.Temperature < 0.0 || *c.Temperature > 2.0) {
 		return fmt.Errorf("temperature must be between 0.0 and 2.0")
 	}
 
-	if c.TopP < 0.0 || c.TopP > 1.0 {
+	if c.TopP != nil && (*c.TopP < 0.0 || *c.TopP > 1.0) {
 		return fmt.Errorf("top_p must be between 0.0 and 1.0")
 	}
 
@@ -288,16 +500,67 @@ func (c *ChatConfig) Validate() error {
 		return fmt.Errorf("n must be at least 1")
 	}
 
-	if c.PresencePenalty < -2.0 || c.PresencePenalty > 2.0 {
+	if c.PresencePenalty != nil && (*c.PresencePenalty < -2.0 || *c.PresencePenalty > 2.0) {
 		return fmt.Errorf("presence_penalty must be between -2.0 and 2.0")
 	}
 
-	if c.FrequencyPenalty < -2.0 || c.FrequencyPenalty > 2.0 {
+	if c.FrequencyPenalty != nil && (*c.FrequencyPenalty < -2.0 || *c.FrequencyPenalty > 2.0) {
 		return fmt.Errorf("frequency_penalty must be between -2.0 and 2.0")
 	}
 
-	if c.TopLogProbs < 0 || c.TopLogProbs > 5 {
-		return fmt.Errorf("top_logprobs must be between 0 and 5")
+	if c.TopLogProbs < 0 || c.TopLogProbs > 20 {
+		return fmt.Errorf("top_logprobs must be between 0 and 20")
+	}
+
+	if err := types.ValidateChatMetadata(c.Metadata); err != nil {
+		return fmt.Errorf("invalid metadata: %w", err)
+	}
+
+	if c.Audio != nil {
+		if err := c.Audio.Validate(); err != nil {
+			return fmt.Errorf("invalid audio output: %w", err)
+		}
+	}
+
+	for i, model := range c.FallbackModels {
+		if model == "" {
+			return fmt.Errorf("fallback_models[%d] cannot be empty", i)
+		}
+	}
+
+	if c.Moderator != nil {
+		switch c.ModerationScope {
+		case ModerateInput, ModerateOutput, ModerateBoth:
+		default:
+			return fmt.Errorf("moderation_scope must be input, output or both")
+		}
+		switch c.ModerationAction {
+		case ModerationBlock, ModerationAnnotate:
+		default:
+			return fmt.Errorf("moderation_action must be block or annotate")
+		}
+	}
+
+	for category, threshold := range c.ModerationThresholds {
+		if threshold < 0 || threshold > 1 {
+			return fmt.Errorf("moderation threshold for %s must be between 0 and 1", category)
+		}
+	}
+
+	if c.StreamResumeRetries < 0 {
+		return fmt.Errorf("stream_resume_retries must be non-negative")
+	}
+
+	if c.StreamResumeDelay < 0 {
+		return fmt.Errorf("stream_resume_delay must be non-negative")
+	}
+
+	if !c.CachePolicy.Valid() {
+		return fmt.Errorf("cache_policy must be empty, force or bypass")
+	}
+
+	if c.ReasoningEffort != "" && !types.IsValidReasoningEffort(c.ReasoningEffort) {
+		return fmt.Errorf("reasoning_effort must be low, medium or high")
 	}
 
 	if c.Timeout <= 0 {
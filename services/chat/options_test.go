@@ -0,0 +1,78 @@
+package chat
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hewenyu/newapi-go/types"
+)
+
+func TestToRequestSendsZeroTemperature(t *testing.T) {
+	config := DefaultChatConfig()
+	WithTemperature(0)(config)
+	WithSeed(0)(config)
+
+	data, err := json.Marshal(config.ToRequest([]types.ChatMessage{types.NewUserMessage("hi")}))
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(data, &body); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	if v, ok := body["temperature"]; !ok || v.(float64) != 0 {
+		t.Errorf("Expected temperature = 0 to be sent, got %v", body["temperature"])
+	}
+	if _, ok := body["seed"]; !ok {
+		t.Errorf("Expected seed = 0 to be sent")
+	}
+	if _, ok := body["top_p"]; ok {
+		t.Errorf("Expected unset top_p to be omitted")
+	}
+}
+
+func TestToRequestOmitsUnsetSampling(t *testing.T) {
+	data, err := json.Marshal(DefaultChatConfig().ToRequest([]types.ChatMessage{types.NewUserMessage("hi")}))
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(data, &body); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	for _, key := range []string{"temperature", "top_p", "presence_penalty", "frequency_penalty", "seed"} {
+		if v, ok := body[key]; ok {
+			t.Errorf("Expected unset %s to be omitted, got %v", key, v)
+		}
+	}
+}
+
+func TestToRequestMaxCompletionTokens(t *testing.T) {
+	config := DefaultChatConfig()
+	WithMaxCompletionTokens(2048)(config)
+
+	req := config.ToRequest([]types.ChatMessage{types.NewUserMessage("hi")})
+	if req.MaxTokens != 0 {
+		t.Errorf("Expected max_tokens to be dropped, got %d", req.MaxTokens)
+	}
+	if req.GetMaxTokens() != 2048 {
+		t.Errorf("Expected GetMaxTokens() = 2048, got %d", req.GetMaxTokens())
+	}
+}
+
+func TestValidateMetadataLimits(t *testing.T) {
+	config := DefaultChatConfig()
+	metadata := make(map[string]string)
+	for i := 0; i < types.MaxChatMetadataPairs+1; i++ {
+		metadata[string(rune('a'+i))] = "v"
+	}
+	WithMetadata(metadata)(config)
+
+	if err := config.Validate(); err == nil {
+		t.Errorf("Expected error for too many metadata pairs, got nil")
+	}
+}
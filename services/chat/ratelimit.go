@@ -0,0 +1,93 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RateLimiter 请求速率限制器，每次请求前调用Wait等待许可
+type RateLimiter interface {
+	// Wait 阻塞直到获得许可或上下文取消
+	Wait(ctx context.Context) error
+}
+
+// TokenBucketLimiter 令牌桶速率限制器
+type TokenBucketLimiter struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	mu     sync.Mutex
+}
+
+// NewTokenBucketLimiter 创建令牌桶速率限制器，ratePerSecond为每秒请求数，burst为允许的突发请求数。
+// ratePerSecond不大于0时不限制速率
+func NewTokenBucketLimiter(ratePerSecond float64, burst int) *TokenBucketLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &TokenBucketLimiter{
+		rate:   ratePerSecond,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait 等待一个令牌
+func (l *TokenBucketLimiter) Wait(ctx context.Context) error {
+	if l.rate <= 0 {
+		return ctx.Err()
+	}
+
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+		l.last = now
+
+		if l.tokens >= 1 {
+			l.tokens--
+			l.mu.Unlock()
+			return nil
+		}
+		wait := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+		l.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// SetRateLimiter 设置请求速率限制器，所有聊天请求（包括批量请求）发送前都会等待许可，传入nil取消限制
+func (s *ChatService) SetRateLimiter(limiter RateLimiter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.limiter = limiter
+}
+
+// waitRateLimit 等待速率限制器许可
+func (s *ChatService) waitRateLimit(ctx context.Context) error {
+	s.mu.RLock()
+	limiter := s.limiter
+	s.mu.RUnlock()
+
+	if limiter == nil {
+		return nil
+	}
+	if err := limiter.Wait(ctx); err != nil {
+		return fmt.Errorf("rate limiter: %w", err)
+	}
+	return nil
+}
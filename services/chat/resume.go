@@ -0,0 +1,263 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hewenyu/newapi-go/types"
+	"go.uber.org/zap"
+)
+
+// modelStream 可报告处理模型的流
+type modelStream interface {
+	types.StreamResponse
+	ServedModel() string
+}
+
+// openResumableStream 打开流式请求，配置了StreamResumeRetries时包装为可续传的流
+func (s *ChatService) openResumableStream(ctx context.Context, messages []types.ChatMessage, config *ChatConfig) (modelStream, error) {
+	stream, err := s.openStream(ctx, messages, config)
+	if err != nil {
+		return nil, err
+	}
+	if config.StreamResumeRetries <= 0 {
+		return stream, nil
+	}
+	return &resumableStream{service: s, ctx: ctx, messages: messages, config: config, current: stream}, nil
+}
+
+// resumableStream 可续传的流。传输层已在服务端支持时通过Last-Event-ID重连，
+// 仍然中断时（包括既没有finish_reason也没有[DONE]标记就结束）发起续写请求，将已输出的内容作为助手消息前缀，
+// 并去除续写结果中重复的前缀，调用方看到的是一条连续的流。
+// 已输出工具调用或音频、或请求了多个选择时无法续写，直接返回原错误
+type resumableStream struct {
+	service  *ChatService
+	ctx      context.Context
+	messages []types.ChatMessage
+	config   *ChatConfig
+
+	mu       sync.Mutex
+	current  *streamReaderAdapter
+	retries  int
+	content  strings.Builder
+	partial  bool
+	finished bool
+	skip     string
+	held     string
+	done     bool
+	closed   bool
+	err      error
+}
+
+// Next 获取下一个事件
+func (r *resumableStream) Next() (*types.StreamEvent, error) {
+	for {
+		r.mu.Lock()
+		current := r.current
+		r.mu.Unlock()
+
+		event, err := current.Next()
+		if err == nil {
+			return r.observe(event), nil
+		}
+
+		if !r.resumable(err) {
+			return nil, r.finish(err)
+		}
+		if resumeErr := r.resume(err); resumeErr != nil {
+			return nil, r.finish(resumeErr)
+		}
+	}
+}
+
+// resumable 判断中断是否可以通过续写恢复
+func (r *resumableStream) resumable(err error) bool {
+	if r.ctx.Err() != nil || r.finished || r.partial || r.config.N > 1 {
+		return false
+	}
+	if r.retries >= r.config.StreamResumeRetries {
+		return false
+	}
+
+	r.mu.Lock()
+	closed, current := r.closed, r.current
+	r.mu.Unlock()
+	if closed {
+		return false
+	}
+
+	// 既没有finish_reason也没有[DONE]标记的结束视为连接被截断，部分网关不下发finish_reason
+	if err == io.EOF {
+		return !current.doneReceived()
+	}
+	var streamErr *types.StreamError
+	return errors.As(err, &streamErr) && streamErr.Code == types.ErrCodeStreamError
+}
+
+// resume 以已输出的内容为前缀发起续写请求
+func (r *resumableStream) resume(cause error) error {
+	r.retries++
+	partial := r.content.String()
+	r.service.logger.Warn("Chat completion stream interrupted, requesting continuation",
+		zap.Int("attempt", r.retries),
+		zap.Int("partial_length", len(partial)),
+		zap.Error(cause))
+
+	// 中断的请求同样计入用量
+	r.current.meter.Finish()
+	r.current.Close()
+
+	if r.config.StreamResumeDelay > 0 {
+		timer := time.NewTimer(r.config.StreamResumeDelay)
+		select {
+		case <-r.ctx.Done():
+			timer.Stop()
+			return r.ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	messages := r.messages
+	if partial != "" {
+		messages = make([]types.ChatMessage, 0, len(r.messages)+1)
+		messages = append(messages, r.messages...)
+		messages = append(messages, types.NewAssistantMessage(partial))
+	}
+
+	stream, err := r.service.openStream(r.ctx, messages, r.config)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.current = stream
+	r.skip, r.held = partial, ""
+	if r.closed {
+		stream.Close()
+	}
+	return nil
+}
+
+// observe 记录已输出的内容，续写后去除重复的前缀
+func (r *resumableStream) observe(event *types.StreamEvent) *types.StreamEvent {
+	if event.Type != types.StreamEventTypeData {
+		return event
+	}
+
+	var chunk types.ChatCompletionChunk
+	if err := json.Unmarshal(event.Data, &chunk); err != nil {
+		return event
+	}
+
+	modified := false
+	for i := range chunk.Choices {
+		choice := &chunk.Choices[i]
+		if choice.FinishReason != "" {
+			r.finished = true
+		}
+		if len(choice.Delta.ToolCalls) > 0 || choice.Delta.Audio != nil {
+			r.partial = true
+		}
+		if choice.Index != 0 {
+			continue
+		}
+
+		delta := choice.GetContent()
+		if r.skip != "" && delta != "" {
+			delta = r.dedupe(delta)
+			choice.Delta.Content = delta
+			modified = true
+		}
+		r.content.WriteString(delta)
+	}
+	if r.finished {
+		r.skip, r.held = "", ""
+	}
+
+	if !modified {
+		return event
+	}
+	data, err := json.Marshal(chunk)
+	if err != nil {
+		return event
+	}
+	rewritten := *event
+	rewritten.Data = data
+	return &rewritten
+}
+
+// dedupe 去除续写结果中与已输出内容重复的前缀。与前缀一致的增量暂不输出，
+// 出现分歧时说明模型是在续写，暂存的内容原样补发
+func (r *resumableStream) dedupe(delta string) string {
+	candidate := r.held + delta
+	switch {
+	case strings.HasPrefix(r.skip, candidate):
+		r.held = candidate
+		if len(candidate) == len(r.skip) {
+			r.skip, r.held = "", ""
+		}
+		return ""
+	case strings.HasPrefix(candidate, r.skip):
+		out := candidate[len(r.skip):]
+		r.skip, r.held = "", ""
+		return out
+	default:
+		r.skip, r.held = "", ""
+		return candidate
+	}
+}
+
+// finish 记录流结束状态
+func (r *resumableStream) finish(err error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.done = true
+	if err != io.EOF {
+		r.err = err
+	}
+	return err
+}
+
+// Close 关闭流
+func (r *resumableStream) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.closed = true
+	r.done = true
+	return r.current.Close()
+}
+
+// Err 获取错误
+func (r *resumableStream) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.err
+}
+
+// Done 检查是否完成
+func (r *resumableStream) Done() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.done
+}
+
+// Context 获取上下文
+func (r *resumableStream) Context() context.Context {
+	return r.ctx
+}
+
+// ServedModel 获取处理请求的模型
+func (r *resumableStream) ServedModel() string {
+	return r.config.Model
+}
@@ -0,0 +1,138 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hewenyu/newapi-go/types"
+)
+
+// finishedChunks 构造以stop结束的流式文本块
+func finishedChunks(deltas ...string) []types.ChatCompletionChunk {
+	chunks := textChunks(deltas...)
+	chunks[len(chunks)-1].Choices[0].FinishReason = types.FinishReasonStop
+	return chunks
+}
+
+// truncatedStream 构造没有finish_reason和[DONE]标记就结束的SSE流，模拟连接被截断
+func truncatedStream(deltas ...string) rawReply {
+	var body strings.Builder
+	for _, chunk := range textChunks(deltas...) {
+		data, _ := json.Marshal(chunk)
+		fmt.Fprintf(&body, "data: %s\n\n", data)
+	}
+	return rawReply{status: http.StatusOK, contentType: "text/event-stream", body: body.String()}
+}
+
+func TestStreamResumeDeduplicatesRepeatedPrefix(t *testing.T) {
+	service, fake := newFakeService(t,
+		truncatedStream("Hel", "lo"),
+		finishedChunks("He", "llo", " world"),
+	)
+
+	stream, err := service.StreamChatCompletion(context.Background(),
+		[]types.ChatMessage{types.NewUserMessage("hi")},
+		WithStreamResume(1, 0),
+	)
+	if err != nil {
+		t.Fatalf("StreamChatCompletion failed: %v", err)
+	}
+
+	var text string
+	for delta, err := range stream.TextDeltas() {
+		if err != nil {
+			t.Fatalf("unexpected stream error: %v", err)
+		}
+		text += delta
+	}
+	if text != "Hello world" {
+		t.Errorf("streamed text = %q, want %q", text, "Hello world")
+	}
+	if resp := stream.Accumulated(); resp.GetFirstContent() != "Hello world" {
+		t.Errorf("accumulated content = %q", resp.GetFirstContent())
+	}
+
+	if len(fake.requests) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(fake.requests))
+	}
+	messages := fake.requests[1].Messages
+	if last := messages[len(messages)-1]; last.Role != types.ChatRoleAssistant || last.Content != "Hello" {
+		t.Errorf("continuation prefix = %+v", last)
+	}
+}
+
+func TestStreamResumeAppendsContinuation(t *testing.T) {
+	service, _ := newFakeService(t,
+		rawReply{
+			status:      http.StatusOK,
+			contentType: "text/event-stream",
+			body:        "data: {\"choices\":[{\"index\":0,\"delta\":{\"role\":\"assistant\",\"content\":\"Hello\"}}]}\n\n",
+		},
+		finishedChunks(" wo", "rld"),
+	)
+
+	stream, err := service.StreamChatCompletion(context.Background(),
+		[]types.ChatMessage{types.NewUserMessage("hi")},
+		WithStreamResume(1, 0),
+	)
+	if err != nil {
+		t.Fatalf("StreamChatCompletion failed: %v", err)
+	}
+	for _, err := range stream.Chunks() {
+		if err != nil {
+			t.Fatalf("unexpected stream error: %v", err)
+		}
+	}
+
+	resp := stream.Accumulated()
+	if resp.GetFirstContent() != "Hello world" || resp.Choices[0].FinishReason != types.FinishReasonStop {
+		t.Errorf("accumulated response: content=%q finish=%q", resp.GetFirstContent(), resp.Choices[0].FinishReason)
+	}
+}
+
+func TestStreamWithoutResumeEndsAtTruncation(t *testing.T) {
+	service, fake := newFakeService(t, textChunks("Hel", "lo"), finishedChunks("unused"))
+
+	stream, err := service.StreamChatCompletion(context.Background(), []types.ChatMessage{types.NewUserMessage("hi")})
+	if err != nil {
+		t.Fatalf("StreamChatCompletion failed: %v", err)
+	}
+	for range stream.Chunks() {
+	}
+
+	if len(fake.requests) != 1 {
+		t.Errorf("expected 1 request without resume, got %d", len(fake.requests))
+	}
+	if resp := stream.Accumulated(); resp.GetFirstContent() != "Hello" {
+		t.Errorf("accumulated content = %q", resp.GetFirstContent())
+	}
+}
+
+func TestStreamResumeSkipsStreamEndedWithDone(t *testing.T) {
+	// 网关没有下发finish_reason，但流以[DONE]正常结束，不应发起续写请求
+	service, fake := newFakeService(t, textChunks("Hel", "lo"), finishedChunks("unused"))
+
+	stream, err := service.StreamChatCompletion(context.Background(),
+		[]types.ChatMessage{types.NewUserMessage("hi")},
+		WithStreamResume(1, 0),
+	)
+	if err != nil {
+		t.Fatalf("StreamChatCompletion failed: %v", err)
+	}
+	for _, err := range stream.Chunks() {
+		if err != nil {
+			t.Fatalf("unexpected stream error: %v", err)
+		}
+	}
+
+	if len(fake.requests) != 1 {
+		t.Errorf("expected 1 request, got %d", len(fake.requests))
+	}
+	if resp := stream.Accumulated(); resp.GetFirstContent() != "Hello" {
+		t.Errorf("accumulated content = %q", resp.GetFirstContent())
+	}
+}
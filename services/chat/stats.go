@@ -0,0 +1,326 @@
+package chat
+
+import (
+	"context"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hewenyu/newapi-go/types"
+)
+
+// StreamStatsHook 流式请求结束时接收统计信息的钩子，在结束流的goroutine中同步调用，应尽快返回
+type StreamStatsHook func(ctx context.Context, stats *types.StreamStats)
+
+// SetStreamStatsHook 设置流统计钩子，每个流式聊天请求在读完、出错或被关闭时调用一次，传入nil取消
+func (s *ChatService) SetStreamStatsHook(hook StreamStatsHook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.statsHook = hook
+}
+
+// streamStatsHook 获取流统计钩子
+func (s *ChatService) streamStatsHook() StreamStatsHook {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.statsHook
+}
+
+// statsContextKey 统计收集器在上下文中的键
+type statsContextKey struct{}
+
+// transportMetrics 传输层读取器提供的连接统计
+type transportMetrics interface {
+	BytesRead() int64
+	FirstByteTime() time.Time
+	DoneReceived() bool
+}
+
+// streamStatsCollector 收集一个流式请求的统计信息，续传和模型降级打开的多个连接累计到同一个收集器
+type streamStatsCollector struct {
+	mu           sync.Mutex
+	start        time.Time
+	firstByte    time.Time
+	bytes        int64
+	doneReceived bool
+}
+
+// withStatsCollector 创建统计收集器并放入上下文，供打开连接时汇总传输层统计
+func withStatsCollector(ctx context.Context) (context.Context, *streamStatsCollector) {
+	collector := &streamStatsCollector{start: time.Now()}
+	return context.WithValue(ctx, statsContextKey{}, collector), collector
+}
+
+// statsCollectorFrom 获取上下文中的统计收集器，没有时返回nil，nil收集器的方法均为空操作
+func statsCollectorFrom(ctx context.Context) *streamStatsCollector {
+	collector, _ := ctx.Value(statsContextKey{}).(*streamStatsCollector)
+	return collector
+}
+
+// received 累计读取的字节数并记录第一个字节的时间
+func (c *streamStatsCollector) received(bytes int64, firstByte time.Time) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.bytes += bytes
+	if c.firstByte.IsZero() && !firstByte.IsZero() {
+		c.firstByte = firstByte
+	}
+}
+
+// ended 记录连接是否以[DONE]标记结束，以最后一个连接为准
+func (c *streamStatsCollector) ended(done bool) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.doneReceived = done
+}
+
+// report 将传输层的字节数和结束标记汇总到统计收集器
+func (a *streamReaderAdapter) report(err error) {
+	metrics, ok := a.reader.(transportMetrics)
+	if !ok || a.collector == nil {
+		return
+	}
+
+	bytes := metrics.BytesRead()
+	a.collector.received(bytes-a.reported, metrics.FirstByteTime())
+	a.reported = bytes
+	if err == io.EOF {
+		a.collector.ended(metrics.DoneReceived())
+	}
+}
+
+// doneReceived 检查连接是否以[DONE]标记结束，传输层不支持时返回false
+func (a *streamReaderAdapter) doneReceived() bool {
+	metrics, ok := a.reader.(transportMetrics)
+	return ok && metrics.DoneReceived()
+}
+
+// chunkObserver 接收处理器解析后的数据块，避免统计层重复解析JSON
+type chunkObserver interface {
+	observeChunk(chunk *types.ChatCompletionChunk)
+}
+
+// statsStream 统计流式响应的时延和吞吐，流结束时生成统计信息并调用钩子。
+// 数据块由ChatStreamProcessor解析后通过observeChunk传入，统计层本身不解析JSON
+type statsStream struct {
+	stream    types.StreamResponse
+	collector *streamStatsCollector
+	hook      StreamStatsHook
+
+	mu           sync.Mutex
+	events       int
+	deltaChunks  int
+	usageTokens  int
+	firstDelta   time.Time
+	lastDelta    time.Time
+	gaps         []time.Duration
+	finishReason string
+	stats        *types.StreamStats
+}
+
+// Next 获取下一个事件
+func (s *statsStream) Next() (*types.StreamEvent, error) {
+	event, err := s.stream.Next()
+	switch {
+	case err == io.EOF:
+		s.finish(types.StreamEndEOF, nil)
+	case err != nil:
+		s.finish(types.StreamEndError, err)
+	case event.Type == types.StreamEventTypeData:
+		s.mu.Lock()
+		if s.stats == nil {
+			s.events++
+		}
+		s.mu.Unlock()
+	}
+	return event, err
+}
+
+// observeChunk 记录数据块的内容增量时间、完成原因和用量
+func (s *statsStream) observeChunk(chunk *types.ChatCompletionChunk) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stats != nil {
+		return
+	}
+
+	if chunk.Usage != nil {
+		s.usageTokens = chunk.Usage.CompletionTokens
+	}
+	delta := false
+	for i := range chunk.Choices {
+		choice := &chunk.Choices[i]
+		if hasDelta(&choice.Delta) {
+			delta = true
+		}
+		if choice.FinishReason != "" {
+			s.finishReason = choice.FinishReason
+		}
+	}
+	if !delta {
+		return
+	}
+
+	s.deltaChunks++
+	if s.firstDelta.IsZero() {
+		s.firstDelta = now
+	} else {
+		s.gaps = append(s.gaps, now.Sub(s.lastDelta))
+	}
+	s.lastDelta = now
+}
+
+// hasDelta 检查增量是否包含生成的内容
+func hasDelta(delta *types.ChatMessage) bool {
+	if delta.GetTextContent() != "" || delta.ReasoningContent != "" || delta.Refusal != "" || delta.Audio != nil {
+		return true
+	}
+	for _, call := range delta.ToolCalls {
+		if call.Function.Arguments != "" {
+			return true
+		}
+	}
+	return delta.FunctionCall != nil && delta.FunctionCall.Arguments != ""
+}
+
+// finish 生成统计信息并调用钩子，只有第一次调用生效
+func (s *statsStream) finish(endReason string, err error) {
+	end := time.Now()
+	collector := s.collector
+	model := s.ServedModel()
+
+	s.mu.Lock()
+	if s.stats != nil {
+		s.mu.Unlock()
+		return
+	}
+
+	collector.mu.Lock()
+	stats := &types.StreamStats{
+		StartTime:     collector.start,
+		EndTime:       end,
+		Duration:      end.Sub(collector.start),
+		EventCount:    s.events,
+		BytesReceived: collector.bytes,
+		State:         types.StreamStateCompleted,
+		Model:         model,
+		FinishReason:  s.finishReason,
+		EndReason:     endReason,
+	}
+	if !collector.firstByte.IsZero() {
+		stats.TimeToFirstByte = collector.firstByte.Sub(collector.start)
+	}
+	if endReason == types.StreamEndEOF && collector.doneReceived {
+		stats.EndReason = types.StreamEndDone
+	}
+	collector.mu.Unlock()
+
+	switch endReason {
+	case types.StreamEndError:
+		stats.State = types.StreamStateError
+		stats.ErrorCount = 1
+		stats.Error = err.Error()
+	case types.StreamEndClosed:
+		stats.State = types.StreamStateClosed
+	}
+
+	// 服务端未返回用量时以包含内容增量的数据块数近似
+	stats.CompletionTokens = s.deltaChunks
+	if s.usageTokens > 0 {
+		stats.CompletionTokens = s.usageTokens
+	}
+	if !s.firstDelta.IsZero() {
+		stats.TimeToFirstToken = s.firstDelta.Sub(collector.start)
+		if generation := end.Sub(s.firstDelta); generation > 0 {
+			stats.TokensPerSecond = float64(stats.CompletionTokens) / generation.Seconds()
+		}
+	}
+	stats.InterChunkLatency = percentiles(s.gaps)
+	s.stats = stats
+	s.mu.Unlock()
+
+	if s.hook != nil {
+		s.hook(s.stream.Context(), stats)
+	}
+}
+
+// percentiles 按最近秩法计算延迟分位数
+func percentiles(samples []time.Duration) types.LatencyPercentiles {
+	if len(samples) == 0 {
+		return types.LatencyPercentiles{}
+	}
+
+	sorted := append([]time.Duration(nil), samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	rank := func(p int) time.Duration {
+		index := (p*len(sorted)+99)/100 - 1
+		return sorted[max(index, 0)]
+	}
+	return types.LatencyPercentiles{
+		P50: rank(50),
+		P90: rank(90),
+		P99: rank(99),
+		Max: sorted[len(sorted)-1],
+	}
+}
+
+// Stats 获取统计信息，流结束前返回nil
+func (s *statsStream) Stats() *types.StreamStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.stats
+}
+
+// Close 关闭流，流结束前关闭时以StreamEndClosed记录统计信息
+func (s *statsStream) Close() error {
+	s.finish(types.StreamEndClosed, nil)
+	return s.stream.Close()
+}
+
+// Err 获取错误
+func (s *statsStream) Err() error {
+	return s.stream.Err()
+}
+
+// Done 检查是否完成
+func (s *statsStream) Done() bool {
+	return s.stream.Done()
+}
+
+// Context 获取上下文
+func (s *statsStream) Context() context.Context {
+	return s.stream.Context()
+}
+
+// ServedModel 获取实际处理请求的模型
+func (s *statsStream) ServedModel() string {
+	if reporter, ok := s.stream.(interface{ ServedModel() string }); ok {
+		return reporter.ServedModel()
+	}
+	return ""
+}
+
+// CacheStatus 获取缓存状态
+func (s *statsStream) CacheStatus() string {
+	if reporter, ok := s.stream.(interface{ CacheStatus() string }); ok {
+		return reporter.CacheStatus()
+	}
+	return ""
+}
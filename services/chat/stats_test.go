@@ -0,0 +1,153 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hewenyu/newapi-go/types"
+)
+
+// statsSink 记录钩子收到的统计信息
+type statsSink struct {
+	mu    sync.Mutex
+	stats []*types.StreamStats
+}
+
+// hook 返回写入记录的钩子
+func (s *statsSink) hook(ctx context.Context, stats *types.StreamStats) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stats = append(s.stats, stats)
+}
+
+// single 获取唯一的一条记录
+func (s *statsSink) single(t *testing.T) *types.StreamStats {
+	t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.stats) != 1 {
+		t.Fatalf("hook called %d times, want 1", len(s.stats))
+	}
+	return s.stats[0]
+}
+
+func TestStreamStatsCompleted(t *testing.T) {
+	chunks := finishedChunks("Hel", "lo", "!")
+	service, _ := newFakeService(t, chunks)
+	sink := &statsSink{}
+	service.SetStreamStatsHook(sink.hook)
+
+	stream, err := service.StreamChatCompletion(context.Background(), []types.ChatMessage{types.NewUserMessage("hi")})
+	if err != nil {
+		t.Fatalf("StreamChatCompletion failed: %v", err)
+	}
+	for _, err := range stream.TextDeltas() {
+		if err != nil {
+			t.Fatalf("unexpected stream error: %v", err)
+		}
+	}
+
+	stats := sink.single(t)
+	if stream.Stats() != stats {
+		t.Errorf("Stats() = %+v, want the hook's stats", stream.Stats())
+	}
+	if stats.EndReason != types.StreamEndDone || stats.State != types.StreamStateCompleted {
+		t.Errorf("end reason = %q, state = %q", stats.EndReason, stats.State)
+	}
+	if stats.EventCount != 3 || stats.CompletionTokens != 3 {
+		t.Errorf("events = %d, tokens = %d, want 3 and 3", stats.EventCount, stats.CompletionTokens)
+	}
+	if stats.FinishReason != types.FinishReasonStop {
+		t.Errorf("finish reason = %q", stats.FinishReason)
+	}
+
+	var wire int64
+	for _, chunk := range chunks {
+		data, _ := json.Marshal(chunk)
+		wire += int64(len("data: \n\n") + len(data))
+	}
+	wire += int64(len("data: [DONE]\n\n"))
+	if stats.BytesReceived != wire {
+		t.Errorf("bytes received = %d, want %d", stats.BytesReceived, wire)
+	}
+
+	if stats.TimeToFirstByte <= 0 || stats.TimeToFirstToken < stats.TimeToFirstByte || stats.Duration < stats.TimeToFirstToken {
+		t.Errorf("ttfb = %v, ttft = %v, duration = %v", stats.TimeToFirstByte, stats.TimeToFirstToken, stats.Duration)
+	}
+	if stats.InterChunkLatency.Max < stats.InterChunkLatency.P50 {
+		t.Errorf("inter-chunk latency = %+v", stats.InterChunkLatency)
+	}
+}
+
+func TestStreamStatsEndReasons(t *testing.T) {
+	service, _ := newFakeService(t,
+		rawReply{status: http.StatusOK, contentType: "text/event-stream",
+			body: `data: {"choices":[{"delta":{"content":"a"}}]}` + "\n\n"},
+		textChunks("a", "b", "c"),
+	)
+	sink := &statsSink{}
+	service.SetStreamStatsHook(sink.hook)
+	messages := []types.ChatMessage{types.NewUserMessage("hi")}
+
+	stream, err := service.StreamChatCompletion(context.Background(), messages)
+	if err != nil {
+		t.Fatalf("StreamChatCompletion failed: %v", err)
+	}
+	for _, err := range stream.Chunks() {
+		if err != nil {
+			t.Fatalf("unexpected stream error: %v", err)
+		}
+	}
+	if stats := sink.single(t); stats.EndReason != types.StreamEndEOF {
+		t.Errorf("end reason without [DONE] = %q, want %q", stats.EndReason, types.StreamEndEOF)
+	}
+
+	sink.stats = nil
+	stream, err = service.StreamChatCompletion(context.Background(), messages)
+	if err != nil {
+		t.Fatalf("StreamChatCompletion failed: %v", err)
+	}
+	for _, err := range stream.Chunks() {
+		if err != nil {
+			t.Fatalf("unexpected stream error: %v", err)
+		}
+		break
+	}
+	stats := sink.single(t)
+	if stats.EndReason != types.StreamEndClosed || stats.State != types.StreamStateClosed {
+		t.Errorf("end reason after early close = %q, state = %q", stats.EndReason, stats.State)
+	}
+	if stats.EventCount != 1 {
+		t.Errorf("events = %d, want 1", stats.EventCount)
+	}
+}
+
+func TestPercentiles(t *testing.T) {
+	samples := make([]time.Duration, 0, 100)
+	for i := 100; i >= 1; i-- {
+		samples = append(samples, time.Duration(i)*time.Millisecond)
+	}
+
+	got := percentiles(samples)
+	want := types.LatencyPercentiles{
+		P50: 50 * time.Millisecond,
+		P90: 90 * time.Millisecond,
+		P99: 99 * time.Millisecond,
+		Max: 100 * time.Millisecond,
+	}
+	if got != want {
+		t.Errorf("percentiles = %+v, want %+v", got, want)
+	}
+	if samples[0] != 100*time.Millisecond {
+		t.Error("percentiles modified its input")
+	}
+	if got := percentiles(nil); got != (types.LatencyPercentiles{}) {
+		t.Errorf("percentiles(nil) = %+v", got)
+	}
+}
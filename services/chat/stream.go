@@ -3,6 +3,7 @@ package chat
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
@@ -58,6 +59,10 @@ func (p *ChatStreamProcessor) Next() (*types.StreamEvent, error) {
 		if parseErr != nil {
 			p.logger.Warn("Failed to parse chat completion chunk", zap.Error(parseErr))
 		} else {
+			// 统计层复用这里的解析结果
+			if observer, ok := p.stream.(chunkObserver); ok {
+				observer.observeChunk(chunk)
+			}
 			p.mu.Lock()
 			p.chunks = append(p.chunks, *chunk)
 			p.mu.Unlock()
@@ -67,6 +72,27 @@ func (p *ChatStreamProcessor) Next() (*types.StreamEvent, error) {
 	return event, nil
 }
 
+// Recv 获取下一个解析后的块，跳过无法解析的事件，流结束时返回io.EOF
+func (p *ChatStreamProcessor) Recv() (*types.ChatCompletionChunk, error) {
+	for {
+		p.mu.RLock()
+		count := len(p.chunks)
+		p.mu.RUnlock()
+
+		if _, err := p.Next(); err != nil {
+			return nil, err
+		}
+
+		p.mu.RLock()
+		if len(p.chunks) > count {
+			chunk := p.chunks[len(p.chunks)-1]
+			p.mu.RUnlock()
+			return &chunk, nil
+		}
+		p.mu.RUnlock()
+	}
+}
+
 // Close 关闭流式处理器
 func (p *ChatStreamProcessor) Close() error {
 	p.mu.Lock()
@@ -101,6 +127,22 @@ func (p *ChatStreamProcessor) Context() context.Context {
 	return context.Background()
 }
 
+// ServedModel 获取实际处理请求的模型，启用模型降级时为最终提供输出的模型
+func (p *ChatStreamProcessor) ServedModel() string {
+	if reporter, ok := p.stream.(interface{ ServedModel() string }); ok {
+		return reporter.ServedModel()
+	}
+	return ""
+}
+
+// Stats 获取流的时延和吞吐统计，流读完、出错或关闭前返回nil
+func (p *ChatStreamProcessor) Stats() *types.StreamStats {
+	if reporter, ok := p.stream.(interface{ Stats() *types.StreamStats }); ok {
+		return reporter.Stats()
+	}
+	return nil
+}
+
 // GetChunks 获取所有已接收的块
 func (p *ChatStreamProcessor) GetChunks() []types.ChatCompletionChunk {
 	p.mu.RLock()
@@ -136,6 +178,20 @@ func (p *ChatStreamProcessor) CollectContent() string {
 	return content.String()
 }
 
+// CollectReasoningContent 收集完整的推理内容
+func (p *ChatStreamProcessor) CollectReasoningContent() string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var reasoning strings.Builder
+	for _, chunk := range p.chunks {
+		for _, choice := range chunk.Choices {
+			reasoning.WriteString(choice.GetReasoningContent())
+		}
+	}
+	return reasoning.String()
+}
+
 // CollectResponse 收集完整的响应
 func (p *ChatStreamProcessor) CollectResponse() *types.ChatCompletionResponse {
 	p.mu.RLock()
@@ -145,7 +201,37 @@ func (p *ChatStreamProcessor) CollectResponse() *types.ChatCompletionResponse {
 		return nil
 	}
 
-	firstChunk := p.chunks[0]
+	response := mergeChunks(p.chunks)
+	response.ServedModel = p.ServedModel()
+	if response.ServedModel == "" {
+		response.ServedModel = response.Model
+	}
+	response.CacheStatus = p.CacheStatus()
+	response.Moderation = p.Moderation()
+	return response
+}
+
+// Moderation 获取内容审核标注，未启用审核时为nil，输出审核结果在流结束后可用
+func (p *ChatStreamProcessor) Moderation() *types.ModerationAnnotation {
+	if reporter, ok := p.stream.(interface {
+		Moderation() *types.ModerationAnnotation
+	}); ok {
+		return reporter.Moderation()
+	}
+	return nil
+}
+
+// CacheStatus 获取响应缓存状态，未配置缓存时为空
+func (p *ChatStreamProcessor) CacheStatus() string {
+	if reporter, ok := p.stream.(interface{ CacheStatus() string }); ok {
+		return reporter.CacheStatus()
+	}
+	return ""
+}
+
+// mergeChunks 将流式块合并为完整响应，chunks不能为空
+func mergeChunks(chunks []types.ChatCompletionChunk) *types.ChatCompletionResponse {
+	firstChunk := chunks[0]
 
 	// 构建完整响应
 	response := &types.ChatCompletionResponse{
@@ -159,7 +245,7 @@ func (p *ChatStreamProcessor) CollectResponse() *types.ChatCompletionResponse {
 	// 合并所有选择
 	choiceMap := make(map[int]*types.ChatCompletionChoice)
 
-	for _, chunk := range p.chunks {
+	for _, chunk := range chunks {
 		for _, chunkChoice := range chunk.Choices {
 			if choice, exists := choiceMap[chunkChoice.Index]; exists {
 				// 合并内容
@@ -173,6 +259,12 @@ func (p *ChatStreamProcessor) CollectResponse() *types.ChatCompletionResponse {
 					}
 				}
 
+				// 合并推理内容
+				choice.Message.ReasoningContent += chunkChoice.Delta.ReasoningContent
+
+				// 合并对数概率
+				choice.LogProbs = types.MergeLogProbs(choice.LogProbs, chunkChoice.LogProbs)
+
 				// 更新结束原因
 				if chunkChoice.FinishReason != "" {
 					choice.FinishReason = chunkChoice.FinishReason
@@ -180,18 +272,20 @@ func (p *ChatStreamProcessor) CollectResponse() *types.ChatCompletionResponse {
 
 				// 合并工具调用
 				if len(chunkChoice.Delta.ToolCalls) > 0 {
-					choice.Message.ToolCalls = append(choice.Message.ToolCalls, chunkChoice.Delta.ToolCalls...)
+					choice.Message.ToolCalls = types.MergeToolCallDeltas(choice.Message.ToolCalls, chunkChoice.Delta.ToolCalls)
 				}
 			} else {
 				// 新建选择
 				choiceMap[chunkChoice.Index] = &types.ChatCompletionChoice{
 					Index: chunkChoice.Index,
 					Message: types.ChatMessage{
-						Role:      chunkChoice.Delta.Role,
-						Content:   chunkChoice.Delta.Content,
-						ToolCalls: chunkChoice.Delta.ToolCalls,
+						Role:             chunkChoice.Delta.Role,
+						Content:          chunkChoice.Delta.Content,
+						ReasoningContent: chunkChoice.Delta.ReasoningContent,
+						ToolCalls:        types.MergeToolCallDeltas(nil, chunkChoice.Delta.ToolCalls),
 					},
 					FinishReason: chunkChoice.FinishReason,
+					LogProbs:     types.MergeLogProbs(nil, chunkChoice.LogProbs),
 				}
 			}
 		}
@@ -205,6 +299,7 @@ func (p *ChatStreamProcessor) CollectResponse() *types.ChatCompletionResponse {
 	// 转换为切片
 	for i := 0; i < len(choiceMap); i++ {
 		if choice, exists := choiceMap[i]; exists {
+			choice.Message.ToolCalls = types.ClearToolCallIndexes(choice.Message.ToolCalls)
 			response.Choices = append(response.Choices, *choice)
 		}
 	}
@@ -221,22 +316,29 @@ func (p *ChatStreamProcessor) parseChunk(data json.RawMessage) (*types.ChatCompl
 	return &chunk, nil
 }
 
-// ChatStreamReader 聊天流式读取器
+// ChatStreamReader 聊天流式读取器，在后台协程中预读块。
+// 读取协程在流结束、上下文取消或Close时退出，Close会等待其退出后返回
 type ChatStreamReader struct {
 	processor *ChatStreamProcessor
 	logger    utils.Logger
 	buffer    chan types.ChatCompletionChunk
 	ctx       context.Context
 	cancel    context.CancelFunc
-	wg        sync.WaitGroup
+	stopped   chan struct{}
+	closeOnce sync.Once
+	closeErr  error
 	mu        sync.RWMutex
-	finished  bool
+	closed    bool
 	err       error
 }
 
-// NewChatStreamReader 创建新的聊天流式读取器
+// NewChatStreamReader 创建新的聊天流式读取器，使用流的上下文，上下文取消时关闭流并结束读取
 func NewChatStreamReader(processor *ChatStreamProcessor, logger utils.Logger) *ChatStreamReader {
-	ctx, cancel := context.WithCancel(context.Background())
+	parent := processor.Context()
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithCancel(parent)
 
 	reader := &ChatStreamReader{
 		processor: processor,
@@ -244,23 +346,18 @@ func NewChatStreamReader(processor *ChatStreamProcessor, logger utils.Logger) *C
 		buffer:    make(chan types.ChatCompletionChunk, 100),
 		ctx:       ctx,
 		cancel:    cancel,
+		stopped:   make(chan struct{}),
 	}
 
 	// 启动读取协程
-	reader.wg.Add(1)
 	go reader.readLoop()
 
 	return reader
 }
 
-// Read 读取下一个块
+// Read 读取下一个块，流正常结束时返回io.EOF
 func (r *ChatStreamReader) Read() (*types.ChatCompletionChunk, error) {
-	select {
-	case <-r.ctx.Done():
-		return nil, r.ctx.Err()
-	case chunk := <-r.buffer:
-		return &chunk, nil
-	}
+	return r.read(nil)
 }
 
 // ReadWithTimeout 带超时的读取
@@ -268,40 +365,67 @@ func (r *ChatStreamReader) ReadWithTimeout(timeout time.Duration) (*types.ChatCo
 	timer := time.NewTimer(timeout)
 	defer timer.Stop()
 
-	select {
-	case <-r.ctx.Done():
-		return nil, r.ctx.Err()
-	case <-timer.C:
+	chunk, err := r.read(timer.C)
+	if err == errReadTimeout {
 		return nil, fmt.Errorf("read timeout after %v", timeout)
-	case chunk := <-r.buffer:
-		return &chunk, nil
 	}
+	return chunk, err
 }
 
-// Close 关闭读取器
-func (r *ChatStreamReader) Close() error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+// errReadTimeout 读取超时
+var errReadTimeout = errors.New("read timeout")
 
-	if r.finished {
-		return nil
+// read 等待下一个块，读取协程退出后先取完已缓冲的块
+func (r *ChatStreamReader) read(timeout <-chan time.Time) (*types.ChatCompletionChunk, error) {
+	select {
+	case chunk := <-r.buffer:
+		return &chunk, nil
+	case <-r.stopped:
+		select {
+		case chunk := <-r.buffer:
+			return &chunk, nil
+		default:
+		}
+		return nil, r.result()
+	case <-r.ctx.Done():
+		return nil, r.result()
+	case <-timeout:
+		return nil, errReadTimeout
 	}
+}
 
-	r.finished = true
-	r.cancel()
-
-	// 等待读取协程结束
-	r.wg.Wait()
-
-	// 关闭缓冲区
-	close(r.buffer)
+// result 获取读取结束的原因
+func (r *ChatStreamReader) result() error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 
-	// 关闭处理器
-	if r.processor != nil {
-		return r.processor.Close()
+	if r.err != nil {
+		return r.err
+	}
+	if r.closed {
+		return io.EOF
 	}
+	if err := r.ctx.Err(); err != nil {
+		return err
+	}
+	return io.EOF
+}
 
-	return nil
+// Close 关闭读取器和底层流，等待读取协程退出，可重复调用
+func (r *ChatStreamReader) Close() error {
+	r.closeOnce.Do(func() {
+		r.mu.Lock()
+		r.closed = true
+		r.mu.Unlock()
+
+		r.cancel()
+		// 关闭底层流使阻塞中的读取返回
+		if r.processor != nil {
+			r.closeErr = r.processor.Close()
+		}
+		<-r.stopped
+	})
+	return r.closeErr
 }
 
 // Err 获取错误
@@ -311,34 +435,47 @@ func (r *ChatStreamReader) Err() error {
 	return r.err
 }
 
-// Done 检查是否完成
+// Done 检查是否已关闭或读完所有块
 func (r *ChatStreamReader) Done() bool {
 	r.mu.RLock()
-	defer r.mu.RUnlock()
-	return r.finished
+	closed := r.closed
+	r.mu.RUnlock()
+	if closed {
+		return true
+	}
+
+	select {
+	case <-r.stopped:
+		return len(r.buffer) == 0
+	default:
+		return false
+	}
 }
 
 // readLoop 读取循环
 func (r *ChatStreamReader) readLoop() {
-	defer r.wg.Done()
+	defer close(r.stopped)
 
-	for {
-		select {
-		case <-r.ctx.Done():
-			return
-		default:
-		}
+	// 上下文取消时关闭底层流，避免阻塞在网络读取上
+	stop := context.AfterFunc(r.ctx, func() { r.processor.Close() })
+	defer stop()
 
+	for {
 		event, err := r.processor.Next()
 		if err != nil {
-			if err == io.EOF {
+			r.mu.Lock()
+			switch {
+			case err == io.EOF:
 				r.logger.Debug("Chat stream completed")
-			} else {
+			case r.closed:
+				// 主动关闭导致的读取错误
+			case r.ctx.Err() != nil:
+				r.err = r.ctx.Err()
+			default:
 				r.logger.Error("Chat stream error", zap.Error(err))
-				r.mu.Lock()
 				r.err = err
-				r.mu.Unlock()
 			}
+			r.mu.Unlock()
 			return
 		}
 
@@ -367,6 +504,10 @@ func ProcessStream(ctx context.Context, stream types.StreamResponse, handler Cha
 	processor := NewChatStreamProcessor(stream, logger)
 	defer processor.Close()
 
+	// ctx取消时关闭流，使阻塞中的读取返回
+	stop := context.AfterFunc(ctx, func() { processor.Close() })
+	defer stop()
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -376,6 +517,10 @@ func ProcessStream(ctx context.Context, stream types.StreamResponse, handler Cha
 
 		event, err := processor.Next()
 		if err != nil {
+			// ctx取消后关闭流导致的结束以上下文错误返回
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
 			if err == io.EOF {
 				return nil
 			}
@@ -396,12 +541,39 @@ func ProcessStream(ctx context.Context, stream types.StreamResponse, handler Cha
 	}
 }
 
+// DeltaHandler 增量文本处理函数类型
+type DeltaHandler func(delta string) error
+
+// ProcessStreamDeltas 处理流式响应，分别回调推理增量和回答增量
+// onReasoning 或 onContent 为 nil 时忽略对应的增量
+func ProcessStreamDeltas(ctx context.Context, stream types.StreamResponse, onReasoning, onContent DeltaHandler) error {
+	return ProcessStream(ctx, stream, func(chunk *types.ChatCompletionChunk) error {
+		for _, choice := range chunk.Choices {
+			if reasoning := choice.GetReasoningContent(); reasoning != "" && onReasoning != nil {
+				if err := onReasoning(reasoning); err != nil {
+					return err
+				}
+			}
+			if content := choice.GetContent(); content != "" && onContent != nil {
+				if err := onContent(content); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
 // CollectStreamResponse 收集流式响应为完整响应
 func CollectStreamResponse(ctx context.Context, stream types.StreamResponse) (*types.ChatCompletionResponse, error) {
 	logger := utils.GetLogger()
 	processor := NewChatStreamProcessor(stream, logger)
 	defer processor.Close()
 
+	// ctx取消时关闭流，使阻塞中的读取返回
+	stop := context.AfterFunc(ctx, func() { processor.Close() })
+	defer stop()
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -411,6 +583,10 @@ func CollectStreamResponse(ctx context.Context, stream types.StreamResponse) (*t
 
 		_, err := processor.Next()
 		if err != nil {
+			// ctx取消后关闭流导致的结束以上下文错误返回
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, ctxErr
+			}
 			if err == io.EOF {
 				break
 			}
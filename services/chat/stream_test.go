@@ -0,0 +1,135 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hewenyu/newapi-go/internal/utils"
+	"github.com/hewenyu/newapi-go/types"
+)
+
+// reasoningChunks 创建先输出推理增量、再输出回答增量的响应块
+func reasoningChunks(reasoning []string, content []string) []types.ChatCompletionChunk {
+	var chunks []types.ChatCompletionChunk
+	for _, delta := range reasoning {
+		chunks = append(chunks, types.ChatCompletionChunk{
+			ID:      "chatcmpl-stream",
+			Model:   "deepseek-reasoner",
+			Choices: []types.ChatCompletionChunkChoice{{Delta: types.ChatMessage{ReasoningContent: delta}}},
+		})
+	}
+	return append(chunks, textChunks(content...)...)
+}
+
+func TestProcessStreamDeltasSeparatesReasoning(t *testing.T) {
+	service, _ := newFakeService(t, reasoningChunks([]string{"Think", "ing"}, []string{"Ans", "wer"}))
+
+	stream, err := service.CreateChatCompletionStream(context.Background(), []types.ChatMessage{types.NewUserMessage("hi")})
+	if err != nil {
+		t.Fatalf("CreateChatCompletionStream failed: %v", err)
+	}
+
+	var order []string
+	err = ProcessStreamDeltas(context.Background(), stream,
+		func(delta string) error {
+			order = append(order, "reasoning:"+delta)
+			return nil
+		},
+		func(delta string) error {
+			order = append(order, "content:"+delta)
+			return nil
+		})
+	if err != nil {
+		t.Fatalf("ProcessStreamDeltas failed: %v", err)
+	}
+
+	want := []string{"reasoning:Think", "reasoning:ing", "content:Ans", "content:wer"}
+	if len(order) != len(want) {
+		t.Fatalf("deltas = %v, want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Errorf("delta %d = %q, want %q", i, order[i], want[i])
+		}
+	}
+}
+
+func TestProcessStreamDeltasNilHandlerAndError(t *testing.T) {
+	service, _ := newFakeService(t,
+		reasoningChunks([]string{"Think"}, []string{"Ans", "wer"}),
+		reasoningChunks([]string{"Think"}, []string{"Ans"}),
+	)
+	messages := []types.ChatMessage{types.NewUserMessage("hi")}
+
+	// onReasoning为nil时只回调回答增量
+	stream, err := service.CreateChatCompletionStream(context.Background(), messages)
+	if err != nil {
+		t.Fatalf("CreateChatCompletionStream failed: %v", err)
+	}
+	var content string
+	if err := ProcessStreamDeltas(context.Background(), stream, nil, func(delta string) error {
+		content += delta
+		return nil
+	}); err != nil {
+		t.Fatalf("ProcessStreamDeltas failed: %v", err)
+	}
+	if content != "Answer" {
+		t.Errorf("content = %q, want %q", content, "Answer")
+	}
+
+	// 回调返回的错误终止处理
+	stream, err = service.CreateChatCompletionStream(context.Background(), messages)
+	if err != nil {
+		t.Fatalf("CreateChatCompletionStream failed: %v", err)
+	}
+	stop := errors.New("stop")
+	err = ProcessStreamDeltas(context.Background(), stream, func(string) error { return stop }, nil)
+	if !errors.Is(err, stop) {
+		t.Errorf("err = %v, want %v", err, stop)
+	}
+}
+
+func TestCollectReasoningContent(t *testing.T) {
+	service, _ := newFakeService(t, reasoningChunks([]string{"Think", "ing"}, []string{"Ans", "wer"}))
+
+	stream, err := service.CreateChatCompletionStream(context.Background(), []types.ChatMessage{types.NewUserMessage("hi")})
+	if err != nil {
+		t.Fatalf("CreateChatCompletionStream failed: %v", err)
+	}
+	processor := NewChatStreamProcessor(stream, utils.GetLogger())
+	defer processor.Close()
+
+	for {
+		if _, err := processor.Recv(); err != nil {
+			break
+		}
+	}
+	if got := processor.CollectReasoningContent(); got != "Thinking" {
+		t.Errorf("CollectReasoningContent() = %q, want %q", got, "Thinking")
+	}
+	if got := processor.CollectContent(); got != "Answer" {
+		t.Errorf("CollectContent() = %q, want %q", got, "Answer")
+	}
+}
+
+func TestRequestStripsReasoningContent(t *testing.T) {
+	service, fake := newFakeService(t, textResponse("ok"))
+
+	previous := types.NewAssistantMessage("Answer")
+	previous.ReasoningContent = "Thinking"
+	messages := []types.ChatMessage{types.NewUserMessage("hi"), previous, types.NewUserMessage("and?")}
+	if _, err := service.CreateChatCompletion(context.Background(), messages); err != nil {
+		t.Fatalf("CreateChatCompletion failed: %v", err)
+	}
+
+	// 推理模型不接受输入中的reasoning_content，调用方的消息保持不变
+	for i, message := range fake.requests[0].Messages {
+		if message.ReasoningContent != "" {
+			t.Errorf("message %d sent reasoning content %q", i, message.ReasoningContent)
+		}
+	}
+	if messages[1].ReasoningContent != "Thinking" {
+		t.Error("caller messages should not be modified")
+	}
+}
@@ -0,0 +1,214 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"iter"
+	"sync"
+
+	"github.com/hewenyu/newapi-go/internal/utils"
+	"github.com/hewenyu/newapi-go/jsonstream"
+	"github.com/hewenyu/newapi-go/types"
+)
+
+// Stream 类型化的聊天流，直接产出解析后的块，无需手动解码types.StreamEvent。
+// 迭代器结束或提前退出时关闭底层流，已接收的块仍可通过Accumulated合并为完整响应
+type Stream struct {
+	processor *ChatStreamProcessor
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// NewStream 将types.StreamResponse包装为类型化的聊天流
+func NewStream(stream types.StreamResponse) *Stream {
+	if processor, ok := stream.(*ChatStreamProcessor); ok {
+		return &Stream{processor: processor}
+	}
+	return &Stream{processor: NewChatStreamProcessor(stream, utils.GetLogger())}
+}
+
+// StreamChatCompletion 创建类型化的流式聊天完成
+func (s *ChatService) StreamChatCompletion(ctx context.Context, messages []types.ChatMessage, options ...ChatOption) (*Stream, error) {
+	stream, err := s.CreateChatCompletionStream(ctx, messages, options...)
+	if err != nil {
+		return nil, err
+	}
+	return NewStream(stream), nil
+}
+
+// Recv 获取下一个块，流结束时返回io.EOF
+func (s *Stream) Recv() (*types.ChatCompletionChunk, error) {
+	return s.processor.Recv()
+}
+
+// Chunks 按顺序迭代流中的块。流出错时产出一次非nil错误后结束，正常结束时不产出io.EOF
+func (s *Stream) Chunks() iter.Seq2[*types.ChatCompletionChunk, error] {
+	return func(yield func(*types.ChatCompletionChunk, error) bool) {
+		defer s.Close()
+
+		for {
+			chunk, err := s.processor.Recv()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				yield(nil, fmt.Errorf("stream error: %w", err))
+				return
+			}
+			if !yield(chunk, nil) {
+				return
+			}
+		}
+	}
+}
+
+// TextDeltas 迭代回答内容的增量文本，跳过不含内容的块
+func (s *Stream) TextDeltas() iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		for chunk, err := range s.Chunks() {
+			if err != nil {
+				yield("", err)
+				return
+			}
+			for i := range chunk.Choices {
+				if content := chunk.Choices[i].GetContent(); content != "" {
+					if !yield(content, nil) {
+						return
+					}
+				}
+			}
+		}
+	}
+}
+
+// ToolCallDeltas 迭代工具调用的增量片段。片段的Index标识所属的工具调用，
+// 参数分多次到达，需要完整的工具调用时使用Accumulated
+func (s *Stream) ToolCallDeltas() iter.Seq2[types.ToolCall, error] {
+	return func(yield func(types.ToolCall, error) bool) {
+		for chunk, err := range s.Chunks() {
+			if err != nil {
+				yield(types.ToolCall{}, err)
+				return
+			}
+			for i := range chunk.Choices {
+				for _, toolCall := range chunk.Choices[i].Delta.ToolCalls {
+					if !yield(toolCall, nil) {
+						return
+					}
+				}
+			}
+		}
+	}
+}
+
+// ContentJSON 增量解析回答内容中的JSON，适用于response_format为json_object或json_schema的单选择请求。
+// 迭代JSON Pointer路径更新，迭代过程中可调用parser.Value获取部分值，结束后调用parser.Final或Decode获取严格校验的值
+func (s *Stream) ContentJSON(parser *jsonstream.Parser) iter.Seq2[jsonstream.Update, error] {
+	return func(yield func(jsonstream.Update, error) bool) {
+		for delta, err := range s.TextDeltas() {
+			if err != nil {
+				yield(jsonstream.Update{}, err)
+				return
+			}
+			updates, err := parser.Write(delta)
+			for _, update := range updates {
+				if !yield(update, nil) {
+					return
+				}
+			}
+			if err != nil {
+				yield(jsonstream.Update{}, err)
+				return
+			}
+		}
+	}
+}
+
+// ToolArgumentUpdate 工具调用参数的增量更新
+type ToolArgumentUpdate struct {
+	// Index 工具调用在消息中的序号
+	Index int
+	// ID 工具调用ID
+	ID string
+	// Name 函数名称
+	Name string
+	// Update 参数中的路径更新
+	Update jsonstream.Update
+	// Parser 该工具调用参数的解析器，可获取部分值或在结束后严格校验
+	Parser *jsonstream.Parser
+}
+
+// ToolArgumentsJSON 增量解析工具调用参数，每个工具调用使用独立的解析器
+func (s *Stream) ToolArgumentsJSON() iter.Seq2[ToolArgumentUpdate, error] {
+	return func(yield func(ToolArgumentUpdate, error) bool) {
+		calls := make(map[int]*ToolArgumentUpdate)
+		for delta, err := range s.ToolCallDeltas() {
+			if err != nil {
+				yield(ToolArgumentUpdate{}, err)
+				return
+			}
+
+			index := len(calls)
+			if delta.Index != nil {
+				index = *delta.Index
+			}
+			call, exists := calls[index]
+			if !exists {
+				call = &ToolArgumentUpdate{Index: index, Parser: jsonstream.NewParser()}
+				calls[index] = call
+			}
+			if delta.ID != "" {
+				call.ID = delta.ID
+			}
+			if delta.Function.Name != "" {
+				call.Name = delta.Function.Name
+			}
+
+			updates, err := call.Parser.Write(delta.Function.Arguments)
+			for _, update := range updates {
+				result := *call
+				result.Update = update
+				if !yield(result, nil) {
+					return
+				}
+			}
+			if err != nil {
+				yield(ToolArgumentUpdate{Index: index, ID: call.ID, Name: call.Name, Parser: call.Parser},
+					fmt.Errorf("invalid arguments for tool call %s: %w", call.Name, err))
+				return
+			}
+		}
+	}
+}
+
+// Accumulated 将已接收的块合并为完整响应，尚未接收到任何块时返回nil
+func (s *Stream) Accumulated() *types.ChatCompletionResponse {
+	return s.processor.CollectResponse()
+}
+
+// Stats 获取流的时延和吞吐统计，流读完、出错或关闭前返回nil
+func (s *Stream) Stats() *types.StreamStats {
+	return s.processor.Stats()
+}
+
+// Err 获取流的错误，正常结束时为nil
+func (s *Stream) Err() error {
+	if err := s.processor.Err(); err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+// Close 关闭流，可重复调用
+func (s *Stream) Close() error {
+	s.closeOnce.Do(func() {
+		s.closeErr = s.processor.Close()
+	})
+	return s.closeErr
+}
+
+// Context 获取上下文
+func (s *Stream) Context() context.Context {
+	return s.processor.Context()
+}
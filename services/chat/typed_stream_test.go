@@ -0,0 +1,233 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hewenyu/newapi-go/jsonstream"
+	"github.com/hewenyu/newapi-go/types"
+)
+
+func TestStreamTextDeltasAndAccumulated(t *testing.T) {
+	service, _ := newFakeService(t, textChunks("Hel", "lo", "!"))
+
+	stream, err := service.StreamChatCompletion(context.Background(), []types.ChatMessage{types.NewUserMessage("hi")})
+	if err != nil {
+		t.Fatalf("StreamChatCompletion failed: %v", err)
+	}
+
+	var text strings.Builder
+	for delta, err := range stream.TextDeltas() {
+		if err != nil {
+			t.Fatalf("unexpected stream error: %v", err)
+		}
+		text.WriteString(delta)
+	}
+	if text.String() != "Hello!" {
+		t.Errorf("deltas = %q, want %q", text.String(), "Hello!")
+	}
+
+	resp := stream.Accumulated()
+	if resp == nil || resp.GetFirstContent() != "Hello!" {
+		t.Fatalf("accumulated response = %+v", resp)
+	}
+	if stream.Err() != nil {
+		t.Errorf("Err() = %v, want nil", stream.Err())
+	}
+}
+
+func TestStreamChunksStopsEarly(t *testing.T) {
+	service, _ := newFakeService(t, textChunks("a", "b", "c"))
+
+	stream, err := service.StreamChatCompletion(context.Background(), []types.ChatMessage{types.NewUserMessage("hi")})
+	if err != nil {
+		t.Fatalf("StreamChatCompletion failed: %v", err)
+	}
+
+	count := 0
+	for chunk, err := range stream.Chunks() {
+		if err != nil {
+			t.Fatalf("unexpected stream error: %v", err)
+		}
+		if chunk.ID != "chatcmpl-stream" {
+			t.Errorf("chunk ID = %q", chunk.ID)
+		}
+		count++
+		break
+	}
+	if count != 1 {
+		t.Fatalf("received %d chunks, want 1", count)
+	}
+	if resp := stream.Accumulated(); resp == nil || resp.GetFirstContent() != "a" {
+		t.Errorf("accumulated after break = %+v", resp)
+	}
+	if err := stream.Close(); err != nil {
+		t.Errorf("second Close failed: %v", err)
+	}
+}
+
+func TestStreamToolCallDeltas(t *testing.T) {
+	first, second := 0, 0
+	chunks := []types.ChatCompletionChunk{
+		{ID: "chatcmpl-tool", Choices: []types.ChatCompletionChunkChoice{{Delta: types.ChatMessage{
+			Role: types.ChatRoleAssistant,
+			ToolCalls: []types.ToolCall{{Index: &first, ID: "call_1", Type: types.ToolCallTypeFunction,
+				Function: types.FunctionCall{Name: "lookup", Arguments: `{"q":`}}},
+		}}}},
+		{ID: "chatcmpl-tool", Choices: []types.ChatCompletionChunkChoice{{Delta: types.ChatMessage{
+			ToolCalls: []types.ToolCall{{Index: &second, Function: types.FunctionCall{Arguments: `"go"}`}}},
+		}, FinishReason: types.FinishReasonToolCalls}}},
+	}
+	service, _ := newFakeService(t, chunks)
+
+	stream, err := service.StreamChatCompletion(context.Background(), []types.ChatMessage{types.NewUserMessage("hi")})
+	if err != nil {
+		t.Fatalf("StreamChatCompletion failed: %v", err)
+	}
+
+	var arguments strings.Builder
+	for delta, err := range stream.ToolCallDeltas() {
+		if err != nil {
+			t.Fatalf("unexpected stream error: %v", err)
+		}
+		if delta.Index == nil || *delta.Index != 0 {
+			t.Errorf("delta index = %v, want 0", delta.Index)
+		}
+		arguments.WriteString(delta.Function.Arguments)
+	}
+	if arguments.String() != `{"q":"go"}` {
+		t.Errorf("arguments = %q", arguments.String())
+	}
+
+	resp := stream.Accumulated()
+	if resp == nil || len(resp.Choices) != 1 || len(resp.Choices[0].Message.ToolCalls) != 1 {
+		t.Fatalf("accumulated response = %+v", resp)
+	}
+	if call := resp.Choices[0].Message.ToolCalls[0]; call.ID != "call_1" || call.Function.Arguments != `{"q":"go"}` {
+		t.Errorf("accumulated tool call = %+v", call)
+	}
+}
+
+func TestStreamYieldsError(t *testing.T) {
+	service, _ := newFakeService(t, rawReply{status: 200, contentType: "text/event-stream", body: "data: {\"id\":\"x\",\"choices\":[]}\n\ndata: {broken\n\n"})
+
+	stream, err := service.StreamChatCompletion(context.Background(), []types.ChatMessage{types.NewUserMessage("hi")})
+	if err != nil {
+		t.Fatalf("StreamChatCompletion failed: %v", err)
+	}
+
+	var errs int
+	for _, err := range stream.Chunks() {
+		if err != nil {
+			errs++
+		}
+	}
+	if errs != 1 {
+		t.Errorf("received %d errors, want 1", errs)
+	}
+	if stream.Err() == nil {
+		t.Error("Err() should report the parse error")
+	}
+}
+
+func TestStreamContentJSON(t *testing.T) {
+	service, _ := newFakeService(t, textChunks(`{"items":[{"na`, `me":"a"},{"name":"b`, `c"}]}`))
+
+	stream, err := service.StreamChatCompletion(context.Background(), []types.ChatMessage{types.NewUserMessage("hi")})
+	if err != nil {
+		t.Fatalf("StreamChatCompletion failed: %v", err)
+	}
+
+	parser := jsonstream.NewParser()
+	var name strings.Builder
+	for update, err := range stream.ContentJSON(parser) {
+		if err != nil {
+			t.Fatalf("unexpected stream error: %v", err)
+		}
+		if update.Path == "/items/1/name" && update.Op == jsonstream.OpAppend {
+			name.WriteString(update.Delta)
+		}
+	}
+	if name.String() != "bc" {
+		t.Errorf("appended name = %q, want %q", name.String(), "bc")
+	}
+
+	var result struct {
+		Items []struct {
+			Name string `json:"name"`
+		} `json:"items"`
+	}
+	if err := parser.Decode(&result); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if len(result.Items) != 2 || result.Items[1].Name != "bc" {
+		t.Errorf("decoded = %+v", result)
+	}
+}
+
+func TestStreamContentJSONSyntaxError(t *testing.T) {
+	service, _ := newFakeService(t, textChunks(`{"a":1`, `]`))
+
+	stream, err := service.StreamChatCompletion(context.Background(), []types.ChatMessage{types.NewUserMessage("hi")})
+	if err != nil {
+		t.Fatalf("StreamChatCompletion failed: %v", err)
+	}
+
+	var streamErr error
+	for _, err := range stream.ContentJSON(jsonstream.NewParser()) {
+		if err != nil {
+			streamErr = err
+		}
+	}
+	var syntaxErr *jsonstream.SyntaxError
+	if !errors.As(streamErr, &syntaxErr) {
+		t.Fatalf("error = %v, want SyntaxError", streamErr)
+	}
+}
+
+func TestStreamToolArgumentsJSON(t *testing.T) {
+	first, second := 0, 1
+	chunks := []types.ChatCompletionChunk{
+		{ID: "chatcmpl-tool", Choices: []types.ChatCompletionChunkChoice{{Delta: types.ChatMessage{
+			Role: types.ChatRoleAssistant,
+			ToolCalls: []types.ToolCall{{Index: &first, ID: "call_1", Type: types.ToolCallTypeFunction,
+				Function: types.FunctionCall{Name: "lookup", Arguments: `{"q":"g`}}},
+		}}}},
+		{ID: "chatcmpl-tool", Choices: []types.ChatCompletionChunkChoice{{Delta: types.ChatMessage{
+			ToolCalls: []types.ToolCall{{Index: &first, Function: types.FunctionCall{Arguments: `o"}`}}},
+		}}}},
+		{ID: "chatcmpl-tool", Choices: []types.ChatCompletionChunkChoice{{Delta: types.ChatMessage{
+			ToolCalls: []types.ToolCall{{Index: &second, ID: "call_2", Type: types.ToolCallTypeFunction,
+				Function: types.FunctionCall{Name: "weather", Arguments: `{"days":3}`}}},
+		}, FinishReason: types.FinishReasonToolCalls}}},
+	}
+	service, _ := newFakeService(t, chunks)
+
+	stream, err := service.StreamChatCompletion(context.Background(), []types.ChatMessage{types.NewUserMessage("hi")})
+	if err != nil {
+		t.Fatalf("StreamChatCompletion failed: %v", err)
+	}
+
+	parsers := make(map[string]*jsonstream.Parser)
+	for update, err := range stream.ToolArgumentsJSON() {
+		if err != nil {
+			t.Fatalf("unexpected stream error: %v", err)
+		}
+		if update.Index == 0 && (update.ID != "call_1" || update.Name != "lookup") {
+			t.Errorf("update for index 0 = %+v", update)
+		}
+		parsers[update.Name] = update.Parser
+	}
+
+	if len(parsers) != 2 {
+		t.Fatalf("got %d tool calls, want 2", len(parsers))
+	}
+	if value, err := parsers["lookup"].Final(); err != nil || value.(map[string]interface{})["q"] != "go" {
+		t.Errorf("lookup arguments = %v, %v", value, err)
+	}
+	if value, err := parsers["weather"].Final(); err != nil || value.(map[string]interface{})["days"] != float64(3) {
+		t.Errorf("weather arguments = %v, %v", value, err)
+	}
+}
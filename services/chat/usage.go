@@ -0,0 +1,39 @@
+package chat
+
+import (
+	"context"
+
+	"github.com/hewenyu/newapi-go/usage"
+)
+
+// SetUsageRecorder 设置用量记录器，每次发送请求前检查预算，请求成功后记录用量，传入nil取消记录。
+// 缓存命中的响应不记录用量，流式请求的Token用量需要配合WithStreamIncludeUsage使用
+func (s *ChatService) SetUsageRecorder(recorder usage.Recorder) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.recorder = recorder
+}
+
+// usageRecorder 获取用量记录器
+func (s *ChatService) usageRecorder() usage.Recorder {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.recorder
+}
+
+// checkBudget 检查请求是否超出预算
+func (s *ChatService) checkBudget(ctx context.Context, model string) error {
+	if recorder := s.usageRecorder(); recorder != nil {
+		return recorder.Check(ctx, model)
+	}
+	return nil
+}
+
+// recordUsage 记录请求用量
+func (s *ChatService) recordUsage(ctx context.Context, record usage.Record) {
+	if recorder := s.usageRecorder(); recorder != nil {
+		recorder.Record(ctx, record)
+	}
+}
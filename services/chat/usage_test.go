@@ -0,0 +1,112 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/hewenyu/newapi-go/types"
+	"github.com/hewenyu/newapi-go/usage"
+)
+
+func TestUsageRecordedAndBudgetEnforced(t *testing.T) {
+	resp := textResponse("hello")
+	resp.Usage = types.Usage{PromptTokens: 4000, CompletionTokens: 0, TotalTokens: 4000}
+	service, fake := newFakeService(t, resp, resp)
+
+	tracker := usage.NewTracker(usage.WithBudget(usage.Budget{Name: "feature", Tag: "feature-a", MaxCost: 0.01}))
+	service.SetUsageRecorder(tracker)
+
+	ctx := usage.WithTags(context.Background(), "feature-a")
+	messages := []types.ChatMessage{types.NewUserMessage("hi")}
+	if _, err := service.CreateChatCompletion(ctx, messages, WithModel("gpt-4o")); err != nil {
+		t.Fatalf("CreateChatCompletion failed: %v", err)
+	}
+
+	snapshot := tracker.Snapshot()
+	if got := snapshot.ByTag["feature-a"]; got.Requests != 1 || got.PromptTokens != 4000 {
+		t.Errorf("feature-a totals = %+v", got)
+	}
+
+	_, err := service.CreateChatCompletion(ctx, messages, WithModel("gpt-4o"))
+	if !errors.Is(err, usage.ErrBudgetExceeded) {
+		t.Fatalf("expected budget error, got %v", err)
+	}
+	if len(fake.requests) != 1 {
+		t.Errorf("rejected request should not be sent, requests = %d", len(fake.requests))
+	}
+}
+
+func TestStreamUsageRecorded(t *testing.T) {
+	chunks := textChunks("Hel", "lo")
+	chunks = append(chunks, types.ChatCompletionChunk{
+		ID:      "chatcmpl-stream",
+		Model:   "gpt-4o",
+		Choices: []types.ChatCompletionChunkChoice{},
+		Usage:   &types.Usage{PromptTokens: 7, CompletionTokens: 2, TotalTokens: 9},
+	})
+	service, _ := newFakeService(t, chunks)
+
+	tracker := usage.NewTracker()
+	service.SetUsageRecorder(tracker)
+
+	stream, err := service.CreateChatCompletionStream(context.Background(),
+		[]types.ChatMessage{types.NewUserMessage("hi")}, WithModel("gpt-4o"), WithStreamIncludeUsage())
+	if err != nil {
+		t.Fatalf("CreateChatCompletionStream failed: %v", err)
+	}
+	defer stream.Close()
+	for {
+		if _, err := stream.Next(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("Next failed: %v", err)
+		}
+	}
+
+	total := tracker.Total()
+	if total.Requests != 1 || total.PromptTokens != 7 || total.CompletionTokens != 2 {
+		t.Errorf("total = %+v", total)
+	}
+}
+
+func TestStreamUsageRecordedOnCloseAndError(t *testing.T) {
+	service, _ := newFakeService(t,
+		textChunks("Hel", "lo"),
+		rawReply{status: 200, contentType: "text/event-stream", body: "data: {\"id\":\"chatcmpl-broken\"\n\n"},
+	)
+	tracker := usage.NewTracker()
+	service.SetUsageRecorder(tracker)
+	messages := []types.ChatMessage{types.NewUserMessage("hi")}
+
+	// 读取部分内容后提前关闭，重复关闭只记录一次
+	stream, err := service.CreateChatCompletionStream(context.Background(), messages, WithModel("gpt-4o"))
+	if err != nil {
+		t.Fatalf("CreateChatCompletionStream failed: %v", err)
+	}
+	if _, err := stream.Next(); err != nil {
+		t.Fatalf("Next failed: %v", err)
+	}
+	stream.Close()
+	stream.Close()
+	if total := tracker.Total(); total.Requests != 1 {
+		t.Errorf("requests after Close = %d, want 1", total.Requests)
+	}
+
+	// 读取出错的流同样记录
+	stream, err = service.CreateChatCompletionStream(context.Background(), messages, WithModel("gpt-4o"))
+	if err != nil {
+		t.Fatalf("CreateChatCompletionStream failed: %v", err)
+	}
+	if _, err := stream.Next(); err == nil || err == io.EOF {
+		t.Fatalf("expected a parse error, got %v", err)
+	}
+	if total := tracker.Total(); total.Requests != 2 {
+		t.Errorf("requests after error = %d, want 2", total.Requests)
+	}
+	stream.Close()
+	if total := tracker.Total(); total.Requests != 2 {
+		t.Errorf("requests after closing the failed stream = %d, want 2", total.Requests)
+	}
+}
@@ -0,0 +1,200 @@
+package completions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/hewenyu/newapi-go/internal/transport"
+	"github.com/hewenyu/newapi-go/internal/utils"
+	"github.com/hewenyu/newapi-go/types"
+	"github.com/hewenyu/newapi-go/usage"
+	"go.uber.org/zap"
+)
+
+// CompletionService 旧版文本补全服务结构体
+type CompletionService struct {
+	transport transport.HTTPTransport
+	logger    utils.Logger
+	config    *CompletionConfig
+	recorder  usage.Recorder
+	mu        sync.RWMutex
+}
+
+// NewCompletionService 创建新的文本补全服务实例
+func NewCompletionService(transport transport.HTTPTransport, logger utils.Logger, options ...CompletionOption) *CompletionService {
+	config := DefaultCompletionConfig()
+
+	// 应用选项
+	for _, option := range options {
+		option(config)
+	}
+
+	return &CompletionService{
+		transport: transport,
+		logger:    logger,
+		config:    config,
+	}
+}
+
+// parseJSONResponse 解析JSON响应
+func parseJSONResponse(resp *http.Response, v interface{}) error {
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("failed to unmarshal JSON: %w", err)
+	}
+
+	return nil
+}
+
+// CreateCompletion 创建单个提示词的文本补全
+func (s *CompletionService) CreateCompletion(ctx context.Context, prompt string, options ...CompletionOption) (*types.CompletionResponse, error) {
+	return s.create(ctx, prompt, options)
+}
+
+// CreateCompletions 创建多个提示词的文本补全，每个提示词生成n个选择
+func (s *CompletionService) CreateCompletions(ctx context.Context, prompts []string, options ...CompletionOption) (*types.CompletionResponse, error) {
+	return s.create(ctx, prompts, options)
+}
+
+// CreateCompletionFromTokens 使用Token作为提示词创建文本补全
+func (s *CompletionService) CreateCompletionFromTokens(ctx context.Context, tokens []int, options ...CompletionOption) (*types.CompletionResponse, error) {
+	return s.create(ctx, tokens, options)
+}
+
+// buildRequest 应用选项并构建经过验证的请求
+func (s *CompletionService) buildRequest(prompt interface{}, options []CompletionOption, stream bool) (*types.CompletionRequest, error) {
+	// 创建配置副本并应用选项
+	config := s.getConfig()
+	for _, option := range options {
+		option(config)
+	}
+
+	// 验证配置
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid completion config: %w", err)
+	}
+
+	// 构建请求，stream_options 仅适用于流式请求
+	req := config.ToRequest(prompt)
+	req.Stream = stream
+	if !stream {
+		req.StreamOptions = nil
+	}
+
+	// 验证请求参数
+	if err := req.ValidateParameters(); err != nil {
+		return nil, fmt.Errorf("invalid request parameters: %w", err)
+	}
+	return req, nil
+}
+
+// create 发送非流式文本补全请求
+func (s *CompletionService) create(ctx context.Context, prompt interface{}, options []CompletionOption) (*types.CompletionResponse, error) {
+	req, err := s.buildRequest(prompt, options, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkBudget(ctx, req.Model); err != nil {
+		return nil, err
+	}
+
+	// 发送请求
+	resp, err := s.transport.Post(ctx, "/v1/completions", req)
+	if err != nil {
+		s.logger.Error("Failed to create completion", zap.Error(err))
+		return nil, fmt.Errorf("failed to create completion: %w", err)
+	}
+	statusCode := resp.StatusCode
+
+	// 解析响应
+	var completionResp types.CompletionResponse
+	if err := parseJSONResponse(resp, &completionResp); err != nil {
+		if statusCode >= http.StatusBadRequest {
+			apiErr := types.FromHTTPStatusCode(statusCode, http.StatusText(statusCode))
+			s.logger.Error("API returned error", zap.Int("status_code", statusCode))
+			return nil, fmt.Errorf("API error: %w", apiErr)
+		}
+		s.logger.Error("Failed to parse completion response", zap.Error(err))
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	// 检查API错误
+	if completionResp.IsError() {
+		errResp := completionResp.GetError()
+		apiErr := types.NewAPIError(errResp.Type, errResp.Code, errResp.Message, statusCode).WithParam(errResp.Param)
+		s.logger.Error("API returned error", zap.String("error", errResp.Message))
+		return nil, fmt.Errorf("API error: %w", apiErr)
+	}
+	if statusCode >= http.StatusBadRequest {
+		apiErr := types.FromHTTPStatusCode(statusCode, http.StatusText(statusCode))
+		s.logger.Error("API returned error", zap.Int("status_code", statusCode))
+		return nil, fmt.Errorf("API error: %w", apiErr)
+	}
+
+	s.recordUsage(ctx, usage.FromUsage(usage.ServiceCompletion, req.Model, completionResp.Usage))
+
+	s.logger.Debug("Completion created successfully", zap.String("id", completionResp.ID))
+	return &completionResp, nil
+}
+
+// CreateCompletionStream 创建流式文本补全
+func (s *CompletionService) CreateCompletionStream(ctx context.Context, prompt string, options ...CompletionOption) (types.StreamResponse, error) {
+	req, err := s.buildRequest(prompt, options, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkBudget(ctx, req.Model); err != nil {
+		return nil, err
+	}
+
+	// 发送流式请求
+	streamReader, err := s.transport.PostStream(ctx, "/v1/completions", req)
+	if err != nil {
+		s.logger.Error("Failed to create completion stream", zap.Error(err))
+		return nil, fmt.Errorf("failed to create completion stream: %w", err)
+	}
+
+	stream := &streamReaderAdapter{
+		reader: streamReader,
+		ctx:    ctx,
+		meter:  usage.NewStreamMeter(ctx, s.usageRecorder(), usage.ServiceCompletion, req.Model),
+	}
+
+	s.logger.Debug("Completion stream created successfully")
+	return NewCompletionStreamProcessor(stream, s.logger), nil
+}
+
+// UpdateConfig 更新配置
+func (s *CompletionService) UpdateConfig(options ...CompletionOption) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, option := range options {
+		option(s.config)
+	}
+}
+
+// GetConfig 获取配置副本
+func (s *CompletionService) GetConfig() *CompletionConfig {
+	return s.getConfig()
+}
+
+// getConfig 获取配置副本（内部使用）
+func (s *CompletionService) getConfig() *CompletionConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.config.Clone()
+}
@@ -0,0 +1,166 @@
+package completions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hewenyu/newapi-go/internal/transport"
+	"github.com/hewenyu/newapi-go/internal/utils"
+	"github.com/hewenyu/newapi-go/types"
+	"github.com/hewenyu/newapi-go/usage"
+)
+
+// newTestService 创建连接到测试处理函数的文本补全服务
+func newTestService(t *testing.T, handler http.HandlerFunc) *CompletionService {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return NewCompletionService(transport.NewHTTPClient(server.URL, "test-key"), utils.GetLogger())
+}
+
+func TestCreateCompletionSendsLegacyParameters(t *testing.T) {
+	var body map[string]interface{}
+	service := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/completions" {
+			t.Errorf("path = %s, want /v1/completions", r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		json.NewEncoder(w).Encode(types.CompletionResponse{
+			ID:      "cmpl-1",
+			Object:  types.CompletionObjectText,
+			Choices: []types.CompletionChoice{{Text: " world", FinishReason: types.FinishReasonStop}},
+		})
+	})
+
+	resp, err := service.CreateCompletion(context.Background(), "hello",
+		WithModel("davinci-002"), WithSuffix("!"), WithEcho(true), WithBestOf(3), WithLogProbs(2))
+	if err != nil {
+		t.Fatalf("CreateCompletion failed: %v", err)
+	}
+	if resp.GetFirstText() != " world" {
+		t.Errorf("text = %q", resp.GetFirstText())
+	}
+
+	want := map[string]interface{}{
+		"model": "davinci-002", "prompt": "hello", "suffix": "!", "echo": true, "best_of": float64(3), "logprobs": float64(2),
+	}
+	for key, value := range want {
+		if body[key] != value {
+			t.Errorf("request %s = %v, want %v", key, body[key], value)
+		}
+	}
+	if _, ok := body["stream"]; ok {
+		t.Error("non-stream request must not send stream")
+	}
+}
+
+func TestCreateCompletionValidation(t *testing.T) {
+	service := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("invalid request reached the server")
+	})
+	ctx := context.Background()
+
+	if _, err := service.CreateCompletion(ctx, ""); err == nil {
+		t.Error("expected error for empty prompt")
+	}
+	if _, err := service.CreateCompletion(ctx, "hi", WithN(2), WithBestOf(1)); err == nil {
+		t.Error("expected error for best_of < n")
+	}
+	if _, err := service.CreateCompletionStream(ctx, "hi", WithBestOf(2)); err == nil {
+		t.Error("expected error for best_of with stream")
+	}
+	if _, err := service.CreateCompletion(ctx, "hi", WithLogProbs(types.MaxCompletionLogProbs+1)); err == nil {
+		t.Error("expected error for logprobs above the limit")
+	}
+}
+
+func TestCreateCompletionAPIError(t *testing.T) {
+	service := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprint(w, `{"error":{"type":"rate_limit_error","message":"slow down"}}`)
+	})
+
+	_, err := service.CreateCompletion(context.Background(), "hi")
+	var apiErr *types.APIError
+	if !errors.As(err, &apiErr) || apiErr.HTTPStatusCode != http.StatusTooManyRequests {
+		t.Fatalf("err = %v, want APIError with status 429", err)
+	}
+}
+
+func TestCreateCompletionStream(t *testing.T) {
+	service := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		var req types.CompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if !req.Stream {
+			t.Error("expected stream request")
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, text := range []string{"Once", " upon", " a time"} {
+			data, _ := json.Marshal(types.CompletionResponse{ID: "cmpl-s", Model: "davinci-002",
+				Choices: []types.CompletionChoice{{Text: text}}})
+			fmt.Fprintf(w, "data: %s\n\n", data)
+		}
+		fmt.Fprint(w, `data: {"id":"cmpl-s","choices":[],"usage":{"prompt_tokens":2,"completion_tokens":3,"total_tokens":5}}`+"\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	})
+
+	stream, err := service.CreateCompletionStream(context.Background(), "tell a story", WithStreamIncludeUsage())
+	if err != nil {
+		t.Fatalf("CreateCompletionStream failed: %v", err)
+	}
+	defer stream.Close()
+
+	processor := stream.(*CompletionStreamProcessor)
+	var received string
+	for {
+		chunk, err := processor.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv failed: %v", err)
+		}
+		if len(chunk.Choices) > 0 {
+			received += chunk.Choices[0].Text
+		}
+	}
+
+	if received != "Once upon a time" || processor.CollectText() != received {
+		t.Errorf("received %q, collected %q", received, processor.CollectText())
+	}
+	resp := processor.CollectResponse()
+	if resp.Usage == nil || resp.Usage.TotalTokens != 5 {
+		t.Errorf("usage = %+v", resp.Usage)
+	}
+}
+
+func TestCompletionStreamUsageRecordedOnClose(t *testing.T) {
+	service := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, `data: {"id":"cmpl-s","choices":[{"text":"Once"}]}`+"\n\n")
+		fmt.Fprint(w, `data: {"id":"cmpl-s","choices":[{"text":" upon"}]}`+"\n\n")
+	})
+	tracker := usage.NewTracker()
+	service.SetUsageRecorder(tracker)
+
+	stream, err := service.CreateCompletionStream(context.Background(), "tell a story")
+	if err != nil {
+		t.Fatalf("CreateCompletionStream failed: %v", err)
+	}
+	if _, err := stream.Next(); err != nil {
+		t.Fatalf("Next failed: %v", err)
+	}
+
+	// 未读完就关闭的流同样记录一次请求
+	stream.Close()
+	stream.Close()
+	if total := tracker.Total(); total.Requests != 1 {
+		t.Errorf("requests = %d, want 1", total.Requests)
+	}
+}
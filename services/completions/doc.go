@@ -0,0 +1,4 @@
+// Package completions provides legacy text completion functionality for the New-API Go SDK.
+// This package handles the /v1/completions endpoint used by base and fine-tuned
+// models that do not support the chat completion format.
+package completions
@@ -0,0 +1,234 @@
+package completions
+
+import (
+	"fmt"
+
+	"github.com/hewenyu/newapi-go/types"
+)
+
+// CompletionOption 文本补全选项函数类型
+type CompletionOption func(*CompletionConfig)
+
+// CompletionConfig 文本补全配置结构
+// 采样参数使用指针类型，nil 表示不发送该参数
+type CompletionConfig struct {
+	Model            string                   `json:"model"`
+	Suffix           string                   `json:"suffix"`
+	MaxTokens        int                      `json:"max_tokens"`
+	Temperature      *float64                 `json:"temperature"`
+	TopP             *float64                 `json:"top_p"`
+	N                int                      `json:"n"`
+	StreamOptions    *types.ChatStreamOptions `json:"stream_options"`
+	LogProbs         *int                     `json:"logprobs"`
+	Echo             bool                     `json:"echo"`
+	Stop             interface{}              `json:"stop"`
+	PresencePenalty  *float64                 `json:"presence_penalty"`
+	FrequencyPenalty *float64                 `json:"frequency_penalty"`
+	BestOf           int                      `json:"best_of"`
+	LogitBias        map[string]float64       `json:"logit_bias"`
+	Seed             *int                     `json:"seed"`
+	User             string                   `json:"user"`
+	ExtraBody        map[string]interface{}   `json:"extra_body"`
+}
+
+// DefaultCompletionConfig 返回默认的文本补全配置
+func DefaultCompletionConfig() *CompletionConfig {
+	return &CompletionConfig{
+		Model:     "gpt-3.5-turbo-instruct",
+		MaxTokens: 256,
+		N:         1,
+		LogitBias: make(map[string]float64),
+		ExtraBody: make(map[string]interface{}),
+	}
+}
+
+// WithModel 设置补全模型
+func WithModel(model string) CompletionOption {
+	return func(config *CompletionConfig) {
+		config.Model = model
+	}
+}
+
+// WithSuffix 设置插入文本之后的后缀
+func WithSuffix(suffix string) CompletionOption {
+	return func(config *CompletionConfig) {
+		config.Suffix = suffix
+	}
+}
+
+// WithMaxTokens 设置最大Token数量
+func WithMaxTokens(maxTokens int) CompletionOption {
+	return func(config *CompletionConfig) {
+		config.MaxTokens = maxTokens
+	}
+}
+
+// WithTemperature 设置温度参数
+func WithTemperature(temperature float64) CompletionOption {
+	return func(config *CompletionConfig) {
+		config.Temperature = types.Float64Ptr(temperature)
+	}
+}
+
+// WithTopP 设置Top-P参数
+func WithTopP(topP float64) CompletionOption {
+	return func(config *CompletionConfig) {
+		config.TopP = types.Float64Ptr(topP)
+	}
+}
+
+// WithN 设置生成数量
+func WithN(n int) CompletionOption {
+	return func(config *CompletionConfig) {
+		config.N = n
+	}
+}
+
+// WithStreamIncludeUsage 在流式响应的最后一个块中包含使用量统计
+func WithStreamIncludeUsage() CompletionOption {
+	return func(config *CompletionConfig) {
+		config.StreamOptions = &types.ChatStreamOptions{IncludeUsage: true}
+	}
+}
+
+// WithLogProbs 设置返回的候选Token对数概率数量，最大为types.MaxCompletionLogProbs
+func WithLogProbs(logProbs int) CompletionOption {
+	return func(config *CompletionConfig) {
+		config.LogProbs = types.IntPtr(logProbs)
+	}
+}
+
+// WithEcho 设置是否在结果中回显提示词
+func WithEcho(echo bool) CompletionOption {
+	return func(config *CompletionConfig) {
+		config.Echo = echo
+	}
+}
+
+// WithStop 设置停止序列
+func WithStop(stop interface{}) CompletionOption {
+	return func(config *CompletionConfig) {
+		config.Stop = stop
+	}
+}
+
+// WithPresencePenalty 设置存在惩罚
+func WithPresencePenalty(penalty float64) CompletionOption {
+	return func(config *CompletionConfig) {
+		config.PresencePenalty = types.Float64Ptr(penalty)
+	}
+}
+
+// WithFrequencyPenalty 设置频率惩罚
+func WithFrequencyPenalty(penalty float64) CompletionOption {
+	return func(config *CompletionConfig) {
+		config.FrequencyPenalty = types.Float64Ptr(penalty)
+	}
+}
+
+// WithBestOf 设置服务端生成的候选数量，返回其中对数概率最高的n个，不能用于流式请求
+func WithBestOf(bestOf int) CompletionOption {
+	return func(config *CompletionConfig) {
+		config.BestOf = bestOf
+	}
+}
+
+// WithLogitBias 设置Logit偏置
+func WithLogitBias(bias map[string]float64) CompletionOption {
+	return func(config *CompletionConfig) {
+		config.LogitBias = bias
+	}
+}
+
+// WithSeed 设置随机种子
+func WithSeed(seed int) CompletionOption {
+	return func(config *CompletionConfig) {
+		config.Seed = types.IntPtr(seed)
+	}
+}
+
+// WithUser 设置用户标识
+func WithUser(user string) CompletionOption {
+	return func(config *CompletionConfig) {
+		config.User = user
+	}
+}
+
+// WithExtraBody 设置额外的请求体参数
+func WithExtraBody(extraBody map[string]interface{}) CompletionOption {
+	return func(config *CompletionConfig) {
+		config.ExtraBody = extraBody
+	}
+}
+
+// ToRequest 将配置转换为请求结构
+func (c *CompletionConfig) ToRequest(prompt interface{}) *types.CompletionRequest {
+	req := &types.CompletionRequest{
+		Model:            c.Model,
+		Prompt:           prompt,
+		Suffix:           c.Suffix,
+		MaxTokens:        c.MaxTokens,
+		Temperature:      c.Temperature,
+		TopP:             c.TopP,
+		N:                c.N,
+		StreamOptions:    c.StreamOptions,
+		LogProbs:         c.LogProbs,
+		Echo:             c.Echo,
+		Stop:             c.Stop,
+		PresencePenalty:  c.PresencePenalty,
+		FrequencyPenalty: c.FrequencyPenalty,
+		BestOf:           c.BestOf,
+		LogitBias:        c.LogitBias,
+		Seed:             c.Seed,
+		User:             c.User,
+		ExtraBody:        c.ExtraBody,
+	}
+
+	// 设置默认值
+	req.SetDefaults()
+
+	return req
+}
+
+// Clone 克隆配置
+func (c *CompletionConfig) Clone() *CompletionConfig {
+	clone := *c
+
+	// 深拷贝map
+	if c.LogitBias != nil {
+		clone.LogitBias = make(map[string]float64, len(c.LogitBias))
+		for k, v := range c.LogitBias {
+			clone.LogitBias[k] = v
+		}
+	}
+
+	if c.ExtraBody != nil {
+		clone.ExtraBody = make(map[string]interface{}, len(c.ExtraBody))
+		for k, v := range c.ExtraBody {
+			clone.ExtraBody[k] = v
+		}
+	}
+
+	return &clone
+}
+
+// Validate 验证配置
+func (c *CompletionConfig) Validate() error {
+	if c.Model == "" {
+		return fmt.Errorf("model cannot be empty")
+	}
+
+	if c.MaxTokens < 0 {
+		return fmt.Errorf("max_tokens must be non-negative")
+	}
+
+	if c.N < 1 {
+		return fmt.Errorf("n must be at least 1")
+	}
+
+	if c.BestOf != 0 && c.BestOf < c.N {
+		return fmt.Errorf("best_of must be greater than or equal to n")
+	}
+
+	return nil
+}
@@ -0,0 +1,255 @@
+package completions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/hewenyu/newapi-go/internal/transport"
+	"github.com/hewenyu/newapi-go/internal/utils"
+	"github.com/hewenyu/newapi-go/types"
+	"github.com/hewenyu/newapi-go/usage"
+	"go.uber.org/zap"
+)
+
+// streamReaderAdapter 适配器，将transport.StreamReader适配为types.StreamResponse
+type streamReaderAdapter struct {
+	reader transport.StreamReader
+	ctx    context.Context
+	meter  *usage.StreamMeter
+}
+
+// Next 获取下一个事件
+func (a *streamReaderAdapter) Next() (*types.StreamEvent, error) {
+	data, err := a.reader.Read()
+	if err != nil {
+		// 流结束或出错都记录一次，出错前已产生的用量同样计费
+		a.meter.Finish()
+		return nil, err
+	}
+
+	// transport已返回原始JSON，其他实现的数据再编码为JSON
+	jsonData, ok := data.(json.RawMessage)
+	if !ok {
+		var marshalErr error
+		if jsonData, marshalErr = json.Marshal(data); marshalErr != nil {
+			return nil, fmt.Errorf("failed to marshal stream data: %w", marshalErr)
+		}
+	}
+	a.meter.Observe(jsonData)
+
+	return &types.StreamEvent{
+		Type: types.StreamEventTypeData,
+		Data: jsonData,
+	}, nil
+}
+
+// Close 关闭流，提前关闭的流同样记录用量
+func (a *streamReaderAdapter) Close() error {
+	a.meter.Finish()
+	return a.reader.Close()
+}
+
+// Err 获取错误
+func (a *streamReaderAdapter) Err() error {
+	return a.reader.Err()
+}
+
+// Done 检查是否完成
+func (a *streamReaderAdapter) Done() bool {
+	return false
+}
+
+// Context 获取上下文
+func (a *streamReaderAdapter) Context() context.Context {
+	return a.ctx
+}
+
+// CompletionStreamProcessor 文本补全流式处理器
+type CompletionStreamProcessor struct {
+	stream   types.StreamResponse
+	logger   utils.Logger
+	mu       sync.RWMutex
+	chunks   []types.CompletionResponse
+	finished bool
+	err      error
+}
+
+// NewCompletionStreamProcessor 创建新的文本补全流式处理器
+func NewCompletionStreamProcessor(stream types.StreamResponse, logger utils.Logger) *CompletionStreamProcessor {
+	return &CompletionStreamProcessor{
+		stream: stream,
+		logger: logger,
+		chunks: make([]types.CompletionResponse, 0),
+	}
+}
+
+// Next 获取下一个流式事件，流中的错误体作为API错误返回
+func (p *CompletionStreamProcessor) Next() (*types.StreamEvent, error) {
+	p.mu.RLock()
+	if p.finished {
+		p.mu.RUnlock()
+		return nil, io.EOF
+	}
+	p.mu.RUnlock()
+
+	event, err := p.stream.Next()
+	if err == nil && event.Type == types.StreamEventTypeData {
+		var chunk types.CompletionResponse
+		if parseErr := json.Unmarshal(event.Data, &chunk); parseErr != nil {
+			p.logger.Warn("Failed to parse completion chunk", zap.Error(parseErr))
+		} else if chunk.IsError() {
+			errResp := chunk.GetError()
+			err = fmt.Errorf("API error: %w", types.NewAPIError(errResp.Type, errResp.Code, errResp.Message, 0).WithParam(errResp.Param))
+		} else {
+			p.mu.Lock()
+			p.chunks = append(p.chunks, chunk)
+			p.mu.Unlock()
+		}
+	}
+
+	if err != nil {
+		p.mu.Lock()
+		p.err = err
+		p.finished = true
+		p.mu.Unlock()
+		return nil, err
+	}
+	return event, nil
+}
+
+// Recv 获取下一个解析后的块，流结束时返回io.EOF
+func (p *CompletionStreamProcessor) Recv() (*types.CompletionResponse, error) {
+	for {
+		p.mu.RLock()
+		count := len(p.chunks)
+		p.mu.RUnlock()
+
+		if _, err := p.Next(); err != nil {
+			return nil, err
+		}
+
+		p.mu.RLock()
+		if len(p.chunks) > count {
+			chunk := p.chunks[len(p.chunks)-1]
+			p.mu.RUnlock()
+			return &chunk, nil
+		}
+		p.mu.RUnlock()
+	}
+}
+
+// Close 关闭流式处理器
+func (p *CompletionStreamProcessor) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.finished = true
+	if p.stream != nil {
+		return p.stream.Close()
+	}
+	return nil
+}
+
+// Err 获取错误
+func (p *CompletionStreamProcessor) Err() error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.err
+}
+
+// Done 检查是否完成
+func (p *CompletionStreamProcessor) Done() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.finished
+}
+
+// Context 获取上下文
+func (p *CompletionStreamProcessor) Context() context.Context {
+	if p.stream != nil {
+		return p.stream.Context()
+	}
+	return context.Background()
+}
+
+// GetChunks 获取所有已接收的块
+func (p *CompletionStreamProcessor) GetChunks() []types.CompletionResponse {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	result := make([]types.CompletionResponse, len(p.chunks))
+	copy(result, p.chunks)
+	return result
+}
+
+// CollectText 收集第一个选择的完整文本
+func (p *CompletionStreamProcessor) CollectText() string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var text strings.Builder
+	for _, chunk := range p.chunks {
+		for _, choice := range chunk.Choices {
+			if choice.Index == 0 {
+				text.WriteString(choice.Text)
+			}
+		}
+	}
+	return text.String()
+}
+
+// CollectResponse 将已接收的块合并为完整响应
+func (p *CompletionStreamProcessor) CollectResponse() *types.CompletionResponse {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if len(p.chunks) == 0 {
+		return nil
+	}
+
+	firstChunk := p.chunks[0]
+	response := &types.CompletionResponse{
+		ID:                firstChunk.ID,
+		Object:            types.CompletionObjectText,
+		Created:           firstChunk.Created,
+		Model:             firstChunk.Model,
+		SystemFingerprint: firstChunk.SystemFingerprint,
+		Choices:           make([]types.CompletionChoice, 0),
+	}
+
+	// 合并所有选择
+	choiceMap := make(map[int]*types.CompletionChoice)
+	for _, chunk := range p.chunks {
+		for _, chunkChoice := range chunk.Choices {
+			choice, exists := choiceMap[chunkChoice.Index]
+			if !exists {
+				choice = &types.CompletionChoice{Index: chunkChoice.Index}
+				choiceMap[chunkChoice.Index] = choice
+			}
+			choice.Text += chunkChoice.Text
+			choice.LogProbs = types.MergeLogProbs(choice.LogProbs, chunkChoice.LogProbs)
+			if chunkChoice.FinishReason != "" {
+				choice.FinishReason = chunkChoice.FinishReason
+			}
+		}
+
+		// 更新使用情况
+		if chunk.Usage != nil {
+			usage := *chunk.Usage
+			response.Usage = &usage
+		}
+	}
+
+	// 转换为切片
+	for i := 0; i < len(choiceMap); i++ {
+		if choice, exists := choiceMap[i]; exists {
+			response.Choices = append(response.Choices, *choice)
+		}
+	}
+
+	return response
+}
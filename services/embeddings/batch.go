@@ -0,0 +1,366 @@
+package embeddings
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hewenyu/newapi-go/tokenizer"
+	"github.com/hewenyu/newapi-go/types"
+	"go.uber.org/zap"
+)
+
+// 批量嵌入的默认参数，低于常见网关的单次请求限制
+const (
+	DefaultBatchSize        = 256
+	DefaultBatchTokens      = 100000
+	DefaultBatchConcurrency = 4
+	DefaultBatchRetries     = 2
+	DefaultBatchRetryDelay  = time.Second
+)
+
+// BatchProgress 批量嵌入的进度
+type BatchProgress struct {
+	// TotalBatches 拆分出的请求数
+	TotalBatches int
+	// CompletedBatches 已结束的请求数，包括最终失败的请求
+	CompletedBatches int
+	// TotalInputs 输入总数
+	TotalInputs int
+	// EmbeddedInputs 已成功嵌入的输入数
+	EmbeddedInputs int
+	// FailedInputs 所在请求最终失败的输入数
+	FailedInputs int
+	// Usage 已成功请求的累计用量
+	Usage types.Usage
+}
+
+// BatchOption 批量嵌入选项函数类型
+type BatchOption func(*BatchConfig)
+
+// BatchConfig 批量嵌入配置
+type BatchConfig struct {
+	// BatchSize 每个请求的最大输入数
+	BatchSize int
+	// BatchTokens 每个请求的Token预算，0表示不限制。单个超出预算的输入单独成为一个请求
+	BatchTokens int
+	// Concurrency 最大并发请求数
+	Concurrency int
+	// MaxRetries 单个请求失败后的最大重试次数，只重试限流、服务端和网络等暂时性错误
+	MaxRetries int
+	// RetryDelay 首次重试前的等待时间，之后每次加倍
+	RetryDelay time.Duration
+	// OnProgress 每个请求结束后调用，调用是串行的
+	OnProgress func(BatchProgress)
+	// Options 每个请求使用的嵌入选项
+	Options []EmbeddingOption
+}
+
+// DefaultBatchConfig 默认批量嵌入配置
+func DefaultBatchConfig() *BatchConfig {
+	return &BatchConfig{
+		BatchSize:   DefaultBatchSize,
+		BatchTokens: DefaultBatchTokens,
+		Concurrency: DefaultBatchConcurrency,
+		MaxRetries:  DefaultBatchRetries,
+		RetryDelay:  DefaultBatchRetryDelay,
+	}
+}
+
+// WithBatchSize 设置每个请求的最大输入数
+func WithBatchSize(size int) BatchOption {
+	return func(c *BatchConfig) {
+		c.BatchSize = size
+	}
+}
+
+// WithBatchTokens 设置每个请求的Token预算，0表示不限制
+func WithBatchTokens(tokens int) BatchOption {
+	return func(c *BatchConfig) {
+		c.BatchTokens = tokens
+	}
+}
+
+// WithConcurrency 设置最大并发请求数
+func WithConcurrency(concurrency int) BatchOption {
+	return func(c *BatchConfig) {
+		c.Concurrency = concurrency
+	}
+}
+
+// WithBatchRetry 设置单个请求的最大重试次数和首次重试前的等待时间
+func WithBatchRetry(maxRetries int, delay time.Duration) BatchOption {
+	return func(c *BatchConfig) {
+		c.MaxRetries = maxRetries
+		c.RetryDelay = delay
+	}
+}
+
+// WithProgress 设置进度回调
+func WithProgress(onProgress func(BatchProgress)) BatchOption {
+	return func(c *BatchConfig) {
+		c.OnProgress = onProgress
+	}
+}
+
+// WithRequestOptions 设置每个请求使用的嵌入选项
+func WithRequestOptions(options ...EmbeddingOption) BatchOption {
+	return func(c *BatchConfig) {
+		c.Options = append(c.Options, options...)
+	}
+}
+
+// Validate 验证配置
+func (c *BatchConfig) Validate() error {
+	if c.BatchSize <= 0 {
+		return types.NewValidationError("batch_size", c.BatchSize, "batch size must be positive", types.ErrCodeInvalidParameter)
+	}
+	if c.BatchTokens < 0 {
+		return types.NewValidationError("batch_tokens", c.BatchTokens, "batch tokens must be non-negative", types.ErrCodeInvalidParameter)
+	}
+	if c.Concurrency <= 0 {
+		return types.NewValidationError("concurrency", c.Concurrency, "concurrency must be positive", types.ErrCodeInvalidParameter)
+	}
+	if c.MaxRetries < 0 {
+		return types.NewValidationError("max_retries", c.MaxRetries, "max retries must be non-negative", types.ErrCodeInvalidParameter)
+	}
+	if c.RetryDelay < 0 {
+		return types.NewValidationError("retry_delay", c.RetryDelay, "retry delay must be non-negative", types.ErrCodeInvalidParameter)
+	}
+	return nil
+}
+
+// BatchResult EmbedAll的结果
+type BatchResult struct {
+	// Response 合并后的响应，Data按输入顺序排列且Index为输入下标，只包含成功的输入；Usage为所有成功请求的总和
+	Response *types.EmbeddingResponse
+	// Errors 与输入一一对应的错误，成功的输入为nil
+	Errors []error
+	// Failed 失败的输入数
+	Failed int
+}
+
+// Err 获取合并的错误，全部成功时返回nil
+func (r *BatchResult) Err() error {
+	if r.Failed == 0 {
+		return nil
+	}
+
+	// 同一请求的输入共享同一个错误，只保留一份
+	var errs []error
+	var last error
+	for _, err := range r.Errors {
+		if err != nil && err != last {
+			errs = append(errs, err)
+			last = err
+		}
+	}
+	return fmt.Errorf("%d of %d inputs failed: %w", r.Failed, len(r.Errors), errors.Join(errs...))
+}
+
+// embeddingBatch 拆分出的单个请求
+type embeddingBatch struct {
+	start int
+	texts []string
+}
+
+// EmbedAll 将大量文本按输入数和Token预算拆分为多个请求，以有界并发发送并按输入顺序合并结果。
+// 失败的请求单独重试，重试后仍失败时其输入记录在BatchResult.Errors中，其余输入的结果照常返回，
+// 此时同时返回非nil的错误
+func (s *EmbeddingService) EmbedAll(ctx context.Context, texts []string, options ...BatchOption) (*BatchResult, error) {
+	if len(texts) == 0 {
+		return nil, fmt.Errorf("input texts cannot be empty")
+	}
+	for i, text := range texts {
+		if text == "" {
+			return nil, fmt.Errorf("input text at index %d cannot be empty", i)
+		}
+	}
+
+	batchConfig := DefaultBatchConfig()
+	for _, option := range options {
+		option(batchConfig)
+	}
+	if err := batchConfig.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid batch config: %w", err)
+	}
+
+	config := s.getConfig()
+	for _, option := range batchConfig.Options {
+		option(config)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid embedding config: %w", err)
+	}
+	if err := s.ValidateInputLength(config.Model, texts); err != nil {
+		return nil, err
+	}
+
+	batches := splitBatches(texts, config.Model, batchConfig.BatchSize, batchConfig.BatchTokens)
+	s.logger.Debug("Embedding inputs split into batches",
+		zap.Int("inputs", len(texts)), zap.Int("batches", len(batches)))
+
+	result := &BatchResult{
+		Response: &types.EmbeddingResponse{
+			Object: "list",
+			Model:  config.Model,
+			Data:   make([]types.Embedding, 0, len(texts)),
+		},
+		Errors: make([]error, len(texts)),
+	}
+	embeddings := make([][]types.Embedding, len(batches))
+	progress := BatchProgress{TotalBatches: len(batches), TotalInputs: len(texts)}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, batchConfig.Concurrency)
+
+	for i, batch := range batches {
+		// 上下文取消后尚未开始的请求直接以上下文错误结束
+		acquired := false
+		select {
+		case <-ctx.Done():
+		case sem <- struct{}{}:
+			acquired = true
+		}
+
+		wg.Add(1)
+		go func(i int, batch embeddingBatch) {
+			defer wg.Done()
+
+			var resp *types.EmbeddingResponse
+			err := ctx.Err()
+			if acquired {
+				defer func() { <-sem }()
+				if err == nil {
+					resp, err = s.embedBatch(ctx, batch, batchConfig)
+				}
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			progress.CompletedBatches++
+			if err != nil {
+				err = fmt.Errorf("batch of inputs %d-%d: %w", batch.start, batch.start+len(batch.texts)-1, err)
+				for j := range batch.texts {
+					result.Errors[batch.start+j] = err
+				}
+				result.Failed += len(batch.texts)
+				progress.FailedInputs += len(batch.texts)
+			} else {
+				embeddings[i] = resp.Data
+				addUsage(&result.Response.Usage, &resp.Usage)
+				progress.EmbeddedInputs += len(batch.texts)
+				progress.Usage = result.Response.Usage
+			}
+			if batchConfig.OnProgress != nil {
+				batchConfig.OnProgress(progress)
+			}
+		}(i, batch)
+	}
+	wg.Wait()
+
+	for _, data := range embeddings {
+		result.Response.Data = append(result.Response.Data, data...)
+	}
+	return result, result.Err()
+}
+
+// embedBatch 发送单个请求，暂时性错误按指数退避重试。响应中的Index改为输入下标
+func (s *EmbeddingService) embedBatch(ctx context.Context, batch embeddingBatch, config *BatchConfig) (*types.EmbeddingResponse, error) {
+	delay := config.RetryDelay
+	for attempt := 0; ; attempt++ {
+		resp, err := s.CreateEmbeddings(ctx, batch.texts, config.Options...)
+		if err == nil {
+			return remapBatch(resp, batch)
+		}
+		if attempt >= config.MaxRetries || !retryableBatchError(ctx, err) {
+			return nil, err
+		}
+
+		s.logger.Warn("Embedding batch failed, retrying", zap.Error(err),
+			zap.Int("start", batch.start), zap.Int("attempt", attempt+1))
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+		delay *= 2
+	}
+}
+
+// remapBatch 校验响应覆盖了请求中的每个输入，并将Index改为输入下标
+func remapBatch(resp *types.EmbeddingResponse, batch embeddingBatch) (*types.EmbeddingResponse, error) {
+	ordered := make([]types.Embedding, len(batch.texts))
+	seen := make([]bool, len(batch.texts))
+	for _, embedding := range resp.Data {
+		if embedding.Index < 0 || embedding.Index >= len(ordered) || seen[embedding.Index] {
+			return nil, fmt.Errorf("response has unexpected embedding index %d", embedding.Index)
+		}
+		seen[embedding.Index] = true
+		ordered[embedding.Index] = embedding
+	}
+	if len(resp.Data) != len(batch.texts) {
+		return nil, fmt.Errorf("response has %d embeddings, expected %d", len(resp.Data), len(batch.texts))
+	}
+
+	for i := range ordered {
+		ordered[i].Index = batch.start + i
+	}
+	resp.Data = ordered
+	return resp, nil
+}
+
+// retryableBatchError 检查请求失败是否为暂时性错误。请求参数、认证和内容相关的错误重试也不会成功
+func retryableBatchError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var validationErr *types.ValidationError
+	if errors.As(err, &validationErr) {
+		return false
+	}
+
+	switch types.ClassifyError(err) {
+	case types.ErrorClassInvalidRequest, types.ErrorClassAuthentication,
+		types.ErrorClassContextLength, types.ErrorClassContentFilter:
+		return false
+	default:
+		return true
+	}
+}
+
+// splitBatches 按输入顺序贪心拆分，每个请求不超过batchSize个输入和maxTokens个Token
+func splitBatches(texts []string, model string, batchSize, maxTokens int) []embeddingBatch {
+	tok := tokenizer.ForModel(model)
+
+	var batches []embeddingBatch
+	start, tokens := 0, 0
+	for i, text := range texts {
+		count := 0
+		if maxTokens > 0 {
+			count = tok.Count(text)
+		}
+
+		full := i-start >= batchSize || (maxTokens > 0 && i > start && tokens+count > maxTokens)
+		if full {
+			batches = append(batches, embeddingBatch{start: start, texts: texts[start:i]})
+			start, tokens = i, 0
+		}
+		tokens += count
+	}
+	return append(batches, embeddingBatch{start: start, texts: texts[start:]})
+}
+
+// addUsage 累加用量
+func addUsage(total, usage *types.Usage) {
+	total.PromptTokens += usage.PromptTokens
+	total.CompletionTokens += usage.CompletionTokens
+	total.TotalTokens += usage.TotalTokens
+}
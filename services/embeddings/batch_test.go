@@ -0,0 +1,234 @@
+package embeddings
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hewenyu/newapi-go/internal/transport"
+	"github.com/hewenyu/newapi-go/internal/utils"
+	"github.com/hewenyu/newapi-go/tokenizer"
+	"github.com/hewenyu/newapi-go/types"
+)
+
+// fakeEmbeddingServer 模拟嵌入接口，输入"t<n>"的向量为[n]，数据按倒序返回以验证按Index重排
+type fakeEmbeddingServer struct {
+	mu       sync.Mutex
+	requests [][]string
+	formats  []string
+	// fail 返回输入对应的错误状态码，0表示成功
+	fail func(input []string, attempt int) int
+}
+
+// ServeHTTP 处理嵌入请求
+func (f *fakeEmbeddingServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Input          []string `json:"input"`
+		EncodingFormat string   `json:"encoding_format"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+
+	f.mu.Lock()
+	attempt := 0
+	for _, previous := range f.requests {
+		if strings.Join(previous, ",") == strings.Join(req.Input, ",") {
+			attempt++
+		}
+	}
+	f.requests = append(f.requests, req.Input)
+	f.formats = append(f.formats, req.EncodingFormat)
+	f.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if f.fail != nil {
+		if status := f.fail(req.Input, attempt); status != 0 {
+			w.WriteHeader(status)
+			fmt.Fprintf(w, `{"error":{"message":"failed with %d","type":"api_error"}}`, status)
+			return
+		}
+	}
+
+	resp := types.EmbeddingResponse{Object: "list", Model: "text-embedding-3-small"}
+	for i := len(req.Input) - 1; i >= 0; i-- {
+		n, _ := strconv.Atoi(strings.TrimPrefix(req.Input[i], "t"))
+		embedding := types.Embedding{Object: "embedding", Index: i, Embedding: []float64{float64(n), 0.1}}
+		if req.EncodingFormat == types.EmbeddingEncodingFormatBase64 {
+			embedding.UseFloat32()
+		}
+		resp.Data = append(resp.Data, embedding)
+	}
+	resp.Usage = types.Usage{PromptTokens: len(req.Input), TotalTokens: len(req.Input)}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// newBatchService 创建连接到模拟服务器的嵌入服务
+func newBatchService(t *testing.T, fake *fakeEmbeddingServer) *EmbeddingService {
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	return NewEmbeddingService(transport.NewHTTPClient(server.URL, "test-key"), utils.GetLogger())
+}
+
+// inputs 构造n个输入"t0".."t<n-1>"
+func inputs(n int) []string {
+	texts := make([]string, n)
+	for i := range texts {
+		texts[i] = "t" + strconv.Itoa(i)
+	}
+	return texts
+}
+
+func TestEmbedAllReassemblesInOrder(t *testing.T) {
+	fake := &fakeEmbeddingServer{}
+	service := newBatchService(t, fake)
+
+	var progress []BatchProgress
+	result, err := service.EmbedAll(context.Background(), inputs(10),
+		WithBatchSize(3), WithConcurrency(2),
+		WithProgress(func(p BatchProgress) { progress = append(progress, p) }))
+	if err != nil {
+		t.Fatalf("EmbedAll failed: %v", err)
+	}
+
+	if len(fake.requests) != 4 {
+		t.Errorf("sent %d requests, want 4", len(fake.requests))
+	}
+	if len(result.Response.Data) != 10 {
+		t.Fatalf("got %d embeddings, want 10", len(result.Response.Data))
+	}
+	for i, embedding := range result.Response.Data {
+		if embedding.Index != i || embedding.Embedding[0] != float64(i) {
+			t.Errorf("embedding %d = index %d, vector %v", i, embedding.Index, embedding.Embedding)
+		}
+	}
+	if result.Response.Usage.PromptTokens != 10 || result.Response.Usage.TotalTokens != 10 {
+		t.Errorf("usage = %+v, want 10 tokens", result.Response.Usage)
+	}
+
+	if len(progress) != 4 {
+		t.Fatalf("progress called %d times, want 4", len(progress))
+	}
+	if last := progress[3]; last.CompletedBatches != 4 || last.EmbeddedInputs != 10 || last.TotalInputs != 10 {
+		t.Errorf("final progress = %+v", last)
+	}
+}
+
+func TestEmbedAllRetriesFailedBatch(t *testing.T) {
+	fake := &fakeEmbeddingServer{fail: func(input []string, attempt int) int {
+		if input[0] == "t4" && attempt == 0 {
+			return http.StatusServiceUnavailable
+		}
+		return 0
+	}}
+	service := newBatchService(t, fake)
+
+	result, err := service.EmbedAll(context.Background(), inputs(8), WithBatchSize(4), WithBatchRetry(1, 0))
+	if err != nil {
+		t.Fatalf("EmbedAll failed: %v", err)
+	}
+	if len(fake.requests) != 3 {
+		t.Errorf("sent %d requests, want 3", len(fake.requests))
+	}
+	if len(result.Response.Data) != 8 || result.Failed != 0 {
+		t.Errorf("got %d embeddings and %d failures", len(result.Response.Data), result.Failed)
+	}
+}
+
+func TestEmbedAllPartialFailure(t *testing.T) {
+	fake := &fakeEmbeddingServer{fail: func(input []string, _ int) int {
+		if input[0] == "t2" {
+			return http.StatusBadRequest
+		}
+		return 0
+	}}
+	service := newBatchService(t, fake)
+
+	result, err := service.EmbedAll(context.Background(), inputs(6), WithBatchSize(2), WithBatchRetry(3, 0))
+	if err == nil {
+		t.Fatal("expected an error for the failed batch")
+	}
+	if result == nil {
+		t.Fatal("expected partial results")
+	}
+	if len(fake.requests) != 3 {
+		t.Errorf("sent %d requests, want 3 (invalid requests are not retried)", len(fake.requests))
+	}
+
+	if result.Failed != 2 {
+		t.Errorf("failed = %d, want 2", result.Failed)
+	}
+	for i, itemErr := range result.Errors {
+		if failed := i == 2 || i == 3; failed != (itemErr != nil) {
+			t.Errorf("error for input %d = %v", i, itemErr)
+		}
+	}
+	if types.ClassifyError(result.Errors[2]) != types.ErrorClassInvalidRequest {
+		t.Errorf("error class = %q", types.ClassifyError(result.Errors[2]))
+	}
+
+	var indexes []int
+	for _, embedding := range result.Response.Data {
+		indexes = append(indexes, embedding.Index)
+	}
+	if fmt.Sprint(indexes) != "[0 1 4 5]" {
+		t.Errorf("embedding indexes = %v", indexes)
+	}
+}
+
+func TestSplitBatchesTokenBudget(t *testing.T) {
+	model := "text-embedding-3-small"
+	texts := []string{
+		strings.Repeat("word ", 40),
+		strings.Repeat("word ", 40),
+		strings.Repeat("word ", 200),
+		"short",
+		"short",
+	}
+	tok := tokenizer.ForModel(model)
+	budget := tok.Count(texts[0]) + tok.Count(texts[1])
+
+	batches := splitBatches(texts, model, 10, budget)
+
+	var got []string
+	for _, batch := range batches {
+		tokens := 0
+		for _, text := range batch.texts {
+			tokens += tok.Count(text)
+		}
+		if tokens > budget && len(batch.texts) > 1 {
+			t.Errorf("batch at %d has %d tokens, budget %d", batch.start, tokens, budget)
+		}
+		if len(got) != batch.start {
+			t.Errorf("batch starts at %d, want %d", batch.start, len(got))
+		}
+		got = append(got, batch.texts...)
+	}
+	if len(batches) != 3 || len(got) != len(texts) {
+		t.Errorf("got %d batches covering %d inputs", len(batches), len(got))
+	}
+}
+
+func TestBatchConfigValidate(t *testing.T) {
+	invalid := []BatchOption{
+		WithBatchSize(0),
+		WithBatchTokens(-1),
+		WithConcurrency(0),
+		WithBatchRetry(-1, 0),
+	}
+	for i, option := range invalid {
+		config := DefaultBatchConfig()
+		option(config)
+		if config.Validate() == nil {
+			t.Errorf("option %d: expected validation error", i)
+		}
+	}
+	if err := DefaultBatchConfig().Validate(); err != nil {
+		t.Errorf("default config invalid: %v", err)
+	}
+}
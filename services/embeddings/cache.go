@@ -0,0 +1,71 @@
+package embeddings
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/hewenyu/newapi-go/cache"
+	"github.com/hewenyu/newapi-go/types"
+	"go.uber.org/zap"
+)
+
+// cacheNamespace 嵌入响应的缓存键前缀
+const cacheNamespace = "embeddings"
+
+// SetCache 设置响应缓存，c为nil时禁用缓存，ttl不大于0时缓存不过期
+func (s *EmbeddingService) SetCache(c cache.Cache, ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cache = c
+	s.cacheTTL = ttl
+}
+
+// cacheKeyRequest 参与缓存键计算的请求内容
+type cacheKeyRequest struct {
+	Request   *types.EmbeddingRequest `json:"request"`
+	ExtraBody map[string]interface{}  `json:"extra_body,omitempty"`
+}
+
+// cacheKey 计算请求的缓存键，失败时返回空字符串
+func (s *EmbeddingService) cacheKey(req *types.EmbeddingRequest) string {
+	key, err := cache.Key(cacheNamespace, cacheKeyRequest{Request: req, ExtraBody: req.ExtraBody})
+	if err != nil {
+		s.logger.Warn("Failed to compute cache key", zap.Error(err))
+		return ""
+	}
+	return key
+}
+
+// cacheGet 读取缓存的响应，缓存错误只记录日志
+func (s *EmbeddingService) cacheGet(ctx context.Context, c cache.Cache, key string) *types.EmbeddingResponse {
+	data, ok, err := c.Get(ctx, key)
+	if err != nil {
+		s.logger.Warn("Failed to read response cache", zap.String("key", key), zap.Error(err))
+		return nil
+	}
+	if !ok {
+		return nil
+	}
+
+	var resp types.EmbeddingResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		s.logger.Warn("Discarding invalid response cache entry", zap.String("key", key), zap.Error(err))
+		return nil
+	}
+	resp.CacheStatus = types.CacheStatusHit
+	return &resp
+}
+
+// cacheSet 写入响应，缓存错误只记录日志
+func (s *EmbeddingService) cacheSet(ctx context.Context, c cache.Cache, key string, ttl time.Duration, resp *types.EmbeddingResponse) {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		s.logger.Warn("Failed to encode response cache entry", zap.Error(err))
+		return
+	}
+	if err := c.Set(ctx, key, data, ttl); err != nil {
+		s.logger.Warn("Failed to write response cache", zap.String("key", key), zap.Error(err))
+	}
+}
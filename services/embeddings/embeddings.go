@@ -7,18 +7,31 @@ import (
 	"io"
 	"net/http"
 	"sync"
+	"time"
 
+	"github.com/hewenyu/newapi-go/cache"
 	"github.com/hewenyu/newapi-go/internal/transport"
 	"github.com/hewenyu/newapi-go/internal/utils"
+	"github.com/hewenyu/newapi-go/tokenizer"
 	"github.com/hewenyu/newapi-go/types"
+	"github.com/hewenyu/newapi-go/usage"
 	"go.uber.org/zap"
 )
 
+// 未在能力注册表中登记的模型使用的默认值
+const (
+	defaultMaxInputLength = 8192
+	defaultDimensions     = 1536
+)
+
 // EmbeddingService 嵌入服务结构体
 type EmbeddingService struct {
 	transport transport.HTTPTransport
 	logger    utils.Logger
 	config    *EmbeddingConfig
+	cache     cache.Cache
+	cacheTTL  time.Duration
+	recorder  usage.Recorder
 	mu        sync.RWMutex
 }
 
@@ -72,6 +85,11 @@ func (s *EmbeddingService) CreateEmbedding(ctx context.Context, text string, opt
 		return nil, fmt.Errorf("invalid embedding config: %w", err)
 	}
 
+	// 验证输入长度
+	if err := s.ValidateInputLength(config.Model, text); err != nil {
+		return nil, err
+	}
+
 	// 构建请求
 	req := config.ToRequest(text)
 
@@ -80,29 +98,13 @@ func (s *EmbeddingService) CreateEmbedding(ctx context.Context, text string, opt
 		return nil, fmt.Errorf("invalid request parameters: %w", err)
 	}
 
-	// 发送请求
-	resp, err := s.transport.Post(ctx, "/v1/embeddings", req)
+	embeddingResp, err := s.send(ctx, req, config, "embedding")
 	if err != nil {
-		s.logger.Error("Failed to create embedding", zap.Error(err))
-		return nil, fmt.Errorf("failed to create embedding: %w", err)
-	}
-
-	// 解析响应
-	var embeddingResp types.EmbeddingResponse
-	if err := parseJSONResponse(resp, &embeddingResp); err != nil {
-		s.logger.Error("Failed to parse embedding response", zap.Error(err))
-		return nil, fmt.Errorf("failed to parse response: %w", err)
-	}
-
-	// 检查API错误
-	if embeddingResp.IsError() {
-		apiErr := embeddingResp.GetError()
-		s.logger.Error("API returned error", zap.String("error", apiErr.Message))
-		return nil, fmt.Errorf("API error: %s", apiErr.Message)
+		return nil, err
 	}
 
 	s.logger.Debug("Embedding created successfully", zap.Int("count", embeddingResp.GetEmbeddingCount()))
-	return &embeddingResp, nil
+	return embeddingResp, nil
 }
 
 // CreateEmbeddings 创建批量文本的嵌入向量
@@ -129,6 +131,11 @@ func (s *EmbeddingService) CreateEmbeddings(ctx context.Context, texts []string,
 		return nil, fmt.Errorf("invalid embedding config: %w", err)
 	}
 
+	// 验证输入长度
+	if err := s.ValidateInputLength(config.Model, texts); err != nil {
+		return nil, err
+	}
+
 	// 构建请求
 	req := config.ToRequest(texts)
 
@@ -137,29 +144,13 @@ func (s *EmbeddingService) CreateEmbeddings(ctx context.Context, texts []string,
 		return nil, fmt.Errorf("invalid request parameters: %w", err)
 	}
 
-	// 发送请求
-	resp, err := s.transport.Post(ctx, "/v1/embeddings", req)
+	embeddingResp, err := s.send(ctx, req, config, "embeddings")
 	if err != nil {
-		s.logger.Error("Failed to create embeddings", zap.Error(err))
-		return nil, fmt.Errorf("failed to create embeddings: %w", err)
-	}
-
-	// 解析响应
-	var embeddingResp types.EmbeddingResponse
-	if err := parseJSONResponse(resp, &embeddingResp); err != nil {
-		s.logger.Error("Failed to parse embeddings response", zap.Error(err))
-		return nil, fmt.Errorf("failed to parse response: %w", err)
-	}
-
-	// 检查API错误
-	if embeddingResp.IsError() {
-		apiErr := embeddingResp.GetError()
-		s.logger.Error("API returned error", zap.String("error", apiErr.Message))
-		return nil, fmt.Errorf("API error: %s", apiErr.Message)
+		return nil, err
 	}
 
 	s.logger.Debug("Embeddings created successfully", zap.Int("count", embeddingResp.GetEmbeddingCount()))
-	return &embeddingResp, nil
+	return embeddingResp, nil
 }
 
 // CreateEmbeddingFromTokens 从token创建嵌入向量
@@ -180,6 +171,11 @@ func (s *EmbeddingService) CreateEmbeddingFromTokens(ctx context.Context, tokens
 		return nil, fmt.Errorf("invalid embedding config: %w", err)
 	}
 
+	// 验证输入长度
+	if err := s.ValidateInputLength(config.Model, tokens); err != nil {
+		return nil, err
+	}
+
 	// 构建请求
 	req := config.ToRequest(tokens)
 
@@ -188,31 +184,91 @@ func (s *EmbeddingService) CreateEmbeddingFromTokens(ctx context.Context, tokens
 		return nil, fmt.Errorf("invalid request parameters: %w", err)
 	}
 
+	embeddingResp, err := s.send(ctx, req, config, "embedding from tokens")
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Debug("Embedding from tokens created successfully", zap.Int("count", embeddingResp.GetEmbeddingCount()))
+	return embeddingResp, nil
+}
+
+// send 发送嵌入请求，配置了缓存时先查询缓存。嵌入结果是确定性的，除非策略为cache.PolicyBypass否则总是缓存
+func (s *EmbeddingService) send(ctx context.Context, req *types.EmbeddingRequest, config *EmbeddingConfig, action string) (*types.EmbeddingResponse, error) {
+	s.mu.RLock()
+	c, ttl := s.cache, s.cacheTTL
+	s.mu.RUnlock()
+
+	var key string
+	if c != nil && config.CachePolicy != cache.PolicyBypass {
+		key = s.cacheKey(req)
+	}
+	if key != "" {
+		if embeddingResp := s.cacheGet(ctx, c, key); embeddingResp != nil {
+			useVectorType(embeddingResp, config)
+			return embeddingResp, nil
+		}
+	}
+
+	if err := s.checkBudget(ctx, req.Model); err != nil {
+		return nil, err
+	}
+
 	// 发送请求
 	resp, err := s.transport.Post(ctx, "/v1/embeddings", req)
 	if err != nil {
-		s.logger.Error("Failed to create embedding from tokens", zap.Error(err))
-		return nil, fmt.Errorf("failed to create embedding from tokens: %w", err)
+		s.logger.Error("Failed to create "+action, zap.Error(err))
+		return nil, fmt.Errorf("failed to create %s: %w", action, err)
 	}
 
+	statusCode := resp.StatusCode
+
 	// 解析响应
 	var embeddingResp types.EmbeddingResponse
 	if err := parseJSONResponse(resp, &embeddingResp); err != nil {
+		if statusCode >= http.StatusBadRequest {
+			apiErr := types.FromHTTPStatusCode(statusCode, http.StatusText(statusCode))
+			s.logger.Error("API returned error", zap.Int("status_code", statusCode))
+			return nil, fmt.Errorf("API error: %w", apiErr)
+		}
 		s.logger.Error("Failed to parse embedding response", zap.Error(err))
 		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
 
 	// 检查API错误
 	if embeddingResp.IsError() {
-		apiErr := embeddingResp.GetError()
-		s.logger.Error("API returned error", zap.String("error", apiErr.Message))
-		return nil, fmt.Errorf("API error: %s", apiErr.Message)
+		errResp := embeddingResp.GetError()
+		apiErr := types.NewAPIError(errResp.Type, errResp.Code, errResp.Message, statusCode).WithParam(errResp.Param)
+		s.logger.Error("API returned error", zap.String("error", errResp.Message))
+		return nil, fmt.Errorf("API error: %w", apiErr)
+	}
+	if statusCode >= http.StatusBadRequest {
+		apiErr := types.FromHTTPStatusCode(statusCode, http.StatusText(statusCode))
+		s.logger.Error("API returned error", zap.Int("status_code", statusCode))
+		return nil, fmt.Errorf("API error: %w", apiErr)
 	}
+	s.recordUsage(ctx, usage.FromUsage(usage.ServiceEmbedding, req.Model, &embeddingResp.Usage))
 
-	s.logger.Debug("Embedding from tokens created successfully", zap.Int("count", embeddingResp.GetEmbeddingCount()))
+	if c != nil {
+		embeddingResp.CacheStatus = types.CacheStatusBypass
+		if key != "" {
+			embeddingResp.CacheStatus = types.CacheStatusMiss
+			s.cacheSet(ctx, c, key, ttl, &embeddingResp)
+		}
+	}
+	useVectorType(&embeddingResp, config)
 	return &embeddingResp, nil
 }
 
+// useVectorType 按配置统一向量的存储形式。base64响应解码为float32形式，默认转换为float64形式以保持兼容
+func useVectorType(resp *types.EmbeddingResponse, config *EmbeddingConfig) {
+	if config.Float32Vectors {
+		resp.UseFloat32()
+	} else {
+		resp.UseFloat64()
+	}
+}
+
 // UpdateConfig 更新配置
 func (s *EmbeddingService) UpdateConfig(options ...EmbeddingOption) {
 	s.mu.Lock()
@@ -266,39 +322,58 @@ func (s *EmbeddingService) ValidateInput(input interface{}) error {
 	return nil
 }
 
-// GetSupportedModels 获取支持的模型列表
-func (s *EmbeddingService) GetSupportedModels() []string {
-	return []string{
-		"text-embedding-3-small",
-		"text-embedding-3-large",
-		"text-embedding-ada-002",
+// CountTokens 计算文本在当前模型下的Token数量
+func (s *EmbeddingService) CountTokens(text string) int {
+	return tokenizer.CountText(s.getConfig().Model, text)
+}
+
+// ValidateInputLength 验证输入的Token数量不超过模型的最大输入长度。
+// 文本输入仅在分词器可以精确计数时检查，避免估算误差拒绝合法输入
+func (s *EmbeddingService) ValidateInputLength(model string, input interface{}) error {
+	maxLength := s.GetMaxInputLength(model)
+	tok := tokenizer.ForModel(model)
+
+	switch v := input.(type) {
+	case string:
+		if tok.Exact() {
+			if count := tok.Count(v); count > maxLength {
+				return fmt.Errorf("input text has %d tokens, exceeds maximum %d for model %s", count, maxLength, model)
+			}
+		}
+	case []string:
+		if tok.Exact() {
+			for i, text := range v {
+				if count := tok.Count(text); count > maxLength {
+					return fmt.Errorf("input text at index %d has %d tokens, exceeds maximum %d for model %s", i, count, maxLength, model)
+				}
+			}
+		}
+	case []int:
+		if len(v) > maxLength {
+			return fmt.Errorf("input has %d tokens, exceeds maximum %d for model %s", len(v), maxLength, model)
+		}
 	}
+
+	return nil
 }
 
-// GetMaxInputLength 获取最大输入长度
+// GetSupportedModels 获取能力注册表中登记的嵌入模型列表
+func (s *EmbeddingService) GetSupportedModels() []string {
+	return types.DefaultCapabilityRegistry().Models(types.ModelTypeEmbedding)
+}
+
+// GetMaxInputLength 获取最大输入长度，未登记的模型返回默认值
 func (s *EmbeddingService) GetMaxInputLength(model string) int {
-	// 不同模型的最大token长度
-	switch model {
-	case "text-embedding-3-small", "text-embedding-3-large":
-		return 8192
-	case "text-embedding-ada-002":
-		return 8192
-	default:
-		return 8192 // 默认值
+	if capabilities, ok := types.LookupModelCapabilities(model); ok && capabilities.MaxInputTokens > 0 {
+		return capabilities.MaxInputTokens
 	}
+	return defaultMaxInputLength
 }
 
-// GetDefaultDimensions 获取默认维度
+// GetDefaultDimensions 获取默认维度，未登记的模型返回默认值
 func (s *EmbeddingService) GetDefaultDimensions(model string) int {
-	// 不同模型的默认维度
-	switch model {
-	case "text-embedding-3-small":
-		return 1536
-	case "text-embedding-3-large":
-		return 3072
-	case "text-embedding-ada-002":
-		return 1536
-	default:
-		return 1536 // 默认值
+	if capabilities, ok := types.LookupModelCapabilities(model); ok && capabilities.EmbeddingDimensions > 0 {
+		return capabilities.EmbeddingDimensions
 	}
+	return defaultDimensions
 }
@@ -0,0 +1,81 @@
+package embeddings
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hewenyu/newapi-go/cache"
+	"github.com/hewenyu/newapi-go/types"
+)
+
+func TestCreateEmbeddingsDecodesBase64ByDefault(t *testing.T) {
+	fake := &fakeEmbeddingServer{}
+	service := newBatchService(t, fake)
+
+	resp, err := service.CreateEmbeddings(context.Background(), inputs(2))
+	if err != nil {
+		t.Fatalf("CreateEmbeddings failed: %v", err)
+	}
+	if fake.formats[0] != types.EmbeddingEncodingFormatBase64 {
+		t.Errorf("encoding format = %q, want base64", fake.formats[0])
+	}
+
+	// 模拟服务器倒序返回，第一项为输入t1
+	embedding := resp.GetFirstEmbedding()
+	if embedding.Float32 != nil {
+		t.Error("float32 vector should be converted to float64 by default")
+	}
+	// 0.1经过float32编码后精度降低
+	if len(embedding.Embedding) != 2 || embedding.Embedding[0] != 1 || embedding.Embedding[1] != float64(float32(0.1)) {
+		t.Errorf("embedding = %v", embedding.Embedding)
+	}
+}
+
+func TestCreateEmbeddingsFloat32Vectors(t *testing.T) {
+	for _, format := range []string{types.EmbeddingEncodingFormatBase64, types.EmbeddingEncodingFormatFloat} {
+		fake := &fakeEmbeddingServer{}
+		service := newBatchService(t, fake)
+		service.SetCache(cache.NewMemoryCache(16), 0)
+
+		// 第二次请求命中缓存，缓存的响应同样按配置转换
+		for _, want := range []string{types.CacheStatusMiss, types.CacheStatusHit} {
+			resp, err := service.CreateEmbeddings(context.Background(), inputs(2),
+				WithEncodingFormat(format), WithFloat32Vectors(true))
+			if err != nil {
+				t.Fatalf("%s: CreateEmbeddings failed: %v", format, err)
+			}
+			if resp.CacheStatus != want {
+				t.Errorf("%s: cache status = %q, want %q", format, resp.CacheStatus, want)
+			}
+
+			embedding := resp.GetFirstEmbedding()
+			if embedding.Embedding != nil {
+				t.Errorf("%s: float64 vector should be released", format)
+			}
+			if len(embedding.Float32) != 2 || embedding.Float32[0] != 1 || embedding.Float32[1] != 0.1 {
+				t.Errorf("%s: embedding = %v", format, embedding.Float32)
+			}
+		}
+		if len(fake.requests) != 1 {
+			t.Errorf("%s: sent %d requests, want 1", format, len(fake.requests))
+		}
+	}
+}
+
+func TestValidateInputLengthCountsTokens(t *testing.T) {
+	service := newBatchService(t, &fakeEmbeddingServer{})
+	model := "text-embedding-3-small"
+
+	// cl100k_base中" hello"是单个Token，超出8192个Token的文本在发送前被拒绝
+	long := "hello" + strings.Repeat(" hello", 8192)
+	if err := service.ValidateInputLength(model, long); err == nil {
+		t.Error("expected an error for input over the model limit")
+	}
+	if err := service.ValidateInputLength(model, []string{"ok", long}); err == nil || !strings.Contains(err.Error(), "index 1") {
+		t.Errorf("ValidateInputLength([]string) = %v", err)
+	}
+	if err := service.ValidateInputLength(model, strings.Repeat("hello ", 100)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
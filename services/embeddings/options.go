@@ -1,6 +1,9 @@
 package embeddings
 
 import (
+	"fmt"
+
+	"github.com/hewenyu/newapi-go/cache"
 	"github.com/hewenyu/newapi-go/types"
 )
 
@@ -14,13 +17,16 @@ type EmbeddingConfig struct {
 	Dimensions     int                    `json:"dimensions,omitempty"`
 	User           string                 `json:"user,omitempty"`
 	ExtraBody      map[string]interface{} `json:"-"`
+	CachePolicy    cache.Policy           `json:"-"`
+	// Float32Vectors 以float32形式保存响应中的向量，见types.Embedding.Float32
+	Float32Vectors bool `json:"-"`
 }
 
-// DefaultEmbeddingConfig 创建默认嵌入配置
+// DefaultEmbeddingConfig 创建默认嵌入配置。默认请求base64格式并透明解码，响应体积约为浮点数组的四分之一
 func DefaultEmbeddingConfig() *EmbeddingConfig {
 	return &EmbeddingConfig{
 		Model:          "text-embedding-3-small",
-		EncodingFormat: types.EmbeddingEncodingFormatFloat,
+		EncodingFormat: types.EmbeddingEncodingFormatBase64,
 		Dimensions:     0, // 0表示使用模型默认维度
 	}
 }
@@ -65,6 +71,21 @@ func WithExtraBody(extraBody map[string]interface{}) EmbeddingOption {
 	}
 }
 
+// WithCachePolicy 设置响应缓存策略，仅在服务配置了缓存时生效。嵌入请求默认总是缓存
+func WithCachePolicy(policy cache.Policy) EmbeddingOption {
+	return func(c *EmbeddingConfig) {
+		c.CachePolicy = policy
+	}
+}
+
+// WithFloat32Vectors 设置是否以float32形式保存响应中的向量，内存占用减半。
+// 启用时向量保存在types.Embedding.Float32中，Embedding字段为nil
+func WithFloat32Vectors(enabled bool) EmbeddingOption {
+	return func(c *EmbeddingConfig) {
+		c.Float32Vectors = enabled
+	}
+}
+
 // ToRequest 将配置转换为嵌入请求
 func (c *EmbeddingConfig) ToRequest(input interface{}) *types.EmbeddingRequest {
 	req := &types.EmbeddingRequest{
@@ -88,6 +109,12 @@ func (c *EmbeddingConfig) Validate() error {
 		return types.NewValidationError("model", c.Model, "model is required", types.ErrCodeMissingParameter)
 	}
 
+	// 未登记的模型交由网关校验，已登记的模型必须是嵌入模型
+	capabilities, known := types.LookupModelCapabilities(c.Model)
+	if known && capabilities.Type != types.ModelTypeEmbedding {
+		return types.NewValidationError("model", c.Model, "model is not an embedding model", types.ErrCodeInvalidModel)
+	}
+
 	if c.EncodingFormat != "" {
 		switch c.EncodingFormat {
 		case types.EmbeddingEncodingFormatFloat, types.EmbeddingEncodingFormatBase64:
@@ -100,6 +127,14 @@ func (c *EmbeddingConfig) Validate() error {
 	if c.Dimensions < 0 {
 		return types.NewValidationError("dimensions", c.Dimensions, "dimensions must be non-negative", types.ErrCodeInvalidParameter)
 	}
+	if known && capabilities.EmbeddingDimensions > 0 && c.Dimensions > capabilities.EmbeddingDimensions {
+		return types.NewValidationError("dimensions", c.Dimensions,
+			fmt.Sprintf("dimensions must not exceed %d for model %s", capabilities.EmbeddingDimensions, c.Model), types.ErrCodeInvalidParameter)
+	}
+
+	if !c.CachePolicy.Valid() {
+		return types.NewValidationError("cache_policy", c.CachePolicy, "invalid cache policy", types.ErrCodeInvalidParameter)
+	}
 
 	return nil
 }
@@ -111,6 +146,8 @@ func (c *EmbeddingConfig) Clone() *EmbeddingConfig {
 		EncodingFormat: c.EncodingFormat,
 		Dimensions:     c.Dimensions,
 		User:           c.User,
+		CachePolicy:    c.CachePolicy,
+		Float32Vectors: c.Float32Vectors,
 	}
 
 	if c.ExtraBody != nil {
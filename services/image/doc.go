@@ -1,4 +1,4 @@
-// Package image provides image generation and manipulation functionality for the New-API Go SDK.
-// This package handles image generation, editing, and variations using various
-// image models supported by the New-API service.
+// Package image provides image generation functionality for the New-API Go SDK.
+// This package generates images from text prompts using the image models
+// supported by the New-API service and records per-image usage.
 package image
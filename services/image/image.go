@@ -0,0 +1,145 @@
+package image
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/hewenyu/newapi-go/internal/transport"
+	"github.com/hewenyu/newapi-go/internal/utils"
+	"github.com/hewenyu/newapi-go/types"
+	"github.com/hewenyu/newapi-go/usage"
+	"go.uber.org/zap"
+)
+
+// ImageService 图像服务结构体
+type ImageService struct {
+	transport transport.HTTPTransport
+	logger    utils.Logger
+	config    *ImageConfig
+	recorder  usage.Recorder
+	mu        sync.RWMutex
+}
+
+// NewImageService 创建新的图像服务实例
+func NewImageService(transport transport.HTTPTransport, logger utils.Logger, options ...ImageOption) *ImageService {
+	config := DefaultImageConfig()
+
+	// 应用选项
+	for _, option := range options {
+		option(config)
+	}
+
+	return &ImageService{
+		transport: transport,
+		logger:    logger,
+		config:    config,
+	}
+}
+
+// parseJSONResponse 解析JSON响应
+func parseJSONResponse(resp *http.Response, v interface{}) error {
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("failed to unmarshal JSON: %w", err)
+	}
+
+	return nil
+}
+
+// CreateImage 根据提示词生成图像
+func (s *ImageService) CreateImage(ctx context.Context, prompt string, options ...ImageOption) (*types.ImageResponse, error) {
+	// 创建配置副本并应用选项
+	config := s.getConfig()
+	for _, option := range options {
+		option(config)
+	}
+
+	// 验证配置
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid image config: %w", err)
+	}
+
+	// 构建请求
+	req := config.ToRequest(prompt)
+
+	// 验证请求参数
+	if err := req.ValidateParameters(); err != nil {
+		return nil, fmt.Errorf("invalid request parameters: %w", err)
+	}
+
+	if err := s.checkBudget(ctx, req.Model); err != nil {
+		return nil, err
+	}
+
+	// 发送请求
+	resp, err := s.transport.Post(ctx, "/v1/images/generations", req)
+	if err != nil {
+		s.logger.Error("Failed to create image", zap.Error(err))
+		return nil, fmt.Errorf("failed to create image: %w", err)
+	}
+	statusCode := resp.StatusCode
+
+	// 解析响应
+	var imageResp types.ImageResponse
+	if err := parseJSONResponse(resp, &imageResp); err != nil {
+		if statusCode >= http.StatusBadRequest {
+			apiErr := types.FromHTTPStatusCode(statusCode, http.StatusText(statusCode))
+			s.logger.Error("API returned error", zap.Int("status_code", statusCode))
+			return nil, fmt.Errorf("API error: %w", apiErr)
+		}
+		s.logger.Error("Failed to parse image response", zap.Error(err))
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	// 检查API错误
+	if imageResp.IsError() {
+		errResp := imageResp.GetError()
+		apiErr := types.NewAPIError(errResp.Type, errResp.Code, errResp.Message, statusCode).WithParam(errResp.Param)
+		s.logger.Error("API returned error", zap.String("error", errResp.Message))
+		return nil, fmt.Errorf("API error: %w", apiErr)
+	}
+	if statusCode >= http.StatusBadRequest {
+		apiErr := types.FromHTTPStatusCode(statusCode, http.StatusText(statusCode))
+		s.logger.Error("API returned error", zap.Int("status_code", statusCode))
+		return nil, fmt.Errorf("API error: %w", apiErr)
+	}
+
+	// 按实际返回的图像数量计费
+	s.recordUsage(ctx, usage.Record{Service: usage.ServiceImage, Model: req.Model, Images: imageResp.GetImageCount()})
+
+	s.logger.Debug("Image created successfully", zap.Int("images", imageResp.GetImageCount()))
+	return &imageResp, nil
+}
+
+// UpdateConfig 更新配置
+func (s *ImageService) UpdateConfig(options ...ImageOption) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, option := range options {
+		option(s.config)
+	}
+}
+
+// GetConfig 获取配置副本
+func (s *ImageService) GetConfig() *ImageConfig {
+	return s.getConfig()
+}
+
+// getConfig 获取配置副本（内部使用）
+func (s *ImageService) getConfig() *ImageConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.config.Clone()
+}
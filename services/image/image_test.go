@@ -0,0 +1,74 @@
+package image
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hewenyu/newapi-go/internal/transport"
+	"github.com/hewenyu/newapi-go/internal/utils"
+	"github.com/hewenyu/newapi-go/types"
+	"github.com/hewenyu/newapi-go/usage"
+)
+
+// newTestService 创建按请求的n返回图像并记录请求的图像服务
+func newTestService(t *testing.T, requests *[]types.ImageGenerationRequest) *ImageService {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/images/generations" {
+			t.Errorf("path = %s, want /v1/images/generations", r.URL.Path)
+		}
+		var req types.ImageGenerationRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		*requests = append(*requests, req)
+
+		resp := types.ImageResponse{Created: 1700000000}
+		for i := 0; i < req.N; i++ {
+			resp.Data = append(resp.Data, types.ImageData{URL: "https://example.com/image.png"})
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(server.Close)
+	return NewImageService(transport.NewHTTPClient(server.URL, "test-key"), utils.GetLogger())
+}
+
+func TestCreateImageRecordsImages(t *testing.T) {
+	var requests []types.ImageGenerationRequest
+	service := newTestService(t, &requests)
+	tracker := usage.NewTracker()
+	service.SetUsageRecorder(tracker)
+
+	resp, err := service.CreateImage(context.Background(), "a red fox", WithN(2), WithQuality(types.ImageQualityHD))
+	if err != nil {
+		t.Fatalf("CreateImage failed: %v", err)
+	}
+	if resp.GetImageCount() != 2 {
+		t.Errorf("got %d images, want 2", resp.GetImageCount())
+	}
+	if req := requests[0]; req.Model != DefaultImageModel || req.Prompt != "a red fox" || req.Quality != types.ImageQualityHD || req.Style != "" {
+		t.Errorf("request = %+v", req)
+	}
+
+	// dall-e-3按每张图像计价
+	totals := tracker.Snapshot().ByService[usage.ServiceImage]
+	if totals.Requests != 1 || totals.Images != 2 || math.Abs(totals.Cost-0.08) > 1e-9 {
+		t.Errorf("image usage = %+v", totals)
+	}
+}
+
+func TestCreateImageValidation(t *testing.T) {
+	var requests []types.ImageGenerationRequest
+	service := newTestService(t, &requests)
+
+	if _, err := service.CreateImage(context.Background(), ""); err == nil {
+		t.Error("expected error for empty prompt")
+	}
+	if _, err := service.CreateImage(context.Background(), "a red fox", WithN(11)); err == nil {
+		t.Error("expected error for n over 10")
+	}
+	if len(requests) != 0 {
+		t.Errorf("invalid requests should not be sent, got %d", len(requests))
+	}
+}
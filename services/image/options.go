@@ -0,0 +1,112 @@
+package image
+
+import (
+	"github.com/hewenyu/newapi-go/types"
+)
+
+// DefaultImageModel 默认的图像生成模型
+const DefaultImageModel = "dall-e-3"
+
+// ImageOption 图像选项函数类型
+type ImageOption func(*ImageConfig)
+
+// ImageConfig 图像生成配置结构体
+type ImageConfig struct {
+	Model          string `json:"model"`
+	N              int    `json:"n"`
+	Size           string `json:"size"`
+	ResponseFormat string `json:"response_format"`
+	Quality        string `json:"quality,omitempty"`
+	Style          string `json:"style,omitempty"`
+	User           string `json:"user,omitempty"`
+}
+
+// DefaultImageConfig 创建默认图像生成配置
+func DefaultImageConfig() *ImageConfig {
+	return &ImageConfig{
+		Model:          DefaultImageModel,
+		N:              1,
+		Size:           types.ImageSize1024x1024,
+		ResponseFormat: types.ImageFormatURL,
+	}
+}
+
+// WithModel 设置图像生成模型
+func WithModel(model string) ImageOption {
+	return func(c *ImageConfig) {
+		c.Model = model
+	}
+}
+
+// WithN 设置生成的图像数量
+func WithN(n int) ImageOption {
+	return func(c *ImageConfig) {
+		c.N = n
+	}
+}
+
+// WithSize 设置图像尺寸
+func WithSize(size string) ImageOption {
+	return func(c *ImageConfig) {
+		c.Size = size
+	}
+}
+
+// WithResponseFormat 设置返回格式，取值为url或b64_json
+func WithResponseFormat(format string) ImageOption {
+	return func(c *ImageConfig) {
+		c.ResponseFormat = format
+	}
+}
+
+// WithQuality 设置图像质量
+func WithQuality(quality string) ImageOption {
+	return func(c *ImageConfig) {
+		c.Quality = quality
+	}
+}
+
+// WithStyle 设置图像风格
+func WithStyle(style string) ImageOption {
+	return func(c *ImageConfig) {
+		c.Style = style
+	}
+}
+
+// WithUser 设置用户标识
+func WithUser(user string) ImageOption {
+	return func(c *ImageConfig) {
+		c.User = user
+	}
+}
+
+// ToRequest 将配置转换为图像生成请求
+func (c *ImageConfig) ToRequest(prompt string) *types.ImageGenerationRequest {
+	return &types.ImageGenerationRequest{
+		Model:          c.Model,
+		Prompt:         prompt,
+		N:              c.N,
+		Size:           c.Size,
+		ResponseFormat: c.ResponseFormat,
+		Quality:        c.Quality,
+		Style:          c.Style,
+		User:           c.User,
+	}
+}
+
+// Validate 验证配置
+func (c *ImageConfig) Validate() error {
+	if c.Model == "" {
+		return types.NewValidationError("model", c.Model, "model is required", types.ErrCodeMissingParameter)
+	}
+	if c.N < 1 || c.N > 10 {
+		return types.NewValidationError("n", c.N, "n must be between 1 and 10", types.ErrCodeInvalidParameter)
+	}
+	return nil
+}
+
+// Clone 克隆配置
+func (c *ImageConfig) Clone() *ImageConfig {
+	cloned := *c
+	return &cloned
+}
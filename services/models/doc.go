@@ -0,0 +1,6 @@
+// Package models provides model discovery functionality for the New-API Go SDK.
+// This package lists and retrieves the models exposed by the New-API gateway,
+// caches the list for a configurable TTL and merges each model with the local
+// capability registry (context window, tool and vision support, JSON mode,
+// embedding dimensions and pricing).
+package models
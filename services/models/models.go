@@ -0,0 +1,270 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/hewenyu/newapi-go/internal/transport"
+	"github.com/hewenyu/newapi-go/internal/utils"
+	"github.com/hewenyu/newapi-go/types"
+	"go.uber.org/zap"
+)
+
+// ModelInfo 网关返回的模型信息与本地能力注册表合并后的结果
+type ModelInfo struct {
+	types.Model
+	// Capabilities 模型能力，Known为false时为零值
+	Capabilities types.ModelCapabilities `json:"capabilities"`
+	// Known 模型是否在能力注册表中登记
+	Known bool `json:"known"`
+}
+
+// ModelService 模型服务结构体
+type ModelService struct {
+	transport transport.HTTPTransport
+	logger    utils.Logger
+	config    *ModelConfig
+	mu        sync.RWMutex
+
+	// models 缓存的模型列表及获取时间
+	models    []types.Model
+	fetchedAt time.Time
+}
+
+// modelListResponse 模型列表响应
+type modelListResponse struct {
+	types.ListResponse
+	Error *types.ErrorResponse `json:"error,omitempty"`
+}
+
+// modelResponse 单个模型响应
+type modelResponse struct {
+	types.Model
+	Error *types.ErrorResponse `json:"error,omitempty"`
+}
+
+// NewModelService 创建新的模型服务实例
+func NewModelService(transport transport.HTTPTransport, logger utils.Logger, options ...ModelOption) *ModelService {
+	config := DefaultModelConfig()
+
+	// 应用选项
+	for _, option := range options {
+		option(config)
+	}
+
+	return &ModelService{
+		transport: transport,
+		logger:    logger,
+		config:    config,
+	}
+}
+
+// ListModels 获取网关可用的模型列表，结果在CacheTTL内复用
+func (s *ModelService) ListModels(ctx context.Context) ([]types.Model, error) {
+	if models, ok := s.cachedModels(); ok {
+		return models, nil
+	}
+	return s.Refresh(ctx)
+}
+
+// Refresh 忽略缓存重新获取模型列表并更新缓存
+func (s *ModelService) Refresh(ctx context.Context) ([]types.Model, error) {
+	var listResp modelListResponse
+	if err := s.get(ctx, "/v1/models", "list models", &listResp, func() *types.ErrorResponse { return listResp.Error }); err != nil {
+		return nil, err
+	}
+
+	models := make([]types.Model, 0)
+	if len(listResp.Data) > 0 {
+		if err := json.Unmarshal(listResp.Data, &models); err != nil {
+			s.logger.Error("Failed to parse model list", zap.Error(err))
+			return nil, fmt.Errorf("failed to parse model list: %w", err)
+		}
+	}
+
+	s.mu.Lock()
+	s.models = models
+	s.fetchedAt = time.Now()
+	s.mu.Unlock()
+
+	s.logger.Debug("Model list refreshed", zap.Int("models", len(models)))
+	return append([]types.Model(nil), models...), nil
+}
+
+// InvalidateCache 清除缓存的模型列表
+func (s *ModelService) InvalidateCache() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.models = nil
+	s.fetchedAt = time.Time{}
+}
+
+// GetModel 获取指定模型，优先从缓存的模型列表中查找
+func (s *ModelService) GetModel(ctx context.Context, id string) (*types.Model, error) {
+	if id == "" {
+		return nil, types.NewValidationError("id", id, "model id is required", types.ErrCodeMissingParameter)
+	}
+
+	if models, ok := s.cachedModels(); ok {
+		for i := range models {
+			if models[i].ID == id {
+				return &models[i], nil
+			}
+		}
+	}
+
+	var modelResp modelResponse
+	if err := s.get(ctx, "/v1/models/"+url.PathEscape(id), "get model", &modelResp, func() *types.ErrorResponse { return modelResp.Error }); err != nil {
+		return nil, err
+	}
+
+	model := modelResp.Model
+	return &model, nil
+}
+
+// ListModelInfo 获取模型列表并合并能力注册表中的模型能力
+func (s *ModelService) ListModelInfo(ctx context.Context) ([]ModelInfo, error) {
+	models, err := s.ListModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	registry := s.getConfig().registry()
+	infos := make([]ModelInfo, len(models))
+	for i, model := range models {
+		infos[i] = newModelInfo(registry, model)
+	}
+	return infos, nil
+}
+
+// GetModelInfo 获取指定模型并合并能力注册表中的模型能力
+func (s *ModelService) GetModelInfo(ctx context.Context, id string) (*ModelInfo, error) {
+	model, err := s.GetModel(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	info := newModelInfo(s.getConfig().registry(), *model)
+	return &info, nil
+}
+
+// SupportsModel 检查网关是否提供指定模型
+func (s *ModelService) SupportsModel(ctx context.Context, id string) (bool, error) {
+	models, err := s.ListModels(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	for _, model := range models {
+		if model.ID == id {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// LookupCapabilities 在配置的能力注册表中查找模型能力，不访问网关
+func (s *ModelService) LookupCapabilities(model string) (types.ModelCapabilities, bool) {
+	return s.getConfig().registry().Lookup(model)
+}
+
+// newModelInfo 合并模型信息与模型能力
+func newModelInfo(registry *types.CapabilityRegistry, model types.Model) ModelInfo {
+	capabilities, known := registry.Lookup(model.ID)
+	if known {
+		capabilities.ID = model.ID
+	}
+	return ModelInfo{Model: model, Capabilities: capabilities, Known: known}
+}
+
+// cachedModels 获取未过期的缓存模型列表副本
+func (s *ModelService) cachedModels() ([]types.Model, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.models == nil || s.config.CacheTTL <= 0 || time.Since(s.fetchedAt) >= s.config.CacheTTL {
+		return nil, false
+	}
+	return append([]types.Model(nil), s.models...), true
+}
+
+// get 发送GET请求并解析响应，apiError返回响应体中的错误信息
+func (s *ModelService) get(ctx context.Context, path, action string, v interface{}, apiError func() *types.ErrorResponse) error {
+	resp, err := s.transport.Get(ctx, path, nil)
+	if err != nil {
+		s.logger.Error("Failed to "+action, zap.Error(err))
+		return fmt.Errorf("failed to %s: %w", action, err)
+	}
+	statusCode := resp.StatusCode
+
+	// 解析响应
+	if err := parseJSONResponse(resp, v); err != nil {
+		if statusCode >= http.StatusBadRequest {
+			apiErr := types.FromHTTPStatusCode(statusCode, http.StatusText(statusCode))
+			s.logger.Error("API returned error", zap.Int("status_code", statusCode))
+			return fmt.Errorf("API error: %w", apiErr)
+		}
+		s.logger.Error("Failed to parse models response", zap.Error(err))
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	// 检查API错误
+	if errResp := apiError(); errResp != nil {
+		apiErr := types.NewAPIError(errResp.Type, errResp.Code, errResp.Message, statusCode).WithParam(errResp.Param)
+		s.logger.Error("API returned error", zap.String("error", errResp.Message))
+		return fmt.Errorf("API error: %w", apiErr)
+	}
+	if statusCode >= http.StatusBadRequest {
+		apiErr := types.FromHTTPStatusCode(statusCode, http.StatusText(statusCode))
+		s.logger.Error("API returned error", zap.Int("status_code", statusCode))
+		return fmt.Errorf("API error: %w", apiErr)
+	}
+
+	return nil
+}
+
+// parseJSONResponse 解析JSON响应
+func parseJSONResponse(resp *http.Response, v interface{}) error {
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("failed to unmarshal JSON: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateConfig 更新配置，缓存时间的变化对已缓存的列表立即生效
+func (s *ModelService) UpdateConfig(options ...ModelOption) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, option := range options {
+		option(s.config)
+	}
+}
+
+// GetConfig 获取配置副本
+func (s *ModelService) GetConfig() *ModelConfig {
+	return s.getConfig()
+}
+
+// getConfig 获取配置副本（内部使用）
+func (s *ModelService) getConfig() *ModelConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.config.Clone()
+}
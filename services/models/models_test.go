@@ -0,0 +1,137 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hewenyu/newapi-go/internal/transport"
+	"github.com/hewenyu/newapi-go/internal/utils"
+	"github.com/hewenyu/newapi-go/types"
+)
+
+// newTestService 创建返回固定模型列表并统计请求次数的模型服务
+func newTestService(t *testing.T, requests *int32, options ...ModelOption) *ModelService {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		switch r.URL.Path {
+		case "/v1/models":
+			w.Write([]byte(`{"object":"list","data":[` +
+				`{"id":"gpt-4o-2024-08-06","object":"model","owned_by":"openai"},` +
+				`{"id":"my-finetune","object":"model","owned_by":"custom"}]}`))
+		case "/v1/models/text-embedding-3-large":
+			w.Write([]byte(`{"id":"text-embedding-3-large","object":"model","owned_by":"openai"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"message":"model not found","type":"invalid_request_error","code":"model_not_found"}}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return NewModelService(transport.NewHTTPClient(server.URL, "test-key"), utils.GetLogger(), options...)
+}
+
+func TestListModelsCachesWithinTTL(t *testing.T) {
+	var requests int32
+	service := newTestService(t, &requests)
+
+	for i := 0; i < 3; i++ {
+		list, err := service.ListModels(context.Background())
+		if err != nil {
+			t.Fatalf("ListModels failed: %v", err)
+		}
+		if len(list) != 2 || list[0].ID != "gpt-4o-2024-08-06" {
+			t.Fatalf("ListModels() = %+v", list)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("requests = %d, want 1", requests)
+	}
+
+	if _, err := service.GetModel(context.Background(), "my-finetune"); err != nil {
+		t.Fatalf("GetModel from cache failed: %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("GetModel should use the cached list, requests = %d", requests)
+	}
+
+	if _, err := service.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("requests after Refresh = %d, want 2", requests)
+	}
+}
+
+func TestListModelsExpiredCache(t *testing.T) {
+	var requests int32
+	service := newTestService(t, &requests, WithCacheTTL(time.Millisecond))
+
+	service.ListModels(context.Background())
+	time.Sleep(5 * time.Millisecond)
+	service.ListModels(context.Background())
+	if requests != 2 {
+		t.Errorf("requests = %d, want 2", requests)
+	}
+}
+
+func TestGetModelInfoMergesCapabilities(t *testing.T) {
+	var requests int32
+	service := newTestService(t, &requests)
+
+	infos, err := service.ListModelInfo(context.Background())
+	if err != nil {
+		t.Fatalf("ListModelInfo failed: %v", err)
+	}
+	if !infos[0].Known || infos[0].Capabilities.ContextWindow != 128000 || !infos[0].Capabilities.Vision {
+		t.Errorf("gpt-4o info = %+v", infos[0])
+	}
+	if infos[0].Capabilities.ID != "gpt-4o-2024-08-06" {
+		t.Errorf("capabilities ID = %q", infos[0].Capabilities.ID)
+	}
+	if infos[1].Known {
+		t.Errorf("my-finetune should be unknown: %+v", infos[1])
+	}
+
+	info, err := service.GetModelInfo(context.Background(), "text-embedding-3-large")
+	if err != nil {
+		t.Fatalf("GetModelInfo failed: %v", err)
+	}
+	if info.Capabilities.Type != types.ModelTypeEmbedding || info.Capabilities.EmbeddingDimensions != 3072 {
+		t.Errorf("embedding info = %+v", info)
+	}
+}
+
+func TestGetModelNotFound(t *testing.T) {
+	var requests int32
+	service := newTestService(t, &requests)
+
+	_, err := service.GetModel(context.Background(), "missing")
+	var apiErr *types.APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected APIError, got %v", err)
+	}
+	if apiErr.HTTPStatusCode != http.StatusNotFound || apiErr.Code != "model_not_found" {
+		t.Errorf("apiErr = %+v", apiErr)
+	}
+}
+
+func TestCustomRegistry(t *testing.T) {
+	var requests int32
+	registry := types.NewCapabilityRegistry(types.ModelCapabilities{ID: "my-finetune", Type: types.ModelTypeChat, ContextWindow: 4096})
+	service := newTestService(t, &requests, WithRegistry(registry))
+
+	infos, err := service.ListModelInfo(context.Background())
+	if err != nil {
+		t.Fatalf("ListModelInfo failed: %v", err)
+	}
+	if infos[0].Known {
+		t.Errorf("gpt-4o should be unknown to the custom registry")
+	}
+	if !infos[1].Known || infos[1].Capabilities.ContextWindow != 4096 {
+		t.Errorf("my-finetune info = %+v", infos[1])
+	}
+}
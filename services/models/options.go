@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+
+	"github.com/hewenyu/newapi-go/types"
+)
+
+// DefaultCacheTTL 模型列表的默认缓存时间
+const DefaultCacheTTL = 5 * time.Minute
+
+// ModelOption 模型服务选项函数类型
+type ModelOption func(*ModelConfig)
+
+// ModelConfig 模型服务配置结构体
+type ModelConfig struct {
+	// CacheTTL 模型列表的缓存时间，不大于0时每次都从网关获取
+	CacheTTL time.Duration `json:"cache_ttl"`
+	// Registry 用于合并模型能力的注册表，nil时使用全局默认注册表
+	Registry *types.CapabilityRegistry `json:"-"`
+}
+
+// DefaultModelConfig 创建默认模型服务配置
+func DefaultModelConfig() *ModelConfig {
+	return &ModelConfig{
+		CacheTTL: DefaultCacheTTL,
+	}
+}
+
+// WithCacheTTL 设置模型列表的缓存时间
+func WithCacheTTL(ttl time.Duration) ModelOption {
+	return func(c *ModelConfig) {
+		c.CacheTTL = ttl
+	}
+}
+
+// WithRegistry 设置模型能力注册表
+func WithRegistry(registry *types.CapabilityRegistry) ModelOption {
+	return func(c *ModelConfig) {
+		c.Registry = registry
+	}
+}
+
+// registry 获取生效的模型能力注册表
+func (c *ModelConfig) registry() *types.CapabilityRegistry {
+	if c.Registry != nil {
+		return c.Registry
+	}
+	return types.DefaultCapabilityRegistry()
+}
+
+// Clone 克隆配置
+func (c *ModelConfig) Clone() *ModelConfig {
+	cloned := *c
+	return &cloned
+}
@@ -0,0 +1,5 @@
+// Package moderation provides content moderation functionality for the New-API Go SDK.
+// This package classifies text and images against the moderation categories
+// relayed by the New-API service and implements chat.Moderator so that chat
+// requests can screen user input and model output.
+package moderation
@@ -0,0 +1,185 @@
+package moderation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/hewenyu/newapi-go/internal/transport"
+	"github.com/hewenyu/newapi-go/internal/utils"
+	"github.com/hewenyu/newapi-go/types"
+	"go.uber.org/zap"
+)
+
+// ModerationService 内容审核服务结构体
+type ModerationService struct {
+	transport transport.HTTPTransport
+	logger    utils.Logger
+	config    *ModerationConfig
+	mu        sync.RWMutex
+}
+
+// NewModerationService 创建新的内容审核服务实例
+func NewModerationService(transport transport.HTTPTransport, logger utils.Logger, options ...ModerationOption) *ModerationService {
+	config := DefaultModerationConfig()
+
+	// 应用选项
+	for _, option := range options {
+		option(config)
+	}
+
+	return &ModerationService{
+		transport: transport,
+		logger:    logger,
+		config:    config,
+	}
+}
+
+// parseJSONResponse 解析JSON响应
+func parseJSONResponse(resp *http.Response, v interface{}) error {
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("failed to unmarshal JSON: %w", err)
+	}
+
+	return nil
+}
+
+// Moderate 审核单个文本
+func (s *ModerationService) Moderate(ctx context.Context, text string, options ...ModerationOption) (*types.ModerationResponse, error) {
+	return s.create(ctx, text, options)
+}
+
+// ModerateBatch 批量审核文本，每个文本对应一个结果
+func (s *ModerationService) ModerateBatch(ctx context.Context, texts []string, options ...ModerationOption) (*types.ModerationResponse, error) {
+	return s.create(ctx, texts, options)
+}
+
+// ModerateMultimodal 将文本和图片作为同一内容整体审核，返回一个结果。需要支持多模态的审核模型
+func (s *ModerationService) ModerateMultimodal(ctx context.Context, inputs []types.ModerationInput, options ...ModerationOption) (*types.ModerationResponse, error) {
+	return s.create(ctx, inputs, options)
+}
+
+// ModerateContent 将一组输入项作为同一内容整体审核，实现chat.Moderator接口。
+// 只包含文本时按批量文本审核并合并结果，从而兼容仅支持文本的审核模型
+func (s *ModerationService) ModerateContent(ctx context.Context, inputs []types.ModerationInput) (*types.ModerationResult, error) {
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("moderation inputs cannot be empty")
+	}
+
+	texts := make([]string, 0, len(inputs))
+	for _, input := range inputs {
+		if input.Type != types.ModerationInputTypeText {
+			texts = nil
+			break
+		}
+		texts = append(texts, input.Text)
+	}
+
+	var resp *types.ModerationResponse
+	var err error
+	if texts != nil {
+		resp, err = s.ModerateBatch(ctx, texts)
+	} else {
+		resp, err = s.ModerateMultimodal(ctx, inputs)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Results) == 0 {
+		return nil, fmt.Errorf("moderation response contains no results")
+	}
+
+	result := types.MergeModerationResults(resp.Results)
+	return &result, nil
+}
+
+// create 发送审核请求
+func (s *ModerationService) create(ctx context.Context, input interface{}, options []ModerationOption) (*types.ModerationResponse, error) {
+	// 创建配置副本并应用选项
+	config := s.getConfig()
+	for _, option := range options {
+		option(config)
+	}
+
+	// 验证配置
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid moderation config: %w", err)
+	}
+
+	// 构建请求
+	req := config.ToRequest(input)
+
+	// 验证请求参数
+	if err := req.ValidateParameters(); err != nil {
+		return nil, fmt.Errorf("invalid request parameters: %w", err)
+	}
+
+	// 发送请求
+	resp, err := s.transport.Post(ctx, "/v1/moderations", req)
+	if err != nil {
+		s.logger.Error("Failed to create moderation", zap.Error(err))
+		return nil, fmt.Errorf("failed to create moderation: %w", err)
+	}
+	statusCode := resp.StatusCode
+
+	// 解析响应
+	var moderationResp types.ModerationResponse
+	if err := parseJSONResponse(resp, &moderationResp); err != nil {
+		if statusCode >= http.StatusBadRequest {
+			apiErr := types.FromHTTPStatusCode(statusCode, http.StatusText(statusCode))
+			s.logger.Error("API returned error", zap.Int("status_code", statusCode))
+			return nil, fmt.Errorf("API error: %w", apiErr)
+		}
+		s.logger.Error("Failed to parse moderation response", zap.Error(err))
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	// 检查API错误
+	if moderationResp.IsError() {
+		errResp := moderationResp.GetError()
+		apiErr := types.NewAPIError(errResp.Type, errResp.Code, errResp.Message, statusCode).WithParam(errResp.Param)
+		s.logger.Error("API returned error", zap.String("error", errResp.Message))
+		return nil, fmt.Errorf("API error: %w", apiErr)
+	}
+	if statusCode >= http.StatusBadRequest {
+		apiErr := types.FromHTTPStatusCode(statusCode, http.StatusText(statusCode))
+		s.logger.Error("API returned error", zap.Int("status_code", statusCode))
+		return nil, fmt.Errorf("API error: %w", apiErr)
+	}
+
+	s.logger.Debug("Moderation created successfully", zap.Int("results", len(moderationResp.Results)))
+	return &moderationResp, nil
+}
+
+// UpdateConfig 更新配置
+func (s *ModerationService) UpdateConfig(options ...ModerationOption) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, option := range options {
+		option(s.config)
+	}
+}
+
+// GetConfig 获取配置副本
+func (s *ModerationService) GetConfig() *ModerationConfig {
+	return s.getConfig()
+}
+
+// getConfig 获取配置副本（内部使用）
+func (s *ModerationService) getConfig() *ModerationConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.config.Clone()
+}
@@ -0,0 +1,100 @@
+package moderation
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hewenyu/newapi-go/internal/transport"
+	"github.com/hewenyu/newapi-go/internal/utils"
+	"github.com/hewenyu/newapi-go/services/chat"
+	"github.com/hewenyu/newapi-go/types"
+)
+
+var _ chat.Moderator = (*ModerationService)(nil)
+
+// newTestService 创建返回固定结果并记录请求输入的审核服务
+func newTestService(t *testing.T, results []types.ModerationResult, inputs *[]json.RawMessage) *ModerationService {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/moderations" {
+			t.Errorf("path = %s, want /v1/moderations", r.URL.Path)
+		}
+		var req struct {
+			Input json.RawMessage `json:"input"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		*inputs = append(*inputs, req.Input)
+		json.NewEncoder(w).Encode(types.ModerationResponse{ID: "modr-1", Results: results})
+	}))
+	t.Cleanup(server.Close)
+	return NewModerationService(transport.NewHTTPClient(server.URL, "test-key"), utils.GetLogger())
+}
+
+func TestModerateResultHelpers(t *testing.T) {
+	var inputs []json.RawMessage
+	service := newTestService(t, []types.ModerationResult{{
+		Flagged:        true,
+		Categories:     map[types.ModerationCategory]bool{types.ModerationViolence: true, types.ModerationHate: false},
+		CategoryScores: map[types.ModerationCategory]float64{types.ModerationViolence: 0.91, types.ModerationHate: 0.4},
+	}}, &inputs)
+
+	resp, err := service.Moderate(context.Background(), "some text")
+	if err != nil {
+		t.Fatalf("Moderate failed: %v", err)
+	}
+	if !resp.IsFlagged() {
+		t.Error("expected response to be flagged")
+	}
+
+	result := resp.Results[0]
+	if got := result.FlaggedCategories(); len(got) != 1 || got[0] != types.ModerationViolence {
+		t.Errorf("FlaggedCategories() = %v", got)
+	}
+	strict := result.Violations(map[types.ModerationCategory]float64{types.ModerationHate: 0.3})
+	if len(strict) != 2 || strict[0] != types.ModerationHate || strict[1] != types.ModerationViolence {
+		t.Errorf("Violations(strict) = %v", strict)
+	}
+	lenient := result.Violations(map[types.ModerationCategory]float64{types.ModerationViolence: 0.95})
+	if len(lenient) != 0 {
+		t.Errorf("Violations(lenient) = %v", lenient)
+	}
+}
+
+func TestModerateContentInputs(t *testing.T) {
+	var inputs []json.RawMessage
+	service := newTestService(t, []types.ModerationResult{
+		{CategoryScores: map[types.ModerationCategory]float64{types.ModerationHate: 0.2}},
+		{Flagged: true, Categories: map[types.ModerationCategory]bool{types.ModerationHate: true},
+			CategoryScores: map[types.ModerationCategory]float64{types.ModerationHate: 0.8}},
+	}, &inputs)
+	ctx := context.Background()
+
+	result, err := service.ModerateContent(ctx, []types.ModerationInput{
+		types.NewModerationTextInput("a"), types.NewModerationTextInput("b"),
+	})
+	if err != nil {
+		t.Fatalf("ModerateContent failed: %v", err)
+	}
+	if !result.Flagged || result.Score(types.ModerationHate) != 0.8 {
+		t.Errorf("merged result = %+v", result)
+	}
+	if string(inputs[0]) != `["a","b"]` {
+		t.Errorf("text input = %s, want string array", inputs[0])
+	}
+
+	if _, err := service.ModerateContent(ctx, []types.ModerationInput{
+		types.NewModerationTextInput("look"), types.NewModerationImageInput("https://example.com/a.png"),
+	}); err != nil {
+		t.Fatalf("ModerateContent failed: %v", err)
+	}
+	var parts []types.ModerationInput
+	if err := json.Unmarshal(inputs[1], &parts); err != nil || len(parts) != 2 || parts[1].ImageURL == nil {
+		t.Errorf("multimodal input = %s", inputs[1])
+	}
+
+	if _, err := service.ModerateMultimodal(ctx, []types.ModerationInput{{Type: types.ModerationInputTypeImageURL}}); err == nil {
+		t.Error("expected error for image input without url")
+	}
+}
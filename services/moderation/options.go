@@ -0,0 +1,49 @@
+package moderation
+
+import (
+	"github.com/hewenyu/newapi-go/types"
+)
+
+// ModerationOption 审核选项函数类型
+type ModerationOption func(*ModerationConfig)
+
+// ModerationConfig 审核配置结构体
+type ModerationConfig struct {
+	Model string `json:"model"`
+}
+
+// DefaultModerationConfig 创建默认审核配置
+func DefaultModerationConfig() *ModerationConfig {
+	return &ModerationConfig{
+		Model: types.ModerationModelOmniLatest,
+	}
+}
+
+// WithModel 设置审核模型
+func WithModel(model string) ModerationOption {
+	return func(c *ModerationConfig) {
+		c.Model = model
+	}
+}
+
+// ToRequest 将配置转换为审核请求
+func (c *ModerationConfig) ToRequest(input interface{}) *types.ModerationRequest {
+	return &types.ModerationRequest{
+		Input: input,
+		Model: c.Model,
+	}
+}
+
+// Validate 验证配置
+func (c *ModerationConfig) Validate() error {
+	if c.Model == "" {
+		return types.NewValidationError("model", c.Model, "model is required", types.ErrCodeMissingParameter)
+	}
+	return nil
+}
+
+// Clone 克隆配置
+func (c *ModerationConfig) Clone() *ModerationConfig {
+	cloned := *c
+	return &cloned
+}
@@ -0,0 +1,18 @@
+// Package stream provides utilities for redistributing streaming responses for
+// the New-API Go SDK.
+//
+// A Mux reads one upstream types.StreamResponse and hands every event to any
+// number of subscribers, each of which is itself a types.StreamResponse with its
+// own buffer. The backpressure policy decides what happens when a subscriber
+// falls behind: block the source, drop events for that subscriber, or end that
+// subscriber with ErrSlowConsumer. Subscribers that join after the stream has
+// started first receive the events already read. The upstream stream is closed
+// when it ends or when every subscriber has closed.
+//
+// The writers bridge a chat stream to other transports: CopyText copies the
+// answer text to any io.Writer, WriteSSE re-emits OpenAI-compatible SSE,
+// WriteNDJSON emits one JSON chunk per line and WriteDataStream speaks the
+// Vercel AI SDK data stream protocol. The Serve variants set the response
+// headers, flush after every event and close the upstream stream when the HTTP
+// client disconnects, so a pass-through endpoint is a single call.
+package stream
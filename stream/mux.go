@@ -0,0 +1,409 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/hewenyu/newapi-go/types"
+)
+
+var (
+	// ErrSlowConsumer 订阅者在BackpressureError模式下跟不上上游时返回的错误
+	ErrSlowConsumer = errors.New("stream subscriber fell behind")
+	// ErrMuxClosed 多路复用器已关闭
+	ErrMuxClosed = errors.New("stream multiplexer closed")
+)
+
+// Mux 流式响应多路复用器，从一个上游流读取事件并分发给多个独立的订阅者。
+// 每个订阅者有独立的缓冲区，上游结束或所有订阅者关闭后自动关闭上游流
+type Mux struct {
+	source types.StreamResponse
+	config *MuxConfig
+
+	mu          sync.Mutex
+	subscribers map[*Subscriber]struct{}
+	history     []*types.StreamEvent
+	started     bool
+	ended       bool
+	err         error
+	closed      bool
+
+	closing   chan struct{}
+	stopped   chan struct{}
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// NewMux 创建多路复用器。先通过Subscribe注册订阅者，再调用Start开始读取上游
+func NewMux(source types.StreamResponse, options ...MuxOption) (*Mux, error) {
+	if source == nil {
+		return nil, fmt.Errorf("source stream cannot be nil")
+	}
+
+	config := DefaultMuxConfig()
+	for _, option := range options {
+		option(config)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid mux config: %w", err)
+	}
+
+	return &Mux{
+		source:      source,
+		config:      config,
+		subscribers: make(map[*Subscriber]struct{}),
+		closing:     make(chan struct{}),
+		stopped:     make(chan struct{}),
+	}, nil
+}
+
+// Tee 将上游流复制为n个独立的订阅者并立即开始读取
+func Tee(source types.StreamResponse, n int, options ...MuxOption) ([]*Subscriber, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("subscriber count must be positive")
+	}
+
+	mux, err := NewMux(source, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	subscribers := make([]*Subscriber, n)
+	for i := range subscribers {
+		if subscribers[i], err = mux.Subscribe(); err != nil {
+			return nil, err
+		}
+	}
+	mux.Start()
+	return subscribers, nil
+}
+
+// Subscribe 注册订阅者。开始读取后注册的订阅者先收到保留的历史事件，
+// 上游已结束时读完历史事件后以上游的结束状态结束
+func (m *Mux) Subscribe() (*Subscriber, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return nil, ErrMuxClosed
+	}
+
+	s := &Subscriber{
+		mux:   m,
+		queue: append([]*types.StreamEvent(nil), m.history...),
+		ready: make(chan struct{}, 1),
+		space: make(chan struct{}, 1),
+		done:  make(chan struct{}),
+	}
+	if m.ended {
+		s.end(m.err)
+	}
+	m.subscribers[s] = struct{}{}
+	return s, nil
+}
+
+// Start 开始读取上游并分发事件，重复调用无效
+func (m *Mux) Start() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.started || m.closed {
+		return
+	}
+	m.started = true
+	go m.pump()
+}
+
+// Close 关闭上游流并结束所有订阅者，等待读取协程退出后返回
+func (m *Mux) Close() error {
+	m.closeOnce.Do(func() {
+		m.mu.Lock()
+		m.closed = true
+		started := m.started
+		subscribers := m.snapshot()
+		m.mu.Unlock()
+
+		close(m.closing)
+		m.closeErr = m.source.Close()
+		for _, s := range subscribers {
+			s.end(ErrMuxClosed)
+		}
+		if started {
+			<-m.stopped
+		}
+	})
+	return m.closeErr
+}
+
+// Done 检查上游流是否已经结束
+func (m *Mux) Done() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.ended || m.closed
+}
+
+// Err 获取上游流的错误，正常结束时为nil
+func (m *Mux) Err() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.err
+}
+
+// pump 读取上游并分发事件
+func (m *Mux) pump() {
+	defer close(m.stopped)
+
+	// 上游上下文取消时关闭上游，避免阻塞在读取上
+	ctx := m.source.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	stop := context.AfterFunc(ctx, func() { m.source.Close() })
+	defer stop()
+
+	for {
+		event, err := m.source.Next()
+		if err != nil {
+			// 上下文取消后关闭上游导致的结束以上下文错误返回
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				err = ctxErr
+			}
+			m.finish(err)
+			return
+		}
+
+		m.mu.Lock()
+		if m.closed {
+			m.mu.Unlock()
+			return
+		}
+		m.remember(event)
+		subscribers := m.snapshot()
+		m.mu.Unlock()
+
+		for _, s := range subscribers {
+			s.deliver(event)
+		}
+	}
+}
+
+// finish 上游结束，通知所有订阅者并释放上游连接
+func (m *Mux) finish(err error) {
+	m.mu.Lock()
+	if m.closed {
+		// 主动关闭导致的读取错误
+		m.mu.Unlock()
+		return
+	}
+	m.ended = true
+	if err != io.EOF {
+		m.err = err
+	}
+	subscribers := m.snapshot()
+	m.mu.Unlock()
+
+	for _, s := range subscribers {
+		s.end(m.err)
+	}
+	m.source.Close()
+}
+
+// remember 保留历史事件供迟到的订阅者重放，调用方需持有锁
+func (m *Mux) remember(event *types.StreamEvent) {
+	switch size := m.config.ReplaySize; {
+	case size == 0:
+	case size < 0 || len(m.history) < size:
+		m.history = append(m.history, event)
+	default:
+		copy(m.history, m.history[1:])
+		m.history[len(m.history)-1] = event
+	}
+}
+
+// snapshot 获取当前订阅者列表，调用方需持有锁
+func (m *Mux) snapshot() []*Subscriber {
+	subscribers := make([]*Subscriber, 0, len(m.subscribers))
+	for s := range m.subscribers {
+		subscribers = append(subscribers, s)
+	}
+	return subscribers
+}
+
+// release 移除已关闭的订阅者，上游未结束且没有订阅者时关闭多路复用器
+func (m *Mux) release(s *Subscriber) {
+	m.mu.Lock()
+	delete(m.subscribers, s)
+	last := len(m.subscribers) == 0 && m.started && !m.ended
+	m.mu.Unlock()
+
+	if last {
+		m.Close()
+	}
+}
+
+// Subscriber 多路复用器的订阅者，实现types.StreamResponse
+type Subscriber struct {
+	mux *Mux
+
+	mu      sync.Mutex
+	queue   []*types.StreamEvent
+	ended   bool
+	err     error
+	closed  bool
+	dropped int
+
+	ready     chan struct{}
+	space     chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// Next 获取下一个事件，上游正常结束时返回io.EOF
+func (s *Subscriber) Next() (*types.StreamEvent, error) {
+	ctx := s.Context()
+	for {
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			return nil, io.EOF
+		}
+		if len(s.queue) > 0 {
+			event := s.queue[0]
+			s.queue[0] = nil
+			s.queue = s.queue[1:]
+			s.mu.Unlock()
+			notify(s.space)
+			return event, nil
+		}
+		if s.ended {
+			err := s.err
+			s.mu.Unlock()
+			if err == nil {
+				return nil, io.EOF
+			}
+			return nil, err
+		}
+		s.mu.Unlock()
+
+		select {
+		case <-s.ready:
+		case <-s.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Close 关闭订阅者，所有订阅者关闭后上游流随之关闭
+func (s *Subscriber) Close() error {
+	s.closeOnce.Do(func() {
+		s.mu.Lock()
+		s.closed = true
+		s.queue = nil
+		s.mu.Unlock()
+
+		close(s.done)
+		s.mux.release(s)
+	})
+	return nil
+}
+
+// Err 获取订阅者的错误，上游正常结束时为nil
+func (s *Subscriber) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.err
+}
+
+// Done 检查是否已读完所有事件
+func (s *Subscriber) Done() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.closed || (s.ended && len(s.queue) == 0)
+}
+
+// Context 获取上游流的上下文
+func (s *Subscriber) Context() context.Context {
+	if ctx := s.mux.source.Context(); ctx != nil {
+		return ctx
+	}
+	return context.Background()
+}
+
+// Dropped 获取BackpressureDrop模式下因缓冲区已满而丢弃的事件数
+func (s *Subscriber) Dropped() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.dropped
+}
+
+// deliver 按背压策略将事件放入缓冲区
+func (s *Subscriber) deliver(event *types.StreamEvent) {
+	config := s.mux.config
+	for {
+		s.mu.Lock()
+		if s.closed || s.ended {
+			s.mu.Unlock()
+			return
+		}
+		if len(s.queue) < config.BufferSize {
+			s.queue = append(s.queue, event)
+			s.mu.Unlock()
+			notify(s.ready)
+			return
+		}
+
+		switch config.Backpressure {
+		case BackpressureDrop:
+			s.dropped++
+			s.mu.Unlock()
+			return
+		case BackpressureError:
+			s.ended, s.err = true, ErrSlowConsumer
+			s.mu.Unlock()
+			notify(s.ready)
+			return
+		}
+		s.mu.Unlock()
+
+		// 上游上下文取消后不再等待，读取协程随后因上游出错而结束
+		select {
+		case <-s.space:
+		case <-s.done:
+			return
+		case <-s.mux.closing:
+			return
+		case <-s.Context().Done():
+			return
+		}
+	}
+}
+
+// end 结束订阅者，只有第一次调用生效
+func (s *Subscriber) end(err error) {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended, s.err = true, err
+	s.mu.Unlock()
+
+	notify(s.ready)
+}
+
+// notify 非阻塞地发送信号
+func notify(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,258 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hewenyu/newapi-go/types"
+)
+
+// chanStream 由通道驱动的测试上游流，Close会解除阻塞中的Next
+type chanStream struct {
+	events    chan *types.StreamEvent
+	closed    chan struct{}
+	closeOnce sync.Once
+	err       error
+}
+
+func newChanStream(buffer int) *chanStream {
+	return &chanStream{events: make(chan *types.StreamEvent, buffer), closed: make(chan struct{})}
+}
+
+func (c *chanStream) Next() (*types.StreamEvent, error) {
+	select {
+	case event, ok := <-c.events:
+		if !ok {
+			if c.err != nil {
+				return nil, c.err
+			}
+			return nil, io.EOF
+		}
+		return event, nil
+	case <-c.closed:
+		return nil, errors.New("stream closed")
+	}
+}
+
+func (c *chanStream) Close() error {
+	c.closeOnce.Do(func() { close(c.closed) })
+	return nil
+}
+
+func (c *chanStream) isClosed() bool {
+	select {
+	case <-c.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *chanStream) Err() error               { return c.err }
+func (c *chanStream) Done() bool               { return c.isClosed() }
+func (c *chanStream) Context() context.Context { return context.Background() }
+
+func dataEvent(i int) *types.StreamEvent {
+	data, _ := json.Marshal(map[string]int{"n": i})
+	return &types.StreamEvent{Type: types.StreamEventTypeData, Data: data}
+}
+
+// drain 读取订阅者的全部事件
+func drain(s *Subscriber) ([]string, error) {
+	var got []string
+	for {
+		event, err := s.Next()
+		if err == io.EOF {
+			return got, nil
+		}
+		if err != nil {
+			return got, err
+		}
+		got = append(got, string(event.Data))
+	}
+}
+
+func TestTeeDeliversEveryEventToEverySubscriber(t *testing.T) {
+	source := newChanStream(10)
+	for i := 0; i < 10; i++ {
+		source.events <- dataEvent(i)
+	}
+	close(source.events)
+
+	subscribers, err := Tee(source, 3, WithBufferSize(2))
+	if err != nil {
+		t.Fatalf("Tee failed: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	results := make([][]string, len(subscribers))
+	for i, s := range subscribers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer s.Close()
+			got, err := drain(s)
+			if err != nil {
+				t.Errorf("subscriber %d: %v", i, err)
+			}
+			results[i] = got
+		}()
+	}
+	wg.Wait()
+
+	for i, got := range results {
+		if len(got) != 10 || got[0] != `{"n":0}` || got[9] != `{"n":9}` {
+			t.Errorf("subscriber %d received %v", i, got)
+		}
+	}
+	if !source.isClosed() {
+		t.Error("source should be closed after it ends")
+	}
+}
+
+func TestBackpressureDropAndError(t *testing.T) {
+	for _, policy := range []Backpressure{BackpressureDrop, BackpressureError} {
+		t.Run(policy.String(), func(t *testing.T) {
+			source := newChanStream(10)
+			for i := 0; i < 5; i++ {
+				source.events <- dataEvent(i)
+			}
+			close(source.events)
+
+			mux, err := NewMux(source, WithBufferSize(2), WithBackpressure(policy))
+			if err != nil {
+				t.Fatalf("NewMux failed: %v", err)
+			}
+			slow, _ := mux.Subscribe()
+			mux.Start()
+
+			// 慢订阅者不读取，等待上游读完
+			deadline := time.Now().Add(time.Second)
+			for !mux.Done() && time.Now().Before(deadline) {
+				time.Sleep(time.Millisecond)
+			}
+
+			got, err := drain(slow)
+			if len(got) != 2 {
+				t.Errorf("slow subscriber received %d events, want 2", len(got))
+			}
+			switch policy {
+			case BackpressureDrop:
+				if err != nil || slow.Dropped() != 3 {
+					t.Errorf("drop: err=%v dropped=%d, want nil and 3", err, slow.Dropped())
+				}
+			case BackpressureError:
+				if !errors.Is(err, ErrSlowConsumer) {
+					t.Errorf("error: got %v, want ErrSlowConsumer", err)
+				}
+			}
+		})
+	}
+}
+
+func TestLateSubscriberReplaysHistory(t *testing.T) {
+	source := newChanStream(10)
+	mux, err := NewMux(source)
+	if err != nil {
+		t.Fatalf("NewMux failed: %v", err)
+	}
+	early, _ := mux.Subscribe()
+	mux.Start()
+
+	source.events <- dataEvent(0)
+	source.events <- dataEvent(1)
+	for i := 0; i < 2; i++ {
+		if _, err := early.Next(); err != nil {
+			t.Fatalf("early Next failed: %v", err)
+		}
+	}
+
+	late, err := mux.Subscribe()
+	if err != nil {
+		t.Fatalf("late Subscribe failed: %v", err)
+	}
+	source.events <- dataEvent(2)
+	close(source.events)
+
+	got, err := drain(late)
+	if err != nil {
+		t.Fatalf("late subscriber: %v", err)
+	}
+	want := fmt.Sprint([]string{`{"n":0}`, `{"n":1}`, `{"n":2}`})
+	if fmt.Sprint(got) != want {
+		t.Errorf("late subscriber received %v, want %v", got, want)
+	}
+}
+
+func TestClosingAllSubscribersClosesSource(t *testing.T) {
+	source := newChanStream(0)
+	subscribers, err := Tee(source, 2)
+	if err != nil {
+		t.Fatalf("Tee failed: %v", err)
+	}
+
+	subscribers[0].Close()
+	if source.isClosed() {
+		t.Fatal("source closed while a subscriber is still active")
+	}
+	subscribers[1].Close()
+	if !source.isClosed() {
+		t.Error("source should be closed once every subscriber has closed")
+	}
+	if _, err := subscribers[1].Next(); err != io.EOF {
+		t.Errorf("Next after Close = %v, want io.EOF", err)
+	}
+}
+
+func TestMuxCloseUnblocksBlockedSubscriber(t *testing.T) {
+	source := newChanStream(0)
+	mux, err := NewMux(source)
+	if err != nil {
+		t.Fatalf("NewMux failed: %v", err)
+	}
+	s, _ := mux.Subscribe()
+	mux.Start()
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := s.Next()
+		errc <- err
+	}()
+
+	mux.Close()
+	select {
+	case err := <-errc:
+		if !errors.Is(err, ErrMuxClosed) {
+			t.Errorf("Next = %v, want ErrMuxClosed", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Next still blocked after Close")
+	}
+	if _, err := mux.Subscribe(); !errors.Is(err, ErrMuxClosed) {
+		t.Errorf("Subscribe after Close = %v, want ErrMuxClosed", err)
+	}
+}
+
+func TestSourceErrorPropagates(t *testing.T) {
+	source := newChanStream(1)
+	source.err = errors.New("upstream failed")
+	source.events <- dataEvent(0)
+	close(source.events)
+
+	subscribers, err := Tee(source, 2)
+	if err != nil {
+		t.Fatalf("Tee failed: %v", err)
+	}
+	for _, s := range subscribers {
+		got, err := drain(s)
+		if len(got) != 1 || err == nil || err.Error() != "upstream failed" {
+			t.Errorf("received %v, %v", got, err)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package stream
+
+import (
+	"github.com/hewenyu/newapi-go/types"
+)
+
+// Backpressure 订阅者缓冲区已满时的处理方式
+type Backpressure int
+
+const (
+	// BackpressureBlock 暂停读取上游，等待慢订阅者消费，所有订阅者同步前进
+	BackpressureBlock Backpressure = iota
+	// BackpressureDrop 丢弃该订阅者的新事件，不影响其他订阅者
+	BackpressureDrop
+	// BackpressureError 该订阅者读完已缓冲的事件后以ErrSlowConsumer结束
+	BackpressureError
+)
+
+// String 获取处理方式名称
+func (b Backpressure) String() string {
+	switch b {
+	case BackpressureBlock:
+		return "block"
+	case BackpressureDrop:
+		return "drop"
+	case BackpressureError:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+// MuxOption 多路复用器选项函数类型
+type MuxOption func(*MuxConfig)
+
+// MuxConfig 多路复用器配置
+type MuxConfig struct {
+	// BufferSize 每个订阅者的缓冲事件数
+	BufferSize int `json:"buffer_size"`
+	// Backpressure 缓冲区已满时的处理方式
+	Backpressure Backpressure `json:"backpressure"`
+	// ReplaySize 为迟到订阅者保留的最近事件数，0表示不保留，负数表示保留全部
+	ReplaySize int `json:"replay_size"`
+}
+
+// DefaultMuxConfig 返回默认的多路复用器配置，聊天流的事件数有限，默认保留全部事件供迟到订阅者重放
+func DefaultMuxConfig() *MuxConfig {
+	return &MuxConfig{
+		BufferSize:   64,
+		Backpressure: BackpressureBlock,
+		ReplaySize:   -1,
+	}
+}
+
+// WithBufferSize 设置每个订阅者的缓冲事件数
+func WithBufferSize(size int) MuxOption {
+	return func(config *MuxConfig) {
+		config.BufferSize = size
+	}
+}
+
+// WithBackpressure 设置缓冲区已满时的处理方式
+func WithBackpressure(backpressure Backpressure) MuxOption {
+	return func(config *MuxConfig) {
+		config.Backpressure = backpressure
+	}
+}
+
+// WithReplay 设置为迟到订阅者保留的最近事件数，0表示不保留，负数表示保留全部
+func WithReplay(size int) MuxOption {
+	return func(config *MuxConfig) {
+		config.ReplaySize = size
+	}
+}
+
+// Validate 验证配置
+func (c *MuxConfig) Validate() error {
+	if c.BufferSize <= 0 {
+		return types.NewValidationError("buffer_size", c.BufferSize,
+			"buffer size must be positive", types.ErrCodeInvalidParameter)
+	}
+
+	switch c.Backpressure {
+	case BackpressureBlock, BackpressureDrop, BackpressureError:
+	default:
+		return types.NewValidationError("backpressure", c.Backpressure,
+			"backpressure must be block, drop or error", types.ErrCodeInvalidParameter)
+	}
+
+	return nil
+}
@@ -0,0 +1,430 @@
+package stream
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/hewenyu/newapi-go/types"
+)
+
+// 输出格式的Content-Type
+const (
+	ContentTypeSSE        = "text/event-stream"
+	ContentTypeNDJSON     = "application/x-ndjson"
+	ContentTypeDataStream = "text/plain; charset=utf-8"
+)
+
+// downstreamError 写入下游失败的错误，通常表示客户端已断开
+type downstreamError struct {
+	err error
+}
+
+// Error 实现error接口
+func (e *downstreamError) Error() string {
+	return fmt.Sprintf("failed to write stream: %v", e.err)
+}
+
+// Unwrap 返回底层错误
+func (e *downstreamError) Unwrap() error {
+	return e.err
+}
+
+// CopyText 将聊天流的回答增量文本写入w，返回写入的字节数。
+// w实现了Flush时每个增量后刷新，写入失败时关闭上游流
+func CopyText(w io.Writer, src types.StreamResponse) (int64, error) {
+	var written int64
+	err := forward(src.Context(), src, func(event *types.StreamEvent) error {
+		chunk, err := parseChunk(event)
+		if err != nil || chunk == nil {
+			return err
+		}
+		for i := range chunk.Choices {
+			content := chunk.Choices[i].GetContent()
+			if content == "" {
+				continue
+			}
+			n, err := io.WriteString(w, content)
+			written += int64(n)
+			if err != nil {
+				return &downstreamError{err: err}
+			}
+		}
+		return flush(w)
+	})
+	return written, err
+}
+
+// WriteSSE 以OpenAI兼容的SSE格式重新输出聊天流，每个事件后刷新，正常结束时写入 data: [DONE]。
+// 上游出错时写入 data: {"error":{...}} 事件后返回该错误
+func WriteSSE(w io.Writer, src types.StreamResponse) error {
+	return writeSSE(src.Context(), w, src)
+}
+
+// WriteNDJSON 将聊天流的每个块输出为一行JSON，上游出错时输出一行 {"error":{...}}
+func WriteNDJSON(w io.Writer, src types.StreamResponse) error {
+	return writeNDJSON(src.Context(), w, src)
+}
+
+// WriteDataStream 将聊天流转换为Vercel AI SDK的数据流协议（v1），
+// 输出文本、推理、工具调用、步骤结束和消息结束部分，上游出错时输出错误部分
+func WriteDataStream(w io.Writer, src types.StreamResponse) error {
+	return writeDataStream(src.Context(), w, src)
+}
+
+// ServeSSE 设置SSE响应头并以OpenAI兼容格式转发聊天流，客户端断开时关闭上游流
+func ServeSSE(w http.ResponseWriter, r *http.Request, src types.StreamResponse) error {
+	w.Header().Set("Content-Type", ContentTypeSSE)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	return writeSSE(r.Context(), newResponseFlusher(w), src)
+}
+
+// ServeNDJSON 设置NDJSON响应头并转发聊天流，客户端断开时关闭上游流
+func ServeNDJSON(w http.ResponseWriter, r *http.Request, src types.StreamResponse) error {
+	w.Header().Set("Content-Type", ContentTypeNDJSON)
+	w.Header().Set("Cache-Control", "no-cache")
+	return writeNDJSON(r.Context(), newResponseFlusher(w), src)
+}
+
+// ServeDataStream 设置Vercel AI SDK数据流响应头并转发聊天流，客户端断开时关闭上游流
+func ServeDataStream(w http.ResponseWriter, r *http.Request, src types.StreamResponse) error {
+	w.Header().Set("Content-Type", ContentTypeDataStream)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Vercel-AI-Data-Stream", "v1")
+	return writeDataStream(r.Context(), newResponseFlusher(w), src)
+}
+
+// writeSSE 以SSE格式转发
+func writeSSE(ctx context.Context, w io.Writer, src types.StreamResponse) error {
+	writer := types.NewStreamWriter(w)
+	err := forward(ctx, src, func(event *types.StreamEvent) error {
+		if event.Type != types.StreamEventTypeData {
+			return nil
+		}
+		if err := writer.WriteEvent(&types.StreamEvent{ID: event.ID, Data: event.Data}); err != nil {
+			return &downstreamError{err: err}
+		}
+		return flush(w)
+	})
+
+	if err == nil {
+		if writeErr := writer.WriteDone(); writeErr != nil {
+			return &downstreamError{err: writeErr}
+		}
+		return flush(w)
+	}
+	if upstream(err) {
+		writer.WriteData(errorBody(err))
+		flush(w)
+	}
+	return err
+}
+
+// writeNDJSON 以NDJSON格式转发
+func writeNDJSON(ctx context.Context, w io.Writer, src types.StreamResponse) error {
+	err := forward(ctx, src, func(event *types.StreamEvent) error {
+		if event.Type != types.StreamEventTypeData {
+			return nil
+		}
+		if err := writeLine(w, compact(event.Data)); err != nil {
+			return err
+		}
+		return flush(w)
+	})
+
+	if err != nil && upstream(err) {
+		if data, marshalErr := json.Marshal(errorBody(err)); marshalErr == nil {
+			writeLine(w, data)
+			flush(w)
+		}
+	}
+	return err
+}
+
+// writeDataStream 以Vercel AI SDK数据流格式转发
+func writeDataStream(ctx context.Context, w io.Writer, src types.StreamResponse) error {
+	encoder := &dataStreamEncoder{w: w, toolCalls: make(map[int]*types.ToolCall)}
+	err := forward(ctx, src, func(event *types.StreamEvent) error {
+		chunk, err := parseChunk(event)
+		if err != nil || chunk == nil {
+			return err
+		}
+		if err := encoder.chunk(chunk); err != nil {
+			return err
+		}
+		return flush(w)
+	})
+
+	if err == nil {
+		if err := encoder.finish(); err != nil {
+			return err
+		}
+		return flush(w)
+	}
+	if upstream(err) {
+		encoder.part('3', errorBody(err).Error.Message)
+		flush(w)
+	}
+	return err
+}
+
+// forward 逐个读取事件并交给emit处理。ctx结束时关闭上游流以解除阻塞的读取，
+// emit失败（通常是下游断开）时同样关闭上游流，使取消传递到上游请求
+func forward(ctx context.Context, src types.StreamResponse, emit func(*types.StreamEvent) error) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	stop := context.AfterFunc(ctx, func() { src.Close() })
+	defer stop()
+
+	for {
+		event, err := src.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			return err
+		}
+
+		if err := emit(event); err != nil {
+			src.Close()
+			return err
+		}
+	}
+}
+
+// upstream 检查错误是否来自上游，下游断开或已取消时无法再写入错误信息
+func upstream(err error) bool {
+	var downstream *downstreamError
+	return !errors.As(err, &downstream) && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+// streamErrorBody 流中的错误体
+type streamErrorBody struct {
+	Error *types.ErrorResponse `json:"error"`
+}
+
+// errorBody 将错误转换为OpenAI格式的错误体
+func errorBody(err error) streamErrorBody {
+	var apiErr *types.APIError
+	if errors.As(err, &apiErr) {
+		return streamErrorBody{Error: &types.ErrorResponse{Type: apiErr.Type, Code: apiErr.Code, Message: apiErr.Message, Param: apiErr.Param}}
+	}
+	return streamErrorBody{Error: &types.ErrorResponse{Type: types.ErrTypeAPIError, Code: types.ErrCodeStreamError, Message: err.Error()}}
+}
+
+// parseChunk 解析聊天块，非数据事件返回nil，流中的错误体作为API错误返回
+func parseChunk(event *types.StreamEvent) (*types.ChatCompletionChunk, error) {
+	if event.Type != types.StreamEventTypeData {
+		return nil, nil
+	}
+
+	var body struct {
+		types.ChatCompletionChunk
+		Error *types.ErrorResponse `json:"error"`
+	}
+	if err := json.Unmarshal(event.Data, &body); err != nil {
+		return nil, fmt.Errorf("failed to parse chat completion chunk: %w", err)
+	}
+	if body.Error != nil {
+		return nil, fmt.Errorf("API error: %w", types.NewAPIError(body.Error.Type, body.Error.Code, body.Error.Message, 0).WithParam(body.Error.Param))
+	}
+	return &body.ChatCompletionChunk, nil
+}
+
+// compact 去除JSON中的换行，保证一行一个对象
+func compact(data []byte) []byte {
+	if !bytes.ContainsAny(data, "\r\n") {
+		return data
+	}
+	var buffer bytes.Buffer
+	if err := json.Compact(&buffer, data); err != nil {
+		return bytes.ReplaceAll(bytes.ReplaceAll(data, []byte("\r"), nil), []byte("\n"), []byte(" "))
+	}
+	return buffer.Bytes()
+}
+
+// writeLine 写入一行
+func writeLine(w io.Writer, data []byte) error {
+	if _, err := w.Write(append(append([]byte(nil), data...), '\n')); err != nil {
+		return &downstreamError{err: err}
+	}
+	return nil
+}
+
+// flush 刷新支持缓冲的写入器
+func flush(w io.Writer) error {
+	var err error
+	switch f := w.(type) {
+	case interface{ Flush() error }:
+		err = f.Flush()
+	case http.Flusher:
+		f.Flush()
+	}
+	if err != nil {
+		return &downstreamError{err: err}
+	}
+	return nil
+}
+
+// responseFlusher 通过http.ResponseController刷新，兼容中间件包装的ResponseWriter
+type responseFlusher struct {
+	http.ResponseWriter
+	controller *http.ResponseController
+}
+
+// newResponseFlusher 包装ResponseWriter
+func newResponseFlusher(w http.ResponseWriter) *responseFlusher {
+	return &responseFlusher{ResponseWriter: w, controller: http.NewResponseController(w)}
+}
+
+// Flush 刷新响应，ResponseWriter不支持刷新时忽略
+func (f *responseFlusher) Flush() error {
+	if err := f.controller.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
+
+// dataStreamEncoder Vercel AI SDK数据流协议编码器
+type dataStreamEncoder struct {
+	w            io.Writer
+	started      bool
+	finishReason string
+	usage        *types.Usage
+	toolCalls    map[int]*types.ToolCall
+	order        []int
+}
+
+// chunk 编码一个聊天块
+func (e *dataStreamEncoder) chunk(chunk *types.ChatCompletionChunk) error {
+	if !e.started {
+		e.started = true
+		if err := e.part('f', map[string]string{"messageId": chunk.ID}); err != nil {
+			return err
+		}
+	}
+	if chunk.Usage != nil {
+		e.usage = chunk.Usage
+	}
+
+	for i := range chunk.Choices {
+		choice := &chunk.Choices[i]
+		if choice.Index != 0 {
+			continue
+		}
+		if reasoning := choice.GetReasoningContent(); reasoning != "" {
+			if err := e.part('g', reasoning); err != nil {
+				return err
+			}
+		}
+		if content := choice.GetContent(); content != "" {
+			if err := e.part('0', content); err != nil {
+				return err
+			}
+		}
+		for _, delta := range choice.Delta.ToolCalls {
+			if err := e.toolCallDelta(delta); err != nil {
+				return err
+			}
+		}
+		if choice.FinishReason != "" {
+			e.finishReason = choice.FinishReason
+		}
+	}
+	return nil
+}
+
+// toolCallDelta 编码工具调用的开始和参数增量
+func (e *dataStreamEncoder) toolCallDelta(delta types.ToolCall) error {
+	index := len(e.order)
+	if delta.Index != nil {
+		index = *delta.Index
+	}
+
+	call, exists := e.toolCalls[index]
+	if !exists {
+		call = &types.ToolCall{ID: delta.ID, Type: delta.Type, Function: types.FunctionCall{Name: delta.Function.Name}}
+		e.toolCalls[index] = call
+		e.order = append(e.order, index)
+		if err := e.part('b', map[string]string{"toolCallId": call.ID, "toolName": call.Function.Name}); err != nil {
+			return err
+		}
+	}
+
+	if delta.Function.Arguments == "" {
+		return nil
+	}
+	call.Function.Arguments += delta.Function.Arguments
+	return e.part('c', map[string]string{"toolCallId": call.ID, "argsTextDelta": delta.Function.Arguments})
+}
+
+// finish 输出完整的工具调用、步骤结束和消息结束部分
+func (e *dataStreamEncoder) finish() error {
+	for _, index := range e.order {
+		call := e.toolCalls[index]
+		args := json.RawMessage(call.Function.Arguments)
+		if !json.Valid(args) {
+			args = json.RawMessage("{}")
+		}
+		if err := e.part('9', map[string]interface{}{"toolCallId": call.ID, "toolName": call.Function.Name, "args": args}); err != nil {
+			return err
+		}
+	}
+
+	reason, usage := dataStreamFinishReason(e.finishReason), e.usagePart()
+	if err := e.part('e', map[string]interface{}{"finishReason": reason, "usage": usage, "isContinued": false}); err != nil {
+		return err
+	}
+	return e.part('d', map[string]interface{}{"finishReason": reason, "usage": usage})
+}
+
+// usagePart 获取数据流协议的用量字段
+func (e *dataStreamEncoder) usagePart() map[string]int {
+	if e.usage == nil {
+		return map[string]int{"promptTokens": 0, "completionTokens": 0}
+	}
+	return map[string]int{"promptTokens": e.usage.PromptTokens, "completionTokens": e.usage.CompletionTokens}
+}
+
+// part 写入一个 "<类型>:<JSON>\n" 部分
+func (e *dataStreamEncoder) part(code byte, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode data stream part: %w", err)
+	}
+	line := make([]byte, 0, len(data)+3)
+	line = append(line, code, ':')
+	line = append(line, data...)
+	line = append(line, '\n')
+	if _, err := e.w.Write(line); err != nil {
+		return &downstreamError{err: err}
+	}
+	return nil
+}
+
+// dataStreamFinishReason 将OpenAI的结束原因转换为数据流协议的取值
+func dataStreamFinishReason(reason string) string {
+	switch reason {
+	case types.FinishReasonStop:
+		return "stop"
+	case types.FinishReasonLength:
+		return "length"
+	case types.FinishReasonContentFilter:
+		return "content-filter"
+	case types.FinishReasonToolCalls, types.FinishReasonFunctionCall:
+		return "tool-calls"
+	case "":
+		return "unknown"
+	default:
+		return "other"
+	}
+}
@@ -0,0 +1,154 @@
+package stream
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hewenyu/newapi-go/types"
+)
+
+// chunkEvent 构造聊天块事件
+func chunkEvent(chunk types.ChatCompletionChunk) *types.StreamEvent {
+	data, _ := json.Marshal(chunk)
+	return &types.StreamEvent{Type: types.StreamEventTypeData, Data: data}
+}
+
+// chatSource 构造包含给定块并正常结束的上游流
+func chatSource(chunks ...types.ChatCompletionChunk) *chanStream {
+	source := newChanStream(len(chunks))
+	for _, chunk := range chunks {
+		source.events <- chunkEvent(chunk)
+	}
+	close(source.events)
+	return source
+}
+
+// textChunk 构造文本块
+func textChunk(content, finishReason string) types.ChatCompletionChunk {
+	return types.ChatCompletionChunk{
+		ID: "chatcmpl-1",
+		Choices: []types.ChatCompletionChunkChoice{{
+			Delta:        types.ChatMessage{Content: content},
+			FinishReason: finishReason,
+		}},
+	}
+}
+
+func TestCopyText(t *testing.T) {
+	var out strings.Builder
+	n, err := CopyText(&out, chatSource(textChunk("Hel", ""), textChunk("lo", types.FinishReasonStop)))
+	if err != nil {
+		t.Fatalf("CopyText failed: %v", err)
+	}
+	if out.String() != "Hello" || n != 5 {
+		t.Errorf("CopyText wrote %q (%d bytes)", out.String(), n)
+	}
+}
+
+func TestWriteSSEAndNDJSON(t *testing.T) {
+	var sse strings.Builder
+	if err := WriteSSE(&sse, chatSource(textChunk("Hi", types.FinishReasonStop))); err != nil {
+		t.Fatalf("WriteSSE failed: %v", err)
+	}
+	if !strings.HasPrefix(sse.String(), `data: {"id":"chatcmpl-1"`) || !strings.HasSuffix(sse.String(), "\n\ndata: [DONE]\n\n") {
+		t.Errorf("unexpected SSE output:\n%s", sse.String())
+	}
+
+	var ndjson strings.Builder
+	if err := WriteNDJSON(&ndjson, chatSource(textChunk("a", ""), textChunk("b", types.FinishReasonStop))); err != nil {
+		t.Fatalf("WriteNDJSON failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(ndjson.String(), "\n"), "\n")
+	if len(lines) != 2 || !json.Valid([]byte(lines[0])) || !json.Valid([]byte(lines[1])) {
+		t.Errorf("unexpected NDJSON output:\n%s", ndjson.String())
+	}
+}
+
+func TestWriteSSEReportsUpstreamError(t *testing.T) {
+	source := newChanStream(1)
+	source.events <- &types.StreamEvent{Type: types.StreamEventTypeData, Data: []byte(`{"id":"x","choices":[]}`)}
+	source.err = types.NewAPIError(types.ErrTypeAPIError, "", "upstream down", 0)
+	close(source.events)
+
+	var sse strings.Builder
+	if err := WriteSSE(&sse, source); err == nil {
+		t.Fatal("expected upstream error")
+	}
+	if !strings.Contains(sse.String(), `data: {"error":{"type":"api_error","code":"","message":"upstream down"}}`) ||
+		strings.Contains(sse.String(), "[DONE]") {
+		t.Errorf("unexpected SSE output:\n%s", sse.String())
+	}
+}
+
+func TestWriteDataStream(t *testing.T) {
+	index := 0
+	toolStart := types.ChatCompletionChunk{ID: "chatcmpl-1", Choices: []types.ChatCompletionChunkChoice{{Delta: types.ChatMessage{
+		ToolCalls: []types.ToolCall{{Index: &index, ID: "call_1", Type: types.ToolCallTypeFunction, Function: types.FunctionCall{Name: "lookup", Arguments: `{"q":`}}},
+	}}}}
+	toolArgs := types.ChatCompletionChunk{ID: "chatcmpl-1", Choices: []types.ChatCompletionChunkChoice{{Delta: types.ChatMessage{
+		ToolCalls: []types.ToolCall{{Index: &index, Function: types.FunctionCall{Arguments: `"go"}`}}},
+	}, FinishReason: types.FinishReasonToolCalls}}, Usage: &types.Usage{PromptTokens: 3, CompletionTokens: 4}}
+
+	var out strings.Builder
+	if err := WriteDataStream(&out, chatSource(textChunk("Hi", ""), toolStart, toolArgs)); err != nil {
+		t.Fatalf("WriteDataStream failed: %v", err)
+	}
+
+	want := []string{
+		`f:{"messageId":"chatcmpl-1"}`,
+		`0:"Hi"`,
+		`b:{"toolCallId":"call_1","toolName":"lookup"}`,
+		`c:{"argsTextDelta":"{\"q\":","toolCallId":"call_1"}`,
+		`c:{"argsTextDelta":"\"go\"}","toolCallId":"call_1"}`,
+		`9:{"args":{"q":"go"},"toolCallId":"call_1","toolName":"lookup"}`,
+		`e:{"finishReason":"tool-calls","isContinued":false,"usage":{"completionTokens":4,"promptTokens":3}}`,
+		`d:{"finishReason":"tool-calls","usage":{"completionTokens":4,"promptTokens":3}}`,
+	}
+	got := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("data stream output:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestServeSSEClientDisconnectClosesUpstream(t *testing.T) {
+	source := newChanStream(1)
+	source.events <- chunkEvent(textChunk("first", ""))
+
+	served := make(chan error, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served <- ServeSSE(w, r, source)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != ContentTypeSSE {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if err != nil || !strings.Contains(line, "first") {
+		t.Fatalf("first event = %q, %v", line, err)
+	}
+
+	cancel()
+	resp.Body.Close()
+
+	select {
+	case <-served:
+	case <-time.After(2 * time.Second):
+		t.Fatal("handler did not return after client disconnect")
+	}
+	if !source.isClosed() {
+		t.Error("upstream stream should be closed after client disconnect")
+	}
+}
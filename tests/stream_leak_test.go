@@ -0,0 +1,384 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hewenyu/newapi-go/internal/transport"
+	"github.com/hewenyu/newapi-go/internal/utils"
+	"github.com/hewenyu/newapi-go/services/chat"
+	"github.com/hewenyu/newapi-go/stream"
+	"github.com/hewenyu/newapi-go/types"
+)
+
+// 流式读取的协程泄漏测试，不需要真实API，建议配合竞态检测运行：go test -race ./tests -run TestStreamLeak
+
+const modulePath = "github.com/hewenyu/newapi-go/"
+
+// checkLeaks 在测试结束时检查由SDK启动的协程是否全部退出。
+// 需要在创建测试服务器之后调用，以便在服务器释放连接之前检查
+func checkLeaks(t *testing.T) {
+	t.Cleanup(func() {
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			leaked := sdkGoroutines()
+			if len(leaked) == 0 {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Errorf("%d goroutines leaked:\n\n%s", len(leaked), strings.Join(leaked, "\n\n"))
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	})
+}
+
+// sdkGoroutines 获取由SDK代码（测试代码除外）创建的协程调用栈
+func sdkGoroutines() []string {
+	buf := make([]byte, 1<<16)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+
+	var leaked []string
+	for _, stack := range strings.Split(string(buf), "\n\n") {
+		index := strings.Index(stack, "\ncreated by ")
+		if index < 0 {
+			continue
+		}
+		creator := stack[index:]
+		if strings.Contains(creator, modulePath) && !strings.Contains(creator, modulePath+"tests.") {
+			leaked = append(leaked, stack)
+		}
+	}
+	return leaked
+}
+
+// newHoldingServer 创建聊天流服务，发送count个块后保持连接，直到客户端断开或测试结束
+func newHoldingServer(t *testing.T, count int) *httptest.Server {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 0; i < count; i++ {
+			fmt.Fprintf(w, "data: {\"id\":\"chatcmpl-leak\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"%d \"}}]}\n\n", i)
+		}
+		w.(http.Flusher).Flush()
+
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(release) })
+	return server
+}
+
+// openChatStream 打开到测试服务器的聊天流
+func openChatStream(t *testing.T, ctx context.Context, server *httptest.Server) *chat.ChatStreamProcessor {
+	service := chat.NewChatService(transport.NewHTTPClient(server.URL, "test-key"), utils.GetLogger())
+	resp, err := service.CreateChatCompletionStream(ctx, []types.ChatMessage{types.NewUserMessage("hi")})
+	require.NoError(t, err)
+
+	processor, ok := resp.(*chat.ChatStreamProcessor)
+	require.True(t, ok, "stream is %T", resp)
+	return processor
+}
+
+// within 在限定时间内执行fn，超时则测试失败
+func within(t *testing.T, timeout time.Duration, name string, fn func()) {
+	t.Helper()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn()
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		t.Fatalf("%s did not return within %v", name, timeout)
+	}
+}
+
+func TestStreamLeakChatReaderCloseWhileReading(t *testing.T) {
+	server := newHoldingServer(t, 3)
+	checkLeaks(t)
+
+	reader := chat.NewChatStreamReader(openChatStream(t, context.Background(), server), utils.GetLogger())
+
+	result := make(chan error, 1)
+	go func() {
+		for {
+			if _, err := reader.Read(); err != nil {
+				result <- err
+				return
+			}
+		}
+	}()
+
+	// 读完已发送的块后阻塞在读取上
+	time.Sleep(50 * time.Millisecond)
+	within(t, 2*time.Second, "Close", func() { assert.NoError(t, reader.Close()) })
+
+	select {
+	case err := <-result:
+		assert.ErrorIs(t, err, io.EOF)
+	case <-time.After(2 * time.Second):
+		t.Fatal("Read did not return after Close")
+	}
+
+	// 关闭后继续读取和重复关闭不会panic
+	_, err := reader.Read()
+	assert.ErrorIs(t, err, io.EOF)
+	assert.NoError(t, reader.Close())
+	assert.True(t, reader.Done())
+}
+
+func TestStreamLeakChatReaderAbandoned(t *testing.T) {
+	// 超过读取器缓冲区的块数，使读取协程阻塞在写入缓冲区上
+	server := newHoldingServer(t, 300)
+	checkLeaks(t)
+
+	reader := chat.NewChatStreamReader(openChatStream(t, context.Background(), server), utils.GetLogger())
+	time.Sleep(50 * time.Millisecond)
+
+	within(t, 2*time.Second, "Close", func() { assert.NoError(t, reader.Close()) })
+}
+
+func TestStreamLeakChatReaderContextCancel(t *testing.T) {
+	server := newHoldingServer(t, 300)
+	checkLeaks(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	reader := chat.NewChatStreamReader(openChatStream(t, ctx, server), utils.GetLogger())
+
+	_, err := reader.Read()
+	require.NoError(t, err)
+	cancel()
+
+	// 未调用Close，读取协程也应随上下文取消退出
+	within(t, 2*time.Second, "Read", func() {
+		for {
+			if _, err = reader.Read(); err != nil {
+				break
+			}
+		}
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestStreamLeakProcessStreamCancel(t *testing.T) {
+	server := newHoldingServer(t, 1)
+	checkLeaks(t)
+
+	// 流使用独立的上下文，只有ProcessStream的上下文被取消
+	stream := openChatStream(t, context.Background(), server)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var err error
+	within(t, 2*time.Second, "ProcessStream", func() {
+		err = chat.ProcessStream(ctx, stream, func(*types.ChatCompletionChunk) error {
+			cancel()
+			return nil
+		})
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestStreamLeakTypedStreamBreak(t *testing.T) {
+	server := newHoldingServer(t, 300)
+	checkLeaks(t)
+
+	stream := chat.NewStream(openChatStream(t, context.Background(), server))
+	within(t, 2*time.Second, "Chunks", func() {
+		for _, err := range stream.Chunks() {
+			assert.NoError(t, err)
+			break
+		}
+	})
+	assert.NotNil(t, stream.Accumulated())
+}
+
+func TestStreamLeakMuxContextCancel(t *testing.T) {
+	server := newHoldingServer(t, 300)
+	checkLeaks(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	mux, err := stream.NewMux(openChatStream(t, ctx, server), stream.WithBufferSize(1))
+	require.NoError(t, err)
+
+	// 订阅者不读取也不关闭，读取协程阻塞在背压上
+	_, err = mux.Subscribe()
+	require.NoError(t, err)
+	mux.Start()
+	time.Sleep(50 * time.Millisecond)
+
+	cancel()
+	within(t, 2*time.Second, "mux shutdown", func() {
+		for !mux.Done() {
+			time.Sleep(5 * time.Millisecond)
+		}
+	})
+	assert.ErrorIs(t, mux.Err(), context.Canceled)
+}
+
+func TestStreamLeakStreamReader(t *testing.T) {
+	checkLeaks(t)
+
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	reader := types.NewStreamReader(pr, context.Background())
+
+	go fmt.Fprint(pw, "data: {\"n\":1}\n\n")
+	event, err := reader.Next()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"n":1}`, string(event.Data))
+
+	result := make(chan error, 1)
+	go func() {
+		_, err := reader.Next()
+		result <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	require.NoError(t, reader.Close())
+
+	select {
+	case err := <-result:
+		assert.ErrorIs(t, err, io.EOF)
+	case <-time.After(2 * time.Second):
+		t.Fatal("Next did not return after Close")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	pr, pw2 := io.Pipe()
+	defer pw2.Close()
+	reader = types.NewStreamReader(pr, ctx)
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	within(t, 2*time.Second, "Next", func() { _, err = reader.Next() })
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestStreamLeakStreamProcessor(t *testing.T) {
+	checkLeaks(t)
+
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	processor := types.NewStreamProcessor(types.NewStreamReader(pr, context.Background()))
+
+	received := make(chan struct{}, 1)
+	processor.AddHandler(types.StreamEventTypeData, func(*types.StreamEvent) error {
+		received <- struct{}{}
+		return nil
+	})
+	require.NoError(t, processor.Start())
+
+	go fmt.Fprint(pw, "data: {\"n\":1}\n\n")
+	select {
+	case <-received:
+	case <-time.After(2 * time.Second):
+		t.Fatal("handler was not called")
+	}
+
+	// 处理协程阻塞在读取上，Stop关闭流后退出
+	require.NoError(t, processor.Stop())
+	within(t, 2*time.Second, "Wait", processor.Wait)
+}
+
+func TestStreamLeakStreamProcessorReadError(t *testing.T) {
+	checkLeaks(t)
+
+	processor := types.NewStreamProcessor(types.NewStreamReader(iotest.ErrReader(errors.New("boom")), context.Background()))
+
+	var errorEvents atomic.Int32
+	processor.AddHandler(types.StreamEventTypeError, func(*types.StreamEvent) error {
+		errorEvents.Add(1)
+		return nil
+	})
+	require.NoError(t, processor.Start())
+
+	// 读取出错后退出，而不是反复重试同一个出错的流
+	within(t, 2*time.Second, "Wait", processor.Wait)
+	assert.Equal(t, int32(1), errorEvents.Load())
+}
+
+// closeCounter 统计Close调用次数的响应体
+type closeCounter struct {
+	io.Reader
+	closes atomic.Int32
+}
+
+func (c *closeCounter) Close() error {
+	c.closes.Add(1)
+	return nil
+}
+
+func TestStreamLeakFinishedStreamsReleaseContext(t *testing.T) {
+	// 同一个长期存在的上下文上打开大量流，结束或关闭的流不能继续挂在上下文上
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const count = 1000
+	jsonBodies := make([]*closeCounter, count)
+	rawBodies := make([]*closeCounter, count)
+	for i := 0; i < count; i++ {
+		jsonBodies[i] = &closeCounter{Reader: strings.NewReader("data: {\"n\":1}\n\ndata: [DONE]\n\n")}
+		reader := transport.NewJSONStreamReader(ctx, jsonBodies[i])
+		for {
+			if _, err := reader.Read(); err != nil {
+				require.ErrorIs(t, err, io.EOF)
+				break
+			}
+		}
+		// 一半的流读到结束后不调用Close
+		if i%2 == 0 {
+			require.NoError(t, reader.Close())
+		}
+
+		rawBodies[i] = &closeCounter{Reader: strings.NewReader("data: {\"n\":1}\n\n")}
+		raw := types.NewStreamReader(rawBodies[i], ctx)
+		for {
+			if _, err := raw.Next(); err != nil {
+				require.ErrorIs(t, err, io.EOF)
+				break
+			}
+		}
+		if i%2 == 0 {
+			require.NoError(t, raw.Close())
+		}
+	}
+
+	// 取消上下文时不再触发已结束流的回调
+	cancel()
+	time.Sleep(50 * time.Millisecond)
+
+	for i := 0; i < count; i++ {
+		want := int32(0)
+		if i%2 == 0 {
+			want = 1
+		}
+		require.Equal(t, want, jsonBodies[i].closes.Load(), "JSONStreamReader %d", i)
+		require.Equal(t, int32(1), rawBodies[i].closes.Load(), "StreamReader %d", i)
+	}
+}
@@ -0,0 +1,165 @@
+package tokenizer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// Encoding BPE编码，基于rank表进行字节对合并
+type Encoding struct {
+	name     string
+	match    matchFunc
+	ranks    map[string]int
+	decoder  map[int][]byte
+	special  map[string]int
+	maxToken int
+}
+
+// Name 获取编码名称
+func (e *Encoding) Name() string {
+	return e.name
+}
+
+// Exact 是否为精确计数
+func (e *Encoding) Exact() bool {
+	return true
+}
+
+// Count 计算文本的Token数量
+func (e *Encoding) Count(text string) int {
+	count := 0
+	for _, piece := range splitText(text, e.match) {
+		if _, ok := e.ranks[piece]; ok {
+			count++
+			continue
+		}
+		count += len(e.bytePairMerge([]byte(piece)))
+	}
+	return count
+}
+
+// Encode 将文本编码为Token序列，特殊Token按普通文本处理
+func (e *Encoding) Encode(text string) []int {
+	var tokens []int
+	for _, piece := range splitText(text, e.match) {
+		if rank, ok := e.ranks[piece]; ok {
+			tokens = append(tokens, rank)
+			continue
+		}
+		tokens = append(tokens, e.bytePairMerge([]byte(piece))...)
+	}
+	return tokens
+}
+
+// Decode 将Token序列解码为文本
+func (e *Encoding) Decode(tokens []int) (string, error) {
+	var buf bytes.Buffer
+	for _, token := range tokens {
+		data, ok := e.decoder[token]
+		if !ok {
+			return "", fmt.Errorf("unknown token: %d", token)
+		}
+		buf.Write(data)
+	}
+	return buf.String(), nil
+}
+
+// MaxTokenValue 获取最大Token值
+func (e *Encoding) MaxTokenValue() int {
+	return e.maxToken
+}
+
+// bytePairMerge 对单个预分词片段执行字节对合并，返回Token序列
+func (e *Encoding) bytePairMerge(piece []byte) []int {
+	// parts保存每个片段的起始位置，最后一个元素为片段结束位置
+	parts := make([]int, len(piece)+1)
+	for i := range parts {
+		parts[i] = i
+	}
+
+	for len(parts) > 2 {
+		minRank, minIndex := math.MaxInt, -1
+		for i := 0; i+2 < len(parts); i++ {
+			if rank, ok := e.ranks[string(piece[parts[i]:parts[i+2]])]; ok && rank < minRank {
+				minRank, minIndex = rank, i
+			}
+		}
+		if minIndex < 0 {
+			break
+		}
+		parts = append(parts[:minIndex+1], parts[minIndex+2:]...)
+	}
+
+	tokens := make([]int, 0, len(parts)-1)
+	for i := 0; i+1 < len(parts); i++ {
+		tokens = append(tokens, e.ranks[string(piece[parts[i]:parts[i+1]])])
+	}
+	return tokens
+}
+
+// parseRanks 解析tiktoken格式的rank文件，每行为 "<base64 token> <rank>"
+func parseRanks(r io.Reader) (map[string]int, error) {
+	ranks := make(map[string]int)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		fields := bytes.Fields(scanner.Bytes())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid rank line %d", line)
+		}
+
+		token, err := base64.StdEncoding.DecodeString(string(fields[0]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid token at line %d: %w", line, err)
+		}
+		rank, err := strconv.Atoi(string(fields[1]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid rank at line %d: %w", line, err)
+		}
+		ranks[string(token)] = rank
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read ranks: %w", err)
+	}
+	if len(ranks) == 0 {
+		return nil, fmt.Errorf("rank file is empty")
+	}
+	return ranks, nil
+}
+
+// newEncoding 使用rank表和编码规范创建编码
+func newEncoding(spec encodingSpec, ranks map[string]int) *Encoding {
+	enc := &Encoding{
+		name:    spec.name,
+		match:   spec.match,
+		ranks:   ranks,
+		decoder: make(map[int][]byte, len(ranks)+len(spec.special)),
+		special: spec.special,
+	}
+
+	for token, rank := range ranks {
+		enc.decoder[rank] = []byte(token)
+		if rank > enc.maxToken {
+			enc.maxToken = rank
+		}
+	}
+	for token, rank := range spec.special {
+		enc.decoder[rank] = []byte(token)
+		if rank > enc.maxToken {
+			enc.maxToken = rank
+		}
+	}
+	return enc
+}
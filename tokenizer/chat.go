@@ -0,0 +1,193 @@
+package tokenizer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	_ "image/gif"  // 注册GIF解码器以读取图像尺寸
+	_ "image/jpeg" // 注册JPEG解码器以读取图像尺寸
+	_ "image/png"  // 注册PNG解码器以读取图像尺寸
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/hewenyu/newapi-go/types"
+)
+
+// 聊天格式开销常量，参考OpenAI的聊天格式计数规则
+const (
+	tokensPerMessage  = 3
+	tokensPerName     = 1
+	tokensPerReply    = 3
+	tokensPerToolCall = 3
+)
+
+// 工具定义开销常量
+const (
+	toolFuncInit = 7
+	toolPropInit = 3
+	toolPropKey  = 3
+	toolEnumInit = -3
+	toolEnumItem = 3
+	toolFuncEnd  = 12
+)
+
+// 图像Token常量
+const (
+	imageBaseTokens  = 85
+	imageTileTokens  = 170
+	imageTileSize    = 512
+	imageMaxSide     = 2048
+	imageShortSide   = 768
+	imageDefaultSide = 1024
+)
+
+// CountMessages 计算消息列表的Token数量，包含每条消息的格式开销和回复引导开销
+func CountMessages(model string, messages []types.ChatMessage) int {
+	tok := ForModel(model)
+	total := tokensPerReply
+	for i := range messages {
+		total += countMessage(tok, &messages[i])
+	}
+	return total
+}
+
+// CountMessage 计算单条消息的Token数量，不包含回复引导开销
+func CountMessage(model string, message types.ChatMessage) int {
+	return countMessage(ForModel(model), &message)
+}
+
+// CountText 计算文本在指定模型下的Token数量
+func CountText(model, text string) int {
+	return ForModel(model).Count(text)
+}
+
+// countMessage 计算单条消息的Token数量
+func countMessage(tok Tokenizer, message *types.ChatMessage) int {
+	total := tokensPerMessage + tok.Count(message.Role)
+	if message.Name != "" {
+		total += tokensPerName + tok.Count(message.Name)
+	}
+
+	for _, part := range message.GetContentParts() {
+		total += countContentPart(tok, part)
+	}
+
+	for _, call := range message.ToolCalls {
+		total += tokensPerToolCall + tok.Count(call.Function.Name) + tok.Count(call.Function.Arguments)
+	}
+	if message.FunctionCall != nil {
+		total += tokensPerToolCall + tok.Count(message.FunctionCall.Name) + tok.Count(message.FunctionCall.Arguments)
+	}
+	return total
+}
+
+// countContentPart 计算内容部分的Token数量，音频、文件和视频无法在本地计算，按0计
+func countContentPart(tok Tokenizer, part types.MessageContent) int {
+	switch part.Type {
+	case types.ChatMessageTypeText:
+		return tok.Count(part.Text)
+	case types.ChatMessageTypeImageURL:
+		if part.ImageURL == nil {
+			return 0
+		}
+		width, height := imageDimensions(part.ImageURL.URL)
+		return ImageTokens(width, height, part.ImageURL.Detail)
+	default:
+		return 0
+	}
+}
+
+// CountTools 计算工具定义的Token数量
+func CountTools(model string, tools []types.Tool) int {
+	if len(tools) == 0 {
+		return 0
+	}
+
+	tok := ForModel(model)
+	total := 0
+	for _, tool := range tools {
+		total += countFunction(tok, tool.Function)
+	}
+	return total + toolFuncEnd
+}
+
+// countFunction 计算单个函数定义的Token数量
+func countFunction(tok Tokenizer, function types.ChatFunction) int {
+	total := toolFuncInit + tok.Count(function.Name+":"+strings.TrimSuffix(function.Description, "."))
+
+	properties, _ := function.Parameters["properties"].(map[string]interface{})
+	if len(properties) == 0 {
+		return total
+	}
+
+	total += toolPropInit
+	keys := make([]string, 0, len(properties))
+	for key := range properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		total += toolPropKey
+		property, _ := properties[key].(map[string]interface{})
+		if enum, ok := property["enum"].([]interface{}); ok {
+			total += toolEnumInit
+			for _, item := range enum {
+				total += toolEnumItem + tok.Count(fmt.Sprint(item))
+			}
+		}
+
+		propType, _ := property["type"].(string)
+		propDesc, _ := property["description"].(string)
+		total += tok.Count(key + ":" + propType + ":" + strings.TrimSuffix(propDesc, "."))
+	}
+	return total
+}
+
+// ImageTokens 计算图像的Token数量。low细节固定为基础Token数，
+// 其余情况先缩放到2048x2048以内、短边不超过768，再按512像素切片计算。
+// 尺寸未知（为0）时按1024x1024计算
+func ImageTokens(width, height int, detail string) int {
+	if detail == types.ImageDetailLow {
+		return imageBaseTokens
+	}
+	if width <= 0 || height <= 0 {
+		width, height = imageDefaultSide, imageDefaultSide
+	}
+
+	w, h := float64(width), float64(height)
+	if longest := math.Max(w, h); longest > imageMaxSide {
+		scale := imageMaxSide / longest
+		w, h = w*scale, h*scale
+	}
+	if shortest := math.Min(w, h); shortest > imageShortSide {
+		scale := imageShortSide / shortest
+		w, h = w*scale, h*scale
+	}
+
+	tiles := int(math.Ceil(w/imageTileSize)) * int(math.Ceil(h/imageTileSize))
+	return imageBaseTokens + imageTileTokens*tiles
+}
+
+// imageDimensions 从base64数据URI中读取图像尺寸，无法读取时返回0
+func imageDimensions(url string) (int, int) {
+	if !strings.HasPrefix(url, "data:") {
+		return 0, 0
+	}
+	header, data, ok := strings.Cut(strings.TrimPrefix(url, "data:"), ",")
+	if !ok || !strings.HasSuffix(header, ";base64") {
+		return 0, 0
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return 0, 0
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(raw))
+	if err != nil {
+		return 0, 0
+	}
+	return config.Width, config.Height
+}
@@ -0,0 +1,7 @@
+// Package tokenizer provides a pure-Go byte-pair encoding tokenizer compatible
+// with the cl100k_base and o200k_base encodings, together with helpers that
+// count tokens for chat messages, tool definitions and images.
+//
+// The BPE rank files are embedded from the ranks directory. When a rank file is
+// not available, counting falls back to a CJK-aware estimator.
+package tokenizer
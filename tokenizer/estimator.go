@@ -0,0 +1,43 @@
+package tokenizer
+
+// estimator 基于预分词规则的Token估算器，在rank文件不可用时使用。
+// ASCII字符按约4字节一个Token计算，非ASCII字符（如中日韩文字）按每字符一个Token计算
+type estimator struct {
+	spec encodingSpec
+}
+
+// Name 获取编码名称
+func (e *estimator) Name() string {
+	return e.spec.name
+}
+
+// Exact 估算器的计数不精确
+func (e *estimator) Exact() bool {
+	return false
+}
+
+// Count 估算文本的Token数量
+func (e *estimator) Count(text string) int {
+	count := 0
+	for _, piece := range splitText(text, e.spec.match) {
+		count += estimatePiece(piece)
+	}
+	return count
+}
+
+// estimatePiece 估算单个预分词片段的Token数量
+func estimatePiece(piece string) int {
+	asciiBytes, tokens := 0, 0
+	for _, r := range piece {
+		if r < 0x80 {
+			asciiBytes++
+		} else {
+			tokens++
+		}
+	}
+	tokens += (asciiBytes + 3) / 4
+	if tokens == 0 && piece != "" {
+		tokens = 1
+	}
+	return tokens
+}
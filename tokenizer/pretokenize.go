@@ -0,0 +1,203 @@
+package tokenizer
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// matchFunc 预分词匹配函数，返回从位置i开始的片段结束位置
+type matchFunc func(r []rune, i int) int
+
+// contractions 英文缩写后缀（大小写不敏感）
+var contractions = []string{"s", "t", "re", "ve", "m", "ll", "d"}
+
+// splitText 按匹配函数将文本切分为预分词片段，片段保留原始字节
+func splitText(text string, match matchFunc) []string {
+	runes := make([]rune, 0, len(text))
+	offsets := make([]int, 0, len(text)+1)
+	for offset, r := range text {
+		runes = append(runes, r)
+		offsets = append(offsets, offset)
+	}
+	offsets = append(offsets, len(text))
+
+	pieces := make([]string, 0, len(runes)/3+1)
+	for i := 0; i < len(runes); {
+		end := match(runes, i)
+		if end <= i {
+			end = i + 1
+		}
+		pieces = append(pieces, text[offsets[i]:offsets[end]])
+		i = end
+	}
+	return pieces
+}
+
+// matchCl100k 实现cl100k_base的预分词规则：
+// (?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+
+func matchCl100k(r []rune, i int) int {
+	if end := matchContraction(r, i); end > 0 {
+		return end
+	}
+	if isLetter(r[i]) {
+		return runWhile(r, i, isLetter)
+	}
+	if isPrefix(r[i]) && i+1 < len(r) && isLetter(r[i+1]) {
+		return runWhile(r, i+1, isLetter)
+	}
+	if isNumber(r[i]) {
+		return runUpTo(r, i, isNumber, 3)
+	}
+	if end := matchPunct(r, i, isNewline); end > 0 {
+		return end
+	}
+	return matchWhitespace(r, i)
+}
+
+// matchO200k 实现o200k_base的预分词规则：
+// [^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+(?i:'s|'t|'re|'ve|'m|'ll|'d)?
+// |[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*(?i:'s|'t|'re|'ve|'m|'ll|'d)?
+// |\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n/]*|\s*[\r\n]+|\s+(?!\S)|\s+
+func matchO200k(r []rune, i int) int {
+	starts := make([]int, 0, 2)
+	if isPrefix(r[i]) {
+		starts = append(starts, i+1)
+	}
+	starts = append(starts, i)
+
+	for _, s := range starts {
+		if end := matchUpperLower(r, s); end > 0 {
+			return matchContractionOpt(r, end)
+		}
+	}
+	for _, s := range starts {
+		if end := matchUpperPlus(r, s); end > 0 {
+			return matchContractionOpt(r, end)
+		}
+	}
+
+	if isNumber(r[i]) {
+		return runUpTo(r, i, isNumber, 3)
+	}
+	if end := matchPunct(r, i, isNewlineOrSlash); end > 0 {
+		return end
+	}
+	return matchWhitespace(r, i)
+}
+
+// matchUpperLower 匹配 [Upper]*[Lower]+，按正则回溯语义处理两个字符集的重叠部分
+func matchUpperLower(r []rune, s int) int {
+	e := runWhile(r, s, isUpper)
+	if e < len(r) && isLower(r[e]) {
+		return runWhile(r, e, isLower)
+	}
+	for p := e - 1; p >= s; p-- {
+		if isLower(r[p]) {
+			return p + 1
+		}
+	}
+	return -1
+}
+
+// matchUpperPlus 匹配 [Upper]+[Lower]*
+func matchUpperPlus(r []rune, s int) int {
+	e := runWhile(r, s, isUpper)
+	if e == s {
+		return -1
+	}
+	return runWhile(r, e, isLower)
+}
+
+// matchContraction 匹配英文缩写后缀，未匹配返回-1
+func matchContraction(r []rune, i int) int {
+	if i >= len(r) || r[i] != '\'' {
+		return -1
+	}
+	for _, c := range contractions {
+		n := utf8.RuneCountInString(c)
+		if i+1+n <= len(r) && strings.EqualFold(string(r[i+1:i+1+n]), c) {
+			return i + 1 + n
+		}
+	}
+	return -1
+}
+
+// matchContractionOpt 匹配可选的英文缩写后缀
+func matchContractionOpt(r []rune, end int) int {
+	if c := matchContraction(r, end); c > 0 {
+		return c
+	}
+	return end
+}
+
+// matchPunct 匹配 " ?[^\s\p{L}\p{N}]+" 以及随后的尾随字符
+func matchPunct(r []rune, i int, trailing func(rune) bool) int {
+	start := -1
+	if r[i] == ' ' && i+1 < len(r) && isPunct(r[i+1]) {
+		start = i + 1
+	} else if isPunct(r[i]) {
+		start = i
+	}
+	if start < 0 {
+		return -1
+	}
+	return runWhile(r, runWhile(r, start, isPunct), trailing)
+}
+
+// matchWhitespace 匹配 \s*[\r\n]+|\s+(?!\S)|\s+
+func matchWhitespace(r []rune, i int) int {
+	if !isSpace(r[i]) {
+		return i + 1
+	}
+	end := runWhile(r, i, isSpace)
+	for k := end - 1; k >= i; k-- {
+		if isNewline(r[k]) {
+			return k + 1
+		}
+	}
+	if end == len(r) || end-1 == i {
+		return end
+	}
+	return end - 1
+}
+
+// runWhile 返回从i开始满足条件的最长连续区间的结束位置
+func runWhile(r []rune, i int, pred func(rune) bool) int {
+	for i < len(r) && pred(r[i]) {
+		i++
+	}
+	return i
+}
+
+// runUpTo 与runWhile相同，但最多匹配max个字符
+func runUpTo(r []rune, i int, pred func(rune) bool, max int) int {
+	end := i
+	for end < len(r) && end-i < max && pred(r[end]) {
+		end++
+	}
+	return end
+}
+
+func isLetter(r rune) bool  { return unicode.IsLetter(r) }
+func isNumber(r rune) bool  { return unicode.IsNumber(r) }
+func isSpace(r rune) bool   { return unicode.IsSpace(r) }
+func isNewline(r rune) bool { return r == '\r' || r == '\n' }
+
+func isNewlineOrSlash(r rune) bool { return isNewline(r) || r == '/' }
+
+// isPrefix 对应 [^\r\n\p{L}\p{N}]
+func isPrefix(r rune) bool { return !isNewline(r) && !isLetter(r) && !isNumber(r) }
+
+// isPunct 对应 [^\s\p{L}\p{N}]
+func isPunct(r rune) bool { return !isSpace(r) && !isLetter(r) && !isNumber(r) }
+
+// isUpper 对应 [\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]
+func isUpper(r rune) bool {
+	return unicode.In(r, unicode.Lu, unicode.Lt, unicode.Lm, unicode.Lo, unicode.M)
+}
+
+// isLower 对应 [\p{Ll}\p{Lm}\p{Lo}\p{M}]
+func isLower(r rune) bool {
+	return unicode.In(r, unicode.Ll, unicode.Lm, unicode.Lo, unicode.M)
+}
@@ -0,0 +1,222 @@
+package tokenizer
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"sync"
+)
+
+// 编码名称常量
+const (
+	Cl100kBase = "cl100k_base"
+	O200kBase  = "o200k_base"
+)
+
+// DefaultEncoding 未知模型使用的默认编码
+const DefaultEncoding = Cl100kBase
+
+// ErrRanksUnavailable rank文件不可用
+var ErrRanksUnavailable = errors.New("tokenizer: rank file not available")
+
+//go:embed ranks
+var rankFiles embed.FS
+
+// Tokenizer Token计数接口
+type Tokenizer interface {
+	// Name 编码名称
+	Name() string
+	// Count 计算文本的Token数量
+	Count(text string) int
+	// Exact 计数是否精确，估算器返回false
+	Exact() bool
+}
+
+// encodingSpec 编码规范
+type encodingSpec struct {
+	name    string
+	match   matchFunc
+	special map[string]int
+}
+
+var specs = map[string]encodingSpec{
+	Cl100kBase: {
+		name:  Cl100kBase,
+		match: matchCl100k,
+		special: map[string]int{
+			"<|endoftext|>":   100257,
+			"<|fim_prefix|>":  100258,
+			"<|fim_middle|>":  100259,
+			"<|fim_suffix|>":  100260,
+			"<|endofprompt|>": 100276,
+		},
+	},
+	O200kBase: {
+		name:  O200kBase,
+		match: matchO200k,
+		special: map[string]int{
+			"<|endoftext|>":   199999,
+			"<|endofprompt|>": 200018,
+		},
+	},
+}
+
+// modelEncoding 模型前缀到编码的映射
+type modelEncoding struct {
+	prefix   string
+	encoding string
+}
+
+var (
+	registryMu sync.RWMutex
+	encodings  = make(map[string]*Encoding)
+	loadErrors = make(map[string]error)
+
+	// modelEncodings 按顺序匹配，更具体的前缀需要排在前面
+	modelEncodings = []modelEncoding{
+		{"gpt-4o", O200kBase},
+		{"chatgpt-4o", O200kBase},
+		{"gpt-4.1", O200kBase},
+		{"gpt-4.5", O200kBase},
+		{"gpt-5", O200kBase},
+		{"o1", O200kBase},
+		{"o3", O200kBase},
+		{"o4", O200kBase},
+		{"gpt-4", Cl100kBase},
+		{"gpt-3.5", Cl100kBase},
+		{"gpt-35", Cl100kBase},
+		{"text-embedding-", Cl100kBase},
+	}
+)
+
+// GetEncoding 获取编码，首次使用时从嵌入的rank文件加载
+func GetEncoding(name string) (*Encoding, error) {
+	registryMu.RLock()
+	enc, ok := encodings[name]
+	err := loadErrors[name]
+	registryMu.RUnlock()
+	if ok {
+		return enc, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if enc, ok := encodings[name]; ok {
+		return enc, nil
+	}
+	enc, err = loadEmbedded(name)
+	if err != nil {
+		loadErrors[name] = err
+		return nil, err
+	}
+	encodings[name] = enc
+	return enc, nil
+}
+
+// loadEmbedded 从嵌入文件系统加载编码
+func loadEmbedded(name string) (*Encoding, error) {
+	if _, ok := specs[name]; !ok {
+		return nil, fmt.Errorf("tokenizer: unknown encoding %q", name)
+	}
+
+	file, err := rankFiles.Open("ranks/" + name + ".tiktoken")
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %s", ErrRanksUnavailable, name)
+		}
+		return nil, fmt.Errorf("tokenizer: failed to open ranks for %s: %w", name, err)
+	}
+	defer file.Close()
+
+	return LoadEncoding(name, file)
+}
+
+// LoadEncoding 从tiktoken格式的rank数据创建编码，name必须是已知的编码名称
+func LoadEncoding(name string, r io.Reader) (*Encoding, error) {
+	spec, ok := specs[name]
+	if !ok {
+		return nil, fmt.Errorf("tokenizer: unknown encoding %q", name)
+	}
+
+	ranks, err := parseRanks(r)
+	if err != nil {
+		return nil, fmt.Errorf("tokenizer: failed to load %s: %w", name, err)
+	}
+	return newEncoding(spec, ranks), nil
+}
+
+// LoadEncodingFile 从本地rank文件加载编码并注册
+func LoadEncodingFile(name, path string) (*Encoding, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("tokenizer: failed to open rank file: %w", err)
+	}
+	defer file.Close()
+
+	enc, err := LoadEncoding(name, file)
+	if err != nil {
+		return nil, err
+	}
+	RegisterEncoding(enc)
+	return enc, nil
+}
+
+// RegisterEncoding 注册编码，覆盖同名的已加载编码
+func RegisterEncoding(enc *Encoding) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	encodings[enc.Name()] = enc
+	delete(loadErrors, enc.Name())
+}
+
+// RegisterModelEncoding 注册模型前缀使用的编码，优先于内置映射
+func RegisterModelEncoding(prefix, encoding string) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	modelEncodings = append([]modelEncoding{{prefix, encoding}}, modelEncodings...)
+}
+
+// EncodingForModel 获取模型对应的编码名称，未知模型返回DefaultEncoding
+func EncodingForModel(model string) string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	model = strings.ToLower(model)
+	// 去掉 "openai/" 之类的供应商前缀
+	if idx := strings.LastIndex(model, "/"); idx >= 0 {
+		model = model[idx+1:]
+	}
+	for _, m := range modelEncodings {
+		if strings.HasPrefix(model, m.prefix) {
+			return m.encoding
+		}
+	}
+	return DefaultEncoding
+}
+
+// ForModel 获取模型的Token计数器，rank文件不可用时返回估算器
+func ForModel(model string) Tokenizer {
+	return ForEncoding(EncodingForModel(model))
+}
+
+// ForEncoding 获取编码的Token计数器，rank文件不可用时返回估算器
+func ForEncoding(name string) Tokenizer {
+	if enc, err := GetEncoding(name); err == nil {
+		return enc
+	}
+	spec, ok := specs[name]
+	if !ok {
+		spec = specs[DefaultEncoding]
+	}
+	return &estimator{spec: spec}
+}
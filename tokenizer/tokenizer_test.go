@@ -0,0 +1,199 @@
+package tokenizer
+
+import (
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hewenyu/newapi-go/types"
+)
+
+func TestSplitText(t *testing.T) {
+	tests := []struct {
+		name  string
+		match matchFunc
+		text  string
+		want  []string
+	}{
+		{
+			name:  "cl100k english",
+			match: matchCl100k,
+			text:  "Hello world! I'm  fine\n\n 123456",
+			want:  []string{"Hello", " world", "!", " I", "'m", " ", " fine", "\n\n", " ", "123", "456"},
+		},
+		{
+			name:  "o200k english",
+			match: matchO200k,
+			text:  "Hello world! I'm  fine",
+			want:  []string{"Hello", " world", "!", " I'm", " ", " fine"},
+		},
+		{
+			name:  "cl100k chinese",
+			match: matchCl100k,
+			text:  "你好，世界",
+			want:  []string{"你好", "，世界"},
+		},
+		{
+			name:  "o200k path",
+			match: matchO200k,
+			text:  "}\n/usr",
+			want:  []string{"}\n/", "usr"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitText(tt.text, tt.match); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitText(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodingBytePairMerge(t *testing.T) {
+	var ranks strings.Builder
+	for i, token := range []string{"a", "b", "c", " ", "ab", "abc"} {
+		fmt.Fprintf(&ranks, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(token)), i)
+	}
+
+	enc, err := LoadEncoding(Cl100kBase, strings.NewReader(ranks.String()))
+	if err != nil {
+		t.Fatalf("LoadEncoding failed: %v", err)
+	}
+
+	tokens := enc.Encode("abcab")
+	if want := []int{5, 4}; !reflect.DeepEqual(tokens, want) {
+		t.Errorf("Encode() = %v, want %v", tokens, want)
+	}
+	if got := enc.Count("abcab abc"); got != 4 {
+		t.Errorf("Count() = %d, want 4", got)
+	}
+
+	text, err := enc.Decode(tokens)
+	if err != nil || text != "abcab" {
+		t.Errorf("Decode() = %q, %v", text, err)
+	}
+	if _, err := LoadEncoding("unknown", strings.NewReader(ranks.String())); err == nil {
+		t.Error("expected error for unknown encoding")
+	}
+}
+
+// TestEmbeddedEncodings 使用嵌入的rank文件校验真实编码结果，期望值来自tiktoken。
+// rank文件缺失时GetEncoding返回错误，测试失败
+func TestEmbeddedEncodings(t *testing.T) {
+	tests := []struct {
+		encoding string
+		text     string
+		want     []int
+	}{
+		{Cl100kBase, "hello world", []int{15339, 1917}},
+		{Cl100kBase, "tiktoken is great!", []int{83, 1609, 5963, 374, 2294, 0}},
+		{Cl100kBase, "你好，世界", []int{57668, 53901, 3922, 3574, 244, 98220}},
+		{Cl100kBase, "😀", []int{76460, 222}},
+		{Cl100kBase, "He's they'll we'd I've YOU'RE", []int{1548, 596, 814, 3358, 584, 4265, 358, 3077, 15334, 95253}},
+		{Cl100kBase, "I'm  fine\n\n 123456789", []int{40, 2846, 220, 7060, 271, 220, 4513, 10961, 16474}},
+		{O200kBase, "hello world", []int{24912, 2375}},
+		{O200kBase, "tiktoken is great!", []int{83, 8251, 2488, 382, 2212, 0}},
+		{O200kBase, "你好，世界", []int{177519, 979, 28428}},
+		{O200kBase, "😀", []int{84083}},
+		{O200kBase, "He's they'll we'd I've YOU'RE", []int{98880, 57956, 68530, 9790, 19461, 6, 1099}},
+		{O200kBase, "I'm  fine\n\n 123456789", []int{15390, 220, 8975, 279, 220, 7633, 19354, 29338}},
+	}
+
+	for _, tt := range tests {
+		enc, err := GetEncoding(tt.encoding)
+		if err != nil {
+			t.Fatalf("GetEncoding(%s) failed: %v", tt.encoding, err)
+		}
+		if got := enc.Encode(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Encode(%q) = %v, want %v", tt.encoding, tt.text, got, tt.want)
+		}
+		if got := enc.Count(tt.text); got != len(tt.want) {
+			t.Errorf("%s: Count(%q) = %d, want %d", tt.encoding, tt.text, got, len(tt.want))
+		}
+		if text, err := enc.Decode(tt.want); err != nil || text != tt.text {
+			t.Errorf("%s: Decode(%v) = %q, %v", tt.encoding, tt.want, text, err)
+		}
+	}
+
+	for _, model := range []string{"gpt-4o", "gpt-4", "text-embedding-3-small"} {
+		if tok := ForModel(model); !tok.Exact() {
+			t.Errorf("ForModel(%q) is an estimator, embedded ranks were not loaded", model)
+		}
+	}
+}
+
+func TestEstimatorCountsCJK(t *testing.T) {
+	est := &estimator{spec: specs[Cl100kBase]}
+	if est.Exact() {
+		t.Error("estimator should not be exact")
+	}
+	if got := est.Count("你好世界"); got != 4 {
+		t.Errorf("Count(CJK) = %d, want 4", got)
+	}
+	if got := est.Count("hello"); got != 2 {
+		t.Errorf("Count(ascii) = %d, want 2", got)
+	}
+}
+
+func TestEncodingForModel(t *testing.T) {
+	tests := map[string]string{
+		"gpt-4o-mini":            O200kBase,
+		"openai/gpt-4.1":         O200kBase,
+		"o3-mini":                O200kBase,
+		"gpt-4-turbo":            Cl100kBase,
+		"text-embedding-3-small": Cl100kBase,
+		"deepseek-chat":          DefaultEncoding,
+	}
+	for model, want := range tests {
+		if got := EncodingForModel(model); got != want {
+			t.Errorf("EncodingForModel(%q) = %q, want %q", model, got, want)
+		}
+	}
+}
+
+func TestImageTokens(t *testing.T) {
+	tests := []struct {
+		width, height int
+		detail        string
+		want          int
+	}{
+		{1024, 1024, types.ImageDetailHigh, 765},
+		{2048, 4096, types.ImageDetailHigh, 1105},
+		{4096, 4096, types.ImageDetailLow, 85},
+		{0, 0, "", 765},
+	}
+	for _, tt := range tests {
+		if got := ImageTokens(tt.width, tt.height, tt.detail); got != tt.want {
+			t.Errorf("ImageTokens(%d, %d, %q) = %d, want %d", tt.width, tt.height, tt.detail, got, tt.want)
+		}
+	}
+}
+
+func TestCountMessagesIncludesOverhead(t *testing.T) {
+	messages := []types.ChatMessage{types.NewUserMessage("你好")}
+	tok := ForModel("gpt-4o")
+	want := tokensPerReply + tokensPerMessage + tok.Count(types.ChatRoleUser) + tok.Count("你好")
+	if got := CountMessages("gpt-4o", messages); got != want {
+		t.Errorf("CountMessages() = %d, want %d", got, want)
+	}
+
+	tools := []types.Tool{{
+		Type: types.ToolCallTypeFunction,
+		Function: types.ChatFunction{
+			Name:        "get_weather",
+			Description: "Get weather.",
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"city": map[string]interface{}{"type": "string", "description": "City name"},
+				},
+			},
+		},
+	}}
+	if got := CountTools("gpt-4o", tools); got <= toolFuncEnd {
+		t.Errorf("CountTools() = %d, expected more than the fixed overhead", got)
+	}
+}
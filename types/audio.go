@@ -489,35 +489,14 @@ func IsValidAudioFormat(format string) bool {
 	return false
 }
 
-// IsValidAudioModel 检查音频模型是否有效
+// IsValidAudioModel 检查音频模型是否有效，模型需在能力注册表中登记为转录模型
 func IsValidAudioModel(model string) bool {
-	validModels := []string{
-		AudioModelWhisper1,
-		AudioModelWhisper2,
-		AudioModelSenseVoice,
-	}
-
-	for _, validModel := range validModels {
-		if model == validModel {
-			return true
-		}
-	}
-	return false
+	return DefaultCapabilityRegistry().IsModelType(model, ModelTypeTranscription)
 }
 
-// IsValidTTSModel 检查TTS模型是否有效
+// IsValidTTSModel 检查TTS模型是否有效，模型需在能力注册表中登记为语音合成模型
 func IsValidTTSModel(model string) bool {
-	validModels := []string{
-		AudioModelTTS1,
-		AudioModelTTS1HD,
-	}
-
-	for _, validModel := range validModels {
-		if model == validModel {
-			return true
-		}
-	}
-	return false
+	return DefaultCapabilityRegistry().IsModelType(model, ModelTypeSpeech)
 }
 
 // IsValidAudioVoice 检查音频语音是否有效
@@ -0,0 +1,257 @@
+package types
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ModelType 模型类型
+type ModelType string
+
+// 模型类型常量
+const (
+	ModelTypeChat          ModelType = "chat"
+	ModelTypeCompletion    ModelType = "completion"
+	ModelTypeEmbedding     ModelType = "embedding"
+	ModelTypeTranscription ModelType = "transcription"
+	ModelTypeSpeech        ModelType = "speech"
+	ModelTypeImage         ModelType = "image"
+	ModelTypeModeration    ModelType = "moderation"
+)
+
+// ModelPricing 模型价格，Token价格单位为美元每百万Token。
+// CachedInputPerMillion为0时缓存命中的Token按InputPerMillion计价
+type ModelPricing struct {
+	InputPerMillion       float64 `json:"input_per_million"`
+	CachedInputPerMillion float64 `json:"cached_input_per_million,omitempty"`
+	OutputPerMillion      float64 `json:"output_per_million,omitempty"`
+	// AudioPerMinute 音频转录按时长计价，单位为美元每分钟
+	AudioPerMinute float64 `json:"audio_per_minute,omitempty"`
+	// PerImage 图像生成按张计价，单位为美元每张
+	PerImage float64 `json:"per_image,omitempty"`
+}
+
+// ModelCapabilities 模型能力描述，零值字段表示未知或不支持
+type ModelCapabilities struct {
+	// ID 模型名称，查找时也作为前缀匹配更具体的模型版本
+	ID                  string        `json:"id"`
+	Type                ModelType     `json:"type"`
+	ContextWindow       int           `json:"context_window,omitempty"`
+	MaxOutputTokens     int           `json:"max_output_tokens,omitempty"`
+	MaxInputTokens      int           `json:"max_input_tokens,omitempty"`
+	EmbeddingDimensions int           `json:"embedding_dimensions,omitempty"`
+	Tools               bool          `json:"tools,omitempty"`
+	Vision              bool          `json:"vision,omitempty"`
+	JSONMode            bool          `json:"json_mode,omitempty"`
+	Reasoning           bool          `json:"reasoning,omitempty"`
+	Pricing             *ModelPricing `json:"pricing,omitempty"`
+}
+
+// CapabilityRegistry 模型能力注册表。查找时先精确匹配，再按最长前缀匹配，
+// 带有供应商前缀（如 openai/gpt-4o）的名称在未命中时去掉前缀重试
+type CapabilityRegistry struct {
+	mu      sync.RWMutex
+	entries map[string]ModelCapabilities
+}
+
+// NewCapabilityRegistry 创建模型能力注册表
+func NewCapabilityRegistry(entries ...ModelCapabilities) *CapabilityRegistry {
+	registry := &CapabilityRegistry{entries: make(map[string]ModelCapabilities, len(entries))}
+	for _, entry := range entries {
+		registry.Register(entry)
+	}
+	return registry
+}
+
+// Register 注册模型能力，覆盖同名条目
+func (r *CapabilityRegistry) Register(capabilities ModelCapabilities) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if capabilities.Pricing != nil {
+		pricing := *capabilities.Pricing
+		capabilities.Pricing = &pricing
+	}
+	r.entries[strings.ToLower(capabilities.ID)] = capabilities
+}
+
+// Update 修改已注册的模型能力，不存在时以id和默认类型chat新建
+func (r *CapabilityRegistry) Update(id string, update func(*ModelCapabilities)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := strings.ToLower(id)
+	capabilities, ok := r.entries[key]
+	if !ok {
+		capabilities = ModelCapabilities{ID: id, Type: ModelTypeChat}
+	}
+	update(&capabilities)
+	r.entries[key] = capabilities
+}
+
+// Lookup 查找模型能力
+func (r *CapabilityRegistry) Lookup(model string) (ModelCapabilities, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	model = strings.ToLower(model)
+	if capabilities, ok := r.lookup(model); ok {
+		return capabilities, true
+	}
+	if idx := strings.LastIndex(model, "/"); idx >= 0 {
+		return r.lookup(model[idx+1:])
+	}
+	return ModelCapabilities{}, false
+}
+
+// lookup 精确或最长前缀匹配，调用方需持有锁
+func (r *CapabilityRegistry) lookup(model string) (ModelCapabilities, bool) {
+	if capabilities, ok := r.entries[model]; ok {
+		return capabilities, true
+	}
+
+	best := ""
+	for prefix := range r.entries {
+		if len(prefix) > len(best) && strings.HasPrefix(model, prefix) {
+			best = prefix
+		}
+	}
+	if best == "" {
+		return ModelCapabilities{}, false
+	}
+	return r.entries[best], true
+}
+
+// Models 获取指定类型的已注册模型名称，按名称排序
+func (r *CapabilityRegistry) Models(modelType ModelType) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	models := make([]string, 0)
+	for _, capabilities := range r.entries {
+		if capabilities.Type == modelType {
+			models = append(models, capabilities.ID)
+		}
+	}
+	sort.Strings(models)
+	return models
+}
+
+// IsModelType 检查模型是否已注册为指定类型
+func (r *CapabilityRegistry) IsModelType(model string, modelType ModelType) bool {
+	capabilities, ok := r.Lookup(model)
+	return ok && capabilities.Type == modelType
+}
+
+// defaultCapabilities 内置的模型能力数据
+var defaultCapabilities = []ModelCapabilities{
+	// OpenAI 聊天模型
+	{ID: "gpt-3.5-turbo", Type: ModelTypeChat, ContextWindow: 16385, MaxOutputTokens: 4096, Tools: true, JSONMode: true,
+		Pricing: &ModelPricing{InputPerMillion: 0.5, OutputPerMillion: 1.5}},
+	{ID: "gpt-4", Type: ModelTypeChat, ContextWindow: 8192, MaxOutputTokens: 8192, Tools: true,
+		Pricing: &ModelPricing{InputPerMillion: 30, OutputPerMillion: 60}},
+	{ID: "gpt-4-32k", Type: ModelTypeChat, ContextWindow: 32768, MaxOutputTokens: 8192, Tools: true,
+		Pricing: &ModelPricing{InputPerMillion: 60, OutputPerMillion: 120}},
+	{ID: "gpt-4-turbo", Type: ModelTypeChat, ContextWindow: 128000, MaxOutputTokens: 4096, Tools: true, Vision: true, JSONMode: true,
+		Pricing: &ModelPricing{InputPerMillion: 10, OutputPerMillion: 30}},
+	{ID: "gpt-4o", Type: ModelTypeChat, ContextWindow: 128000, MaxOutputTokens: 16384, Tools: true, Vision: true, JSONMode: true,
+		Pricing: &ModelPricing{InputPerMillion: 2.5, CachedInputPerMillion: 1.25, OutputPerMillion: 10}},
+	{ID: "gpt-4o-mini", Type: ModelTypeChat, ContextWindow: 128000, MaxOutputTokens: 16384, Tools: true, Vision: true, JSONMode: true,
+		Pricing: &ModelPricing{InputPerMillion: 0.15, CachedInputPerMillion: 0.075, OutputPerMillion: 0.6}},
+	{ID: "chatgpt-4o", Type: ModelTypeChat, ContextWindow: 128000, MaxOutputTokens: 16384, Vision: true, JSONMode: true,
+		Pricing: &ModelPricing{InputPerMillion: 5, OutputPerMillion: 15}},
+	{ID: "gpt-4.1", Type: ModelTypeChat, ContextWindow: 1047576, MaxOutputTokens: 32768, Tools: true, Vision: true, JSONMode: true,
+		Pricing: &ModelPricing{InputPerMillion: 2, CachedInputPerMillion: 0.5, OutputPerMillion: 8}},
+	{ID: "gpt-4.1-mini", Type: ModelTypeChat, ContextWindow: 1047576, MaxOutputTokens: 32768, Tools: true, Vision: true, JSONMode: true,
+		Pricing: &ModelPricing{InputPerMillion: 0.4, CachedInputPerMillion: 0.1, OutputPerMillion: 1.6}},
+	{ID: "gpt-4.1-nano", Type: ModelTypeChat, ContextWindow: 1047576, MaxOutputTokens: 32768, Tools: true, Vision: true, JSONMode: true,
+		Pricing: &ModelPricing{InputPerMillion: 0.1, CachedInputPerMillion: 0.025, OutputPerMillion: 0.4}},
+	{ID: "gpt-5", Type: ModelTypeChat, ContextWindow: 400000, MaxOutputTokens: 128000, Tools: true, Vision: true, JSONMode: true, Reasoning: true,
+		Pricing: &ModelPricing{InputPerMillion: 1.25, CachedInputPerMillion: 0.125, OutputPerMillion: 10}},
+	{ID: "o1", Type: ModelTypeChat, ContextWindow: 200000, MaxOutputTokens: 100000, Tools: true, Vision: true, JSONMode: true, Reasoning: true,
+		Pricing: &ModelPricing{InputPerMillion: 15, CachedInputPerMillion: 7.5, OutputPerMillion: 60}},
+	{ID: "o1-mini", Type: ModelTypeChat, ContextWindow: 128000, MaxOutputTokens: 65536, Reasoning: true,
+		Pricing: &ModelPricing{InputPerMillion: 1.1, CachedInputPerMillion: 0.55, OutputPerMillion: 4.4}},
+	{ID: "o3", Type: ModelTypeChat, ContextWindow: 200000, MaxOutputTokens: 100000, Tools: true, Vision: true, JSONMode: true, Reasoning: true,
+		Pricing: &ModelPricing{InputPerMillion: 2, CachedInputPerMillion: 0.5, OutputPerMillion: 8}},
+	{ID: "o3-mini", Type: ModelTypeChat, ContextWindow: 200000, MaxOutputTokens: 100000, Tools: true, JSONMode: true, Reasoning: true,
+		Pricing: &ModelPricing{InputPerMillion: 1.1, CachedInputPerMillion: 0.55, OutputPerMillion: 4.4}},
+	{ID: "o4-mini", Type: ModelTypeChat, ContextWindow: 200000, MaxOutputTokens: 100000, Tools: true, Vision: true, JSONMode: true, Reasoning: true,
+		Pricing: &ModelPricing{InputPerMillion: 1.1, CachedInputPerMillion: 0.275, OutputPerMillion: 4.4}},
+
+	// 其他供应商聊天模型
+	{ID: "claude-3", Type: ModelTypeChat, ContextWindow: 200000, MaxOutputTokens: 4096, Tools: true, Vision: true},
+	{ID: "claude-3-5-sonnet", Type: ModelTypeChat, ContextWindow: 200000, MaxOutputTokens: 8192, Tools: true, Vision: true,
+		Pricing: &ModelPricing{InputPerMillion: 3, CachedInputPerMillion: 0.3, OutputPerMillion: 15}},
+	{ID: "claude-3-5-haiku", Type: ModelTypeChat, ContextWindow: 200000, MaxOutputTokens: 8192, Tools: true, Vision: true,
+		Pricing: &ModelPricing{InputPerMillion: 0.8, CachedInputPerMillion: 0.08, OutputPerMillion: 4}},
+	{ID: "claude-3-opus", Type: ModelTypeChat, ContextWindow: 200000, MaxOutputTokens: 4096, Tools: true, Vision: true,
+		Pricing: &ModelPricing{InputPerMillion: 15, CachedInputPerMillion: 1.5, OutputPerMillion: 75}},
+	{ID: "claude-3-haiku", Type: ModelTypeChat, ContextWindow: 200000, MaxOutputTokens: 4096, Tools: true, Vision: true,
+		Pricing: &ModelPricing{InputPerMillion: 0.25, CachedInputPerMillion: 0.03, OutputPerMillion: 1.25}},
+	{ID: "claude-sonnet-4", Type: ModelTypeChat, ContextWindow: 200000, MaxOutputTokens: 64000, Tools: true, Vision: true, Reasoning: true,
+		Pricing: &ModelPricing{InputPerMillion: 3, CachedInputPerMillion: 0.3, OutputPerMillion: 15}},
+	{ID: "claude-opus-4", Type: ModelTypeChat, ContextWindow: 200000, MaxOutputTokens: 32000, Tools: true, Vision: true, Reasoning: true,
+		Pricing: &ModelPricing{InputPerMillion: 15, CachedInputPerMillion: 1.5, OutputPerMillion: 75}},
+	{ID: "gemini-1.5-pro", Type: ModelTypeChat, ContextWindow: 2097152, MaxOutputTokens: 8192, Tools: true, Vision: true, JSONMode: true},
+	{ID: "gemini-1.5-flash", Type: ModelTypeChat, ContextWindow: 1048576, MaxOutputTokens: 8192, Tools: true, Vision: true, JSONMode: true},
+	{ID: "gemini-2.0-flash", Type: ModelTypeChat, ContextWindow: 1048576, MaxOutputTokens: 8192, Tools: true, Vision: true, JSONMode: true},
+	{ID: "gemini-2.5", Type: ModelTypeChat, ContextWindow: 1048576, MaxOutputTokens: 65536, Tools: true, Vision: true, JSONMode: true, Reasoning: true},
+	{ID: "deepseek-chat", Type: ModelTypeChat, ContextWindow: 65536, MaxOutputTokens: 8192, Tools: true, JSONMode: true,
+		Pricing: &ModelPricing{InputPerMillion: 0.27, CachedInputPerMillion: 0.07, OutputPerMillion: 1.1}},
+	{ID: "deepseek-reasoner", Type: ModelTypeChat, ContextWindow: 65536, MaxOutputTokens: 65536, Reasoning: true,
+		Pricing: &ModelPricing{InputPerMillion: 0.55, CachedInputPerMillion: 0.14, OutputPerMillion: 2.19}},
+	{ID: "qwen-max", Type: ModelTypeChat, ContextWindow: 32768, MaxOutputTokens: 8192, Tools: true, JSONMode: true},
+	{ID: "qwen-plus", Type: ModelTypeChat, ContextWindow: 131072, MaxOutputTokens: 8192, Tools: true, JSONMode: true},
+	{ID: "qwen-turbo", Type: ModelTypeChat, ContextWindow: 1000000, MaxOutputTokens: 8192, Tools: true, JSONMode: true},
+
+	// 文本补全模型
+	{ID: "gpt-3.5-turbo-instruct", Type: ModelTypeCompletion, ContextWindow: 4096, MaxOutputTokens: 4096,
+		Pricing: &ModelPricing{InputPerMillion: 1.5, OutputPerMillion: 2}},
+	{ID: "davinci-002", Type: ModelTypeCompletion, ContextWindow: 16384, MaxOutputTokens: 16384,
+		Pricing: &ModelPricing{InputPerMillion: 2, OutputPerMillion: 2}},
+	{ID: "babbage-002", Type: ModelTypeCompletion, ContextWindow: 16384, MaxOutputTokens: 16384,
+		Pricing: &ModelPricing{InputPerMillion: 0.4, OutputPerMillion: 0.4}},
+
+	// 嵌入模型
+	{ID: "text-embedding-3-small", Type: ModelTypeEmbedding, MaxInputTokens: 8192, EmbeddingDimensions: 1536,
+		Pricing: &ModelPricing{InputPerMillion: 0.02}},
+	{ID: "text-embedding-3-large", Type: ModelTypeEmbedding, MaxInputTokens: 8192, EmbeddingDimensions: 3072,
+		Pricing: &ModelPricing{InputPerMillion: 0.13}},
+	{ID: "text-embedding-ada-002", Type: ModelTypeEmbedding, MaxInputTokens: 8192, EmbeddingDimensions: 1536,
+		Pricing: &ModelPricing{InputPerMillion: 0.1}},
+
+	// 音频模型
+	{ID: AudioModelWhisper1, Type: ModelTypeTranscription, Pricing: &ModelPricing{AudioPerMinute: 0.006}},
+	{ID: AudioModelWhisper2, Type: ModelTypeTranscription},
+	{ID: AudioModelSenseVoice, Type: ModelTypeTranscription},
+	{ID: AudioModelTTS1, Type: ModelTypeSpeech},
+	{ID: AudioModelTTS1HD, Type: ModelTypeSpeech},
+
+	// 图像模型
+	{ID: "dall-e-2", Type: ModelTypeImage, Pricing: &ModelPricing{PerImage: 0.02}},
+	{ID: "dall-e-3", Type: ModelTypeImage, Pricing: &ModelPricing{PerImage: 0.04}},
+
+	// 审核模型
+	{ID: ModerationModelOmniLatest, Type: ModelTypeModeration, Vision: true},
+	{ID: ModerationModelTextLatest, Type: ModelTypeModeration},
+}
+
+// defaultRegistry 全局默认的模型能力注册表
+var defaultRegistry = NewCapabilityRegistry(defaultCapabilities...)
+
+// DefaultCapabilityRegistry 获取全局默认的模型能力注册表，SDK内的模型校验和上下文窗口均使用该注册表
+func DefaultCapabilityRegistry() *CapabilityRegistry {
+	return defaultRegistry
+}
+
+// RegisterModelCapabilities 在全局注册表中注册模型能力，可用于声明网关上的自定义或微调模型
+func RegisterModelCapabilities(capabilities ModelCapabilities) {
+	defaultRegistry.Register(capabilities)
+}
+
+// LookupModelCapabilities 在全局注册表中查找模型能力
+func LookupModelCapabilities(model string) (ModelCapabilities, bool) {
+	return defaultRegistry.Lookup(model)
+}
@@ -0,0 +1,60 @@
+package types
+
+import (
+	"testing"
+)
+
+func TestCapabilityRegistryLookup(t *testing.T) {
+	registry := NewCapabilityRegistry(defaultCapabilities...)
+
+	tests := []struct {
+		model  string
+		wantID string
+	}{
+		{"gpt-4o", "gpt-4o"},
+		{"GPT-4o-2024-08-06", "gpt-4o"},
+		{"gpt-4o-mini-2024-07-18", "gpt-4o-mini"},
+		{"openai/gpt-4.1-mini", "gpt-4.1-mini"},
+		{"tts-1-hd", AudioModelTTS1HD},
+		{"FunAudioLLM/SenseVoiceSmall", AudioModelSenseVoice},
+	}
+	for _, tt := range tests {
+		got, ok := registry.Lookup(tt.model)
+		if !ok || got.ID != tt.wantID {
+			t.Errorf("Lookup(%q) = %q, %v, want %q", tt.model, got.ID, ok, tt.wantID)
+		}
+	}
+
+	if _, ok := registry.Lookup("unknown-model"); ok {
+		t.Error("expected unknown model lookup to fail")
+	}
+}
+
+func TestCapabilityRegistryModelsAndUpdate(t *testing.T) {
+	registry := NewCapabilityRegistry(defaultCapabilities...)
+
+	embeddings := registry.Models(ModelTypeEmbedding)
+	if len(embeddings) != 3 || embeddings[0] != "text-embedding-3-large" {
+		t.Errorf("Models(embedding) = %v", embeddings)
+	}
+
+	registry.Update("my-model", func(c *ModelCapabilities) { c.ContextWindow = 4096 })
+	got, ok := registry.Lookup("my-model-v2")
+	if !ok || got.Type != ModelTypeChat || got.ContextWindow != 4096 {
+		t.Errorf("Lookup(my-model-v2) = %+v, %v", got, ok)
+	}
+
+	registry.Update("gpt-4o", func(c *ModelCapabilities) { c.ContextWindow = 1000 })
+	if got, _ := registry.Lookup("gpt-4o"); got.ContextWindow != 1000 || !got.Vision {
+		t.Errorf("Update should keep existing capabilities: %+v", got)
+	}
+}
+
+func TestAudioModelValidationUsesRegistry(t *testing.T) {
+	if !IsValidAudioModel(AudioModelWhisper1) || IsValidAudioModel(AudioModelTTS1) {
+		t.Error("unexpected transcription model validation result")
+	}
+	if !IsValidTTSModel(AudioModelTTS1HD) || IsValidTTSModel("gpt-4o") {
+		t.Error("unexpected speech model validation result")
+	}
+}
@@ -3,6 +3,7 @@ package types
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 )
 
 // 聊天角色常量
@@ -21,6 +22,9 @@ const (
 	ChatMessageTypeImageBase64  = "image_base64"
 	ChatMessageTypeAudio        = "audio"
 	ChatMessageTypeVideo        = "video"
+	ChatMessageTypeVideoURL     = "video_url"
+	ChatMessageTypeInputAudio   = "input_audio"
+	ChatMessageTypeFile         = "file"
 	ChatMessageTypeToolCall     = "tool_call"
 	ChatMessageTypeToolResponse = "tool_response"
 )
@@ -41,27 +45,30 @@ const (
 	FinishReasonFunctionCall  = "function_call"
 )
 
+// 推理强度常量
+const (
+	ReasoningEffortLow    = "low"
+	ReasoningEffortMedium = "medium"
+	ReasoningEffortHigh   = "high"
+)
+
 // ChatMessage 聊天消息结构体
 type ChatMessage struct {
-	Role         string          `json:"role"`
-	Content      interface{}     `json:"content"`
-	Name         string          `json:"name,omitempty"`
-	ToolCalls    []ToolCall      `json:"tool_calls,omitempty"`
-	ToolCallID   string          `json:"tool_call_id,omitempty"`
-	FunctionCall *FunctionCall   `json:"function_call,omitempty"`
-	Metadata     json.RawMessage `json:"metadata,omitempty"`
-}
-
-// MessageContent 消息内容结构体
-type MessageContent struct {
-	Type     string `json:"type"`
-	Text     string `json:"text,omitempty"`
-	ImageURL string `json:"image_url,omitempty"`
-	Detail   string `json:"detail,omitempty"`
+	Role             string          `json:"role"`
+	Content          interface{}     `json:"content"`
+	ReasoningContent string          `json:"reasoning_content,omitempty"`
+	Refusal          string          `json:"refusal,omitempty"`
+	Audio            *ChatAudio      `json:"audio,omitempty"`
+	Name             string          `json:"name,omitempty"`
+	ToolCalls        []ToolCall      `json:"tool_calls,omitempty"`
+	ToolCallID       string          `json:"tool_call_id,omitempty"`
+	FunctionCall     *FunctionCall   `json:"function_call,omitempty"`
+	Metadata         json.RawMessage `json:"metadata,omitempty"`
 }
 
 // ToolCall 工具调用结构体
 type ToolCall struct {
+	Index    *int         `json:"index,omitempty"`
 	ID       string       `json:"id"`
 	Type     string       `json:"type"`
 	Function FunctionCall `json:"function"`
@@ -74,28 +81,39 @@ type FunctionCall struct {
 }
 
 // ChatCompletionRequest 聊天完成请求结构体
+// 采样参数使用指针类型，nil 表示不发送，从而可以显式发送零值（如 temperature 为 0）
 type ChatCompletionRequest struct {
-	Model            string                 `json:"model"`
-	Messages         []ChatMessage          `json:"messages"`
-	MaxTokens        int                    `json:"max_tokens,omitempty"`
-	Temperature      float64                `json:"temperature,omitempty"`
-	TopP             float64                `json:"top_p,omitempty"`
-	N                int                    `json:"n,omitempty"`
-	Stream           bool                   `json:"stream,omitempty"`
-	Stop             interface{}            `json:"stop,omitempty"`
-	PresencePenalty  float64                `json:"presence_penalty,omitempty"`
-	FrequencyPenalty float64                `json:"frequency_penalty,omitempty"`
-	LogitBias        map[string]float64     `json:"logit_bias,omitempty"`
-	User             string                 `json:"user,omitempty"`
-	Functions        []ChatFunction         `json:"functions,omitempty"`
-	FunctionCall     interface{}            `json:"function_call,omitempty"`
-	Tools            []Tool                 `json:"tools,omitempty"`
-	ToolChoice       interface{}            `json:"tool_choice,omitempty"`
-	ResponseFormat   *ChatResponseFormat    `json:"response_format,omitempty"`
-	Seed             int                    `json:"seed,omitempty"`
-	LogProbs         bool                   `json:"logprobs,omitempty"`
-	TopLogProbs      int                    `json:"top_logprobs,omitempty"`
-	ExtraBody        map[string]interface{} `json:"-"`
+	Model               string                 `json:"model"`
+	Messages            []ChatMessage          `json:"messages"`
+	MaxTokens           int                    `json:"max_tokens,omitempty"`
+	MaxCompletionTokens int                    `json:"max_completion_tokens,omitempty"`
+	Temperature         *float64               `json:"temperature,omitempty"`
+	TopP                *float64               `json:"top_p,omitempty"`
+	N                   int                    `json:"n,omitempty"`
+	Stream              bool                   `json:"stream,omitempty"`
+	StreamOptions       *ChatStreamOptions     `json:"stream_options,omitempty"`
+	Stop                interface{}            `json:"stop,omitempty"`
+	PresencePenalty     *float64               `json:"presence_penalty,omitempty"`
+	FrequencyPenalty    *float64               `json:"frequency_penalty,omitempty"`
+	LogitBias           map[string]float64     `json:"logit_bias,omitempty"`
+	User                string                 `json:"user,omitempty"`
+	Functions           []ChatFunction         `json:"functions,omitempty"`
+	FunctionCall        interface{}            `json:"function_call,omitempty"`
+	Tools               []Tool                 `json:"tools,omitempty"`
+	ToolChoice          interface{}            `json:"tool_choice,omitempty"`
+	ParallelToolCalls   *bool                  `json:"parallel_tool_calls,omitempty"`
+	ResponseFormat      *ChatResponseFormat    `json:"response_format,omitempty"`
+	Seed                *int                   `json:"seed,omitempty"`
+	LogProbs            bool                   `json:"logprobs,omitempty"`
+	TopLogProbs         int                    `json:"top_logprobs,omitempty"`
+	ReasoningEffort     string                 `json:"reasoning_effort,omitempty"`
+	Store               *bool                  `json:"store,omitempty"`
+	Metadata            map[string]string      `json:"metadata,omitempty"`
+	Modalities          []string               `json:"modalities,omitempty"`
+	Audio               *ChatAudioParams       `json:"audio,omitempty"`
+	Prediction          *ChatPrediction        `json:"prediction,omitempty"`
+	ServiceTier         string                 `json:"service_tier,omitempty"`
+	ExtraBody           map[string]interface{} `json:"-"`
 }
 
 // ChatCompletionResponse 聊天完成响应结构体
@@ -107,7 +125,15 @@ type ChatCompletionResponse struct {
 	Choices           []ChatCompletionChoice `json:"choices"`
 	Usage             Usage                  `json:"usage"`
 	SystemFingerprint string                 `json:"system_fingerprint,omitempty"`
+	ServiceTier       string                 `json:"service_tier,omitempty"`
 	Error             *ErrorResponse         `json:"error,omitempty"`
+
+	// ServedModel 实际处理请求的模型，启用模型降级时可能与请求的模型不同
+	ServedModel string `json:"-"`
+	// CacheStatus 响应缓存状态，未配置缓存时为空
+	CacheStatus string `json:"-"`
+	// Moderation 内容审核标注，未启用审核时为nil
+	Moderation *ModerationAnnotation `json:"-"`
 }
 
 // ChatCompletionChoice 聊天完成选择结构体
@@ -128,6 +154,7 @@ type ChatCompletionChunk struct {
 	Choices           []ChatCompletionChunkChoice `json:"choices"`
 	Usage             *Usage                      `json:"usage,omitempty"`
 	SystemFingerprint string                      `json:"system_fingerprint,omitempty"`
+	ServiceTier       string                      `json:"service_tier,omitempty"`
 }
 
 // ChatCompletionChunkChoice 聊天完成流式选择结构体
@@ -143,6 +170,7 @@ type ChatFunction struct {
 	Name        string                 `json:"name"`
 	Description string                 `json:"description,omitempty"`
 	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+	Strict      *bool                  `json:"strict,omitempty"`
 }
 
 // Tool 工具结构体
@@ -153,8 +181,9 @@ type Tool struct {
 
 // ChatResponseFormat 聊天响应格式结构体
 type ChatResponseFormat struct {
-	Type   string `json:"type"`
-	Schema string `json:"schema,omitempty"`
+	Type       string                        `json:"type"`
+	Schema     string                        `json:"schema,omitempty"`
+	JSONSchema *ChatResponseFormatJSONSchema `json:"json_schema,omitempty"`
 }
 
 // LogProbs 日志概率结构体
@@ -232,19 +261,29 @@ func (m *ChatMessage) IsValidRole() bool {
 	}
 }
 
-// GetTextContent 获取文本内容
+// GetTextContent 获取文本内容，多模态消息返回所有文本部分以换行拼接的结果
 func (m *ChatMessage) GetTextContent() string {
-	switch content := m.Content.(type) {
-	case string:
+	if content, ok := m.Content.(string); ok {
 		return content
-	case []MessageContent:
-		for _, c := range content {
-			if c.Type == ChatMessageTypeText {
-				return c.Text
-			}
+	}
+
+	var texts []string
+	for _, part := range m.GetContentParts() {
+		if part.Type == ChatMessageTypeText && part.Text != "" {
+			texts = append(texts, part.Text)
 		}
 	}
-	return ""
+	return strings.Join(texts, "\n")
+}
+
+// GetReasoningContent 获取推理内容
+func (m *ChatMessage) GetReasoningContent() string {
+	return m.ReasoningContent
+}
+
+// HasReasoningContent 检查是否有推理内容
+func (m *ChatMessage) HasReasoningContent() bool {
+	return m.ReasoningContent != ""
 }
 
 // HasToolCalls 检查是否有工具调用
@@ -278,20 +317,37 @@ func (r *ChatCompletionRequest) ValidateParameters() error {
 	if r.MaxTokens < 0 {
 		return NewValidationError("max_tokens", r.MaxTokens, "max_tokens must be positive", ErrCodeInvalidParameter)
 	}
-	if r.Temperature < 0 || r.Temperature > 2 {
-		return NewValidationError("temperature", r.Temperature, "temperature must be between 0 and 2", ErrCodeInvalidParameter)
+	if r.MaxCompletionTokens < 0 {
+		return NewValidationError("max_completion_tokens", r.MaxCompletionTokens, "max_completion_tokens must be positive", ErrCodeInvalidParameter)
+	}
+	if r.Temperature != nil && (*r.Temperature < 0 || *r.Temperature > 2) {
+		return NewValidationError("temperature", *r.Temperature, "temperature must be between 0 and 2", ErrCodeInvalidParameter)
 	}
-	if r.TopP < 0 || r.TopP > 1 {
-		return NewValidationError("top_p", r.TopP, "top_p must be between 0 and 1", ErrCodeInvalidParameter)
+	if r.TopP != nil && (*r.TopP < 0 || *r.TopP > 1) {
+		return NewValidationError("top_p", *r.TopP, "top_p must be between 0 and 1", ErrCodeInvalidParameter)
 	}
 	if r.N < 1 {
 		return NewValidationError("n", r.N, "n must be at least 1", ErrCodeInvalidParameter)
 	}
-	if r.PresencePenalty < -2 || r.PresencePenalty > 2 {
-		return NewValidationError("presence_penalty", r.PresencePenalty, "presence_penalty must be between -2 and 2", ErrCodeInvalidParameter)
+	if r.PresencePenalty != nil && (*r.PresencePenalty < -2 || *r.PresencePenalty > 2) {
+		return NewValidationError("presence_penalty", *r.PresencePenalty, "presence_penalty must be between -2 and 2", ErrCodeInvalidParameter)
+	}
+	if r.FrequencyPenalty != nil && (*r.FrequencyPenalty < -2 || *r.FrequencyPenalty > 2) {
+		return NewValidationError("frequency_penalty", *r.FrequencyPenalty, "frequency_penalty must be between -2 and 2", ErrCodeInvalidParameter)
+	}
+	if r.TopLogProbs < 0 || r.TopLogProbs > 20 {
+		return NewValidationError("top_logprobs", r.TopLogProbs, "top_logprobs must be between 0 and 20", ErrCodeInvalidParameter)
+	}
+	if err := ValidateChatMetadata(r.Metadata); err != nil {
+		return err
+	}
+	if r.Audio != nil {
+		if err := r.Audio.Validate(); err != nil {
+			return err
+		}
 	}
-	if r.FrequencyPenalty < -2 || r.FrequencyPenalty > 2 {
-		return NewValidationError("frequency_penalty", r.FrequencyPenalty, "frequency_penalty must be between -2 and 2", ErrCodeInvalidParameter)
+	if r.ReasoningEffort != "" && !IsValidReasoningEffort(r.ReasoningEffort) {
+		return NewValidationError("reasoning_effort", r.ReasoningEffort, "reasoning_effort must be low, medium or high", ErrCodeInvalidParameter)
 	}
 
 	// 验证消息
@@ -302,19 +358,21 @@ func (r *ChatCompletionRequest) ValidateParameters() error {
 		if msg.Content == nil && !msg.HasToolCalls() && !msg.HasFunctionCall() {
 			return NewValidationError(fmt.Sprintf("messages[%d].content", i), msg.Content, "content cannot be empty", ErrCodeMissingParameter)
 		}
+		if parts, ok := msg.Content.([]MessageContent); ok {
+			for j := range parts {
+				if err := parts[j].Validate(); err != nil {
+					return fmt.Errorf("messages[%d].content[%d]: %w", i, j, err)
+				}
+			}
+		}
 	}
 
 	return nil
 }
 
 // SetDefaults 设置默认值
+// 未设置的采样参数保持为 nil，由服务端使用默认值，o系列等推理模型不接受 temperature 和 top_p
 func (r *ChatCompletionRequest) SetDefaults() {
-	if r.Temperature == 0 {
-		r.Temperature = 1.0
-	}
-	if r.TopP == 0 {
-		r.TopP = 1.0
-	}
 	if r.N == 0 {
 		r.N = 1
 	}
@@ -327,6 +385,9 @@ func (r *ChatCompletionRequest) IsStream() bool {
 
 // GetMaxTokens 获取最大Token数
 func (r *ChatCompletionRequest) GetMaxTokens() int {
+	if r.MaxCompletionTokens > 0 {
+		return r.MaxCompletionTokens
+	}
 	if r.MaxTokens > 0 {
 		return r.MaxTokens
 	}
@@ -377,6 +438,14 @@ func (r *ChatCompletionResponse) GetFirstContent() string {
 	return ""
 }
 
+// GetFirstReasoningContent 获取第一个推理内容
+func (r *ChatCompletionResponse) GetFirstReasoningContent() string {
+	if msg := r.GetFirstMessage(); msg != nil {
+		return msg.GetReasoningContent()
+	}
+	return ""
+}
+
 // ToJSON 转换为JSON字符串
 func (r *ChatCompletionResponse) ToJSON() ([]byte, error) {
 	return json.Marshal(r)
@@ -417,6 +486,11 @@ func (c *ChatCompletionChunkChoice) GetContent() string {
 	return c.Delta.GetTextContent()
 }
 
+// GetReasoningContent 获取推理内容增量
+func (c *ChatCompletionChunkChoice) GetReasoningContent() string {
+	return c.Delta.GetReasoningContent()
+}
+
 // IsValidTool 检查工具是否有效
 func (t *Tool) IsValidTool() bool {
 	return t.Type != "" && t.Function.Name != ""
@@ -436,3 +510,68 @@ func (tc *ToolCall) IsValidToolCall() bool {
 func (fc *FunctionCall) IsValidFunctionCall() bool {
 	return fc.Name != ""
 }
+
+// IsValidReasoningEffort 检查推理强度是否有效
+func IsValidReasoningEffort(effort string) bool {
+	switch effort {
+	case ReasoningEffortLow, ReasoningEffortMedium, ReasoningEffortHigh:
+		return true
+	default:
+		return false
+	}
+}
+
+// StripReasoningContent 移除消息中的推理内容
+// 推理模型不接受输入消息中携带 reasoning_content，多轮对话前需要移除
+func StripReasoningContent(messages []ChatMessage) []ChatMessage {
+	result := make([]ChatMessage, len(messages))
+	for i, message := range messages {
+		message.ReasoningContent = ""
+		result[i] = message
+	}
+	return result
+}
+
+// MergeToolCallDeltas 将流式响应中的工具调用增量合并到已有的工具调用中。
+// 增量按index匹配，参数片段依次拼接；没有index的增量视为完整的工具调用
+func MergeToolCallDeltas(calls []ToolCall, deltas []ToolCall) []ToolCall {
+	for _, delta := range deltas {
+		merged := false
+		if delta.Index != nil {
+			for i := range calls {
+				if calls[i].Index == nil || *calls[i].Index != *delta.Index {
+					continue
+				}
+				if delta.ID != "" {
+					calls[i].ID = delta.ID
+				}
+				if delta.Type != "" {
+					calls[i].Type = delta.Type
+				}
+				if delta.Function.Name != "" {
+					calls[i].Function.Name = delta.Function.Name
+				}
+				calls[i].Function.Arguments += delta.Function.Arguments
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			calls = append(calls, delta)
+		}
+	}
+	return calls
+}
+
+// ClearToolCallIndexes 清除工具调用的流式index，返回副本，用于将流式结果作为历史消息发送
+func ClearToolCallIndexes(calls []ToolCall) []ToolCall {
+	if len(calls) == 0 {
+		return calls
+	}
+	result := make([]ToolCall, len(calls))
+	copy(result, calls)
+	for i := range result {
+		result[i].Index = nil
+	}
+	return result
+}
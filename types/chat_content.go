@@ -0,0 +1,188 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// 图像细节级别常量
+const (
+	ImageDetailAuto = "auto"
+	ImageDetailLow  = "low"
+	ImageDetailHigh = "high"
+)
+
+// 输入音频格式常量
+const (
+	InputAudioFormatWAV = "wav"
+	InputAudioFormatMP3 = "mp3"
+)
+
+// MessageContent 消息内容部分结构体
+type MessageContent struct {
+	Type       string       `json:"type"`
+	Text       string       `json:"text,omitempty"`
+	ImageURL   *ImageURL    `json:"image_url,omitempty"`
+	InputAudio *InputAudio  `json:"input_audio,omitempty"`
+	File       *FileContent `json:"file,omitempty"`
+	VideoURL   *VideoURL    `json:"video_url,omitempty"`
+}
+
+// ImageURL 图像URL结构体，URL可以是网络地址或base64数据URI
+type ImageURL struct {
+	URL    string `json:"url"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// InputAudio 输入音频结构体，Data为base64编码的音频数据
+type InputAudio struct {
+	Data   string `json:"data"`
+	Format string `json:"format"`
+}
+
+// FileContent 文件内容结构体，FileData为base64数据URI
+type FileContent struct {
+	FileID   string `json:"file_id,omitempty"`
+	Filename string `json:"filename,omitempty"`
+	FileData string `json:"file_data,omitempty"`
+}
+
+// VideoURL 视频URL结构体
+type VideoURL struct {
+	URL string `json:"url"`
+}
+
+// Validate 验证内容部分
+func (c *MessageContent) Validate() error {
+	switch c.Type {
+	case ChatMessageTypeText:
+		return nil
+	case ChatMessageTypeImageURL:
+		if c.ImageURL == nil || c.ImageURL.URL == "" {
+			return NewValidationError("image_url", c.ImageURL, "image_url content requires a url", ErrCodeMissingParameter)
+		}
+		if !IsValidImageDetail(c.ImageURL.Detail) {
+			return NewValidationError("image_url.detail", c.ImageURL.Detail, "detail must be auto, low or high", ErrCodeInvalidParameter)
+		}
+	case ChatMessageTypeInputAudio:
+		if c.InputAudio == nil || c.InputAudio.Data == "" {
+			return NewValidationError("input_audio", c.InputAudio, "input_audio content requires data", ErrCodeMissingParameter)
+		}
+		if c.InputAudio.Format != InputAudioFormatWAV && c.InputAudio.Format != InputAudioFormatMP3 {
+			return NewValidationError("input_audio.format", c.InputAudio.Format, "input audio format must be wav or mp3", ErrCodeInvalidParameter)
+		}
+	case ChatMessageTypeFile:
+		if c.File == nil || (c.File.FileID == "" && c.File.FileData == "") {
+			return NewValidationError("file", c.File, "file content requires file_id or file_data", ErrCodeMissingParameter)
+		}
+	case ChatMessageTypeVideoURL:
+		if c.VideoURL == nil || c.VideoURL.URL == "" {
+			return NewValidationError("video_url", c.VideoURL, "video_url content requires a url", ErrCodeMissingParameter)
+		}
+	default:
+		return NewValidationError("type", c.Type, "unsupported content part type", ErrCodeInvalidParameter)
+	}
+	return nil
+}
+
+// IsValidImageDetail 检查图像细节级别是否有效，空值表示使用服务端默认值
+func IsValidImageDetail(detail string) bool {
+	switch detail {
+	case "", ImageDetailAuto, ImageDetailLow, ImageDetailHigh:
+		return true
+	default:
+		return false
+	}
+}
+
+// UnmarshalJSON 反序列化聊天消息，将内容数组解码为[]MessageContent
+func (m *ChatMessage) UnmarshalJSON(data []byte) error {
+	type alias ChatMessage
+	aux := struct {
+		*alias
+		Content json.RawMessage `json:"content"`
+	}{alias: (*alias)(m)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	content, err := decodeMessageContent(aux.Content)
+	if err != nil {
+		return err
+	}
+	m.Content = content
+	return nil
+}
+
+// decodeMessageContent 解码消息内容，支持字符串、内容部分数组和null
+func decodeMessageContent(raw json.RawMessage) (interface{}, error) {
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")) {
+		return nil, nil
+	}
+
+	switch trimmed[0] {
+	case '"':
+		var text string
+		if err := json.Unmarshal(trimmed, &text); err != nil {
+			return nil, fmt.Errorf("failed to decode message content: %w", err)
+		}
+		return text, nil
+	case '[':
+		var parts []MessageContent
+		if err := json.Unmarshal(trimmed, &parts); err != nil {
+			return nil, fmt.Errorf("failed to decode message content parts: %w", err)
+		}
+		return parts, nil
+	default:
+		return nil, fmt.Errorf("unsupported message content: %s", string(trimmed))
+	}
+}
+
+// GetContentParts 获取内容部分，字符串内容会被包装为单个文本部分
+func (m *ChatMessage) GetContentParts() []MessageContent {
+	switch content := m.Content.(type) {
+	case nil:
+		return nil
+	case string:
+		if content == "" {
+			return nil
+		}
+		return []MessageContent{NewTextPart(content)}
+	case []MessageContent:
+		return content
+	default:
+		// 兼容手工构造的map或切片
+		data, err := json.Marshal(content)
+		if err != nil {
+			return nil
+		}
+		var parts []MessageContent
+		if err := json.Unmarshal(data, &parts); err != nil {
+			return nil
+		}
+		return parts
+	}
+}
+
+// HasContentType 检查消息是否包含指定类型的内容部分
+func (m *ChatMessage) HasContentType(contentType string) bool {
+	for _, part := range m.GetContentParts() {
+		if part.Type == contentType {
+			return true
+		}
+	}
+	return false
+}
+
+// IsMultimodal 检查消息是否包含非文本内容部分
+func (m *ChatMessage) IsMultimodal() bool {
+	for _, part := range m.GetContentParts() {
+		if part.Type != ChatMessageTypeText {
+			return true
+		}
+	}
+	return false
+}
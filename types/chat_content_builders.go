@@ -0,0 +1,193 @@
+package types
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// extensionMIMETypes 常见多模态文件扩展名对应的MIME类型，
+// 优先于系统MIME表使用以保证跨平台结果一致
+var extensionMIMETypes = map[string]string{
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+	".bmp":  "image/bmp",
+	".heic": "image/heic",
+	".heif": "image/heif",
+	".wav":  "audio/wav",
+	".mp3":  "audio/mpeg",
+	".pdf":  "application/pdf",
+	".txt":  "text/plain",
+	".mp4":  "video/mp4",
+	".webm": "video/webm",
+}
+
+// DetectMIMEType 检测MIME类型，依次使用文件扩展名和内容嗅探
+func DetectMIMEType(filename string, data []byte) string {
+	ext := strings.ToLower(filepath.Ext(filename))
+	if mimeType, ok := extensionMIMETypes[ext]; ok {
+		return mimeType
+	}
+	if ext != "" {
+		if mimeType := mime.TypeByExtension(ext); mimeType != "" {
+			return strings.TrimSpace(strings.Split(mimeType, ";")[0])
+		}
+	}
+	if len(data) > 0 {
+		return strings.TrimSpace(strings.Split(http.DetectContentType(data), ";")[0])
+	}
+	return "application/octet-stream"
+}
+
+// NewDataURI 创建base64数据URI
+func NewDataURI(mimeType string, data []byte) string {
+	return fmt.Sprintf("data:%s;base64,%s", mimeType, base64.StdEncoding.EncodeToString(data))
+}
+
+// IsRemoteURL 检查字符串是否为网络地址或数据URI
+func IsRemoteURL(s string) bool {
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://") || strings.HasPrefix(s, "data:")
+}
+
+// NewTextPart 创建文本内容部分
+func NewTextPart(text string) MessageContent {
+	return MessageContent{Type: ChatMessageTypeText, Text: text}
+}
+
+// NewImageURLPart 创建图像URL内容部分
+func NewImageURLPart(url, detail string) MessageContent {
+	return MessageContent{
+		Type:     ChatMessageTypeImageURL,
+		ImageURL: &ImageURL{URL: url, Detail: detail},
+	}
+}
+
+// NewImageDataPart 使用原始图像数据创建base64数据URI内容部分，mimeType为空时自动检测
+func NewImageDataPart(data []byte, mimeType, detail string) MessageContent {
+	if mimeType == "" {
+		mimeType = DetectMIMEType("", data)
+	}
+	return NewImageURLPart(NewDataURI(mimeType, data), detail)
+}
+
+// NewImagePartFromReader 从Reader读取图像并创建内容部分
+func NewImagePartFromReader(r io.Reader, mimeType, detail string) (MessageContent, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return MessageContent{}, fmt.Errorf("failed to read image: %w", err)
+	}
+	return NewImageDataPart(data, mimeType, detail), nil
+}
+
+// NewImagePartFromFile 从本地文件读取图像并创建内容部分
+func NewImagePartFromFile(path, detail string) (MessageContent, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return MessageContent{}, fmt.Errorf("failed to read image file: %w", err)
+	}
+	return NewImageDataPart(data, DetectMIMEType(path, data), detail), nil
+}
+
+// NewInputAudioPart 创建输入音频内容部分
+func NewInputAudioPart(data []byte, format string) MessageContent {
+	return MessageContent{
+		Type: ChatMessageTypeInputAudio,
+		InputAudio: &InputAudio{
+			Data:   base64.StdEncoding.EncodeToString(data),
+			Format: format,
+		},
+	}
+}
+
+// NewInputAudioPartFromFile 从本地文件读取音频并创建内容部分，格式由扩展名决定
+func NewInputAudioPartFromFile(path string) (MessageContent, error) {
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	if format != InputAudioFormatWAV && format != InputAudioFormatMP3 {
+		return MessageContent{}, NewValidationError("path", path, "input audio must be a wav or mp3 file", ErrCodeInvalidParameter)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return MessageContent{}, fmt.Errorf("failed to read audio file: %w", err)
+	}
+	return NewInputAudioPart(data, format), nil
+}
+
+// NewFilePart 使用文件数据创建文件内容部分（如PDF）
+func NewFilePart(filename string, data []byte) MessageContent {
+	return MessageContent{
+		Type: ChatMessageTypeFile,
+		File: &FileContent{
+			Filename: filename,
+			FileData: NewDataURI(DetectMIMEType(filename, data), data),
+		},
+	}
+}
+
+// NewFilePartFromFile 从本地文件读取并创建文件内容部分
+func NewFilePartFromFile(path string) (MessageContent, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return MessageContent{}, fmt.Errorf("failed to read file: %w", err)
+	}
+	return NewFilePart(filepath.Base(path), data), nil
+}
+
+// NewFileIDPart 使用已上传文件的ID创建文件内容部分
+func NewFileIDPart(fileID string) MessageContent {
+	return MessageContent{
+		Type: ChatMessageTypeFile,
+		File: &FileContent{FileID: fileID},
+	}
+}
+
+// NewVideoURLPart 创建视频URL内容部分
+func NewVideoURLPart(url string) MessageContent {
+	return MessageContent{
+		Type:     ChatMessageTypeVideoURL,
+		VideoURL: &VideoURL{URL: url},
+	}
+}
+
+// NewMultipartMessage 创建多模态消息
+func NewMultipartMessage(role string, parts ...MessageContent) ChatMessage {
+	return ChatMessage{
+		Role:    role,
+		Content: parts,
+	}
+}
+
+// NewUserMessageWithParts 创建多模态用户消息
+func NewUserMessageWithParts(parts ...MessageContent) ChatMessage {
+	return NewMultipartMessage(ChatRoleUser, parts...)
+}
+
+// NewUserMessageWithImages 创建带图像的用户消息，
+// images可以是网络地址、数据URI或本地文件路径，本地文件会被编码为数据URI
+func NewUserMessageWithImages(text string, images ...string) (ChatMessage, error) {
+	parts := make([]MessageContent, 0, len(images)+1)
+	if text != "" {
+		parts = append(parts, NewTextPart(text))
+	}
+
+	for _, image := range images {
+		if IsRemoteURL(image) {
+			parts = append(parts, NewImageURLPart(image, ""))
+			continue
+		}
+		part, err := NewImagePartFromFile(image, "")
+		if err != nil {
+			return ChatMessage{}, err
+		}
+		parts = append(parts, part)
+	}
+
+	return NewUserMessageWithParts(parts...), nil
+}
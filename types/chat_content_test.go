@@ -0,0 +1,101 @@
+package types
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestChatMessageContentPartsRoundTrip(t *testing.T) {
+	msg := NewUserMessageWithParts(
+		NewTextPart("describe"),
+		NewImageURLPart("https://example.com/cat.png", ImageDetailHigh),
+		NewInputAudioPart([]byte("RIFF"), InputAudioFormatWAV),
+		NewFileIDPart("file-123"),
+		NewTextPart("briefly"),
+	)
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	if !strings.Contains(string(data), `"image_url":{"url":"https://example.com/cat.png","detail":"high"}`) {
+		t.Errorf("unexpected image_url encoding: %s", data)
+	}
+
+	var decoded ChatMessage
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	parts, ok := decoded.Content.([]MessageContent)
+	if !ok || len(parts) != 5 {
+		t.Fatalf("expected 5 typed parts, got %T", decoded.Content)
+	}
+	if parts[1].ImageURL == nil || parts[1].ImageURL.Detail != ImageDetailHigh {
+		t.Errorf("image part not decoded: %+v", parts[1])
+	}
+	if got := decoded.GetTextContent(); got != "describe\nbriefly" {
+		t.Errorf("GetTextContent() = %q", got)
+	}
+	if !decoded.IsMultimodal() {
+		t.Error("expected multimodal message")
+	}
+
+	var plain ChatMessage
+	if err := json.Unmarshal([]byte(`{"role":"assistant","content":"hi"}`), &plain); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if plain.Content != "hi" {
+		t.Errorf("expected string content, got %#v", plain.Content)
+	}
+}
+
+func TestNewUserMessageWithImages(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pixel.png")
+	if err := os.WriteFile(path, []byte("\x89PNG\r\n\x1a\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := NewUserMessageWithImages("what is this?", path, "https://example.com/a.jpg")
+	if err != nil {
+		t.Fatalf("NewUserMessageWithImages failed: %v", err)
+	}
+	parts := msg.GetContentParts()
+	if len(parts) != 3 {
+		t.Fatalf("expected 3 parts, got %d", len(parts))
+	}
+	if !strings.HasPrefix(parts[1].ImageURL.URL, "data:image/png;base64,") {
+		t.Errorf("expected png data URI, got %s", parts[1].ImageURL.URL)
+	}
+	if parts[2].ImageURL.URL != "https://example.com/a.jpg" {
+		t.Errorf("remote URL should be kept, got %s", parts[2].ImageURL.URL)
+	}
+
+	if _, err := NewUserMessageWithImages("x", filepath.Join(t.TempDir(), "missing.png")); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+func TestStripReasoningContent(t *testing.T) {
+	assistant := NewAssistantMessage("Answer")
+	assistant.ReasoningContent = "Thinking"
+	messages := []ChatMessage{NewUserMessage("hi"), assistant}
+
+	stripped := StripReasoningContent(messages)
+	if len(stripped) != 2 || stripped[1].HasReasoningContent() || stripped[1].GetTextContent() != "Answer" {
+		t.Errorf("stripped = %+v", stripped)
+	}
+	if messages[1].ReasoningContent != "Thinking" {
+		t.Error("input messages should not be modified")
+	}
+
+	data, _ := json.Marshal(stripped[1])
+	if strings.Contains(string(data), "reasoning_content") {
+		t.Errorf("stripped message JSON = %s", data)
+	}
+	if len(StripReasoningContent(nil)) != 0 {
+		t.Error("expected empty result for nil input")
+	}
+}
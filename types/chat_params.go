@@ -0,0 +1,159 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// 输出模态常量
+const (
+	ModalityText  = "text"
+	ModalityAudio = "audio"
+)
+
+// 服务层级常量
+const (
+	ServiceTierAuto    = "auto"
+	ServiceTierDefault = "default"
+	ServiceTierFlex    = "flex"
+)
+
+// 响应格式类型常量
+const (
+	ResponseFormatTypeText       = "text"
+	ResponseFormatTypeJSONObject = "json_object"
+	ResponseFormatTypeJSONSchema = "json_schema"
+)
+
+// 预测输出类型常量
+const (
+	PredictionTypeContent = "content"
+)
+
+// 音频输出格式常量
+const (
+	ChatAudioFormatWAV   = "wav"
+	ChatAudioFormatMP3   = "mp3"
+	ChatAudioFormatFLAC  = "flac"
+	ChatAudioFormatOPUS  = "opus"
+	ChatAudioFormatPCM16 = "pcm16"
+)
+
+// 元数据限制常量
+const (
+	MaxChatMetadataPairs    = 16
+	MaxChatMetadataKeyLen   = 64
+	MaxChatMetadataValueLen = 512
+)
+
+// ChatStreamOptions 流式响应选项结构体
+type ChatStreamOptions struct {
+	IncludeUsage bool `json:"include_usage,omitempty"`
+}
+
+// ChatAudioParams 音频输出参数结构体
+type ChatAudioParams struct {
+	Format string `json:"format"`
+	Voice  string `json:"voice"`
+}
+
+// ChatAudio 模型生成的音频响应结构体
+type ChatAudio struct {
+	ID         string `json:"id,omitempty"`
+	Data       string `json:"data,omitempty"`
+	Transcript string `json:"transcript,omitempty"`
+	ExpiresAt  int64  `json:"expires_at,omitempty"`
+}
+
+// ChatPrediction 预测输出配置结构体
+// Content 可以是字符串或内容部分数组
+type ChatPrediction struct {
+	Type    string      `json:"type"`
+	Content interface{} `json:"content"`
+}
+
+// ChatResponseFormatJSONSchema 结构化输出JSON Schema配置
+type ChatResponseFormatJSONSchema struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Schema      map[string]interface{} `json:"schema,omitempty"`
+	Strict      *bool                  `json:"strict,omitempty"`
+}
+
+// NewStaticPrediction 创建静态内容预测
+func NewStaticPrediction(content string) *ChatPrediction {
+	return &ChatPrediction{
+		Type:    PredictionTypeContent,
+		Content: content,
+	}
+}
+
+// NewJSONSchemaResponseFormat 创建JSON Schema响应格式
+func NewJSONSchemaResponseFormat(name string, schema map[string]interface{}, strict bool) *ChatResponseFormat {
+	return &ChatResponseFormat{
+		Type: ResponseFormatTypeJSONSchema,
+		JSONSchema: &ChatResponseFormatJSONSchema{
+			Name:   name,
+			Schema: schema,
+			Strict: BoolPtr(strict),
+		},
+	}
+}
+
+// Validate 验证音频输出参数
+func (a *ChatAudioParams) Validate() error {
+	switch a.Format {
+	case ChatAudioFormatWAV, ChatAudioFormatMP3, ChatAudioFormatFLAC, ChatAudioFormatOPUS, ChatAudioFormatPCM16:
+	default:
+		return NewValidationError("audio.format", a.Format, "invalid audio format", ErrCodeInvalidParameter)
+	}
+	if a.Voice == "" {
+		return NewValidationError("audio.voice", a.Voice, "audio voice is required", ErrCodeMissingParameter)
+	}
+	return nil
+}
+
+// ValidateChatMetadata 验证请求元数据
+func ValidateChatMetadata(metadata map[string]string) error {
+	if len(metadata) > MaxChatMetadataPairs {
+		return NewValidationError("metadata", len(metadata),
+			fmt.Sprintf("metadata cannot have more than %d pairs", MaxChatMetadataPairs), ErrCodeInvalidParameter)
+	}
+	for key, value := range metadata {
+		if len(key) > MaxChatMetadataKeyLen {
+			return NewValidationError("metadata", key,
+				fmt.Sprintf("metadata key cannot exceed %d characters", MaxChatMetadataKeyLen), ErrCodeInvalidParameter)
+		}
+		if len(value) > MaxChatMetadataValueLen {
+			return NewValidationError(fmt.Sprintf("metadata.%s", key), value,
+				fmt.Sprintf("metadata value cannot exceed %d characters", MaxChatMetadataValueLen), ErrCodeInvalidParameter)
+		}
+	}
+	return nil
+}
+
+// MarshalJSON 序列化请求，并将 ExtraBody 中的参数合并到请求体顶层
+// 用于发送SDK尚未建模的供应商参数，与已有字段同名时 ExtraBody 优先
+func (r ChatCompletionRequest) MarshalJSON() ([]byte, error) {
+	type alias ChatCompletionRequest
+	data, err := json.Marshal(alias(r))
+	if err != nil || len(r.ExtraBody) == 0 {
+		return data, err
+	}
+	return mergeExtraBody(data, r.ExtraBody)
+}
+
+// Float64Ptr 返回float64值的指针
+func Float64Ptr(v float64) *float64 {
+	return &v
+}
+
+// IntPtr 返回int值的指针
+func IntPtr(v int) *int {
+	return &v
+}
+
+// BoolPtr 返回bool值的指针
+func BoolPtr(v bool) *bool {
+	return &v
+}
@@ -0,0 +1,54 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestChatCompletionRequestMarshalExtraBody(t *testing.T) {
+	req := ChatCompletionRequest{
+		Model:       "gpt-4o",
+		Messages:    []ChatMessage{NewUserMessage("hi")},
+		Temperature: Float64Ptr(0.5),
+		ExtraBody:   map[string]interface{}{"top_k": 40, "temperature": 0.2},
+	}
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(data, &body); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if body["top_k"] != float64(40) {
+		t.Errorf("Expected top_k = 40 at the top level, got %v", body["top_k"])
+	}
+	if body["temperature"] != 0.2 {
+		t.Errorf("Expected ExtraBody to override temperature, got %v", body["temperature"])
+	}
+	if body["model"] != "gpt-4o" {
+		t.Errorf("Expected model to be kept, got %v", body["model"])
+	}
+	if _, ok := body["ExtraBody"]; ok {
+		t.Errorf("Expected ExtraBody itself not to be serialized")
+	}
+}
+
+func TestChatCompletionRequestMarshalWithoutExtraBody(t *testing.T) {
+	req := ChatCompletionRequest{Model: "gpt-4o", Messages: []ChatMessage{NewUserMessage("hi")}}
+
+	type alias ChatCompletionRequest
+	want, err := json.Marshal(alias(req))
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	got, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("Marshal() = %s, want %s", got, want)
+	}
+}
@@ -14,6 +14,13 @@ type BaseResponse struct {
 	Data    json.RawMessage `json:"data,omitempty"`
 }
 
+// 响应缓存状态常量
+const (
+	CacheStatusHit    = "hit"
+	CacheStatusMiss   = "miss"
+	CacheStatusBypass = "bypass"
+)
+
 // ErrorResponse API错误响应结构体
 type ErrorResponse struct {
 	Type    string      `json:"type"`
@@ -24,9 +31,25 @@ type ErrorResponse struct {
 
 // Usage 使用量统计结构体
 type Usage struct {
-	PromptTokens     int `json:"prompt_tokens"`
-	CompletionTokens int `json:"completion_tokens"`
-	TotalTokens      int `json:"total_tokens"`
+	PromptTokens            int                      `json:"prompt_tokens"`
+	CompletionTokens        int                      `json:"completion_tokens"`
+	TotalTokens             int                      `json:"total_tokens"`
+	PromptTokensDetails     *PromptTokensDetails     `json:"prompt_tokens_details,omitempty"`
+	CompletionTokensDetails *CompletionTokensDetails `json:"completion_tokens_details,omitempty"`
+}
+
+// PromptTokensDetails 提示Token明细结构体
+type PromptTokensDetails struct {
+	CachedTokens int `json:"cached_tokens"`
+	AudioTokens  int `json:"audio_tokens,omitempty"`
+}
+
+// CompletionTokensDetails 补全Token明细结构体
+type CompletionTokensDetails struct {
+	ReasoningTokens          int `json:"reasoning_tokens"`
+	AudioTokens              int `json:"audio_tokens,omitempty"`
+	AcceptedPredictionTokens int `json:"accepted_prediction_tokens,omitempty"`
+	RejectedPredictionTokens int `json:"rejected_prediction_tokens,omitempty"`
 }
 
 // ListResponse 列表响应结构体
@@ -152,6 +175,34 @@ func (u *Usage) CalculateTotal() {
 	u.TotalTokens = u.PromptTokens + u.CompletionTokens
 }
 
+// GetReasoningTokens 获取推理Token数量
+func (u *Usage) GetReasoningTokens() int {
+	if u.CompletionTokensDetails == nil {
+		return 0
+	}
+	return u.CompletionTokensDetails.ReasoningTokens
+}
+
+// GetCachedTokens 获取缓存命中的提示Token数量
+func (u *Usage) GetCachedTokens() int {
+	if u.PromptTokensDetails == nil {
+		return 0
+	}
+	return u.PromptTokensDetails.CachedTokens
+}
+
+// GetAudioTokens 获取音频Token数量（提示与补全之和）
+func (u *Usage) GetAudioTokens() int {
+	total := 0
+	if u.PromptTokensDetails != nil {
+		total += u.PromptTokensDetails.AudioTokens
+	}
+	if u.CompletionTokensDetails != nil {
+		total += u.CompletionTokensDetails.AudioTokens
+	}
+	return total
+}
+
 // IsEmpty 检查使用量是否为空
 func (u *Usage) IsEmpty() bool {
 	return u.PromptTokens == 0 && u.CompletionTokens == 0 && u.TotalTokens == 0
@@ -0,0 +1,201 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// 文本补全对象类型常量
+const (
+	CompletionObjectText = "text_completion"
+)
+
+// 文本补全的logprobs上限
+const MaxCompletionLogProbs = 5
+
+// CompletionRequest 旧版文本补全请求结构体
+// Prompt 可以是 string、[]string、[]int 或 [][]int
+type CompletionRequest struct {
+	Model            string                 `json:"model"`
+	Prompt           interface{}            `json:"prompt"`
+	Suffix           string                 `json:"suffix,omitempty"`
+	MaxTokens        int                    `json:"max_tokens,omitempty"`
+	Temperature      *float64               `json:"temperature,omitempty"`
+	TopP             *float64               `json:"top_p,omitempty"`
+	N                int                    `json:"n,omitempty"`
+	Stream           bool                   `json:"stream,omitempty"`
+	StreamOptions    *ChatStreamOptions     `json:"stream_options,omitempty"`
+	LogProbs         *int                   `json:"logprobs,omitempty"`
+	Echo             bool                   `json:"echo,omitempty"`
+	Stop             interface{}            `json:"stop,omitempty"`
+	PresencePenalty  *float64               `json:"presence_penalty,omitempty"`
+	FrequencyPenalty *float64               `json:"frequency_penalty,omitempty"`
+	BestOf           int                    `json:"best_of,omitempty"`
+	LogitBias        map[string]float64     `json:"logit_bias,omitempty"`
+	Seed             *int                   `json:"seed,omitempty"`
+	User             string                 `json:"user,omitempty"`
+	ExtraBody        map[string]interface{} `json:"-"`
+}
+
+// CompletionResponse 旧版文本补全响应结构体，流式响应的每个块也使用此结构
+type CompletionResponse struct {
+	ID                string             `json:"id"`
+	Object            string             `json:"object"`
+	Created           int64              `json:"created"`
+	Model             string             `json:"model"`
+	Choices           []CompletionChoice `json:"choices"`
+	Usage             *Usage             `json:"usage,omitempty"`
+	SystemFingerprint string             `json:"system_fingerprint,omitempty"`
+	Error             *ErrorResponse     `json:"error,omitempty"`
+}
+
+// CompletionChoice 文本补全选择结构体
+type CompletionChoice struct {
+	Text         string    `json:"text"`
+	Index        int       `json:"index"`
+	LogProbs     *LogProbs `json:"logprobs,omitempty"`
+	FinishReason string    `json:"finish_reason"`
+}
+
+// MarshalJSON 序列化请求，并将 ExtraBody 中的参数合并到请求体顶层
+func (r CompletionRequest) MarshalJSON() ([]byte, error) {
+	type alias CompletionRequest
+	data, err := json.Marshal(alias(r))
+	if err != nil || len(r.ExtraBody) == 0 {
+		return data, err
+	}
+	return mergeExtraBody(data, r.ExtraBody)
+}
+
+// mergeExtraBody 将额外参数合并到JSON对象中，额外参数优先
+func mergeExtraBody(data []byte, extraBody map[string]interface{}) ([]byte, error) {
+	var body map[string]json.RawMessage
+	if err := json.Unmarshal(data, &body); err != nil {
+		return nil, fmt.Errorf("failed to merge extra body: %w", err)
+	}
+	for key, value := range extraBody {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal extra body field %s: %w", key, err)
+		}
+		body[key] = raw
+	}
+	return json.Marshal(body)
+}
+
+// ValidateParameters 验证请求参数
+func (r *CompletionRequest) ValidateParameters() error {
+	if r.Model == "" {
+		return NewValidationError("model", r.Model, "model is required", ErrCodeMissingParameter)
+	}
+	if err := validateCompletionPrompt(r.Prompt); err != nil {
+		return err
+	}
+	if r.MaxTokens < 0 {
+		return NewValidationError("max_tokens", r.MaxTokens, "max_tokens must be positive", ErrCodeInvalidParameter)
+	}
+	if r.Temperature != nil && (*r.Temperature < 0 || *r.Temperature > 2) {
+		return NewValidationError("temperature", *r.Temperature, "temperature must be between 0 and 2", ErrCodeInvalidParameter)
+	}
+	if r.TopP != nil && (*r.TopP < 0 || *r.TopP > 1) {
+		return NewValidationError("top_p", *r.TopP, "top_p must be between 0 and 1", ErrCodeInvalidParameter)
+	}
+	if r.N < 1 {
+		return NewValidationError("n", r.N, "n must be at least 1", ErrCodeInvalidParameter)
+	}
+	if r.LogProbs != nil && (*r.LogProbs < 0 || *r.LogProbs > MaxCompletionLogProbs) {
+		return NewValidationError("logprobs", *r.LogProbs,
+			fmt.Sprintf("logprobs must be between 0 and %d", MaxCompletionLogProbs), ErrCodeInvalidParameter)
+	}
+	if r.PresencePenalty != nil && (*r.PresencePenalty < -2 || *r.PresencePenalty > 2) {
+		return NewValidationError("presence_penalty", *r.PresencePenalty, "presence_penalty must be between -2 and 2", ErrCodeInvalidParameter)
+	}
+	if r.FrequencyPenalty != nil && (*r.FrequencyPenalty < -2 || *r.FrequencyPenalty > 2) {
+		return NewValidationError("frequency_penalty", *r.FrequencyPenalty, "frequency_penalty must be between -2 and 2", ErrCodeInvalidParameter)
+	}
+	if r.BestOf != 0 {
+		if r.BestOf < r.N {
+			return NewValidationError("best_of", r.BestOf, "best_of must be greater than or equal to n", ErrCodeInvalidParameter)
+		}
+		if r.Stream && r.BestOf > 1 {
+			return NewValidationError("best_of", r.BestOf, "best_of cannot be used with stream", ErrCodeInvalidParameter)
+		}
+	}
+	return nil
+}
+
+// validateCompletionPrompt 验证提示词类型
+func validateCompletionPrompt(prompt interface{}) error {
+	switch p := prompt.(type) {
+	case string:
+		if p == "" {
+			return NewValidationError("prompt", prompt, "prompt cannot be empty", ErrCodeMissingParameter)
+		}
+	case []string:
+		if len(p) == 0 {
+			return NewValidationError("prompt", prompt, "prompt array cannot be empty", ErrCodeMissingParameter)
+		}
+		for i, text := range p {
+			if text == "" {
+				return NewValidationError(fmt.Sprintf("prompt[%d]", i), text, "prompt cannot be empty", ErrCodeInvalidParameter)
+			}
+		}
+	case []int:
+		if len(p) == 0 {
+			return NewValidationError("prompt", prompt, "prompt tokens cannot be empty", ErrCodeMissingParameter)
+		}
+	case [][]int:
+		if len(p) == 0 {
+			return NewValidationError("prompt", prompt, "prompt array cannot be empty", ErrCodeMissingParameter)
+		}
+		for i, tokens := range p {
+			if len(tokens) == 0 {
+				return NewValidationError(fmt.Sprintf("prompt[%d]", i), tokens, "prompt tokens cannot be empty", ErrCodeInvalidParameter)
+			}
+		}
+	case nil:
+		return NewValidationError("prompt", prompt, "prompt is required", ErrCodeMissingParameter)
+	default:
+		return NewValidationError("prompt", prompt, "invalid prompt type", ErrCodeInvalidParameter)
+	}
+	return nil
+}
+
+// SetDefaults 设置默认值
+// 未设置的采样参数保持为 nil，由服务端使用默认值
+func (r *CompletionRequest) SetDefaults() {
+	if r.N == 0 {
+		r.N = 1
+	}
+}
+
+// IsError 检查是否包含错误
+func (r *CompletionResponse) IsError() bool {
+	return r.Error != nil
+}
+
+// GetError 获取错误信息
+func (r *CompletionResponse) GetError() *ErrorResponse {
+	return r.Error
+}
+
+// GetFirstChoice 获取第一个选择
+func (r *CompletionResponse) GetFirstChoice() *CompletionChoice {
+	if len(r.Choices) > 0 {
+		return &r.Choices[0]
+	}
+	return nil
+}
+
+// GetFirstText 获取第一个选择的文本
+func (r *CompletionResponse) GetFirstText() string {
+	if choice := r.GetFirstChoice(); choice != nil {
+		return choice.Text
+	}
+	return ""
+}
+
+// IsFinished 检查是否完成
+func (c *CompletionChoice) IsFinished() bool {
+	return c.FinishReason != ""
+}
@@ -28,13 +28,20 @@ type EmbeddingResponse struct {
 	Model  string         `json:"model"`
 	Usage  Usage          `json:"usage"`
 	Error  *ErrorResponse `json:"error,omitempty"`
+
+	// CacheStatus 响应缓存状态，未配置缓存时为空
+	CacheStatus string `json:"-"`
 }
 
-// Embedding 嵌入向量结构体
+// Embedding 嵌入向量结构体。向量以Embedding或Float32之一存储：
+// 浮点数组格式的响应解码到Embedding，base64格式的响应解码到Float32
 type Embedding struct {
 	Object    string    `json:"object"`
 	Embedding []float64 `json:"embedding"`
 	Index     int       `json:"index"`
+
+	// Float32 float32形式的向量，内存占用为Embedding的一半，与Embedding不会同时存在
+	Float32 []float32 `json:"-"`
 }
 
 // EmbeddingInput 嵌入输入类型
@@ -149,10 +156,10 @@ func (r *EmbeddingRequest) ValidateParameters() error {
 	return nil
 }
 
-// SetDefaults 设置默认值
+// SetDefaults 设置默认值。默认使用base64格式，响应体积约为浮点数组的四分之一
 func (r *EmbeddingRequest) SetDefaults() {
 	if r.EncodingFormat == "" {
-		r.EncodingFormat = EmbeddingEncodingFormatFloat
+		r.EncodingFormat = EmbeddingEncodingFormatBase64
 	}
 }
 
@@ -231,8 +238,8 @@ func (r *EmbeddingResponse) GetEmbeddingByIndex(index int) *Embedding {
 // GetAllEmbeddings 获取所有嵌入向量
 func (r *EmbeddingResponse) GetAllEmbeddings() [][]float64 {
 	embeddings := make([][]float64, len(r.Data))
-	for i, embedding := range r.Data {
-		embeddings[i] = embedding.Embedding
+	for i := range r.Data {
+		embeddings[i] = r.Data[i].Vector()
 	}
 	return embeddings
 }
@@ -249,13 +256,17 @@ func (r *EmbeddingResponse) FromJSON(data []byte) error {
 
 // GetDimensions 获取向量维度
 func (e *Embedding) GetDimensions() int {
-	return len(e.Embedding)
+	if e.Embedding != nil {
+		return len(e.Embedding)
+	}
+	return len(e.Float32)
 }
 
 // GetMagnitude 获取向量模长
 func (e *Embedding) GetMagnitude() float64 {
 	var sum float64
-	for _, val := range e.Embedding {
+	for i := 0; i < e.GetDimensions(); i++ {
+		val := e.value(i)
 		sum += val * val
 	}
 	return sum
@@ -268,20 +279,24 @@ func (e *Embedding) Normalize() {
 		for i := range e.Embedding {
 			e.Embedding[i] /= magnitude
 		}
+		for i := range e.Float32 {
+			e.Float32[i] = float32(float64(e.Float32[i]) / magnitude)
+		}
 	}
 }
 
 // CosineSimilarity 计算余弦相似度
 func (e *Embedding) CosineSimilarity(other *Embedding) float64 {
-	if len(e.Embedding) != len(other.Embedding) {
+	if e.GetDimensions() != other.GetDimensions() {
 		return 0.0
 	}
 
 	var dotProduct, magA, magB float64
-	for i := range e.Embedding {
-		dotProduct += e.Embedding[i] * other.Embedding[i]
-		magA += e.Embedding[i] * e.Embedding[i]
-		magB += other.Embedding[i] * other.Embedding[i]
+	for i := 0; i < e.GetDimensions(); i++ {
+		a, b := e.value(i), other.value(i)
+		dotProduct += a * b
+		magA += a * a
+		magB += b * b
 	}
 
 	if magA == 0 || magB == 0 {
@@ -293,13 +308,13 @@ func (e *Embedding) CosineSimilarity(other *Embedding) float64 {
 
 // DotProduct 计算点积
 func (e *Embedding) DotProduct(other *Embedding) float64 {
-	if len(e.Embedding) != len(other.Embedding) {
+	if e.GetDimensions() != other.GetDimensions() {
 		return 0.0
 	}
 
 	var dotProduct float64
-	for i := range e.Embedding {
-		dotProduct += e.Embedding[i] * other.Embedding[i]
+	for i := 0; i < e.GetDimensions(); i++ {
+		dotProduct += e.value(i) * other.value(i)
 	}
 
 	return dotProduct
@@ -307,13 +322,13 @@ func (e *Embedding) DotProduct(other *Embedding) float64 {
 
 // EuclideanDistance 计算欧几里得距离
 func (e *Embedding) EuclideanDistance(other *Embedding) float64 {
-	if len(e.Embedding) != len(other.Embedding) {
+	if e.GetDimensions() != other.GetDimensions() {
 		return 0.0
 	}
 
 	var sum float64
-	for i := range e.Embedding {
-		diff := e.Embedding[i] - other.Embedding[i]
+	for i := 0; i < e.GetDimensions(); i++ {
+		diff := e.value(i) - other.value(i)
 		sum += diff * diff
 	}
 
@@ -322,7 +337,7 @@ func (e *Embedding) EuclideanDistance(other *Embedding) float64 {
 
 // IsValid 检查嵌入向量是否有效
 func (e *Embedding) IsValid() bool {
-	return len(e.Embedding) > 0
+	return e.GetDimensions() > 0
 }
 
 // ToJSON 转换为JSON字符串
@@ -356,7 +371,7 @@ func (c *EmbeddingConfig) ValidateConfig() error {
 // SetDefaults 设置默认配置
 func (c *EmbeddingConfig) SetDefaults() {
 	if c.EncodingFormat == "" {
-		c.EncodingFormat = EmbeddingEncodingFormatFloat
+		c.EncodingFormat = EmbeddingEncodingFormatBase64
 	}
 }
 
@@ -367,8 +382,8 @@ func CompareEmbeddings(emb1, emb2 *Embedding) *EmbeddingComparison {
 	}
 
 	return &EmbeddingComparison{
-		Embedding1:        emb1.Embedding,
-		Embedding2:        emb2.Embedding,
+		Embedding1:        emb1.Vector(),
+		Embedding2:        emb2.Vector(),
 		CosineSimilarity:  emb1.CosineSimilarity(emb2),
 		DotProduct:        emb1.DotProduct(emb2),
 		EuclideanDistance: emb1.EuclideanDistance(emb2),
@@ -383,7 +398,7 @@ func CalculateStats(embeddings []Embedding) *EmbeddingStats {
 
 	stats := &EmbeddingStats{
 		TotalVectors:    len(embeddings),
-		TotalDimensions: len(embeddings[0].Embedding),
+		TotalDimensions: embeddings[0].GetDimensions(),
 		MinMagnitude:    embeddings[0].GetMagnitude(),
 		MaxMagnitude:    embeddings[0].GetMagnitude(),
 	}
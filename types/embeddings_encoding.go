@@ -0,0 +1,168 @@
+package types
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// UnmarshalJSON 解析嵌入向量，浮点数组解码到Embedding，Base64字符串解码到Float32
+func (e *Embedding) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Object    string          `json:"object"`
+		Embedding json.RawMessage `json:"embedding"`
+		Index     int             `json:"index"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	e.Object, e.Index = raw.Object, raw.Index
+	e.Embedding, e.Float32 = nil, nil
+
+	if len(raw.Embedding) > 0 && raw.Embedding[0] == '"' {
+		var encoded string
+		if err := json.Unmarshal(raw.Embedding, &encoded); err != nil {
+			return err
+		}
+		vector, err := DecodeEmbeddingBase64(encoded)
+		if err != nil {
+			return err
+		}
+		e.Float32 = vector
+		return nil
+	}
+	if len(raw.Embedding) == 0 || string(raw.Embedding) == "null" {
+		return nil
+	}
+	return json.Unmarshal(raw.Embedding, &e.Embedding)
+}
+
+// MarshalJSON 序列化嵌入向量，只有float32形式的向量编码为Base64字符串，与接口的base64格式一致
+func (e Embedding) MarshalJSON() ([]byte, error) {
+	type plain Embedding
+	if e.Embedding != nil || e.Float32 == nil {
+		return json.Marshal(plain(e))
+	}
+
+	return json.Marshal(struct {
+		Object    string `json:"object"`
+		Embedding string `json:"embedding"`
+		Index     int    `json:"index"`
+	}{Object: e.Object, Embedding: EncodeEmbeddingBase64(e.Float32), Index: e.Index})
+}
+
+// Vector 获取float64形式的向量，float32形式存储时返回转换后的副本
+func (e *Embedding) Vector() []float64 {
+	if e.Embedding != nil {
+		return e.Embedding
+	}
+	return Float32sToFloat64s(e.Float32)
+}
+
+// Vector32 获取float32形式的向量，float64形式存储时返回转换后的副本
+func (e *Embedding) Vector32() []float32 {
+	if e.Float32 != nil {
+		return e.Float32
+	}
+	return Float64sToFloat32s(e.Embedding)
+}
+
+// UseFloat32 将向量转为float32形式存储并释放float64形式，内存占用减半
+func (e *Embedding) UseFloat32() {
+	if e.Embedding != nil {
+		e.Float32 = Float64sToFloat32s(e.Embedding)
+		e.Embedding = nil
+	}
+}
+
+// UseFloat64 将向量转为float64形式存储并释放float32形式
+func (e *Embedding) UseFloat64() {
+	if e.Float32 != nil {
+		e.Embedding = Float32sToFloat64s(e.Float32)
+		e.Float32 = nil
+	}
+}
+
+// value 获取第i个分量，兼容两种存储形式
+func (e *Embedding) value(i int) float64 {
+	if e.Embedding != nil {
+		return e.Embedding[i]
+	}
+	return float64(e.Float32[i])
+}
+
+// UseFloat32 将所有向量转为float32形式存储
+func (r *EmbeddingResponse) UseFloat32() {
+	for i := range r.Data {
+		r.Data[i].UseFloat32()
+	}
+}
+
+// UseFloat64 将所有向量转为float64形式存储
+func (r *EmbeddingResponse) UseFloat64() {
+	for i := range r.Data {
+		r.Data[i].UseFloat64()
+	}
+}
+
+// GetAllEmbeddings32 获取所有float32形式的嵌入向量
+func (r *EmbeddingResponse) GetAllEmbeddings32() [][]float32 {
+	embeddings := make([][]float32, len(r.Data))
+	for i := range r.Data {
+		embeddings[i] = r.Data[i].Vector32()
+	}
+	return embeddings
+}
+
+// DecodeEmbeddingBase64 解码base64格式的嵌入向量，内容为小端序的float32数组
+func DecodeEmbeddingBase64(encoded string) ([]float32, error) {
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64 embedding: %w", err)
+	}
+	if len(data)%4 != 0 {
+		return nil, fmt.Errorf("invalid base64 embedding: %d bytes is not a multiple of 4", len(data))
+	}
+
+	vector := make([]float32, len(data)/4)
+	for i := range vector {
+		vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:]))
+	}
+	return vector, nil
+}
+
+// EncodeEmbeddingBase64 将嵌入向量编码为base64格式
+func EncodeEmbeddingBase64(vector []float32) string {
+	data := make([]byte, len(vector)*4)
+	for i, v := range vector {
+		binary.LittleEndian.PutUint32(data[i*4:], math.Float32bits(v))
+	}
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+// Float64sToFloat32s 将float64向量转换为float32向量，nil返回nil
+func Float64sToFloat32s(vector []float64) []float32 {
+	if vector == nil {
+		return nil
+	}
+	converted := make([]float32, len(vector))
+	for i, v := range vector {
+		converted[i] = float32(v)
+	}
+	return converted
+}
+
+// Float32sToFloat64s 将float32向量转换为float64向量，nil返回nil
+func Float32sToFloat64s(vector []float32) []float64 {
+	if vector == nil {
+		return nil
+	}
+	converted := make([]float64, len(vector))
+	for i, v := range vector {
+		converted[i] = float64(v)
+	}
+	return converted
+}
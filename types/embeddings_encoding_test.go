@@ -0,0 +1,95 @@
+package types
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestEmbeddingBase64RoundTrip(t *testing.T) {
+	vector := []float32{0, 1, -0.5, 0.1, math.MaxFloat32, float32(math.Inf(-1))}
+
+	encoded := EncodeEmbeddingBase64(vector)
+	decoded, err := DecodeEmbeddingBase64(encoded)
+	if err != nil {
+		t.Fatalf("DecodeEmbeddingBase64 failed: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, vector) {
+		t.Errorf("decoded = %v, want %v", decoded, vector)
+	}
+
+	// 1.0的小端序float32为00 00 80 3f
+	if got := EncodeEmbeddingBase64([]float32{1}); got != "AACAPw==" {
+		t.Errorf("EncodeEmbeddingBase64([1]) = %q", got)
+	}
+
+	for _, invalid := range []string{"not base64!", "AACA"} {
+		if _, err := DecodeEmbeddingBase64(invalid); err == nil {
+			t.Errorf("DecodeEmbeddingBase64(%q): expected error", invalid)
+		}
+	}
+}
+
+func TestEmbeddingUnmarshalFormats(t *testing.T) {
+	var resp EmbeddingResponse
+	data := `{"object":"list","data":[
+		{"object":"embedding","index":0,"embedding":[1,0.5]},
+		{"object":"embedding","index":1,"embedding":"AACAPwAAAD8="}
+	]}`
+	if err := json.Unmarshal([]byte(data), &resp); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	floats, encoded := resp.Data[0], resp.Data[1]
+	if !reflect.DeepEqual(floats.Embedding, []float64{1, 0.5}) || floats.Float32 != nil {
+		t.Errorf("float embedding = %v / %v", floats.Embedding, floats.Float32)
+	}
+	if !reflect.DeepEqual(encoded.Float32, []float32{1, 0.5}) || encoded.Embedding != nil || encoded.Index != 1 {
+		t.Errorf("base64 embedding = %+v", encoded)
+	}
+	if !reflect.DeepEqual(resp.GetAllEmbeddings(), [][]float64{{1, 0.5}, {1, 0.5}}) {
+		t.Errorf("GetAllEmbeddings() = %v", resp.GetAllEmbeddings())
+	}
+	if !reflect.DeepEqual(resp.GetAllEmbeddings32(), [][]float32{{1, 0.5}, {1, 0.5}}) {
+		t.Errorf("GetAllEmbeddings32() = %v", resp.GetAllEmbeddings32())
+	}
+
+	// 两种存储形式可以直接比较
+	if got := floats.DotProduct(&encoded); got != 1.25 {
+		t.Errorf("DotProduct() = %v, want 1.25", got)
+	}
+	if floats.GetDimensions() != 2 || encoded.GetDimensions() != 2 || !encoded.IsValid() {
+		t.Error("unexpected dimensions")
+	}
+}
+
+func TestEmbeddingMarshalPreservesForm(t *testing.T) {
+	embedding := Embedding{Object: "embedding", Index: 3, Embedding: []float64{1, 0.5}}
+	embedding.UseFloat32()
+	if embedding.Embedding != nil || !reflect.DeepEqual(embedding.Float32, []float32{1, 0.5}) {
+		t.Fatalf("UseFloat32() = %+v", embedding)
+	}
+
+	data, err := json.Marshal(embedding)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if string(data) != `{"object":"embedding","embedding":"AACAPwAAAD8=","index":3}` {
+		t.Errorf("Marshal() = %s", data)
+	}
+
+	var decoded Embedding
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	decoded.UseFloat64()
+	if decoded.Float32 != nil || !reflect.DeepEqual(decoded.Embedding, []float64{1, 0.5}) || decoded.Index != 3 {
+		t.Errorf("round trip = %+v", decoded)
+	}
+
+	data, _ = json.Marshal(decoded)
+	if string(data) != `{"object":"embedding","embedding":[1,0.5],"index":3}` {
+		t.Errorf("Marshal() float64 form = %s", data)
+	}
+}
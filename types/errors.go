@@ -2,8 +2,10 @@ package types
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 )
 
 // 错误码常量
@@ -27,6 +29,11 @@ const (
 	ErrCodePayloadTooLarge   = "payload_too_large"
 	ErrCodeUnsupportedMedia  = "unsupported_media_type"
 
+	// 模型错误
+	ErrCodeContextLengthExceeded = "context_length_exceeded"
+	ErrCodeContentFilter         = "content_filter"
+	ErrCodeContentPolicy         = "content_policy_violation"
+
 	// 服务器错误
 	ErrCodeInternalError      = "internal_error"
 	ErrCodeServiceUnavailable = "service_unavailable"
@@ -65,6 +72,21 @@ const (
 	ErrTypeInternal       = "internal_error"
 )
 
+// ErrorClass 错误分类，用于重试和模型降级等决策
+type ErrorClass string
+
+// 错误分类常量
+const (
+	ErrorClassRateLimit      ErrorClass = "rate_limit"
+	ErrorClassServer         ErrorClass = "server"
+	ErrorClassContextLength  ErrorClass = "context_length"
+	ErrorClassContentFilter  ErrorClass = "content_filter"
+	ErrorClassAuthentication ErrorClass = "authentication"
+	ErrorClassInvalidRequest ErrorClass = "invalid_request"
+	ErrorClassNetwork        ErrorClass = "network"
+	ErrorClassUnknown        ErrorClass = "unknown"
+)
+
 // APIError 自定义API错误类型
 type APIError struct {
 	Type           string      `json:"type"`
@@ -211,6 +233,41 @@ func (e *APIError) IsServerError() bool {
 	return e.HTTPStatusCode >= 500
 }
 
+// Class 获取错误分类。上下文超长和内容过滤通常以400返回，因此优先根据错误码和消息判断
+func (e *APIError) Class() ErrorClass {
+	code := strings.ToLower(e.Code)
+	message := strings.ToLower(e.Message)
+
+	switch {
+	case code == ErrCodeContextLengthExceeded ||
+		strings.Contains(message, "context length") ||
+		strings.Contains(message, "context_length_exceeded") ||
+		strings.Contains(message, "maximum context"):
+		return ErrorClassContextLength
+	case code == ErrCodeContentFilter || code == ErrCodeContentPolicy ||
+		strings.Contains(message, "content filter") ||
+		strings.Contains(message, "content management policy"):
+		return ErrorClassContentFilter
+	case e.HTTPStatusCode == http.StatusTooManyRequests || e.Type == ErrTypeRateLimit ||
+		code == ErrCodeRateLimitExceeded || code == ErrCodeTooManyRequests:
+		return ErrorClassRateLimit
+	case e.HTTPStatusCode >= 500:
+		return ErrorClassServer
+	case e.HTTPStatusCode == http.StatusUnauthorized || e.HTTPStatusCode == http.StatusForbidden ||
+		e.Type == ErrTypeAuthentication || e.Type == ErrTypePermission:
+		return ErrorClassAuthentication
+	case e.IsClientError() || e.Type == ErrTypeInvalidRequest:
+		return ErrorClassInvalidRequest
+	case e.Type == ErrTypeAPIError || e.Type == ErrTypeInternal:
+		// 部分上游在HTTP 200中返回错误体，此时没有状态码可用
+		return ErrorClassServer
+	case e.Type == ErrTypeAPIConnection || e.Type == ErrTypeTimeout:
+		return ErrorClassNetwork
+	default:
+		return ErrorClassUnknown
+	}
+}
+
 // ToJSON 转换为JSON字符串
 func (e *APIError) ToJSON() ([]byte, error) {
 	return json.Marshal(e)
@@ -356,6 +413,27 @@ func IsRetryableError(err error) bool {
 	return false
 }
 
+// ClassifyError 获取错误分类，支持被包装的错误
+func ClassifyError(err error) ErrorClass {
+	if err == nil {
+		return ErrorClassUnknown
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Class()
+	}
+	var netErr *NetworkError
+	if errors.As(err, &netErr) {
+		return ErrorClassNetwork
+	}
+	var streamErr *StreamError
+	if errors.As(err, &streamErr) {
+		return ErrorClassNetwork
+	}
+	return ErrorClassUnknown
+}
+
 // GetErrorCode 获取错误码
 func GetErrorCode(err error) string {
 	if apiErr, ok := err.(*APIError); ok {
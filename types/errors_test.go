@@ -0,0 +1,42 @@
+package types
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestAPIErrorClass(t *testing.T) {
+	tests := []struct {
+		name string
+		err  *APIError
+		want ErrorClass
+	}{
+		{"rate limit status", NewAPIError("", "", "slow down", http.StatusTooManyRequests), ErrorClassRateLimit},
+		{"server error", NewAPIError(ErrTypeAPIError, "", "overloaded", http.StatusServiceUnavailable), ErrorClassServer},
+		{"context length code", NewAPIError(ErrTypeInvalidRequest, ErrCodeContextLengthExceeded, "too long", http.StatusBadRequest), ErrorClassContextLength},
+		{"context length message", NewAPIError(ErrTypeInvalidRequest, "", "This model's maximum context length is 8192 tokens", http.StatusBadRequest), ErrorClassContextLength},
+		{"content filter", NewAPIError(ErrTypeInvalidRequest, ErrCodeContentFilter, "blocked", http.StatusBadRequest), ErrorClassContentFilter},
+		{"authentication", NewAPIError(ErrTypeAuthentication, ErrCodeInvalidAPIKey, "bad key", http.StatusUnauthorized), ErrorClassAuthentication},
+		{"invalid request", NewAPIError(ErrTypeInvalidRequest, "", "bad", http.StatusBadRequest), ErrorClassInvalidRequest},
+		{"error body without status", NewAPIError(ErrTypeAPIError, "", "upstream failed", 0), ErrorClassServer},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.err.Class(); got != tt.want {
+				t.Errorf("Class() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassifyWrappedError(t *testing.T) {
+	err := fmt.Errorf("request failed: %w", NewAPIError("", "", "busy", http.StatusTooManyRequests))
+	if got := ClassifyError(err); got != ErrorClassRateLimit {
+		t.Errorf("ClassifyError() = %s, want %s", got, ErrorClassRateLimit)
+	}
+	if got := ClassifyError(fmt.Errorf("plain")); got != ErrorClassUnknown {
+		t.Errorf("ClassifyError() = %s, want %s", got, ErrorClassUnknown)
+	}
+}
@@ -0,0 +1,288 @@
+package types
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// TokenSpan Token在内容中的位置。Start/End为字节偏移，可直接用于content[Start:End]；
+// CharStart/CharEnd为字符（rune）偏移。无法在内容中定位的Token偏移均为-1
+type TokenSpan struct {
+	Index     int     `json:"index"`
+	Token     string  `json:"token"`
+	Logprob   float64 `json:"logprob"`
+	Start     int     `json:"start"`
+	End       int     `json:"end"`
+	CharStart int     `json:"char_start"`
+	CharEnd   int     `json:"char_end"`
+}
+
+// Probability 获取Token的概率
+func (t ChatCompletionTokenLogprob) Probability() float64 {
+	return math.Exp(t.Logprob)
+}
+
+// Entropy 根据top_logprobs计算Token位置的熵（单位nat）。
+// 候选Token的概率先归一化，因此结果是对完整分布熵的近似；没有候选Token时返回0
+func (t ChatCompletionTokenLogprob) Entropy() float64 {
+	if len(t.TopLogprobs) == 0 {
+		return 0
+	}
+
+	total := 0.0
+	for _, top := range t.TopLogprobs {
+		total += math.Exp(top.Logprob)
+	}
+	if total == 0 {
+		return 0
+	}
+
+	entropy := 0.0
+	for _, top := range t.TopLogprobs {
+		if p := math.Exp(top.Logprob) / total; p > 0 {
+			entropy -= p * math.Log(p)
+		}
+	}
+	return entropy
+}
+
+// bytes 获取Token的原始字节，Bytes缺失时使用Token文本
+func (t ChatCompletionTokenLogprob) bytes() []byte {
+	if len(t.Bytes) == 0 {
+		return []byte(t.Token)
+	}
+	data := make([]byte, len(t.Bytes))
+	for i, b := range t.Bytes {
+		data[i] = byte(b)
+	}
+	return data
+}
+
+// Entries 获取逐Token的对数概率。聊天接口直接返回Content，
+// 旧版补全接口的Tokens、TokenLogprobs和TopLogprobs转换为相同结构，候选Token按概率降序排列
+func (l *LogProbs) Entries() []ChatCompletionTokenLogprob {
+	if l == nil {
+		return nil
+	}
+	if len(l.Content) > 0 {
+		return l.Content
+	}
+
+	entries := make([]ChatCompletionTokenLogprob, len(l.Tokens))
+	for i, token := range l.Tokens {
+		entries[i].Token = token
+		if i < len(l.TokenLogprobs) {
+			entries[i].Logprob = l.TokenLogprobs[i]
+		}
+		if i < len(l.TopLogprobs) {
+			for candidate, logprob := range l.TopLogprobs[i] {
+				entries[i].TopLogprobs = append(entries[i].TopLogprobs, TopLogprob{Token: candidate, Logprob: logprob})
+			}
+			sort.Slice(entries[i].TopLogprobs, func(a, b int) bool {
+				return entries[i].TopLogprobs[a].Logprob > entries[i].TopLogprobs[b].Logprob
+			})
+		}
+	}
+	return entries
+}
+
+// Len 获取Token数量
+func (l *LogProbs) Len() int {
+	if l == nil {
+		return 0
+	}
+	if len(l.Content) > 0 {
+		return len(l.Content)
+	}
+	return len(l.Tokens)
+}
+
+// SequenceLogProb 获取序列的对数似然，即所有Token对数概率之和
+func (l *LogProbs) SequenceLogProb() float64 {
+	sum := 0.0
+	for _, entry := range l.Entries() {
+		sum += entry.Logprob
+	}
+	return sum
+}
+
+// MeanLogProb 获取Token的平均对数概率，没有Token时返回0
+func (l *LogProbs) MeanLogProb() float64 {
+	n := l.Len()
+	if n == 0 {
+		return 0
+	}
+	return l.SequenceLogProb() / float64(n)
+}
+
+// Perplexity 获取序列的困惑度 exp(-平均对数概率)，没有Token时返回0
+func (l *LogProbs) Perplexity() float64 {
+	if l.Len() == 0 {
+		return 0
+	}
+	return math.Exp(-l.MeanLogProb())
+}
+
+// Entropies 获取每个Token位置的熵，见ChatCompletionTokenLogprob.Entropy
+func (l *LogProbs) Entropies() []float64 {
+	entries := l.Entries()
+	entropies := make([]float64, len(entries))
+	for i, entry := range entries {
+		entropies[i] = entry.Entropy()
+	}
+	return entropies
+}
+
+// LabelProbabilities 获取分类标签的概率。取第一个非空白Token位置的候选Token，
+// 忽略大小写和首尾空白后与标签相同或为标签前缀的候选概率累加到该标签
+func (l *LogProbs) LabelProbabilities(labels ...string) map[string]float64 {
+	probabilities := make(map[string]float64, len(labels))
+	for _, label := range labels {
+		probabilities[label] = 0
+	}
+
+	entry, ok := firstContentEntry(l.Entries())
+	if !ok {
+		return probabilities
+	}
+
+	candidates := entry.TopLogprobs
+	if !containsTopToken(candidates, entry.Token) {
+		candidates = append([]TopLogprob{{Token: entry.Token, Logprob: entry.Logprob}}, candidates...)
+	}
+
+	for _, candidate := range candidates {
+		token := normalizeLabel(candidate.Token)
+		if token == "" {
+			continue
+		}
+		for _, label := range labels {
+			if strings.HasPrefix(normalizeLabel(label), token) {
+				probabilities[label] += math.Exp(candidate.Logprob)
+			}
+		}
+	}
+	return probabilities
+}
+
+// Classify 获取概率最高的标签及其置信度，置信度为该标签概率在所有标签概率之和中的占比。
+// 没有候选Token匹配任何标签时返回空标签和0
+func (l *LogProbs) Classify(labels ...string) (string, float64) {
+	probabilities := l.LabelProbabilities(labels...)
+
+	best, bestProb, total := "", 0.0, 0.0
+	for _, label := range labels {
+		p := probabilities[label]
+		total += p
+		if p > bestProb {
+			best, bestProb = label, p
+		}
+	}
+	if total == 0 {
+		return "", 0
+	}
+	return best, bestProb / total
+}
+
+// Align 将Token对齐到内容中的位置。Token按顺序拼接应与内容一致，
+// 不一致时在剩余内容中查找Token以重新同步，找不到的Token偏移为-1
+func (l *LogProbs) Align(content string) []TokenSpan {
+	entries := l.Entries()
+	spans := make([]TokenSpan, len(entries))
+
+	pos := 0
+	for i, entry := range entries {
+		span := TokenSpan{Index: i, Token: entry.Token, Logprob: entry.Logprob, Start: -1, End: -1, CharStart: -1, CharEnd: -1}
+
+		data := string(entry.bytes())
+		start := -1
+		if strings.HasPrefix(content[pos:], data) {
+			start = pos
+		} else if data != "" {
+			if idx := strings.Index(content[pos:], data); idx >= 0 {
+				start = pos + idx
+			}
+		}
+
+		if start >= 0 {
+			span.Start, span.End = start, start+len(data)
+			span.CharStart, span.CharEnd = charOffset(content, span.Start), charOffset(content, span.End)
+			pos = span.End
+		}
+		spans[i] = span
+	}
+	return spans
+}
+
+// LowConfidenceSpans 获取概率低于minProbability的Token及其位置，可用于标记可能的幻觉内容
+func (l *LogProbs) LowConfidenceSpans(content string, minProbability float64) []TokenSpan {
+	threshold := math.Log(minProbability)
+
+	var spans []TokenSpan
+	for _, span := range l.Align(content) {
+		if span.Logprob < threshold {
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
+
+// MergeLogProbs 追加流式块中的对数概率，dst为nil时创建新的结构
+func MergeLogProbs(dst, src *LogProbs) *LogProbs {
+	if src == nil {
+		return dst
+	}
+	if dst == nil {
+		dst = &LogProbs{}
+	}
+	dst.Tokens = append(dst.Tokens, src.Tokens...)
+	dst.TokenLogprobs = append(dst.TokenLogprobs, src.TokenLogprobs...)
+	dst.TopLogprobs = append(dst.TopLogprobs, src.TopLogprobs...)
+	dst.TextOffset = append(dst.TextOffset, src.TextOffset...)
+	dst.Content = append(dst.Content, src.Content...)
+	return dst
+}
+
+// firstContentEntry 获取第一个非空白Token
+func firstContentEntry(entries []ChatCompletionTokenLogprob) (ChatCompletionTokenLogprob, bool) {
+	for _, entry := range entries {
+		if strings.TrimSpace(entry.Token) != "" {
+			return entry, true
+		}
+	}
+	return ChatCompletionTokenLogprob{}, false
+}
+
+// containsTopToken 检查候选Token中是否包含指定Token
+func containsTopToken(candidates []TopLogprob, token string) bool {
+	for _, candidate := range candidates {
+		if candidate.Token == token {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeLabel 规范化标签用于比较
+func normalizeLabel(label string) string {
+	return strings.ToLower(strings.TrimSpace(label))
+}
+
+// charOffset 将字节偏移转换为字符偏移，位于多字节字符中间的偏移计入该字符
+func charOffset(content string, byteOffset int) int {
+	if byteOffset >= len(content) {
+		return utf8.RuneCountInString(content)
+	}
+	count := utf8.RuneCountInString(content[:byteOffset])
+	if !utf8.RuneStart(content[byteOffset]) {
+		// content[:byteOffset]末尾的不完整字符被计为多个无效字符，按字节回退修正
+		start := byteOffset
+		for start > 0 && !utf8.RuneStart(content[start]) {
+			start--
+		}
+		count = utf8.RuneCountInString(content[:start]) + 1
+	}
+	return count
+}
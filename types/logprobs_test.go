@@ -0,0 +1,137 @@
+package types
+
+import (
+	"math"
+	"testing"
+)
+
+// approxEqual 比较浮点数
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestLogProbsSequenceMetrics(t *testing.T) {
+	logprobs := &LogProbs{Content: []ChatCompletionTokenLogprob{
+		{Token: "Hello", Logprob: math.Log(0.5)},
+		{Token: " world", Logprob: math.Log(0.25)},
+	}}
+
+	if got := logprobs.SequenceLogProb(); !approxEqual(got, math.Log(0.125)) {
+		t.Errorf("SequenceLogProb() = %v", got)
+	}
+	// exp(-(ln 0.5 + ln 0.25) / 2) = 1 / sqrt(0.125)
+	if got := logprobs.Perplexity(); !approxEqual(got, 1/math.Sqrt(0.125)) {
+		t.Errorf("Perplexity() = %v", got)
+	}
+
+	var empty *LogProbs
+	if empty.Perplexity() != 0 || empty.SequenceLogProb() != 0 || empty.Len() != 0 {
+		t.Error("nil logprobs should report zero metrics")
+	}
+}
+
+func TestTokenEntropy(t *testing.T) {
+	uniform := ChatCompletionTokenLogprob{TopLogprobs: []TopLogprob{
+		{Token: "a", Logprob: math.Log(0.25)},
+		{Token: "b", Logprob: math.Log(0.25)},
+	}}
+	// 归一化后为两个等概率候选，熵为ln 2
+	if got := uniform.Entropy(); !approxEqual(got, math.Ln2) {
+		t.Errorf("Entropy() = %v, want ln 2", got)
+	}
+
+	certain := ChatCompletionTokenLogprob{TopLogprobs: []TopLogprob{{Token: "a", Logprob: 0}}}
+	if got := certain.Entropy(); !approxEqual(got, 0) {
+		t.Errorf("Entropy() of certain token = %v", got)
+	}
+}
+
+func TestClassify(t *testing.T) {
+	logprobs := &LogProbs{Content: []ChatCompletionTokenLogprob{{
+		Token:   "Yes",
+		Logprob: math.Log(0.6),
+		TopLogprobs: []TopLogprob{
+			{Token: "Yes", Logprob: math.Log(0.6)},
+			{Token: " yes", Logprob: math.Log(0.1)},
+			{Token: "No", Logprob: math.Log(0.2)},
+			{Token: "Maybe", Logprob: math.Log(0.1)},
+		},
+	}}}
+
+	probabilities := logprobs.LabelProbabilities("yes", "no")
+	if !approxEqual(probabilities["yes"], 0.7) || !approxEqual(probabilities["no"], 0.2) {
+		t.Errorf("LabelProbabilities() = %v", probabilities)
+	}
+
+	label, confidence := logprobs.Classify("yes", "no")
+	if label != "yes" || !approxEqual(confidence, 0.7/0.9) {
+		t.Errorf("Classify() = %q, %v", label, confidence)
+	}
+
+	if label, confidence := logprobs.Classify("unrelated"); label != "" || confidence != 0 {
+		t.Errorf("Classify(unrelated) = %q, %v", label, confidence)
+	}
+}
+
+func TestAlignMultibyteTokens(t *testing.T) {
+	content := "你好 world"
+	// "你" 的三个字节被拆分为两个Token
+	logprobs := &LogProbs{Content: []ChatCompletionTokenLogprob{
+		{Token: "\\xe4\\xbd", Bytes: []int{0xe4, 0xbd}, Logprob: -0.1},
+		{Token: "\\xa0", Bytes: []int{0xa0}, Logprob: -0.1},
+		{Token: "好", Logprob: -3},
+		{Token: " world", Logprob: -0.2},
+	}}
+
+	spans := logprobs.Align(content)
+	want := []struct{ start, end, charStart, charEnd int }{
+		{0, 2, 0, 1},
+		{2, 3, 1, 1},
+		{3, 6, 1, 2},
+		{6, 12, 2, 8},
+	}
+	for i, w := range want {
+		s := spans[i]
+		if s.Start != w.start || s.End != w.end || s.CharStart != w.charStart || s.CharEnd != w.charEnd {
+			t.Errorf("span %d = %+v, want %+v", i, s, w)
+		}
+	}
+
+	low := logprobs.LowConfidenceSpans(content, 0.5)
+	if len(low) != 1 || content[low[0].Start:low[0].End] != "好" {
+		t.Errorf("LowConfidenceSpans() = %+v", low)
+	}
+}
+
+func TestLegacyLogProbsAndMerge(t *testing.T) {
+	first := &LogProbs{
+		Tokens:        []string{"Hi"},
+		TokenLogprobs: []float64{-0.5},
+		TopLogprobs:   []map[string]float64{{"Hi": -0.5, "Hey": -1.5}},
+		TextOffset:    []int{0},
+	}
+	second := &LogProbs{
+		Tokens:        []string{"!"},
+		TokenLogprobs: []float64{-0.25},
+		TopLogprobs:   []map[string]float64{{"!": -0.25}},
+		TextOffset:    []int{2},
+	}
+
+	merged := MergeLogProbs(MergeLogProbs(nil, first), second)
+	entries := merged.Entries()
+	if len(entries) != 2 || entries[1].Token != "!" {
+		t.Fatalf("Entries() = %+v", entries)
+	}
+	if entries[0].TopLogprobs[0].Token != "Hi" || entries[0].TopLogprobs[1].Token != "Hey" {
+		t.Errorf("top logprobs should be sorted by probability: %+v", entries[0].TopLogprobs)
+	}
+	if got := merged.SequenceLogProb(); !approxEqual(got, -0.75) {
+		t.Errorf("SequenceLogProb() = %v", got)
+	}
+
+	chat := MergeLogProbs(&LogProbs{Content: []ChatCompletionTokenLogprob{{Token: "a"}}},
+		&LogProbs{Content: []ChatCompletionTokenLogprob{{Token: "b"}}})
+	if chat.Len() != 2 {
+		t.Errorf("merged chat logprobs = %+v", chat.Content)
+	}
+}
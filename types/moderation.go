@@ -0,0 +1,256 @@
+package types
+
+import (
+	"sort"
+)
+
+// 审核模型常量
+const (
+	ModerationModelOmniLatest = "omni-moderation-latest"
+	ModerationModelTextLatest = "text-moderation-latest"
+)
+
+// 审核输入类型常量
+const (
+	ModerationInputTypeText     = "text"
+	ModerationInputTypeImageURL = "image_url"
+)
+
+// ModerationCategory 审核类别
+type ModerationCategory string
+
+// 审核类别常量
+const (
+	ModerationHarassment            ModerationCategory = "harassment"
+	ModerationHarassmentThreatening ModerationCategory = "harassment/threatening"
+	ModerationHate                  ModerationCategory = "hate"
+	ModerationHateThreatening       ModerationCategory = "hate/threatening"
+	ModerationIllicit               ModerationCategory = "illicit"
+	ModerationIllicitViolent        ModerationCategory = "illicit/violent"
+	ModerationSelfHarm              ModerationCategory = "self-harm"
+	ModerationSelfHarmIntent        ModerationCategory = "self-harm/intent"
+	ModerationSelfHarmInstructions  ModerationCategory = "self-harm/instructions"
+	ModerationSexual                ModerationCategory = "sexual"
+	ModerationSexualMinors          ModerationCategory = "sexual/minors"
+	ModerationViolence              ModerationCategory = "violence"
+	ModerationViolenceGraphic       ModerationCategory = "violence/graphic"
+)
+
+// ModerationInput 多模态审核输入项
+type ModerationInput struct {
+	Type     string              `json:"type"`
+	Text     string              `json:"text,omitempty"`
+	ImageURL *ModerationImageURL `json:"image_url,omitempty"`
+}
+
+// ModerationImageURL 审核图片地址
+type ModerationImageURL struct {
+	URL string `json:"url"`
+}
+
+// ModerationRequest 审核请求结构体
+// Input 可以是 string、[]string 或 []ModerationInput
+type ModerationRequest struct {
+	Input interface{} `json:"input"`
+	Model string      `json:"model,omitempty"`
+}
+
+// ModerationResponse 审核响应结构体
+type ModerationResponse struct {
+	ID      string             `json:"id"`
+	Model   string             `json:"model"`
+	Results []ModerationResult `json:"results"`
+	Error   *ErrorResponse     `json:"error,omitempty"`
+}
+
+// ModerationResult 单个输入的审核结果
+type ModerationResult struct {
+	Flagged                   bool                            `json:"flagged"`
+	Categories                map[ModerationCategory]bool     `json:"categories"`
+	CategoryScores            map[ModerationCategory]float64  `json:"category_scores"`
+	CategoryAppliedInputTypes map[ModerationCategory][]string `json:"category_applied_input_types,omitempty"`
+}
+
+// ModerationAnnotation 聊天响应的审核标注，未启用或未检查的阶段为nil
+type ModerationAnnotation struct {
+	Input  *ModerationVerdict `json:"input,omitempty"`
+	Output *ModerationVerdict `json:"output,omitempty"`
+}
+
+// ModerationVerdict 按阈值评估后的审核结论
+type ModerationVerdict struct {
+	Flagged    bool                 `json:"flagged"`
+	Categories []ModerationCategory `json:"categories,omitempty"`
+	Result     ModerationResult     `json:"result"`
+}
+
+// NewModerationTextInput 创建文本审核输入
+func NewModerationTextInput(text string) ModerationInput {
+	return ModerationInput{Type: ModerationInputTypeText, Text: text}
+}
+
+// NewModerationImageInput 创建图片审核输入
+func NewModerationImageInput(url string) ModerationInput {
+	return ModerationInput{Type: ModerationInputTypeImageURL, ImageURL: &ModerationImageURL{URL: url}}
+}
+
+// ValidateParameters 验证请求参数
+func (r *ModerationRequest) ValidateParameters() error {
+	switch input := r.Input.(type) {
+	case string:
+		if input == "" {
+			return NewValidationError("input", r.Input, "input text cannot be empty", ErrCodeMissingParameter)
+		}
+	case []string:
+		if len(input) == 0 {
+			return NewValidationError("input", r.Input, "input array cannot be empty", ErrCodeMissingParameter)
+		}
+	case []ModerationInput:
+		if len(input) == 0 {
+			return NewValidationError("input", r.Input, "input array cannot be empty", ErrCodeMissingParameter)
+		}
+		for _, item := range input {
+			if err := item.Validate(); err != nil {
+				return err
+			}
+		}
+	default:
+		return NewValidationError("input", r.Input, "invalid input type", ErrCodeInvalidParameter)
+	}
+	return nil
+}
+
+// Validate 验证审核输入项
+func (i *ModerationInput) Validate() error {
+	switch i.Type {
+	case ModerationInputTypeText:
+		if i.Text == "" {
+			return NewValidationError("input.text", i.Text, "text cannot be empty", ErrCodeMissingParameter)
+		}
+	case ModerationInputTypeImageURL:
+		if i.ImageURL == nil || i.ImageURL.URL == "" {
+			return NewValidationError("input.image_url", i.ImageURL, "image url cannot be empty", ErrCodeMissingParameter)
+		}
+	default:
+		return NewValidationError("input.type", i.Type, "unsupported moderation input type", ErrCodeInvalidParameter)
+	}
+	return nil
+}
+
+// IsError 检查是否包含错误
+func (r *ModerationResponse) IsError() bool {
+	return r.Error != nil
+}
+
+// GetError 获取错误信息
+func (r *ModerationResponse) GetError() *ErrorResponse {
+	return r.Error
+}
+
+// IsFlagged 检查是否有任意输入被标记
+func (r *ModerationResponse) IsFlagged() bool {
+	for _, result := range r.Results {
+		if result.Flagged {
+			return true
+		}
+	}
+	return false
+}
+
+// Score 获取类别的得分
+func (r *ModerationResult) Score(category ModerationCategory) float64 {
+	return r.CategoryScores[category]
+}
+
+// FlaggedCategories 获取服务端标记的类别，按名称排序
+func (r *ModerationResult) FlaggedCategories() []ModerationCategory {
+	categories := make([]ModerationCategory, 0)
+	for category, flagged := range r.Categories {
+		if flagged {
+			categories = append(categories, category)
+		}
+	}
+	sortCategories(categories)
+	return categories
+}
+
+// Violations 获取超出阈值的类别，按名称排序。
+// 阈值中列出的类别在得分不低于阈值时违规，其余类别沿用服务端的标记
+func (r *ModerationResult) Violations(thresholds map[ModerationCategory]float64) []ModerationCategory {
+	violated := make(map[ModerationCategory]bool)
+	for category, flagged := range r.Categories {
+		if _, ok := thresholds[category]; !ok && flagged {
+			violated[category] = true
+		}
+	}
+	for category, threshold := range thresholds {
+		if score, ok := r.CategoryScores[category]; ok && score >= threshold {
+			violated[category] = true
+		}
+	}
+
+	categories := make([]ModerationCategory, 0, len(violated))
+	for category := range violated {
+		categories = append(categories, category)
+	}
+	sortCategories(categories)
+	return categories
+}
+
+// Evaluate 按阈值评估审核结果
+func (r *ModerationResult) Evaluate(thresholds map[ModerationCategory]float64) *ModerationVerdict {
+	categories := r.Violations(thresholds)
+	return &ModerationVerdict{
+		Flagged:    len(categories) > 0,
+		Categories: categories,
+		Result:     *r,
+	}
+}
+
+// MergeModerationResults 合并多个审核结果：任一结果标记即标记，得分取最大值
+func MergeModerationResults(results []ModerationResult) ModerationResult {
+	merged := ModerationResult{
+		Categories:     make(map[ModerationCategory]bool),
+		CategoryScores: make(map[ModerationCategory]float64),
+	}
+	for _, result := range results {
+		merged.Flagged = merged.Flagged || result.Flagged
+		for category, flagged := range result.Categories {
+			merged.Categories[category] = merged.Categories[category] || flagged
+		}
+		for category, score := range result.CategoryScores {
+			if score > merged.CategoryScores[category] {
+				merged.CategoryScores[category] = score
+			}
+		}
+		for category, inputTypes := range result.CategoryAppliedInputTypes {
+			if merged.CategoryAppliedInputTypes == nil {
+				merged.CategoryAppliedInputTypes = make(map[ModerationCategory][]string)
+			}
+			merged.CategoryAppliedInputTypes[category] = appendUnique(merged.CategoryAppliedInputTypes[category], inputTypes...)
+		}
+	}
+	return merged
+}
+
+// appendUnique 追加不重复的字符串
+func appendUnique(dst []string, values ...string) []string {
+	for _, value := range values {
+		found := false
+		for _, existing := range dst {
+			if existing == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			dst = append(dst, value)
+		}
+	}
+	return dst
+}
+
+// sortCategories 按名称排序类别
+func sortCategories(categories []ModerationCategory) {
+	sort.Slice(categories, func(i, j int) bool { return categories[i] < categories[j] })
+}
@@ -39,6 +39,18 @@ const (
 	StreamStateClosed     = "closed"
 )
 
+// 流式结束方式常量
+const (
+	// StreamEndDone 收到[DONE]标记
+	StreamEndDone = "done"
+	// StreamEndEOF 连接在没有[DONE]标记的情况下正常结束
+	StreamEndEOF = "eof"
+	// StreamEndError 读取出错
+	StreamEndError = "error"
+	// StreamEndClosed 调用方在流结束前关闭
+	StreamEndClosed = "closed"
+)
+
 // StreamEvent 流式事件结构体
 type StreamEvent struct {
 	Type      string          `json:"type"`
@@ -63,15 +75,18 @@ type StreamResponse interface {
 	Context() context.Context
 }
 
-// StreamReader 流式读取器
+// StreamReader 流式读取器，在调用方的goroutine中同步读取事件。
+// 底层读取器实现io.Closer时，上下文取消或Close会关闭它，使阻塞中的Next返回
 type StreamReader struct {
 	reader    *bufio.Reader
+	closer    io.Closer
 	ctx       context.Context
 	cancel    context.CancelFunc
+	stop      func() bool
+	released  sync.Once
 	err       error
 	done      bool
 	mutex     sync.RWMutex
-	events    chan *StreamEvent
 	closed    bool
 	state     string
 	startTime time.Time
@@ -90,6 +105,7 @@ type StreamProcessor struct {
 	handlers map[string]func(*StreamEvent) error
 	mutex    sync.RWMutex
 	running  bool
+	stopped  chan struct{}
 	ctx      context.Context
 	cancel   context.CancelFunc
 }
@@ -104,6 +120,33 @@ type StreamStats struct {
 	BytesSent     int64         `json:"bytes_sent"`
 	ErrorCount    int           `json:"error_count"`
 	State         string        `json:"state"`
+
+	// Model 实际服务的模型
+	Model string `json:"model,omitempty"`
+	// TimeToFirstByte 从发起请求到收到第一个响应体字节的时间
+	TimeToFirstByte time.Duration `json:"time_to_first_byte"`
+	// TimeToFirstToken 从发起请求到收到第一个包含内容增量的数据块的时间
+	TimeToFirstToken time.Duration `json:"time_to_first_token"`
+	// InterChunkLatency 相邻的包含内容增量的数据块之间的间隔分布。一个数据块可能包含多个Token，不是逐Token的延迟
+	InterChunkLatency LatencyPercentiles `json:"inter_chunk_latency"`
+	// CompletionTokens 服务端返回的生成Token数，未返回用量时为包含内容增量的数据块数
+	CompletionTokens int `json:"completion_tokens"`
+	// TokensPerSecond 从第一个内容增量到流结束的生成速度，未返回用量时为每秒数据块数
+	TokensPerSecond float64 `json:"tokens_per_second"`
+	// FinishReason 最后一个完成原因
+	FinishReason string `json:"finish_reason,omitempty"`
+	// EndReason 结束方式，取值为StreamEnd常量
+	EndReason string `json:"end_reason"`
+	// Error 流出错时的错误信息
+	Error string `json:"error,omitempty"`
+}
+
+// LatencyPercentiles 延迟分位数
+type LatencyPercentiles struct {
+	P50 time.Duration `json:"p50"`
+	P90 time.Duration `json:"p90"`
+	P99 time.Duration `json:"p99"`
+	Max time.Duration `json:"max"`
 }
 
 // StreamConfig 流式配置
@@ -124,15 +167,20 @@ func NewStreamReader(reader io.Reader, ctx context.Context) *StreamReader {
 	}
 
 	ctx, cancel := context.WithCancel(ctx)
-
-	return &StreamReader{
+	r := &StreamReader{
 		reader:    bufio.NewReader(reader),
 		ctx:       ctx,
 		cancel:    cancel,
-		events:    make(chan *StreamEvent, 100),
+		stop:      func() bool { return false },
 		state:     StreamStateConnecting,
 		startTime: time.Now(),
 	}
+	if closer, ok := reader.(io.Closer); ok {
+		r.closer = closer
+		// 上下文取消时关闭底层读取器，使阻塞中的读取返回
+		r.stop = context.AfterFunc(ctx, func() { closer.Close() })
+	}
+	return r
 }
 
 // NewStreamWriter 创建新的流式写入器
@@ -142,9 +190,13 @@ func NewStreamWriter(writer io.Writer) *StreamWriter {
 	}
 }
 
-// NewStreamProcessor 创建新的流式处理器
+// NewStreamProcessor 创建新的流式处理器，使用流的上下文
 func NewStreamProcessor(reader StreamResponse) *StreamProcessor {
-	ctx, cancel := context.WithCancel(context.Background())
+	parent := reader.Context()
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithCancel(parent)
 
 	return &StreamProcessor{
 		reader:   reader,
@@ -163,14 +215,10 @@ func (r *StreamReader) Next() (*StreamEvent, error) {
 	}
 	r.mutex.RUnlock()
 
-	select {
-	case <-r.ctx.Done():
-		return nil, r.ctx.Err()
-	case event := <-r.events:
-		return event, nil
-	default:
-		return r.readEvent()
+	if err := r.ctx.Err(); err != nil {
+		return nil, err
 	}
+	return r.readEvent()
 }
 
 // Close 关闭流
@@ -184,12 +232,22 @@ func (r *StreamReader) Close() error {
 
 	r.closed = true
 	r.state = StreamStateClosed
-	r.cancel()
-	close(r.events)
+	r.release()
 
 	return nil
 }
 
+// release 注销上下文回调并取消流的上下文，关闭底层读取器，使结束的流不再挂在调用方的上下文上
+func (r *StreamReader) release() {
+	r.released.Do(func() {
+		r.stop()
+		r.cancel()
+		if r.closer != nil {
+			r.closer.Close()
+		}
+	})
+}
+
 // Err 获取错误
 func (r *StreamReader) Err() error {
 	r.mutex.RLock()
@@ -248,11 +306,22 @@ func (r *StreamReader) readEvent() (*StreamEvent, error) {
 
 		line, err := r.reader.ReadString('\n')
 		if err != nil {
+			// 关闭或上下文取消导致的读取错误
+			if ctxErr := r.ctx.Err(); ctxErr != nil {
+				r.mutex.RLock()
+				closed := r.closed
+				r.mutex.RUnlock()
+				if closed {
+					return nil, io.EOF
+				}
+				return nil, ctxErr
+			}
 			if err == io.EOF {
 				r.mutex.Lock()
 				r.done = true
 				r.state = StreamStateCompleted
 				r.mutex.Unlock()
+				r.release()
 				return nil, io.EOF
 			}
 
@@ -341,8 +410,11 @@ func (w *StreamWriter) WriteEvent(event *StreamEvent) error {
 		buffer.WriteString(fmt.Sprintf("retry: %d\n", event.Retry))
 	}
 
+	// 多行数据按SSE规范逐行写入data字段
 	if event.Data != nil {
-		buffer.WriteString(fmt.Sprintf("data: %s\n", string(event.Data)))
+		for _, line := range strings.Split(string(event.Data), "\n") {
+			buffer.WriteString(fmt.Sprintf("data: %s\n", line))
+		}
 	}
 
 	buffer.WriteString("\n")
@@ -395,17 +467,32 @@ func (w *StreamWriter) WriteComplete() error {
 	return w.WriteEvent(event)
 }
 
-// Close 关闭写入器
+// WriteDone 写入OpenAI兼容的结束标记 data: [DONE]
+func (w *StreamWriter) WriteDone() error {
+	return w.WriteEvent(&StreamEvent{
+		Type: StreamEventTypeComplete,
+		Data: json.RawMessage("[DONE]"),
+	})
+}
+
+// Close 写入完成信号并关闭写入器
 func (w *StreamWriter) Close() error {
 	w.mutex.Lock()
-	defer w.mutex.Unlock()
-
-	if w.closed {
+	closed := w.closed
+	w.mutex.Unlock()
+	if closed {
 		return nil
 	}
 
+	if err := w.WriteComplete(); err != nil {
+		return err
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
 	w.closed = true
-	return w.WriteComplete()
+	return nil
 }
 
 // AddHandler 添加事件处理器
@@ -432,13 +519,14 @@ func (p *StreamProcessor) Start() error {
 		return fmt.Errorf("processor is already running")
 	}
 	p.running = true
+	p.stopped = make(chan struct{})
 	p.mutex.Unlock()
 
 	go p.process()
 	return nil
 }
 
-// Stop 停止处理器
+// Stop 停止处理器并关闭流，使阻塞中的读取返回。处理协程随后退出，可调用Wait等待
 func (p *StreamProcessor) Stop() error {
 	p.mutex.Lock()
 	if !p.running {
@@ -449,17 +537,37 @@ func (p *StreamProcessor) Stop() error {
 	p.mutex.Unlock()
 
 	p.cancel()
-	return nil
+	return p.reader.Close()
 }
 
-// process 处理事件
+// Wait 等待处理协程退出，未启动时立即返回。不能在事件处理器中调用
+func (p *StreamProcessor) Wait() {
+	p.mutex.RLock()
+	stopped := p.stopped
+	p.mutex.RUnlock()
+
+	if stopped != nil {
+		<-stopped
+	}
+}
+
+// process 处理事件，流结束、出错、上下文取消或Stop后退出
 func (p *StreamProcessor) process() {
+	p.mutex.RLock()
+	stopped := p.stopped
+	p.mutex.RUnlock()
+
 	defer func() {
 		p.mutex.Lock()
 		p.running = false
 		p.mutex.Unlock()
+		close(stopped)
 	}()
 
+	// 上下文取消时关闭流，避免阻塞在读取上
+	stop := context.AfterFunc(p.ctx, func() { p.reader.Close() })
+	defer stop()
+
 	for {
 		select {
 		case <-p.ctx.Done():
@@ -469,17 +577,18 @@ func (p *StreamProcessor) process() {
 
 		event, err := p.reader.Next()
 		if err != nil {
-			if err == io.EOF {
+			// 流结束或被停止时直接退出，读取错误通知错误处理器后退出
+			if err == io.EOF || p.ctx.Err() != nil {
 				return
 			}
 
-			// 处理错误
+			data, _ := json.Marshal(map[string]string{"error": err.Error()})
 			p.handleEvent(&StreamEvent{
 				Type:  StreamEventTypeError,
 				Event: "error",
-				Data:  json.RawMessage(fmt.Sprintf(`{"error": "%s"}`, err.Error())),
+				Data:  data,
 			})
-			continue
+			return
 		}
 
 		if event != nil {
@@ -502,10 +611,11 @@ func (p *StreamProcessor) handleEvent(event *StreamEvent) {
 			p.mutex.RUnlock()
 
 			if hasErrorHandler && errorHandler != nil {
+				data, _ := json.Marshal(map[string]string{"error": err.Error()})
 				errorEvent := &StreamEvent{
 					Type:  StreamEventTypeError,
 					Event: "handler_error",
-					Data:  json.RawMessage(fmt.Sprintf(`{"error": "%s"}`, err.Error())),
+					Data:  data,
 				}
 				errorHandler(errorEvent)
 			}
@@ -0,0 +1,95 @@
+package usage
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrBudgetExceeded 超出预算时返回的错误，可通过errors.Is判断
+var ErrBudgetExceeded = errors.New("usage budget exceeded")
+
+// Budget 用量预算。Model、Tenant和Tag为空时匹配所有请求，Model按前缀匹配。
+// 累计费用达到MaxCost或累计Token达到MaxTokens后，匹配的新请求将被拒绝
+type Budget struct {
+	Name      string  `json:"name"`
+	Model     string  `json:"model,omitempty"`
+	Tenant    string  `json:"tenant,omitempty"`
+	Tag       string  `json:"tag,omitempty"`
+	MaxCost   float64 `json:"max_cost,omitempty"`
+	MaxTokens int64   `json:"max_tokens,omitempty"`
+}
+
+// BudgetStatus 预算的累计状态
+type BudgetStatus struct {
+	Budget
+	Cost     float64 `json:"cost"`
+	Tokens   int64   `json:"tokens"`
+	Exceeded bool    `json:"exceeded"`
+}
+
+// BudgetExceededError 超出预算的错误
+type BudgetExceededError struct {
+	Status BudgetStatus
+}
+
+// Error 实现error接口
+func (e *BudgetExceededError) Error() string {
+	if e.Status.MaxCost > 0 && e.Status.Cost >= e.Status.MaxCost {
+		return fmt.Sprintf("%s: %s spent $%.4f of $%.4f", ErrBudgetExceeded, e.Status.Name, e.Status.Cost, e.Status.MaxCost)
+	}
+	return fmt.Sprintf("%s: %s used %d of %d tokens", ErrBudgetExceeded, e.Status.Name, e.Status.Tokens, e.Status.MaxTokens)
+}
+
+// Is 支持errors.Is(err, ErrBudgetExceeded)
+func (e *BudgetExceededError) Is(target error) bool {
+	return target == ErrBudgetExceeded
+}
+
+// budgetState 预算及其累计值，由Tracker的锁保护
+type budgetState struct {
+	Budget
+	cost   float64
+	tokens int64
+}
+
+// matches 检查请求是否适用该预算
+func (b *budgetState) matches(model, tenant string, tags []string) bool {
+	if b.Model != "" && !strings.HasPrefix(strings.ToLower(model), strings.ToLower(b.Model)) {
+		return false
+	}
+	if b.Tenant != "" && b.Tenant != tenant {
+		return false
+	}
+	if b.Tag != "" && !containsTag(tags, b.Tag) {
+		return false
+	}
+	return true
+}
+
+// add 累加用量
+func (b *budgetState) add(record Record, cost float64) {
+	b.cost += cost
+	b.tokens += int64(record.PromptTokens + record.CompletionTokens)
+}
+
+// exceeded 检查是否已超出预算
+func (b *budgetState) exceeded() bool {
+	return (b.MaxCost > 0 && b.cost >= b.MaxCost) || (b.MaxTokens > 0 && b.tokens >= b.MaxTokens)
+}
+
+// status 获取预算状态
+func (b *budgetState) status() BudgetStatus {
+	return BudgetStatus{Budget: b.Budget, Cost: b.cost, Tokens: b.tokens, Exceeded: b.exceeded()}
+}
+
+// error 创建超出预算的错误
+func (b *budgetState) error() error {
+	return &BudgetExceededError{Status: b.status()}
+}
+
+// reset 清空累计值
+func (b *budgetState) reset() {
+	b.cost = 0
+	b.tokens = 0
+}
@@ -0,0 +1,58 @@
+package usage
+
+import (
+	"context"
+)
+
+// 上下文键类型
+type contextKey string
+
+// 上下文键常量
+const (
+	tenantKey contextKey = "usage_tenant"
+	tagsKey   contextKey = "usage_tags"
+)
+
+// WithTenant 添加租户到上下文，用量按租户聚合
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey, tenant)
+}
+
+// TenantFromContext 从上下文中获取租户
+func TenantFromContext(ctx context.Context) string {
+	if tenant, ok := ctx.Value(tenantKey).(string); ok {
+		return tenant
+	}
+	return ""
+}
+
+// WithTags 添加标签到上下文，与上下文中已有的标签合并，用量按每个标签分别聚合
+func WithTags(ctx context.Context, tags ...string) context.Context {
+	existing := TagsFromContext(ctx)
+	merged := make([]string, 0, len(existing)+len(tags))
+	merged = append(merged, existing...)
+	for _, tag := range tags {
+		if tag != "" && !containsTag(merged, tag) {
+			merged = append(merged, tag)
+		}
+	}
+	return context.WithValue(ctx, tagsKey, merged)
+}
+
+// TagsFromContext 从上下文中获取标签
+func TagsFromContext(ctx context.Context) []string {
+	if tags, ok := ctx.Value(tagsKey).([]string); ok {
+		return tags
+	}
+	return nil
+}
+
+// containsTag 检查标签是否存在
+func containsTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
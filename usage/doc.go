@@ -0,0 +1,11 @@
+// Package usage provides usage and cost accounting for the New-API Go SDK.
+//
+// A Tracker aggregates the token, audio and image usage reported by every
+// service per model, per tenant, per tag and per service. Tenants and tags are
+// taken from the request context (see WithTenant and WithTags), so a caller can
+// attribute spend to a customer or a product feature without changing service
+// calls. Costs are computed from a PricingTable, which can be loaded from a JSON
+// file and falls back to the prices in the model capability registry. Budgets
+// reject new requests once their limit is reached, and snapshots can be
+// exported as JSON.
+package usage
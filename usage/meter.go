@@ -0,0 +1,66 @@
+package usage
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/hewenyu/newapi-go/types"
+)
+
+// StreamMeter 从流式响应的数据块中提取用量，并在流结束时记录一次。
+// 服务端只在请求了stream_options.include_usage时返回用量，否则只记录请求次数
+type StreamMeter struct {
+	recorder Recorder
+	ctx      context.Context
+	service  string
+	model    string
+	mu       sync.Mutex
+	usage    *types.Usage
+	recorded bool
+}
+
+// NewStreamMeter 创建流式用量计量器，recorder为nil时返回nil，nil计量器的方法均为空操作
+func NewStreamMeter(ctx context.Context, recorder Recorder, service, model string) *StreamMeter {
+	if recorder == nil {
+		return nil
+	}
+	return &StreamMeter{recorder: recorder, ctx: ctx, service: service, model: model}
+}
+
+// Observe 检查数据块中的用量字段
+func (m *StreamMeter) Observe(data []byte) {
+	if m == nil {
+		return
+	}
+
+	var chunk struct {
+		Usage *types.Usage `json:"usage"`
+	}
+	if json.Unmarshal(data, &chunk) != nil || chunk.Usage == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.usage = chunk.Usage
+}
+
+// Finish 记录用量，流结束、出错或关闭时调用，只在第一次调用时生效
+func (m *StreamMeter) Finish() {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	if m.recorded {
+		m.mu.Unlock()
+		return
+	}
+	m.recorded = true
+	record := FromUsage(m.service, m.model, m.usage)
+	m.mu.Unlock()
+
+	m.recorder.Record(m.ctx, record)
+}
@@ -0,0 +1,96 @@
+package usage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/hewenyu/newapi-go/types"
+)
+
+// PricingTable 模型价格表，按模型名称的最长前缀匹配。
+// 表中未配置的模型使用模型能力注册表中的价格
+type PricingTable struct {
+	prices   *types.CapabilityRegistry
+	fallback *types.CapabilityRegistry
+}
+
+// NewPricingTable 创建价格表，未配置的模型回退到全局默认能力注册表
+func NewPricingTable(prices map[string]types.ModelPricing) *PricingTable {
+	table := &PricingTable{
+		prices:   types.NewCapabilityRegistry(),
+		fallback: types.DefaultCapabilityRegistry(),
+	}
+	for model, pricing := range prices {
+		table.Set(model, pricing)
+	}
+	return table
+}
+
+// LoadPricingFile 从JSON文件加载价格表，文件内容为模型名称到价格的映射，例如
+//
+//	{"gpt-4o": {"input_per_million": 2.5, "cached_input_per_million": 1.25, "output_per_million": 10}}
+func LoadPricingFile(path string) (*PricingTable, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pricing file: %w", err)
+	}
+
+	var prices map[string]types.ModelPricing
+	if err := json.Unmarshal(data, &prices); err != nil {
+		return nil, fmt.Errorf("failed to parse pricing file: %w", err)
+	}
+	return NewPricingTable(prices), nil
+}
+
+// Set 设置模型价格，覆盖同名条目和能力注册表中的价格
+func (p *PricingTable) Set(model string, pricing types.ModelPricing) {
+	p.prices.Register(types.ModelCapabilities{ID: model, Pricing: &pricing})
+}
+
+// SetFallback 设置未配置模型使用的能力注册表，nil时不回退
+func (p *PricingTable) SetFallback(registry *types.CapabilityRegistry) {
+	p.fallback = registry
+}
+
+// Lookup 查找模型价格
+func (p *PricingTable) Lookup(model string) (types.ModelPricing, bool) {
+	if capabilities, ok := p.prices.Lookup(model); ok && capabilities.Pricing != nil {
+		return *capabilities.Pricing, true
+	}
+	if p.fallback != nil {
+		if capabilities, ok := p.fallback.Lookup(model); ok && capabilities.Pricing != nil {
+			return *capabilities.Pricing, true
+		}
+	}
+	return types.ModelPricing{}, false
+}
+
+// Cost 计算用量记录的费用（美元），模型没有价格时返回false
+func (p *PricingTable) Cost(record Record) (float64, bool) {
+	pricing, ok := p.Lookup(record.Model)
+	if !ok {
+		return 0, false
+	}
+	return Cost(pricing, record), true
+}
+
+// Cost 按价格计算用量记录的费用（美元）。缓存命中的Token包含在PromptTokens中，
+// 推理Token包含在CompletionTokens中，均不重复计费
+func Cost(pricing types.ModelPricing, record Record) float64 {
+	cached := record.CachedTokens
+	if cached > record.PromptTokens {
+		cached = record.PromptTokens
+	}
+	cachedPrice := pricing.CachedInputPerMillion
+	if cachedPrice == 0 {
+		cachedPrice = pricing.InputPerMillion
+	}
+
+	cost := float64(record.PromptTokens-cached) * pricing.InputPerMillion / 1e6
+	cost += float64(cached) * cachedPrice / 1e6
+	cost += float64(record.CompletionTokens) * pricing.OutputPerMillion / 1e6
+	cost += record.AudioSeconds / 60 * pricing.AudioPerMinute
+	cost += float64(record.Images) * pricing.PerImage
+	return cost
+}
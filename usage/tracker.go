@@ -0,0 +1,289 @@
+package usage
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/hewenyu/newapi-go/types"
+)
+
+// 服务名称常量
+const (
+	ServiceChat          = "chat"
+	ServiceCompletion    = "completion"
+	ServiceEmbedding     = "embedding"
+	ServiceTranscription = "transcription"
+	ServiceImage         = "image"
+)
+
+// Recorder 用量记录接口，服务在请求前调用Check检查预算，在请求成功后调用Record记录用量
+type Recorder interface {
+	// Check 检查请求是否超出预算，超出时返回BudgetExceededError
+	Check(ctx context.Context, model string) error
+	// Record 记录一次请求的用量
+	Record(ctx context.Context, record Record)
+}
+
+// Record 单次请求的用量记录
+type Record struct {
+	Service          string    `json:"service"`
+	Model            string    `json:"model"`
+	Tenant           string    `json:"tenant,omitempty"`
+	Tags             []string  `json:"tags,omitempty"`
+	PromptTokens     int       `json:"prompt_tokens,omitempty"`
+	CompletionTokens int       `json:"completion_tokens,omitempty"`
+	CachedTokens     int       `json:"cached_tokens,omitempty"`
+	ReasoningTokens  int       `json:"reasoning_tokens,omitempty"`
+	AudioSeconds     float64   `json:"audio_seconds,omitempty"`
+	Images           int       `json:"images,omitempty"`
+	Time             time.Time `json:"time"`
+}
+
+// FromUsage 根据响应的Token用量创建用量记录
+func FromUsage(service, model string, u *types.Usage) Record {
+	record := Record{Service: service, Model: model}
+	if u == nil {
+		return record
+	}
+
+	record.PromptTokens = u.PromptTokens
+	record.CompletionTokens = u.CompletionTokens
+	if u.PromptTokensDetails != nil {
+		record.CachedTokens = u.PromptTokensDetails.CachedTokens
+	}
+	if u.CompletionTokensDetails != nil {
+		record.ReasoningTokens = u.CompletionTokensDetails.ReasoningTokens
+	}
+	return record
+}
+
+// Totals 聚合的用量和费用
+type Totals struct {
+	Requests         int64   `json:"requests"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	CachedTokens     int64   `json:"cached_tokens"`
+	ReasoningTokens  int64   `json:"reasoning_tokens"`
+	TotalTokens      int64   `json:"total_tokens"`
+	AudioSeconds     float64 `json:"audio_seconds"`
+	Images           int64   `json:"images"`
+	Cost             float64 `json:"cost"`
+	// UnpricedRequests 模型没有价格、未计入费用的请求数
+	UnpricedRequests int64 `json:"unpriced_requests,omitempty"`
+}
+
+// add 累加一条用量记录
+func (t *Totals) add(record Record, cost float64, priced bool) {
+	t.Requests++
+	t.PromptTokens += int64(record.PromptTokens)
+	t.CompletionTokens += int64(record.CompletionTokens)
+	t.CachedTokens += int64(record.CachedTokens)
+	t.ReasoningTokens += int64(record.ReasoningTokens)
+	t.TotalTokens += int64(record.PromptTokens + record.CompletionTokens)
+	t.AudioSeconds += record.AudioSeconds
+	t.Images += int64(record.Images)
+	t.Cost += cost
+	if !priced {
+		t.UnpricedRequests++
+	}
+}
+
+// Snapshot 用量快照
+type Snapshot struct {
+	Since     time.Time         `json:"since"`
+	At        time.Time         `json:"at"`
+	Total     Totals            `json:"total"`
+	ByModel   map[string]Totals `json:"by_model"`
+	ByTenant  map[string]Totals `json:"by_tenant"`
+	ByTag     map[string]Totals `json:"by_tag"`
+	ByService map[string]Totals `json:"by_service"`
+	Budgets   []BudgetStatus    `json:"budgets,omitempty"`
+}
+
+// TrackerOption 用量跟踪器选项函数类型
+type TrackerOption func(*Tracker)
+
+// WithPricing 设置价格表
+func WithPricing(pricing *PricingTable) TrackerOption {
+	return func(t *Tracker) {
+		t.pricing = pricing
+	}
+}
+
+// WithBudget 添加预算
+func WithBudget(budget Budget) TrackerOption {
+	return func(t *Tracker) {
+		t.budgets = append(t.budgets, &budgetState{Budget: budget})
+	}
+}
+
+// Tracker 用量跟踪器，实现Recorder接口，可安全地并发使用
+type Tracker struct {
+	mu        sync.RWMutex
+	pricing   *PricingTable
+	budgets   []*budgetState
+	since     time.Time
+	total     Totals
+	byModel   map[string]*Totals
+	byTenant  map[string]*Totals
+	byTag     map[string]*Totals
+	byService map[string]*Totals
+}
+
+// NewTracker 创建用量跟踪器，默认价格表使用模型能力注册表中的价格
+func NewTracker(options ...TrackerOption) *Tracker {
+	t := &Tracker{pricing: NewPricingTable(nil)}
+	t.reset()
+
+	// 应用选项
+	for _, option := range options {
+		option(t)
+	}
+	return t
+}
+
+// SetPricing 替换价格表，只影响之后记录的用量
+func (t *Tracker) SetPricing(pricing *PricingTable) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.pricing = pricing
+}
+
+// AddBudget 添加预算，预算从添加时开始累计
+func (t *Tracker) AddBudget(budget Budget) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.budgets = append(t.budgets, &budgetState{Budget: budget})
+}
+
+// Check 检查请求是否超出任一适用的预算
+func (t *Tracker) Check(ctx context.Context, model string) error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	tenant, tags := TenantFromContext(ctx), TagsFromContext(ctx)
+	for _, budget := range t.budgets {
+		if budget.matches(model, tenant, tags) && budget.exceeded() {
+			return budget.error()
+		}
+	}
+	return nil
+}
+
+// Record 记录一次请求的用量，未设置的租户和标签从上下文中获取
+func (t *Tracker) Record(ctx context.Context, record Record) {
+	if record.Tenant == "" {
+		record.Tenant = TenantFromContext(ctx)
+	}
+	if record.Tags == nil {
+		record.Tags = TagsFromContext(ctx)
+	}
+	if record.Time.IsZero() {
+		record.Time = time.Now()
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var cost float64
+	var priced bool
+	if t.pricing != nil {
+		cost, priced = t.pricing.Cost(record)
+	}
+
+	t.total.add(record, cost, priced)
+	addTo(t.byModel, record.Model, record, cost, priced)
+	addTo(t.byTenant, record.Tenant, record, cost, priced)
+	addTo(t.byService, record.Service, record, cost, priced)
+	for _, tag := range record.Tags {
+		addTo(t.byTag, tag, record, cost, priced)
+	}
+	for _, budget := range t.budgets {
+		if budget.matches(record.Model, record.Tenant, record.Tags) {
+			budget.add(record, cost)
+		}
+	}
+}
+
+// addTo 累加到分组，key为空时忽略
+func addTo(groups map[string]*Totals, key string, record Record, cost float64, priced bool) {
+	if key == "" {
+		return
+	}
+	totals, ok := groups[key]
+	if !ok {
+		totals = &Totals{}
+		groups[key] = totals
+	}
+	totals.add(record, cost, priced)
+}
+
+// Total 获取总用量
+func (t *Tracker) Total() Totals {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.total
+}
+
+// Snapshot 获取当前用量快照
+func (t *Tracker) Snapshot() Snapshot {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	snapshot := Snapshot{
+		Since:     t.since,
+		At:        time.Now(),
+		Total:     t.total,
+		ByModel:   copyGroups(t.byModel),
+		ByTenant:  copyGroups(t.byTenant),
+		ByTag:     copyGroups(t.byTag),
+		ByService: copyGroups(t.byService),
+	}
+	for _, budget := range t.budgets {
+		snapshot.Budgets = append(snapshot.Budgets, budget.status())
+	}
+	return snapshot
+}
+
+// copyGroups 复制分组数据
+func copyGroups(groups map[string]*Totals) map[string]Totals {
+	copied := make(map[string]Totals, len(groups))
+	for key, totals := range groups {
+		copied[key] = *totals
+	}
+	return copied
+}
+
+// WriteJSON 将当前用量快照以JSON格式写入w
+func (t *Tracker) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(t.Snapshot())
+}
+
+// Reset 清空所有用量和预算累计
+func (t *Tracker) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.reset()
+	for _, budget := range t.budgets {
+		budget.reset()
+	}
+}
+
+// reset 清空用量，调用方需持有锁
+func (t *Tracker) reset() {
+	t.since = time.Now()
+	t.total = Totals{}
+	t.byModel = make(map[string]*Totals)
+	t.byTenant = make(map[string]*Totals)
+	t.byTag = make(map[string]*Totals)
+	t.byService = make(map[string]*Totals)
+}
@@ -0,0 +1,169 @@
+package usage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hewenyu/newapi-go/types"
+)
+
+// approxEqual 比较浮点数
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestCostWithCachedTokens(t *testing.T) {
+	pricing := types.ModelPricing{InputPerMillion: 2, CachedInputPerMillion: 0.5, OutputPerMillion: 8}
+	record := Record{PromptTokens: 1_000_000, CachedTokens: 400_000, CompletionTokens: 500_000}
+
+	// 600k * 2 + 400k * 0.5 + 500k * 8
+	if got := Cost(pricing, record); !approxEqual(got, 1.2+0.2+4) {
+		t.Errorf("Cost() = %v", got)
+	}
+
+	// 未配置缓存价格时按输入价格计价
+	pricing.CachedInputPerMillion = 0
+	if got := Cost(pricing, record); !approxEqual(got, 2+4) {
+		t.Errorf("Cost() without cached price = %v", got)
+	}
+
+	audio := Cost(types.ModelPricing{AudioPerMinute: 0.006}, Record{AudioSeconds: 90})
+	if !approxEqual(audio, 0.009) {
+		t.Errorf("audio cost = %v", audio)
+	}
+}
+
+func TestTrackerAggregatesByDimension(t *testing.T) {
+	pricing := NewPricingTable(map[string]types.ModelPricing{"my-model": {InputPerMillion: 1, OutputPerMillion: 2}})
+	tracker := NewTracker(WithPricing(pricing))
+
+	ctx := WithTags(WithTenant(context.Background(), "acme"), "search")
+	tracker.Record(ctx, Record{Service: ServiceChat, Model: "my-model-v2", PromptTokens: 1000, CompletionTokens: 500})
+	tracker.Record(WithTags(ctx, "summarize"), Record{Service: ServiceChat, Model: "gpt-4o", PromptTokens: 1000})
+	tracker.Record(context.Background(), Record{Service: ServiceChat, Model: "unknown-model", PromptTokens: 10})
+
+	snapshot := tracker.Snapshot()
+	if snapshot.Total.Requests != 3 || snapshot.Total.TotalTokens != 2510 {
+		t.Errorf("total = %+v", snapshot.Total)
+	}
+	if snapshot.Total.UnpricedRequests != 1 {
+		t.Errorf("unpriced = %d, want 1", snapshot.Total.UnpricedRequests)
+	}
+	if got := snapshot.ByModel["my-model-v2"].Cost; !approxEqual(got, 0.002) {
+		t.Errorf("my-model cost = %v", got)
+	}
+	// gpt-4o 回退到能力注册表中的价格
+	if got := snapshot.ByModel["gpt-4o"].Cost; !approxEqual(got, 0.0025) {
+		t.Errorf("gpt-4o cost = %v", got)
+	}
+	if got := snapshot.ByTenant["acme"].Requests; got != 2 {
+		t.Errorf("acme requests = %d", got)
+	}
+	if got := snapshot.ByTag["search"].Requests; got != 2 {
+		t.Errorf("search requests = %d", got)
+	}
+	if got := snapshot.ByTag["summarize"].Cost; !approxEqual(got, 0.0025) {
+		t.Errorf("summarize cost = %v", got)
+	}
+
+	var buf bytes.Buffer
+	if err := tracker.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+	var decoded Snapshot
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("snapshot is not valid JSON: %v", err)
+	}
+	if decoded.ByService[ServiceChat].Requests != 3 {
+		t.Errorf("decoded by_service = %+v", decoded.ByService)
+	}
+
+	tracker.Reset()
+	if total := tracker.Total(); total.Requests != 0 {
+		t.Errorf("total after reset = %+v", total)
+	}
+}
+
+func TestBudgetRejectsOnceExceeded(t *testing.T) {
+	tracker := NewTracker(
+		WithBudget(Budget{Name: "acme-search", Tenant: "acme", Tag: "search", MaxCost: 0.01}),
+		WithBudget(Budget{Name: "tokens", MaxTokens: 1_000_000}),
+	)
+
+	acme := WithTags(WithTenant(context.Background(), "acme"), "search")
+	other := WithTenant(context.Background(), "other")
+
+	if err := tracker.Check(acme, "gpt-4o"); err != nil {
+		t.Fatalf("unexpected error before spend: %v", err)
+	}
+	// 4000 * 2.5/1M = 0.01
+	tracker.Record(acme, Record{Model: "gpt-4o", PromptTokens: 4000})
+
+	err := tracker.Check(acme, "gpt-4o")
+	if !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("expected ErrBudgetExceeded, got %v", err)
+	}
+	var budgetErr *BudgetExceededError
+	if !errors.As(err, &budgetErr) || budgetErr.Status.Name != "acme-search" {
+		t.Errorf("budget error = %v", err)
+	}
+	if err := tracker.Check(other, "gpt-4o"); err != nil {
+		t.Errorf("other tenant should not be limited: %v", err)
+	}
+
+	tracker.Record(other, Record{Model: "unknown", PromptTokens: 1_000_000})
+	if err := tracker.Check(other, "gpt-4o"); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("expected global token budget to be exceeded, got %v", err)
+	}
+}
+
+func TestLoadPricingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pricing.json")
+	content := `{"gpt-4o": {"input_per_million": 5, "output_per_million": 20}, "custom": {"input_per_million": 1}}`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	table, err := LoadPricingFile(path)
+	if err != nil {
+		t.Fatalf("LoadPricingFile failed: %v", err)
+	}
+	if pricing, ok := table.Lookup("gpt-4o-2024-08-06"); !ok || pricing.InputPerMillion != 5 {
+		t.Errorf("Lookup(gpt-4o) = %+v, %v", pricing, ok)
+	}
+	if pricing, ok := table.Lookup("gpt-4.1"); !ok || pricing.InputPerMillion != 2 {
+		t.Errorf("Lookup(gpt-4.1) should fall back to the registry: %+v, %v", pricing, ok)
+	}
+
+	table.SetFallback(nil)
+	if _, ok := table.Lookup("gpt-4.1"); ok {
+		t.Error("expected no pricing without fallback")
+	}
+}
+
+func TestStreamMeterRecordsOnce(t *testing.T) {
+	tracker := NewTracker()
+	meter := NewStreamMeter(context.Background(), tracker, ServiceChat, "gpt-4o")
+	meter.Observe([]byte(`{"choices":[{"delta":{"content":"hi"}}]}`))
+	meter.Observe([]byte(`{"choices":[],"usage":{"prompt_tokens":10,"completion_tokens":2,"total_tokens":12}}`))
+	meter.Finish()
+	meter.Finish()
+
+	total := tracker.Total()
+	if total.Requests != 1 || total.PromptTokens != 10 || total.CompletionTokens != 2 {
+		t.Errorf("total = %+v", total)
+	}
+
+	var nilMeter *StreamMeter
+	nilMeter.Observe(nil)
+	nilMeter.Finish()
+	if NewStreamMeter(context.Background(), nil, ServiceChat, "gpt-4o") != nil {
+		t.Error("expected nil meter without recorder")
+	}
+}